@@ -1,14 +1,30 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
 	"github.com/jinzhu/configor"
 )
 
 type Config struct {
 	AppConfig     AppConfig `env:"APP_CONFIG"`
+	ServerConfig  ServerConfig
 	DBConfig      DBConfig
 	DataDogConfig DataDogConfig
+	MetricsConfig MetricsConfig
+	TracingConfig TracingConfig
+	BrokerConfig  BrokerConfig
 	PulsarConfig  PulsarConfig
+	KafkaConfig   KafkaConfig
+	NATSConfig    NATSConfig
+	OutboxConfig  OutboxConfig
+	WebhookConfig WebhookConfig
+	CacheConfig   CacheConfig
 }
 
 type AppConfig struct {
@@ -18,14 +34,75 @@ type AppConfig struct {
 	Env     string `default:"development" env:"ENV"`
 }
 
+type ServerConfig struct {
+	// ReadTimeoutMs bounds reading a whole request, body included
+	ReadTimeoutMs       int `default:"15000" env:"SERVER_READ_TIMEOUT_MS"`
+	ReadHeaderTimeoutMs int `default:"5000" env:"SERVER_READ_HEADER_TIMEOUT_MS"`
+	// WriteTimeoutMs bounds handling a request and writing its response
+	WriteTimeoutMs int `default:"30000" env:"SERVER_WRITE_TIMEOUT_MS"`
+	IdleTimeoutMs  int `default:"120000" env:"SERVER_IDLE_TIMEOUT_MS"`
+	// ShutdownTimeoutMs is how long in-flight requests get to finish after
+	// SIGTERM before their connections are closed
+	ShutdownTimeoutMs int `default:"25000" env:"SERVER_SHUTDOWN_TIMEOUT_MS"`
+	// ShutdownDelayMs keeps serving after /readyz starts failing, so load
+	// balancers stop sending traffic before the listener closes
+	ShutdownDelayMs int `default:"5000" env:"SERVER_SHUTDOWN_DELAY_MS"`
+	// ProbeTimeoutMs bounds each /readyz dependency check
+	ProbeTimeoutMs int `default:"2000" env:"SERVER_PROBE_TIMEOUT_MS"`
+	// CORSAllowedOrigins is a comma separated list of origins browsers may
+	// call the API from
+	CORSAllowedOrigins string `default:"http://localhost:8081,http://localhost:3000" env:"SERVER_CORS_ALLOWED_ORIGINS"`
+	// DataLoaderWaitMs is how long a request's dataloaders collect keys
+	// before fetching them together
+	DataLoaderWaitMs int `default:"5" env:"SERVER_DATALOADER_WAIT_MS"`
+	// DataLoaderMaxBatch caps the keys a dataloader fetches at once
+	DataLoaderMaxBatch int `default:"100" env:"SERVER_DATALOADER_MAX_BATCH"`
+}
+
+// AllowedOrigins returns the configured CORS origins
+func (c ServerConfig) AllowedOrigins() []string {
+	return splitList(c.CORSAllowedOrigins)
+}
+
 type DBConfig struct {
+	// Driver is the database to connect to, mysql, postgres or sqlite. With
+	// sqlite DBNAME is the path of the database file.
+	Driver             string `default:"mysql" env:"DBDRIVER"`
 	Host               string `default:"localhost" env:"DBHOST"`
 	DataBase           string `default:"weeb" env:"DBNAME"`
 	User               string `default:"weeb" env:"DBUSERNAME"`
-	Password           string `required:"true" env:"DBPASSWORD" default:"mysecretpassword"`
+	Password           string `required:"true" env:"DBPASSWORD" default:"mysecretpassword" secret:"true"`
 	Port               uint   `default:"3306" env:"DBPORT"`
 	SSLMode            string `default:"false" env:"DBSSL"`
 	MigrationTableName string `env:"DBMIGRATIONTABLE" default:"__migrations_list-service"`
+	// Replicas is a comma separated list of read replica DSNs, written in the
+	// driver's own DSN format. Reads go to a healthy replica, writes to the
+	// primary. Users only see their own writes straight away on the instance
+	// that made them, see ReadYourWritesMs.
+	Replicas string `default:"" env:"DBREPLICAS" secret:"true"`
+	// ReplicaMaxLagMs ejects replicas further behind the primary than this
+	ReplicaMaxLagMs int `default:"5000" env:"DBREPLICA_MAX_LAG_MS"`
+	// ReplicaCheckIntervalMs is how often replica health and lag are checked
+	ReplicaCheckIntervalMs int `default:"5000" env:"DBREPLICA_CHECK_INTERVAL_MS"`
+	// ReadYourWritesMs keeps a user's reads on the primary for this long after
+	// they write. Writes are tracked per instance, so the guarantee only holds
+	// while a user's requests reach the same instance, e.g. through sticky
+	// sessions. Reads landing on another instance may hit a lagging replica.
+	ReadYourWritesMs int `default:"5000" env:"DBREPLICA_READ_YOUR_WRITES_MS"`
+	// MaxOpenConns caps the connections to each database, primary and
+	// replicas alike
+	MaxOpenConns int `default:"25" env:"DBMAX_OPEN_CONNS"`
+	// MaxIdleConns is the pool of connections kept open for reuse
+	MaxIdleConns int `default:"10" env:"DBMAX_IDLE_CONNS"`
+	// ConnMaxLifetimeMs recycles connections before the server drops them,
+	// MySQL wait_timeout is typically 8 hours
+	ConnMaxLifetimeMs int `default:"300000" env:"DBCONN_MAX_LIFETIME_MS"`
+	ConnMaxIdleTimeMs int `default:"90000" env:"DBCONN_MAX_IDLE_TIME_MS"`
+	// SlowQueryMs logs statements that take at least this long, 0 turns slow
+	// query logging off
+	SlowQueryMs int `default:"200" env:"DBSLOW_QUERY_MS"`
+	// StatsIntervalMs is how often connection pool stats are published
+	StatsIntervalMs int `default:"15000" env:"DBSTATS_INTERVAL_MS"`
 }
 
 type DataDogConfig struct {
@@ -33,16 +110,180 @@ type DataDogConfig struct {
 	DD_AGENT_PORT int    `env:"DD_AGENT_PORT" default:"8125"`
 }
 
+type MetricsConfig struct {
+	// Backends is a comma separated list of where metrics are sent, any of
+	// prometheus, datadog and otlp. Every backend receives every metric.
+	Backends string `default:"prometheus" env:"METRICS_BACKENDS"`
+	// DurationBucketsMs are the comma separated, ascending upper bounds of
+	// the resolver and database duration histograms
+	DurationBucketsMs string `default:"1,5,10,25,50,100,250,500,1000,2500,5000" env:"METRICS_DURATION_BUCKETS_MS"`
+	// OTLPEndpoint is the OTLP gRPC endpoint metrics are pushed to
+	OTLPEndpoint string `default:"localhost:4317" env:"OTEL_EXPORTER_OTLP_METRICS_ENDPOINT"`
+	OTLPInsecure bool   `default:"true" env:"OTEL_EXPORTER_OTLP_METRICS_INSECURE"`
+	// OTLPIntervalMs is how often metrics are pushed to the OTLP endpoint
+	OTLPIntervalMs int `default:"15000" env:"OTEL_METRIC_EXPORT_INTERVAL"`
+	// TrackedEntriesIntervalMs is how often serve counts the stored entries
+	// by status, 0 turns the count off. Each count scans the table.
+	TrackedEntriesIntervalMs int `default:"300000" env:"METRICS_TRACKED_ENTRIES_INTERVAL_MS"`
+}
+
+// BackendList returns the configured metrics backends
+func (c MetricsConfig) BackendList() []string {
+	return splitList(c.Backends)
+}
+
+// DurationBuckets returns the configured duration histogram buckets
+func (c MetricsConfig) DurationBuckets() ([]float64, error) {
+	var buckets []float64
+	for _, item := range splitList(c.DurationBucketsMs) {
+		bucket, err := strconv.ParseFloat(item, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", item)
+		}
+		if len(buckets) > 0 && bucket <= buckets[len(buckets)-1] {
+			return nil, fmt.Errorf("%v is not more than the bucket before it", bucket)
+		}
+		buckets = append(buckets, bucket)
+	}
+
+	return buckets, nil
+}
+
+type TracingConfig struct {
+	// Endpoint is the OTLP gRPC endpoint traces are exported to
+	Endpoint string `default:"localhost:4317" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	Insecure bool   `default:"true" env:"OTEL_EXPORTER_OTLP_INSECURE"`
+	// ServiceVersion is reported on every span
+	ServiceVersion string `default:"1.0.0" env:"SERVICE_VERSION"`
+}
+
+type BrokerConfig struct {
+	// Backend is where events are published: pulsar, kafka, nats, memory or file
+	Backend string `default:"pulsar" env:"BROKER_BACKEND"`
+	// Schema is the encoding of published events, json or avro
+	Schema         string `default:"json" env:"BROKER_SCHEMA"`
+	MaxRetries     int    `default:"3" env:"BROKER_MAX_RETRIES"`
+	RetryBackoffMs int    `default:"200" env:"BROKER_RETRY_BACKOFF_MS"`
+	// FilePath is the JSON lines file written by the file backend
+	FilePath string `default:"events.jsonl" env:"BROKER_FILE_PATH"`
+	// Topic is used by the memory and file backends
+	Topic string `default:"myanimelist.public.user-list" env:"BROKER_TOPIC"`
+}
+
 type PulsarConfig struct {
-	URL           string `default:"pulsar://localhost:6650" env:"PULSARURL"`
-	ProducerTopic string `default:"public/default/myanimelist.public.user-list" env:"PULSARPRODUCERTOPIC"`
+	URL                       string `default:"pulsar://localhost:6650" env:"PULSARURL"`
+	ProducerTopic             string `default:"public/default/myanimelist.public.user-list" env:"PULSARPRODUCERTOPIC"`
+	SendTimeoutMs             int    `default:"30000" env:"PULSARSENDTIMEOUTMS"`
+	DisableBatching           bool   `default:"false" env:"PULSARDISABLEBATCHING"`
+	BatchingMaxPublishDelayMs int    `default:"10" env:"PULSARBATCHINGMAXPUBLISHDELAYMS"`
+	BatchingMaxMessages       uint   `default:"1000" env:"PULSARBATCHINGMAXMESSAGES"`
+}
+
+type KafkaConfig struct {
+	// Brokers is a comma separated list of host:port
+	Brokers                string `default:"localhost:9092" env:"KAFKA_BROKERS"`
+	Topic                  string `default:"myanimelist.public.user-list" env:"KAFKA_TOPIC"`
+	BatchTimeoutMs         int    `default:"10" env:"KAFKA_BATCH_TIMEOUT_MS"`
+	AllowAutoTopicCreation bool   `default:"false" env:"KAFKA_ALLOW_AUTO_TOPIC_CREATION"`
 }
 
-func LoadConfigOrPanic() Config {
+type NATSConfig struct {
+	URL     string `default:"nats://localhost:4222" env:"NATS_URL"`
+	Subject string `default:"myanimelist.public.user-list" env:"NATS_SUBJECT"`
+	// JetStream publishes to a stream and waits for it to be stored
+	JetStream bool `default:"true" env:"NATS_JETSTREAM"`
+}
+
+type OutboxConfig struct {
+	// RelayEnabled runs the outbox relay inside serve
+	RelayEnabled   bool `default:"true" env:"OUTBOX_RELAY_ENABLED"`
+	BatchSize      int  `default:"100" env:"OUTBOX_BATCH_SIZE"`
+	PollIntervalMs int  `default:"1000" env:"OUTBOX_POLL_INTERVAL_MS"`
+	// LeaseMs is how long a relay may take to publish a claimed batch before
+	// another relay takes it over
+	LeaseMs int `default:"60000" env:"OUTBOX_LEASE_MS"`
+	// RetentionMs is how long sent messages are kept, 0 keeps them forever
+	RetentionMs int `default:"604800000" env:"OUTBOX_RETENTION_MS"`
+	// MaxAttempts is how often a message is published before the relay gives
+	// up on it, so it no longer holds back later messages. Failed messages
+	// keep failed_at set until cleared, 0 retries forever.
+	MaxAttempts int `default:"20" env:"OUTBOX_MAX_ATTEMPTS"`
+}
+
+type WebhookConfig struct {
+	// WorkerEnabled runs webhook fan-out and delivery inside serve
+	WorkerEnabled bool `default:"true" env:"WEBHOOK_WORKER_ENABLED"`
+	// ConsumerGroup is the broker subscription events are fanned out from
+	ConsumerGroup  string `default:"list-service-webhooks" env:"WEBHOOK_CONSUMER_GROUP"`
+	BatchSize      int    `default:"50" env:"WEBHOOK_BATCH_SIZE"`
+	PollIntervalMs int    `default:"1000" env:"WEBHOOK_POLL_INTERVAL_MS"`
+	TimeoutMs      int    `default:"10000" env:"WEBHOOK_TIMEOUT_MS"`
+	MaxAttempts    int    `default:"8" env:"WEBHOOK_MAX_ATTEMPTS"`
+	RetryBackoffMs int    `default:"30000" env:"WEBHOOK_RETRY_BACKOFF_MS"`
+	MaxBackoffMs   int    `default:"21600000" env:"WEBHOOK_MAX_BACKOFF_MS"`
+	// DisableAfter consecutive failed attempts switch a subscription off
+	DisableAfter int `default:"50" env:"WEBHOOK_DISABLE_AFTER"`
+}
+
+type CacheConfig struct {
+	// Enabled caches list reads. Without RedisURL every instance keeps its
+	// own cache and invalidations do not reach the others, so only enable it
+	// that way for a single instance.
+	Enabled bool `default:"false" env:"CACHE_ENABLED"`
+	// RedisURL selects Redis, an in-process LRU is used when it is empty
+	RedisURL   string `default:"" env:"REDIS_URL" secret:"true"`
+	TTLSeconds int    `default:"300" env:"CACHE_TTL_SECONDS"`
+	// LRUSize is the number of entries kept by the in-process cache
+	LRUSize   int    `default:"10000" env:"CACHE_LRU_SIZE"`
+	KeyPrefix string `default:"list-service" env:"CACHE_KEY_PREFIX"`
+}
+
+// Load reads the configuration with Read and validates it
+func Load(file string) (Config, error) {
+	config, err := Read(file)
+	if err != nil {
+		return Config{}, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	return config, nil
+}
+
+// Read reads the configuration from the struct defaults, then file when it
+// is not empty, then the environment, each overriding the last
+func Read(file string) (Config, error) {
+	var files []string
+	if file != "" {
+		if _, err := os.Stat(file); err != nil {
+			return Config{}, fmt.Errorf("config file: %w", err)
+		}
+		files = append(files, file)
+	}
+
+	// configor reports a malformed override without naming it
+	if errs := checkEnv(reflect.TypeOf(Config{}), envPrefixes()); len(errs) > 0 {
+		return Config{}, errors.Join(errs...)
+	}
+
 	var config = Config{}
-	// Try to load config file, but don't fail if it doesn't exist
-	// All important config should come from environment variables anyway
-	configor.Load(&config)
+	loader := configor.New(&configor.Config{Silent: true, ErrorOnUnmatchedKeys: true})
+	if err := loader.Load(&config, files...); err != nil {
+		return Config{}, fmt.Errorf("loading config: %w", err)
+	}
+
+	return config, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
 
-	return config
+	return items
 }
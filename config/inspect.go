@@ -0,0 +1,151 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const redacted = "********"
+
+// Setting is one effective configuration value and where it was read from
+type Setting struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// Describe lists every setting of cfg, loaded with file, in declaration
+// order. A value that differs from its default without an environment
+// variable set came from the file. With redact, secrets are masked and
+// passwords are dropped from URLs.
+func Describe(cfg Config, file string, redact bool) []Setting {
+	var settings []Setting
+	describe(reflect.ValueOf(cfg), "", envPrefixes(), "", file, redact, &settings)
+	return settings
+}
+
+func describe(value reflect.Value, key string, prefixes []string, parentEnv string, file string, redact bool, settings *[]Setting) {
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		fieldKey := field.Name
+		if key != "" {
+			fieldKey = key + "." + field.Name
+		}
+
+		env := parentEnv
+		if name, _ := lookupEnv(field, prefixes); name != "" {
+			env = name
+		}
+
+		if field.Type.Kind() == reflect.Struct {
+			describe(value.Field(i), fieldKey, append(append([]string{}, prefixes...), field.Name), env, file, redact, settings)
+			continue
+		}
+
+		formatted := fmt.Sprint(value.Field(i).Interface())
+		defaultValue, hasDefault := field.Tag.Lookup("default")
+
+		var source string
+		switch {
+		case env != "":
+			source = "env " + env
+		case hasDefault && formatted == defaultValue:
+			source = "default"
+		case file != "" && !value.Field(i).IsZero():
+			source = "file " + file
+		default:
+			source = "unset"
+		}
+
+		if redact {
+			formatted = redactValue(formatted, field.Tag.Get("secret") == "true")
+		}
+
+		*settings = append(*settings, Setting{Key: fieldKey, Value: formatted, Source: source})
+	}
+}
+
+// envPrefixes returns the prefixes configor reads untagged fields with
+func envPrefixes() []string {
+	if prefix := os.Getenv("CONFIGOR_ENV_PREFIX"); prefix == "-" {
+		return nil
+	} else if prefix != "" {
+		return []string{prefix}
+	}
+
+	return []string{"Configor"}
+}
+
+// lookupEnv returns the environment variable that sets field and its value,
+// trying the names configor does
+func lookupEnv(field reflect.StructField, prefixes []string) (string, string) {
+	names := []string{field.Tag.Get("env")}
+	if names[0] == "" {
+		name := strings.Join(append(append([]string{}, prefixes...), field.Name), "_")
+		names = []string{name, strings.ToUpper(name)}
+	}
+
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return name, value
+		}
+	}
+
+	return "", ""
+}
+
+// checkEnv returns an error for every environment variable that does not
+// parse as the type of the setting it overrides
+func checkEnv(configType reflect.Type, prefixes []string) []error {
+	var errs []error
+	for i := 0; i < configType.NumField(); i++ {
+		field := configType.Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			errs = append(errs, checkEnv(field.Type, append(append([]string{}, prefixes...), field.Name))...)
+			continue
+		}
+
+		name, value := lookupEnv(field, prefixes)
+		if name == "" {
+			continue
+		}
+
+		var err error
+		switch field.Type.Kind() {
+		case reflect.Int, reflect.Int64:
+			_, err = strconv.ParseInt(value, 10, 64)
+		case reflect.Uint, reflect.Uint64:
+			_, err = strconv.ParseUint(value, 10, 64)
+		case reflect.Float64:
+			_, err = strconv.ParseFloat(value, 64)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("env %s: %q is not a valid %s", name, value, field.Type.Kind()))
+		}
+	}
+
+	return errs
+}
+
+// redactValue masks secrets and the password of URLs
+func redactValue(value string, secret bool) string {
+	if value == "" {
+		return value
+	}
+	if secret {
+		return redacted
+	}
+
+	if u, err := url.Parse(value); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			return u.Redacted()
+		}
+	}
+
+	return value
+}
@@ -0,0 +1,185 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+)
+
+// Validate returns every setting that is missing, out of range or malformed.
+// Settings of brokers and caches that are not selected are not checked.
+func (c Config) Validate() error {
+	v := &validator{}
+
+	v.required("AppConfig.APPName", c.AppConfig.APPName)
+	v.port("AppConfig.Port", c.AppConfig.Port)
+
+	v.atLeast("ServerConfig.ReadTimeoutMs", c.ServerConfig.ReadTimeoutMs, 0)
+	v.atLeast("ServerConfig.ReadHeaderTimeoutMs", c.ServerConfig.ReadHeaderTimeoutMs, 0)
+	v.atLeast("ServerConfig.WriteTimeoutMs", c.ServerConfig.WriteTimeoutMs, 0)
+	v.atLeast("ServerConfig.IdleTimeoutMs", c.ServerConfig.IdleTimeoutMs, 0)
+	v.atLeast("ServerConfig.ShutdownTimeoutMs", c.ServerConfig.ShutdownTimeoutMs, 0)
+	v.atLeast("ServerConfig.ShutdownDelayMs", c.ServerConfig.ShutdownDelayMs, 0)
+	v.atLeast("ServerConfig.ProbeTimeoutMs", c.ServerConfig.ProbeTimeoutMs, 1)
+	v.atLeast("ServerConfig.DataLoaderWaitMs", c.ServerConfig.DataLoaderWaitMs, 0)
+	v.atLeast("ServerConfig.DataLoaderMaxBatch", c.ServerConfig.DataLoaderMaxBatch, 1)
+	for _, origin := range c.ServerConfig.AllowedOrigins() {
+		v.url("ServerConfig.CORSAllowedOrigins", origin, "http", "https")
+	}
+
+	v.oneOf("DBConfig.Driver", c.DBConfig.Driver, "mysql", "postgres", "sqlite")
+	v.required("DBConfig.DataBase", c.DBConfig.DataBase)
+	v.required("DBConfig.MigrationTableName", c.DBConfig.MigrationTableName)
+	if c.DBConfig.Driver != "sqlite" {
+		v.required("DBConfig.Host", c.DBConfig.Host)
+		v.required("DBConfig.User", c.DBConfig.User)
+		v.port("DBConfig.Port", int(c.DBConfig.Port))
+	}
+	v.atLeast("DBConfig.ReplicaMaxLagMs", c.DBConfig.ReplicaMaxLagMs, 0)
+	v.atLeast("DBConfig.ReplicaCheckIntervalMs", c.DBConfig.ReplicaCheckIntervalMs, 1)
+	v.atLeast("DBConfig.ReadYourWritesMs", c.DBConfig.ReadYourWritesMs, 0)
+	v.atLeast("DBConfig.MaxOpenConns", c.DBConfig.MaxOpenConns, 1)
+	v.atLeast("DBConfig.MaxIdleConns", c.DBConfig.MaxIdleConns, 0)
+	if c.DBConfig.MaxIdleConns > c.DBConfig.MaxOpenConns {
+		v.add("DBConfig.MaxIdleConns", fmt.Errorf("%d is more than MaxOpenConns %d", c.DBConfig.MaxIdleConns, c.DBConfig.MaxOpenConns))
+	}
+	v.atLeast("DBConfig.ConnMaxLifetimeMs", c.DBConfig.ConnMaxLifetimeMs, 0)
+	v.atLeast("DBConfig.ConnMaxIdleTimeMs", c.DBConfig.ConnMaxIdleTimeMs, 0)
+	v.atLeast("DBConfig.SlowQueryMs", c.DBConfig.SlowQueryMs, 0)
+	v.atLeast("DBConfig.StatsIntervalMs", c.DBConfig.StatsIntervalMs, 1)
+
+	v.port("DataDogConfig.DD_AGENT_PORT", c.DataDogConfig.DD_AGENT_PORT)
+	backends := c.MetricsConfig.BackendList()
+	if len(backends) == 0 {
+		v.add("MetricsConfig.Backends", errors.New("is required"))
+	}
+	for _, backend := range backends {
+		v.oneOf("MetricsConfig.Backends", backend, "prometheus", "datadog", "otlp")
+		if backend == "otlp" {
+			v.hostPort("MetricsConfig.OTLPEndpoint", c.MetricsConfig.OTLPEndpoint)
+			v.atLeast("MetricsConfig.OTLPIntervalMs", c.MetricsConfig.OTLPIntervalMs, 1)
+		}
+	}
+	v.atLeast("MetricsConfig.TrackedEntriesIntervalMs", c.MetricsConfig.TrackedEntriesIntervalMs, 0)
+	if buckets, err := c.MetricsConfig.DurationBuckets(); err != nil {
+		v.add("MetricsConfig.DurationBucketsMs", err)
+	} else if len(buckets) == 0 {
+		v.add("MetricsConfig.DurationBucketsMs", errors.New("is required"))
+	}
+	v.hostPort("TracingConfig.Endpoint", c.TracingConfig.Endpoint)
+
+	v.oneOf("BrokerConfig.Backend", c.BrokerConfig.Backend, "pulsar", "kafka", "nats", "memory", "file")
+	v.oneOf("BrokerConfig.Schema", c.BrokerConfig.Schema, "json", "avro")
+	v.atLeast("BrokerConfig.MaxRetries", c.BrokerConfig.MaxRetries, 0)
+	v.atLeast("BrokerConfig.RetryBackoffMs", c.BrokerConfig.RetryBackoffMs, 0)
+	switch c.BrokerConfig.Backend {
+	case "pulsar":
+		v.url("PulsarConfig.URL", c.PulsarConfig.URL, "pulsar", "pulsar+ssl")
+		v.required("PulsarConfig.ProducerTopic", c.PulsarConfig.ProducerTopic)
+		v.atLeast("PulsarConfig.SendTimeoutMs", c.PulsarConfig.SendTimeoutMs, 0)
+	case "kafka":
+		brokers := splitList(c.KafkaConfig.Brokers)
+		if len(brokers) == 0 {
+			v.add("KafkaConfig.Brokers", errors.New("is required"))
+		}
+		for _, broker := range brokers {
+			v.hostPort("KafkaConfig.Brokers", broker)
+		}
+		v.required("KafkaConfig.Topic", c.KafkaConfig.Topic)
+	case "nats":
+		v.url("NATSConfig.URL", c.NATSConfig.URL, "nats", "tls")
+		v.required("NATSConfig.Subject", c.NATSConfig.Subject)
+	case "memory":
+		v.required("BrokerConfig.Topic", c.BrokerConfig.Topic)
+	case "file":
+		v.required("BrokerConfig.Topic", c.BrokerConfig.Topic)
+		v.required("BrokerConfig.FilePath", c.BrokerConfig.FilePath)
+	}
+
+	v.atLeast("OutboxConfig.BatchSize", c.OutboxConfig.BatchSize, 1)
+	v.atLeast("OutboxConfig.PollIntervalMs", c.OutboxConfig.PollIntervalMs, 1)
+	v.atLeast("OutboxConfig.MaxAttempts", c.OutboxConfig.MaxAttempts, 0)
+
+	v.required("WebhookConfig.ConsumerGroup", c.WebhookConfig.ConsumerGroup)
+	v.atLeast("WebhookConfig.BatchSize", c.WebhookConfig.BatchSize, 1)
+	v.atLeast("WebhookConfig.PollIntervalMs", c.WebhookConfig.PollIntervalMs, 1)
+	v.atLeast("WebhookConfig.TimeoutMs", c.WebhookConfig.TimeoutMs, 1)
+	v.atLeast("WebhookConfig.MaxAttempts", c.WebhookConfig.MaxAttempts, 1)
+	v.atLeast("WebhookConfig.RetryBackoffMs", c.WebhookConfig.RetryBackoffMs, 0)
+	if c.WebhookConfig.MaxBackoffMs < c.WebhookConfig.RetryBackoffMs {
+		v.add("WebhookConfig.MaxBackoffMs", fmt.Errorf("%d is less than RetryBackoffMs %d", c.WebhookConfig.MaxBackoffMs, c.WebhookConfig.RetryBackoffMs))
+	}
+	v.atLeast("WebhookConfig.DisableAfter", c.WebhookConfig.DisableAfter, 0)
+
+	if c.CacheConfig.Enabled {
+		if c.CacheConfig.RedisURL != "" {
+			v.url("CacheConfig.RedisURL", c.CacheConfig.RedisURL, "redis", "rediss", "unix")
+		} else {
+			v.atLeast("CacheConfig.LRUSize", c.CacheConfig.LRUSize, 1)
+		}
+		v.atLeast("CacheConfig.TTLSeconds", c.CacheConfig.TTLSeconds, 1)
+	}
+
+	return errors.Join(v.errs...)
+}
+
+type validator struct {
+	errs []error
+}
+
+func (v *validator) add(key string, err error) {
+	v.errs = append(v.errs, fmt.Errorf("config %s: %w", key, err))
+}
+
+func (v *validator) required(key string, value string) {
+	if value == "" {
+		v.add(key, errors.New("is required"))
+	}
+}
+
+func (v *validator) atLeast(key string, value int, min int) {
+	if value < min {
+		v.add(key, fmt.Errorf("%d is less than %d", value, min))
+	}
+}
+
+func (v *validator) port(key string, value int) {
+	if value < 1 || value > 65535 {
+		v.add(key, fmt.Errorf("%d is not a port number", value))
+	}
+}
+
+func (v *validator) oneOf(key string, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+
+	v.add(key, fmt.Errorf("%q is not one of %v", value, allowed))
+}
+
+// url checks value is an absolute URL with one of schemes. Values are not
+// echoed back as they may hold credentials.
+func (v *validator) url(key string, value string, schemes ...string) {
+	u, err := url.Parse(value)
+	if err != nil {
+		v.add(key, errors.New("is not a valid URL"))
+		return
+	}
+
+	for _, scheme := range schemes {
+		if u.Scheme == scheme && (u.Host != "" || u.Path != "") {
+			return
+		}
+	}
+
+	v.add(key, fmt.Errorf("must be a URL with scheme %v", schemes))
+}
+
+func (v *validator) hostPort(key string, value string) {
+	if _, _, err := net.SplitHostPort(value); err != nil {
+		v.add(key, fmt.Errorf("%q is not host:port", value))
+	}
+}
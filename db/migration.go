@@ -2,79 +2,83 @@ package db
 
 import (
 	"embed"
+	"fmt"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
 	"github.com/golang-migrate/migrate/v4/database/mysql"
+	"github.com/golang-migrate/migrate/v4/database/pgx/v5"
 	"github.com/golang-migrate/migrate/v4/source"
 	"github.com/golang-migrate/migrate/v4/source/httpfs"
-	_ "github.com/golang-migrate/migrate/v4/source/httpfs"
 	"github.com/weeb-vip/list-service/config"
 	"github.com/weeb-vip/list-service/internal/db"
-	"log"
 	"net/http"
 )
 
 var (
-	//go:embed migrations/*.sql
+	// each dialect has its own migration set, with matching versions
+	//go:embed migrations/mysql/*.sql migrations/postgres/*.sql migrations/sqlite/*.sql
 	migrations embed.FS
 )
 
 type driver struct {
 	httpfs.PartialDriver
+	dir string
 }
 
 func (d *driver) Open(rawURL string) (source.Driver, error) {
-	err := d.PartialDriver.Init(http.FS(migrations), "migrations")
+	err := d.PartialDriver.Init(http.FS(migrations), d.dir)
 	if err != nil {
 		return nil, err
 	}
 
 	return d, nil
 }
-func getMigration() (*migrate.Migrate, error) {
-	cfg := config.LoadConfigOrPanic()
-	database := db.NewDatabase(cfg.DBConfig)
-	sqldb, err := database.DB.DB()
-	if err != nil {
-		return nil, err
+
+// migrationDir returns the embedded migration set for the configured driver
+func migrationDir(cfg config.DBConfig) string {
+	switch cfg.Driver {
+	case db.DriverPostgres, db.DriverSQLite:
+		return "migrations/" + cfg.Driver
+	default:
+		return "migrations/" + db.DriverMySQL
 	}
-	dbdriver, err := mysql.WithInstance(sqldb, &mysql.Config{
-		MigrationsTable: cfg.DBConfig.MigrationTableName,
-	})
-	// log files in migrations folder
-	files, err := migrations.ReadDir("migrations")
+}
+
+func databaseDriver(cfg config.DBConfig, conn *db.DB) (database.Driver, error) {
+	sqldb, err := conn.DB.DB()
 	if err != nil {
 		return nil, err
 	}
-	for _, file := range files {
-		println(file.Name())
-	}
-
-	source.Register("embed", &driver{})
 
-	return migrate.NewWithDatabaseInstance("embed://", cfg.DBConfig.DataBase, dbdriver)
+	switch cfg.Driver {
+	case db.DriverMySQL, "":
+		return mysql.WithInstance(sqldb, &mysql.Config{
+			MigrationsTable: cfg.MigrationTableName,
+		})
+	case db.DriverPostgres:
+		return pgx.WithInstance(sqldb, &pgx.Config{
+			MigrationsTable: cfg.MigrationTableName,
+		})
+	case db.DriverSQLite:
+		return newSQLiteDriver(sqldb, cfg.MigrationTableName)
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", cfg.Driver)
+	}
 }
 
-func MigrateUp() error {
-	log.Println("Migrating up")
-	m, err := getMigration()
+// NewMigration returns the migrations of the configured driver applied to
+// database
+func NewMigration(cfg config.DBConfig, database *db.DB) (*migrate.Migrate, error) {
+	dbdriver, err := databaseDriver(cfg, database)
 	if err != nil {
-		return err
-	}
-
-	err = m.Up()
-	if err != nil && err != migrate.ErrNoChange {
-		return err
+		return nil, err
 	}
 
-	return nil
-}
-
-func MigrateDown() error {
-	m, err := getMigration()
+	sourceDriver, err := (&driver{dir: migrationDir(cfg)}).Open("")
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return m.Down()
+	return migrate.NewWithInstance("embed", sourceDriver, cfg.DataBase, dbdriver)
 }
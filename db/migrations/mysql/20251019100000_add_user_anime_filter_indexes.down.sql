@@ -0,0 +1,7 @@
+-- Drop filter and sort indexes for user_anime table
+DROP INDEX idx_user_anime_user_id_progress ON user_anime;
+DROP INDEX idx_user_anime_user_id_score ON user_anime;
+DROP INDEX idx_user_anime_user_id_list_id ON user_anime;
+DROP INDEX idx_user_anime_user_id_status_updated_at ON user_anime;
+DROP INDEX idx_user_anime_user_id_created_at ON user_anime;
+DROP INDEX idx_user_anime_user_id_updated_at ON user_anime;
@@ -0,0 +1,17 @@
+-- Domain events written in the same transaction as the change they describe
+CREATE TABLE IF NOT EXISTS outbox
+(
+    sequence     BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    event_id     VARCHAR(36)  NOT NULL,
+    event_type   VARCHAR(64)  NOT NULL,
+    aggregate_id VARCHAR(36)  NOT NULL,
+    user_id      VARCHAR(36)  NOT NULL,
+    payload      JSON         NOT NULL,
+    occurred_at  TIMESTAMP(6) NOT NULL,
+    attempts     INT          NOT NULL DEFAULT 0,
+    last_error   TEXT         DEFAULT NULL,
+    sent_at      TIMESTAMP    NULL DEFAULT NULL,
+    created_at   TIMESTAMP    DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_outbox_event_id (event_id),
+    INDEX idx_outbox_sent_at_sequence (sent_at, sequence)
+);
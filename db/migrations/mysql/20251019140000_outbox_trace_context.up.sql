@@ -0,0 +1,3 @@
+-- W3C trace context of the request that recorded the event, so the relay can
+-- continue its trace when publishing
+ALTER TABLE outbox ADD COLUMN trace_context JSON NULL;
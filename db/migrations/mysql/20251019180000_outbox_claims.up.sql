@@ -0,0 +1,3 @@
+-- Set while a relay publishes a message, so relays claim batches in short
+-- transactions instead of holding row locks across the publish
+ALTER TABLE outbox ADD COLUMN claimed_until TIMESTAMP NULL DEFAULT NULL;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_list;
+DROP FUNCTION IF EXISTS set_updated_at();
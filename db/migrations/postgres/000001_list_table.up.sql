@@ -0,0 +1,24 @@
+-- Keeps updated_at current on every update, standing in for MySQL's
+-- ON UPDATE CURRENT_TIMESTAMP
+CREATE OR REPLACE FUNCTION set_updated_at() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TABLE IF NOT EXISTS user_list
+(
+    id          VARCHAR(36) PRIMARY KEY,
+    user_id     VARCHAR(36)  NOT NULL,
+    name        VARCHAR(255) NOT NULL,
+    description VARCHAR(255) DEFAULT NULL,
+    tags        VARCHAR(255) DEFAULT NULL,
+    is_public   BOOLEAN      DEFAULT TRUE,
+    created_at  TIMESTAMPTZ  DEFAULT CURRENT_TIMESTAMP,
+    updated_at  TIMESTAMPTZ  DEFAULT CURRENT_TIMESTAMP,
+    deleted_at  TIMESTAMPTZ  DEFAULT NULL
+);
+
+CREATE TRIGGER user_list_set_updated_at BEFORE UPDATE ON user_list
+    FOR EACH ROW EXECUTE FUNCTION set_updated_at();
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS user_anime
+(
+    id                  VARCHAR(36) PRIMARY KEY,
+    user_id             VARCHAR(36) NOT NULL,
+    anime_id            VARCHAR(36) NOT NULL,
+    status              VARCHAR(30)      DEFAULT NULL,
+    score               DOUBLE PRECISION DEFAULT 0.0,
+    episodes            INT              DEFAULT 0,
+    rewatching          INT              DEFAULT 0,
+    rewatching_episodes INT              DEFAULT 0,
+    tags                VARCHAR(255)     DEFAULT NULL,
+    list_id             VARCHAR(36)      DEFAULT NULL,
+    created_at          TIMESTAMPTZ      DEFAULT CURRENT_TIMESTAMP,
+    updated_at          TIMESTAMPTZ      DEFAULT CURRENT_TIMESTAMP,
+    deleted_at          TIMESTAMPTZ      DEFAULT NULL
+);
+
+CREATE TRIGGER user_anime_set_updated_at BEFORE UPDATE ON user_anime
+    FOR EACH ROW EXECUTE FUNCTION set_updated_at();
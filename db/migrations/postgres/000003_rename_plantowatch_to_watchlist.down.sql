@@ -0,0 +1 @@
+UPDATE user_anime SET status = 'plantowatch' WHERE status = 'watchlist';
\ No newline at end of file
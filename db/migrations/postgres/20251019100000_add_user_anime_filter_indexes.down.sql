@@ -0,0 +1,7 @@
+-- Drop filter and sort indexes for user_anime table
+DROP INDEX IF EXISTS idx_user_anime_user_id_progress;
+DROP INDEX IF EXISTS idx_user_anime_user_id_score;
+DROP INDEX IF EXISTS idx_user_anime_user_id_list_id;
+DROP INDEX IF EXISTS idx_user_anime_user_id_status_updated_at;
+DROP INDEX IF EXISTS idx_user_anime_user_id_created_at;
+DROP INDEX IF EXISTS idx_user_anime_user_id_updated_at;
@@ -0,0 +1,9 @@
+-- Back the filter and sort combinations used by the UserAnimes query
+CREATE INDEX idx_user_anime_user_id_updated_at ON user_anime(user_id, updated_at);
+CREATE INDEX idx_user_anime_user_id_created_at ON user_anime(user_id, created_at);
+CREATE INDEX idx_user_anime_user_id_status_updated_at ON user_anime(user_id, status, updated_at);
+CREATE INDEX idx_user_anime_user_id_list_id ON user_anime(user_id, list_id);
+
+-- Score and progress are sorted by their coalesced value, so index the expression
+CREATE INDEX idx_user_anime_user_id_score ON user_anime(user_id, (COALESCE(score, 0)));
+CREATE INDEX idx_user_anime_user_id_progress ON user_anime(user_id, (COALESCE(episodes, 0)));
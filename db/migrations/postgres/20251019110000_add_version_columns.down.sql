@@ -0,0 +1,2 @@
+ALTER TABLE user_list DROP COLUMN version;
+ALTER TABLE user_anime DROP COLUMN version;
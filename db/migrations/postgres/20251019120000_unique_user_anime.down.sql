@@ -0,0 +1,3 @@
+-- Removed duplicates are not restored
+DROP INDEX IF EXISTS uq_user_anime_user_id_anime_id;
+CREATE INDEX idx_user_anime_user_id_anime_id ON user_anime(user_id, anime_id);
@@ -0,0 +1,19 @@
+-- Remove duplicate entries for the same user and anime, keeping one row per
+-- pair: live rows win over soft deleted ones, then the most recently updated,
+-- then the highest id. Run the dedupe command first to see what is removed.
+DELETE FROM user_anime ua
+USING user_anime keep
+WHERE keep.user_id = ua.user_id
+    AND keep.anime_id = ua.anime_id
+    AND keep.id <> ua.id
+    AND (
+        (keep.deleted_at IS NULL AND ua.deleted_at IS NOT NULL)
+        OR (
+            (keep.deleted_at IS NULL) = (ua.deleted_at IS NULL)
+            AND (keep.updated_at > ua.updated_at OR (keep.updated_at = ua.updated_at AND keep.id > ua.id))
+        )
+    );
+
+-- Replace the plain composite index with a unique key
+DROP INDEX IF EXISTS idx_user_anime_user_id_anime_id;
+CREATE UNIQUE INDEX uq_user_anime_user_id_anime_id ON user_anime(user_id, anime_id);
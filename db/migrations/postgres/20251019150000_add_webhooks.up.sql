@@ -0,0 +1,47 @@
+-- Endpoints users registered to be notified of changes to their list
+CREATE TABLE IF NOT EXISTS webhook_subscription
+(
+    id                   VARCHAR(36)   PRIMARY KEY,
+    user_id              VARCHAR(36)   NOT NULL,
+    url                  VARCHAR(2048) NOT NULL,
+    -- comma separated event types, e.g. user_anime.added,user_list.deleted
+    event_types          VARCHAR(255)  NOT NULL,
+    secret               VARCHAR(255)  NOT NULL,
+    enabled              BOOLEAN       NOT NULL DEFAULT TRUE,
+    consecutive_failures INT           NOT NULL DEFAULT 0,
+    disabled_at          TIMESTAMPTZ   NULL DEFAULT NULL,
+    created_at           TIMESTAMPTZ   DEFAULT CURRENT_TIMESTAMP,
+    updated_at           TIMESTAMPTZ   DEFAULT CURRENT_TIMESTAMP,
+    deleted_at           TIMESTAMPTZ   NULL DEFAULT NULL
+);
+
+CREATE INDEX idx_webhook_subscription_user_id ON webhook_subscription(user_id);
+
+CREATE TRIGGER webhook_subscription_set_updated_at BEFORE UPDATE ON webhook_subscription
+    FOR EACH ROW EXECUTE FUNCTION set_updated_at();
+
+-- One row per event and subscription, doubling as the delivery log
+CREATE TABLE IF NOT EXISTS webhook_delivery
+(
+    id               VARCHAR(36)    PRIMARY KEY,
+    subscription_id  VARCHAR(36)    NOT NULL,
+    user_id          VARCHAR(36)    NOT NULL,
+    event_id         VARCHAR(36)    NOT NULL,
+    event_type       VARCHAR(64)    NOT NULL,
+    payload          JSON           NOT NULL,
+    status           VARCHAR(16)    NOT NULL DEFAULT 'pending',
+    attempts         INT            NOT NULL DEFAULT 0,
+    next_attempt_at  TIMESTAMPTZ(6) NULL DEFAULT NULL,
+    last_status_code INT            NULL DEFAULT NULL,
+    last_error       TEXT           DEFAULT NULL,
+    delivered_at     TIMESTAMPTZ    NULL DEFAULT NULL,
+    created_at       TIMESTAMPTZ    DEFAULT CURRENT_TIMESTAMP,
+    updated_at       TIMESTAMPTZ    DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_webhook_delivery_subscription_event UNIQUE (subscription_id, event_id)
+);
+
+CREATE INDEX idx_webhook_delivery_status_next_attempt_at ON webhook_delivery(status, next_attempt_at);
+CREATE INDEX idx_webhook_delivery_subscription_created_at ON webhook_delivery(subscription_id, created_at);
+
+CREATE TRIGGER webhook_delivery_set_updated_at BEFORE UPDATE ON webhook_delivery
+    FOR EACH ROW EXECUTE FUNCTION set_updated_at();
@@ -0,0 +1,15 @@
+-- Progress of data backfills, so an interrupted run resumes after the last
+-- batch it committed
+CREATE TABLE IF NOT EXISTS backfill_checkpoint
+(
+    name         VARCHAR(100) PRIMARY KEY,
+    last_id      VARCHAR(36)  NOT NULL DEFAULT '',
+    scanned      BIGINT       NOT NULL DEFAULT 0,
+    changed      BIGINT       NOT NULL DEFAULT 0,
+    completed_at TIMESTAMPTZ  NULL DEFAULT NULL,
+    created_at   TIMESTAMPTZ  DEFAULT CURRENT_TIMESTAMP,
+    updated_at   TIMESTAMPTZ  DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER backfill_checkpoint_set_updated_at BEFORE UPDATE ON backfill_checkpoint
+    FOR EACH ROW EXECUTE FUNCTION set_updated_at();
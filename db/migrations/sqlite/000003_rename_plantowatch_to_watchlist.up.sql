@@ -0,0 +1 @@
+UPDATE user_anime SET status = 'watchlist' WHERE status = 'plantowatch';
\ No newline at end of file
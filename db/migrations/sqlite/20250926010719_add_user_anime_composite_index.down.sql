@@ -0,0 +1,2 @@
+-- Drop composite index for user_id and anime_id
+DROP INDEX IF EXISTS idx_user_anime_user_id_anime_id;
@@ -0,0 +1,3 @@
+-- Version counters for optimistic concurrency control
+ALTER TABLE user_anime ADD COLUMN version INT NOT NULL DEFAULT 1;
+ALTER TABLE user_list ADD COLUMN version INT NOT NULL DEFAULT 1;
@@ -0,0 +1,18 @@
+-- Domain events written in the same transaction as the change they describe
+CREATE TABLE IF NOT EXISTS outbox
+(
+    sequence     INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id     VARCHAR(36) NOT NULL,
+    event_type   VARCHAR(64) NOT NULL,
+    aggregate_id VARCHAR(36) NOT NULL,
+    user_id      VARCHAR(36) NOT NULL,
+    payload      TEXT        NOT NULL,
+    occurred_at  TIMESTAMP   NOT NULL,
+    attempts     INT         NOT NULL DEFAULT 0,
+    last_error   TEXT        DEFAULT NULL,
+    sent_at      TIMESTAMP   NULL DEFAULT NULL,
+    created_at   TIMESTAMP   DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_outbox_event_id UNIQUE (event_id)
+);
+
+CREATE INDEX idx_outbox_sent_at_sequence ON outbox(sent_at, sequence);
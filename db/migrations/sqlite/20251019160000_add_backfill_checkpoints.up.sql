@@ -0,0 +1,12 @@
+-- Progress of data backfills, so an interrupted run resumes after the last
+-- batch it committed
+CREATE TABLE IF NOT EXISTS backfill_checkpoint
+(
+    name         VARCHAR(100) PRIMARY KEY,
+    last_id      VARCHAR(36)  NOT NULL DEFAULT '',
+    scanned      BIGINT       NOT NULL DEFAULT 0,
+    changed      BIGINT       NOT NULL DEFAULT 0,
+    completed_at TIMESTAMP    NULL DEFAULT NULL,
+    created_at   TIMESTAMP    DEFAULT CURRENT_TIMESTAMP,
+    updated_at   TIMESTAMP    DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,11 @@
+-- What other users may see of a user's profile. Nothing is shared until the
+-- user opts in, users without a row share nothing.
+CREATE TABLE IF NOT EXISTS user_privacy_settings
+(
+    user_id           VARCHAR(36)  PRIMARY KEY,
+    anime_list_public BOOLEAN      NOT NULL DEFAULT FALSE,
+    stats_public      BOOLEAN      NOT NULL DEFAULT FALSE,
+    favorites_public  BOOLEAN      NOT NULL DEFAULT FALSE,
+    created_at        TIMESTAMP    DEFAULT CURRENT_TIMESTAMP,
+    updated_at        TIMESTAMP    DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,4 @@
+-- Set once the relay gives up on a message after too many failed publishes.
+-- Failed messages are kept for inspection and no longer block the messages
+-- after them, clearing failed_at queues them again
+ALTER TABLE outbox ADD COLUMN failed_at TIMESTAMP NULL DEFAULT NULL;
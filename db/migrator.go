@@ -0,0 +1,432 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/weeb-vip/list-service/config"
+	"github.com/weeb-vip/list-service/internal/db"
+	"gorm.io/plugin/dbresolver"
+)
+
+// lastSequentialVersion is the newest of the numbered migrations written
+// before versions became UTC timestamps. Any later sequential number would
+// sort before every timestamp and never run on an existing database.
+const lastSequentialVersion = 4
+
+// versionLayout is the time format of timestamp versions
+const versionLayout = "20060102150405"
+
+var migrationName = regexp.MustCompile(`^[a-z0-9]+(_[a-z0-9]+)*$`)
+
+// Migration is one version of the embedded migrations
+type Migration struct {
+	Version uint   `json:"version"`
+	Name    string `json:"name"`
+
+	up   string
+	down string
+}
+
+// Status is where a database stands against the embedded migrations
+type Status struct {
+	Driver string `json:"driver"`
+	// Version is the last applied migration, nil when none has run
+	Version *uint       `json:"version"`
+	Dirty   bool        `json:"dirty"`
+	Applied []Migration `json:"applied"`
+	Pending []Migration `json:"pending"`
+}
+
+// Step is a migration file run to move between versions
+type Step struct {
+	Version   uint             `json:"version"`
+	Name      string           `json:"name"`
+	Direction source.Direction `json:"direction"`
+	SQL       string           `json:"sql,omitempty"`
+}
+
+// Target is the version a run migrates to
+type Target struct {
+	position func(current int, migrations []Migration) (int, error)
+	apply    func(m *migrate.Migrate) error
+}
+
+// Latest applies every pending migration
+func Latest() Target {
+	return Target{
+		position: func(current int, migrations []Migration) (int, error) { return len(migrations), nil },
+		apply:    func(m *migrate.Migrate) error { return m.Up() },
+	}
+}
+
+// Nothing reverts every applied migration
+func Nothing() Target {
+	return Target{
+		position: func(current int, migrations []Migration) (int, error) { return 0, nil },
+		apply:    func(m *migrate.Migrate) error { return m.Down() },
+	}
+}
+
+// Version migrates up or down to version
+func Version(version uint) Target {
+	return Target{
+		position: func(current int, migrations []Migration) (int, error) {
+			i, ok := indexOf(migrations, version)
+			if !ok {
+				return 0, fmt.Errorf("no migration with version %d", version)
+			}
+
+			return i + 1, nil
+		},
+		apply: func(m *migrate.Migrate) error { return m.Migrate(version) },
+	}
+}
+
+// Steps applies n migrations, or reverts -n when n is negative
+func Steps(n int) Target {
+	return Target{
+		position: func(current int, migrations []Migration) (int, error) {
+			target := current + n
+			if target < 0 || target > len(migrations) {
+				available := len(migrations) - current
+				if n < 0 {
+					available = current
+				}
+				return 0, fmt.Errorf("cannot move %d steps, only %d migrations available", n, available)
+			}
+
+			return target, nil
+		},
+		apply: func(m *migrate.Migrate) error { return m.Steps(n) },
+	}
+}
+
+// Migrator runs the embedded migrations of the configured driver
+type Migrator struct {
+	migrate    *migrate.Migrate
+	driver     string
+	dir        string
+	migrations []Migration
+}
+
+// NewMigrator returns a migrator for database
+func NewMigrator(cfg config.DBConfig, database *db.DB) (*Migrator, error) {
+	dir := migrationDir(cfg)
+	migrations, err := readMigrations(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := NewMigration(cfg, database)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{migrate: m, driver: path.Base(dir), dir: dir, migrations: migrations}, nil
+}
+
+// Close closes the migration source and the database connection
+func (m *Migrator) Close() error {
+	sourceErr, databaseErr := m.migrate.Close()
+	return errors.Join(sourceErr, databaseErr)
+}
+
+// Status returns the current version and the applied and pending migrations
+func (m *Migrator) Status() (*Status, error) {
+	current, dirty, err := m.current()
+	if err != nil {
+		return nil, err
+	}
+
+	status := &Status{
+		Driver:  m.driver,
+		Dirty:   dirty,
+		Applied: append([]Migration{}, m.migrations[:current]...),
+		Pending: append([]Migration{}, m.migrations[current:]...),
+	}
+	if current > 0 {
+		status.Version = &m.migrations[current-1].Version
+	}
+
+	return status, nil
+}
+
+// Plan returns the steps that migrating to target would run, in order, with
+// their SQL. Nothing is changed.
+func (m *Migrator) Plan(target Target) ([]Step, error) {
+	current, dirty, err := m.current()
+	if err != nil {
+		return nil, err
+	}
+	if dirty {
+		return nil, migrate.ErrDirty{Version: int(m.migrations[current-1].Version)}
+	}
+
+	position, err := target.position(current, m.migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	var steps []Step
+	for i := current; i < position; i++ {
+		step, err := m.step(m.migrations[i], source.Up)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+	}
+	for i := current - 1; i >= position; i-- {
+		step, err := m.step(m.migrations[i], source.Down)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+	}
+
+	return steps, nil
+}
+
+// Migrate runs the steps to target and returns them. It is a no-op when the
+// database is already there.
+func (m *Migrator) Migrate(target Target) ([]Step, error) {
+	steps, err := m.Plan(target)
+	if err != nil {
+		return nil, err
+	}
+	if len(steps) == 0 {
+		return nil, nil
+	}
+
+	err = target.apply(m.migrate)
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return nil, err
+	}
+
+	for i := range steps {
+		steps[i].SQL = ""
+	}
+	return steps, nil
+}
+
+// Force records version as applied and clears the dirty flag without running
+// anything, to recover after a failed migration has been fixed by hand. A
+// version of -1 marks no migration as applied.
+func (m *Migrator) Force(version int) error {
+	if version != -1 {
+		if _, ok := indexOf(m.migrations, uint(version)); !ok {
+			return fmt.Errorf("no migration with version %d", version)
+		}
+	}
+
+	return m.migrate.Force(version)
+}
+
+// CheckSchema returns an error unless every embedded migration is applied
+// and the last run did not fail. It only reads the migrations table, so unlike
+// a Migrator it leaves database open.
+func CheckSchema(ctx context.Context, cfg config.DBConfig, database *db.DB) error {
+	migrations, err := readMigrations(migrationDir(cfg))
+	if err != nil {
+		return err
+	}
+
+	var applied []struct {
+		Version int64
+		Dirty   bool
+	}
+	err = database.DB.WithContext(ctx).
+		Clauses(dbresolver.Write).
+		Table(cfg.MigrationTableName).
+		Select("version", "dirty").
+		Find(&applied).Error
+	if err != nil {
+		return err
+	}
+
+	latest := migrations[len(migrations)-1].Version
+	switch {
+	case len(applied) == 0 || applied[0].Version < 0:
+		return fmt.Errorf("no migrations applied, %d pending", len(migrations))
+	case applied[0].Dirty:
+		return fmt.Errorf("migration %d failed and left the database dirty", applied[0].Version)
+	case uint(applied[0].Version) < latest:
+		i, _ := indexOf(migrations, uint(applied[0].Version))
+		return fmt.Errorf("database is at version %d, %d migrations pending", applied[0].Version, len(migrations)-i-1)
+	}
+
+	return nil
+}
+
+// current returns how many migrations are applied and the dirty flag
+func (m *Migrator) current() (int, bool, error) {
+	version, dirty, err := m.migrate.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	i, ok := indexOf(m.migrations, version)
+	if !ok {
+		return 0, false, fmt.Errorf("database is at version %d, which is not one of the %s migrations", version, m.driver)
+	}
+
+	return i + 1, dirty, nil
+}
+
+func (m *Migrator) step(migration Migration, direction source.Direction) (Step, error) {
+	file := migration.up
+	if direction == source.Down {
+		file = migration.down
+	}
+
+	sql, err := migrations.ReadFile(path.Join(m.dir, file))
+	if err != nil {
+		return Step{}, err
+	}
+
+	return Step{Version: migration.Version, Name: migration.Name, Direction: direction, SQL: string(sql)}, nil
+}
+
+func indexOf(migrations []Migration, version uint) (int, bool) {
+	i := sort.Search(len(migrations), func(i int) bool { return migrations[i].Version >= version })
+	return i, i < len(migrations) && migrations[i].Version == version
+}
+
+// readMigrations returns the migrations in an embedded directory ordered by
+// version, failing on any file that is not a well formed up and down pair
+func readMigrations(dir string) ([]Migration, error) {
+	files, err := fs.ReadDir(migrations, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[uint]*Migration{}
+	var errs []error
+	for _, file := range files {
+		parsed, err := source.Parse(file.Name())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s/%s: not a migration file name", dir, file.Name()))
+			continue
+		}
+
+		migration, ok := byVersion[parsed.Version]
+		if !ok {
+			migration = &Migration{Version: parsed.Version, Name: parsed.Identifier}
+			byVersion[parsed.Version] = migration
+		}
+		if migration.Name != parsed.Identifier {
+			errs = append(errs, fmt.Errorf("%s: version %d is used by both %s and %s", dir, parsed.Version, migration.Name, parsed.Identifier))
+			continue
+		}
+
+		if parsed.Direction == source.Up {
+			migration.up = file.Name()
+		} else {
+			migration.down = file.Name()
+		}
+	}
+
+	result := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.up == "" || migration.down == "" {
+			errs = append(errs, fmt.Errorf("%s: version %d %s needs both an up and a down file", dir, migration.Version, migration.Name))
+		}
+		if err := validateVersion(migration.Version); err != nil {
+			errs = append(errs, fmt.Errorf("%s: version %d %s: %w", dir, migration.Version, migration.Name, err))
+		}
+		result = append(result, *migration)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+
+	return result, errors.Join(errs...)
+}
+
+// validateVersion accepts the legacy sequential versions and UTC timestamps
+func validateVersion(version uint) error {
+	if version <= lastSequentialVersion {
+		return nil
+	}
+
+	raw := strconv.FormatUint(uint64(version), 10)
+	if _, err := time.Parse(versionLayout, raw); err != nil {
+		return fmt.Errorf("versions after %d must be UTC timestamps formatted %s", lastSequentialVersion, versionLayout)
+	}
+
+	return nil
+}
+
+// ValidateMigrations checks that every dialect has the same set of well
+// formed migrations
+func ValidateMigrations() error {
+	var errs []error
+	sets := map[string][]Migration{}
+	for _, driver := range []string{db.DriverMySQL, db.DriverPostgres, db.DriverSQLite} {
+		set, err := readMigrations("migrations/" + driver)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		sets[driver] = set
+	}
+
+	reference := sets[db.DriverMySQL]
+	for _, driver := range []string{db.DriverPostgres, db.DriverSQLite} {
+		if !sameVersions(reference, sets[driver]) {
+			errs = append(errs, fmt.Errorf("migrations/%s does not have the same versions and names as migrations/%s", driver, db.DriverMySQL))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func sameVersions(a []Migration, b []Migration) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Version != b[i].Version || a[i].Name != b[i].Name {
+			return false
+		}
+	}
+
+	return true
+}
+
+// CreateMigration writes empty up and down files named name for every
+// dialect under dir, versioned with the UTC time now, and returns their paths
+func CreateMigration(dir string, name string, now time.Time) ([]string, error) {
+	if !migrationName.MatchString(name) {
+		return nil, fmt.Errorf("migration name %q must be lower snake case", name)
+	}
+
+	version := now.UTC().Format(versionLayout)
+	var created []string
+	for _, driver := range []string{db.DriverMySQL, db.DriverPostgres, db.DriverSQLite} {
+		for _, direction := range []source.Direction{source.Up, source.Down} {
+			file := filepath.Join(dir, driver, fmt.Sprintf("%s_%s.%s.sql", version, name, direction))
+			f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+			if err != nil {
+				return created, err
+			}
+			if err := f.Close(); err != nil {
+				return created, err
+			}
+			created = append(created, file)
+		}
+	}
+
+	return created, nil
+}
@@ -0,0 +1,163 @@
+package db_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	migrations "github.com/weeb-vip/list-service/db"
+	"github.com/weeb-vip/list-service/internal/db"
+	"github.com/weeb-vip/list-service/internal/db/dbtest"
+)
+
+func TestValidateMigrations(t *testing.T) {
+	assert.NoError(t, migrations.ValidateMigrations())
+}
+
+func TestMigrator(t *testing.T) {
+	cfg := dbtest.SQLiteConfig(filepath.Join(t.TempDir(), "list-service.db"))
+	database, err := db.NewDatabase(cfg)
+	require.NoError(t, err)
+	migrator, err := migrations.NewMigrator(cfg, database)
+	require.NoError(t, err)
+	defer migrator.Close()
+
+	status, err := migrator.Status()
+	require.NoError(t, err)
+	assert.Nil(t, status.Version)
+	assert.Empty(t, status.Applied)
+	total := len(status.Pending)
+
+	t.Run("dry runs print the SQL without applying it", func(t *testing.T) {
+		steps, err := migrator.Plan(migrations.Steps(2))
+		require.NoError(t, err)
+		require.Len(t, steps, 2)
+		assert.Equal(t, uint(1), steps[0].Version)
+		assert.Equal(t, source.Up, steps[0].Direction)
+		assert.Contains(t, steps[0].SQL, "CREATE TABLE")
+
+		status, err := migrator.Status()
+		require.NoError(t, err)
+		assert.Len(t, status.Pending, total)
+	})
+
+	t.Run("up applies every pending migration", func(t *testing.T) {
+		steps, err := migrator.Migrate(migrations.Latest())
+		require.NoError(t, err)
+		assert.Len(t, steps, total)
+
+		status, err := migrator.Status()
+		require.NoError(t, err)
+		assert.Empty(t, status.Pending)
+		assert.Len(t, status.Applied, total)
+
+		steps, err = migrator.Migrate(migrations.Latest())
+		require.NoError(t, err)
+		assert.Empty(t, steps)
+	})
+
+	t.Run("steps and goto move between versions", func(t *testing.T) {
+		steps, err := migrator.Migrate(migrations.Steps(-2))
+		require.NoError(t, err)
+		require.Len(t, steps, 2)
+		assert.Equal(t, source.Down, steps[0].Direction)
+		assert.Greater(t, steps[0].Version, steps[1].Version)
+
+		status, err := migrator.Status()
+		require.NoError(t, err)
+		assert.Len(t, status.Pending, 2)
+
+		_, err = migrator.Plan(migrations.Steps(3))
+		assert.Error(t, err)
+
+		_, err = migrator.Migrate(migrations.Version(4))
+		require.NoError(t, err)
+		status, err = migrator.Status()
+		require.NoError(t, err)
+		assert.Equal(t, uint(4), *status.Version)
+
+		_, err = migrator.Plan(migrations.Version(5))
+		assert.Error(t, err)
+	})
+
+	t.Run("force only accepts known versions", func(t *testing.T) {
+		assert.Error(t, migrator.Force(5))
+
+		require.NoError(t, migrator.Force(-1))
+		status, err := migrator.Status()
+		require.NoError(t, err)
+		assert.Nil(t, status.Version)
+	})
+}
+
+func TestMigratorRefusesDirtyDatabases(t *testing.T) {
+	cfg := dbtest.SQLiteConfig(filepath.Join(t.TempDir(), "list-service.db"))
+	database, err := db.NewDatabase(cfg)
+	require.NoError(t, err)
+	migrator, err := migrations.NewMigrator(cfg, database)
+	require.NoError(t, err)
+	defer migrator.Close()
+
+	_, err = migrator.Migrate(migrations.Steps(1))
+	require.NoError(t, err)
+	require.NoError(t, database.DB.Exec(`UPDATE "__migrations_list-service" SET dirty = true`).Error)
+
+	status, err := migrator.Status()
+	require.NoError(t, err)
+	assert.True(t, status.Dirty)
+
+	_, err = migrator.Migrate(migrations.Latest())
+	assert.ErrorAs(t, err, &migrate.ErrDirty{})
+
+	require.NoError(t, migrator.Force(1))
+	_, err = migrator.Migrate(migrations.Latest())
+	assert.NoError(t, err)
+}
+
+func TestCheckSchema(t *testing.T) {
+	ctx := context.Background()
+	cfg := dbtest.SQLiteConfig(filepath.Join(t.TempDir(), "list-service.db"))
+	database, err := db.NewDatabase(cfg)
+	require.NoError(t, err)
+	migrator, err := migrations.NewMigrator(cfg, database)
+	require.NoError(t, err)
+	defer migrator.Close()
+
+	assert.Error(t, migrations.CheckSchema(ctx, cfg, database))
+
+	_, err = migrator.Migrate(migrations.Steps(1))
+	require.NoError(t, err)
+	assert.ErrorContains(t, migrations.CheckSchema(ctx, cfg, database), "pending")
+
+	_, err = migrator.Migrate(migrations.Latest())
+	require.NoError(t, err)
+	assert.NoError(t, migrations.CheckSchema(ctx, cfg, database))
+
+	require.NoError(t, database.DB.Exec(`UPDATE "__migrations_list-service" SET dirty = true`).Error)
+	assert.ErrorContains(t, migrations.CheckSchema(ctx, cfg, database), "dirty")
+}
+
+func TestCreateMigration(t *testing.T) {
+	dir := t.TempDir()
+	for _, driver := range []string{db.DriverMySQL, db.DriverPostgres, db.DriverSQLite} {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, driver), 0o755))
+	}
+
+	now := time.Date(2025, 10, 20, 8, 30, 0, 0, time.UTC)
+	files, err := migrations.CreateMigration(dir, "add_notes", now)
+	require.NoError(t, err)
+	assert.Len(t, files, 6)
+	assert.FileExists(t, filepath.Join(dir, db.DriverPostgres, "20251020083000_add_notes.down.sql"))
+
+	_, err = migrations.CreateMigration(dir, "add_notes", now)
+	assert.Error(t, err)
+
+	_, err = migrations.CreateMigration(dir, "Add Notes", now)
+	assert.Error(t, err)
+}
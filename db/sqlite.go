@@ -0,0 +1,155 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+
+	"github.com/golang-migrate/migrate/v4/database"
+)
+
+// sqliteDriver is a golang-migrate driver for SQLite databases opened through
+// gorm. migrate's own sqlite driver registers a second "sqlite" database/sql
+// driver next to the one gorm uses, so it cannot be linked in.
+type sqliteDriver struct {
+	db              *sql.DB
+	migrationsTable string
+	mutex           sync.Mutex
+	locked          bool
+}
+
+func newSQLiteDriver(db *sql.DB, migrationsTable string) (database.Driver, error) {
+	driver := &sqliteDriver{db: db, migrationsTable: strconv.Quote(migrationsTable)}
+
+	_, err := db.Exec("CREATE TABLE IF NOT EXISTS " + driver.migrationsTable + " (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)")
+	if err != nil {
+		return nil, err
+	}
+
+	return driver, nil
+}
+
+// Open is unsupported, the driver only wraps an open database
+func (d *sqliteDriver) Open(url string) (database.Driver, error) {
+	return nil, errors.New("sqlite migrations need an open database")
+}
+
+func (d *sqliteDriver) Close() error {
+	return d.db.Close()
+}
+
+// Lock only guards against concurrent runs in this process, a SQLite file is
+// not shared between deployed instances
+func (d *sqliteDriver) Lock() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.locked {
+		return database.ErrLocked
+	}
+	d.locked = true
+	return nil
+}
+
+func (d *sqliteDriver) Unlock() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if !d.locked {
+		return database.ErrNotLocked
+	}
+	d.locked = false
+	return nil
+}
+
+func (d *sqliteDriver) Run(migration io.Reader) error {
+	query, err := io.ReadAll(migration)
+	if err != nil {
+		return err
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return &database.Error{OrigErr: err, Err: "transaction start failed"}
+	}
+	if _, err := tx.Exec(string(query)); err != nil {
+		_ = tx.Rollback()
+		return &database.Error{OrigErr: err, Query: query}
+	}
+	if err := tx.Commit(); err != nil {
+		return &database.Error{OrigErr: err, Err: "transaction commit failed"}
+	}
+
+	return nil
+}
+
+func (d *sqliteDriver) SetVersion(version int, dirty bool) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return &database.Error{OrigErr: err, Err: "transaction start failed"}
+	}
+
+	query := "DELETE FROM " + d.migrationsTable
+	if _, err := tx.Exec(query); err != nil {
+		_ = tx.Rollback()
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+
+	// a dirty nil version is kept so a failed first migration is still seen
+	if version >= 0 || (version == database.NilVersion && dirty) {
+		query = "INSERT INTO " + d.migrationsTable + " (version, dirty) VALUES (?, ?)"
+		if _, err := tx.Exec(query, version, dirty); err != nil {
+			_ = tx.Rollback()
+			return &database.Error{OrigErr: err, Query: []byte(query)}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return &database.Error{OrigErr: err, Err: "transaction commit failed"}
+	}
+	return nil
+}
+
+func (d *sqliteDriver) Version() (int, bool, error) {
+	var version int
+	var dirty bool
+	err := d.db.QueryRow("SELECT version, dirty FROM "+d.migrationsTable+" LIMIT 1").Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return database.NilVersion, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	return version, dirty, nil
+}
+
+func (d *sqliteDriver) Drop() error {
+	rows, err := d.db.Query("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'")
+	if err != nil {
+		return err
+	}
+
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			rows.Close()
+			return err
+		}
+		tables = append(tables, table)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		if _, err := d.db.Exec("DROP TABLE " + strconv.Quote(table)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
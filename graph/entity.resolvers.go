@@ -6,10 +6,10 @@ package graph
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/weeb-vip/list-service/graph/generated"
 	"github.com/weeb-vip/list-service/graph/model"
+	"github.com/weeb-vip/list-service/internal/resolvers"
 )
 
 // FindAnimeByID is the resolver for the findAnimeByID field.
@@ -21,17 +21,26 @@ func (r *entityResolver) FindAnimeByID(ctx context.Context, id string) (*model.A
 
 // FindAPIInfoByName is the resolver for the findApiInfoByName field.
 func (r *entityResolver) FindAPIInfoByName(ctx context.Context, name string) (*model.APIInfo, error) {
-	panic(fmt.Errorf("not implemented: FindAPIInfoByName - findApiInfoByName"))
+	return &model.APIInfo{
+		Name: name,
+	}, nil
+}
+
+// FindUserByID is the resolver for the findUserByID field.
+func (r *entityResolver) FindUserByID(ctx context.Context, id string) (*model.User, error) {
+	return &model.User{
+		ID: id,
+	}, nil
 }
 
 // FindUserAnimeByID is the resolver for the findUserAnimeByID field.
 func (r *entityResolver) FindUserAnimeByID(ctx context.Context, id string) (*model.UserAnime, error) {
-	panic(fmt.Errorf("not implemented: FindUserAnimeByID - findUserAnimeByID"))
+	return resolvers.FindUserAnimeEntity(ctx, id)
 }
 
 // FindUserListByID is the resolver for the findUserListByID field.
 func (r *entityResolver) FindUserListByID(ctx context.Context, id string) (*model.UserList, error) {
-	panic(fmt.Errorf("not implemented: FindUserListByID - findUserListByID"))
+	return resolvers.FindUserListEntity(ctx, id)
 }
 
 // Entity returns generated.EntityResolver implementation.
@@ -6,8 +6,22 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
 )
 
+type AddAnimeInput struct {
+	AnimeID            string   `json:"animeID"`
+	Status             *Status  `json:"status,omitempty"`
+	Score              *float64 `json:"score,omitempty"`
+	Episodes           *int     `json:"episodes,omitempty"`
+	Rewatching         *int     `json:"rewatching,omitempty"`
+	RewatchingEpisodes *int     `json:"rewatchingEpisodes,omitempty"`
+	Tags               []string `json:"tags,omitempty"`
+	ListID             *string  `json:"listID,omitempty"`
+}
+
 type Anime struct {
 	ID        string     `json:"id"`
 	UserAnime *UserAnime `json:"userAnime,omitempty"`
@@ -24,14 +38,64 @@ type APIInfo struct {
 
 func (APIInfo) IsEntity() {}
 
+type CreatedWebhookSubscription struct {
+	Subscription *WebhookSubscription `json:"subscription"`
+	// Only returned on creation, keep it to verify signatures
+	Secret string `json:"secret"`
+}
+
 type ListServiceAPI struct {
 	// Version of event golang-template service
 	Version string `json:"version"`
+	// Commit the service was built from, when known
+	Commit *string `json:"commit,omitempty"`
+	// When the service was built, RFC 3339, when known
+	BuildTime *string `json:"buildTime,omitempty"`
+	// Go version the service was built with
+	GoVersion string `json:"goVersion"`
 }
 
-type UserAnime struct {
-	ID                 string   `json:"id"`
-	UserID             string   `json:"userID"`
+type PageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor,omitempty"`
+	EndCursor       *string `json:"endCursor,omitempty"`
+}
+
+// What other users may see of a profile, nothing until the user opts in. Lists
+// carry their own isPublic flag.
+type PrivacySettings struct {
+	AnimeListPublic bool       `json:"animeListPublic"`
+	StatsPublic     bool       `json:"statsPublic"`
+	FavoritesPublic bool       `json:"favoritesPublic"`
+	UpdatedAt       *time.Time `json:"updatedAt,omitempty"`
+}
+
+// Partial update of the privacy settings. Fields left out are not changed.
+type PrivacySettingsInput struct {
+	AnimeListPublic graphql.Omittable[*bool] `json:"animeListPublic,omitempty"`
+	StatsPublic     graphql.Omittable[*bool] `json:"statsPublic,omitempty"`
+	FavoritesPublic graphql.Omittable[*bool] `json:"favoritesPublic,omitempty"`
+}
+
+type PushChangesInput struct {
+	Animes []*SyncAnimeEdit `json:"animes,omitempty"`
+	Lists  []*SyncListEdit  `json:"lists,omitempty"`
+}
+
+type PushChangesResult struct {
+	UserAnimes []*UserAnime    `json:"userAnimes"`
+	UserLists  []*UserList     `json:"userLists"`
+	Conflicts  []*SyncConflict `json:"conflicts"`
+}
+
+type StatusCount struct {
+	// null counts the entries without a known status
+	Status *Status `json:"status,omitempty"`
+	Count  int     `json:"count"`
+}
+
+type SyncAnimeEdit struct {
 	AnimeID            string   `json:"animeID"`
 	Status             *Status  `json:"status,omitempty"`
 	Score              *float64 `json:"score,omitempty"`
@@ -40,15 +104,93 @@ type UserAnime struct {
 	RewatchingEpisodes *int     `json:"rewatchingEpisodes,omitempty"`
 	Tags               []string `json:"tags,omitempty"`
 	ListID             *string  `json:"listID,omitempty"`
-	CreatedAt          *string  `json:"createdAt,omitempty"`
-	UpdatedAt          *string  `json:"updatedAt,omitempty"`
-	DeletedAt          *string  `json:"deletedAt,omitempty"`
+	Deleted            *bool    `json:"deleted,omitempty"`
+	// When the edit was made on the client
+	UpdatedAt time.Time `json:"updatedAt"`
 }
 
-func (UserAnime) IsEntity() {}
+type SyncChanges struct {
+	UserAnimes []*UserAnime `json:"userAnimes"`
+	UserLists  []*UserList  `json:"userLists"`
+	Tombstones []*Tombstone `json:"tombstones"`
+	// Pass to the next ChangesSince call
+	SyncToken string `json:"syncToken"`
+	// More changes are waiting, call again with syncToken
+	HasMore bool `json:"hasMore"`
+}
+
+type SyncConflict struct {
+	Type   SyncEntityType `json:"type"`
+	ID     string         `json:"id"`
+	Reason string         `json:"reason"`
+	// Current server state, null when it has been deleted
+	ServerAnime *UserAnime `json:"serverAnime,omitempty"`
+	ServerList  *UserList  `json:"serverList,omitempty"`
+}
+
+type SyncListEdit struct {
+	// Omit to create a new list. Ids the server does not know come back as conflicts.
+	ID          *string  `json:"id,omitempty"`
+	Name        string   `json:"name"`
+	Description *string  `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	IsPublic    *bool    `json:"isPublic,omitempty"`
+	Deleted     *bool    `json:"deleted,omitempty"`
+	// When the edit was made on the client
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type Tombstone struct {
+	Type SyncEntityType `json:"type"`
+	ID   string         `json:"id"`
+	// Set for deleted entries
+	AnimeID   *string   `json:"animeID,omitempty"`
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+// Partial update of an entry. Fields left out are not changed, fields set to
+// null are cleared.
+type UpdateAnimeInput struct {
+	AnimeID            string                      `json:"animeID"`
+	Status             graphql.Omittable[*Status]  `json:"status,omitempty"`
+	Score              graphql.Omittable[*float64] `json:"score,omitempty"`
+	Episodes           graphql.Omittable[*int]     `json:"episodes,omitempty"`
+	Rewatching         graphql.Omittable[*int]     `json:"rewatching,omitempty"`
+	RewatchingEpisodes graphql.Omittable[*int]     `json:"rewatchingEpisodes,omitempty"`
+	Tags               graphql.Omittable[[]string] `json:"tags,omitempty"`
+	ListID             graphql.Omittable[*string]  `json:"listID,omitempty"`
+	// Reject the update if the entry is no longer at this version
+	ExpectedVersion *int `json:"expectedVersion,omitempty"`
+}
+
+// Partial update of a list. Fields left out are not changed.
+type UpdateListInput struct {
+	ID          string                      `json:"id"`
+	Name        graphql.Omittable[*string]  `json:"name,omitempty"`
+	Description graphql.Omittable[*string]  `json:"description,omitempty"`
+	Tags        graphql.Omittable[[]string] `json:"tags,omitempty"`
+	IsPublic    graphql.Omittable[*bool]    `json:"isPublic,omitempty"`
+	// Reject the update if the list is no longer at this version
+	ExpectedVersion *int `json:"expectedVersion,omitempty"`
+}
+
+type User struct {
+	ID string `json:"id"`
+	// Lists of the user, private ones only for the user themselves
+	Lists []*UserList `json:"lists"`
+	// Entries of the user, leaving out the ones on private lists for others
+	AnimeList *UserAnimeConnection `json:"animeList,omitempty"`
+	// Totals over the entries of the user, leaving out the ones on private lists for others
+	Stats *UserStats `json:"stats,omitempty"`
+	// Highest scored entries, best first
+	Favorites []*UserAnime `json:"favorites,omitempty"`
+}
 
-type UserAnimeInput struct {
-	ID                 *string  `json:"id,omitempty"`
+func (User) IsEntity() {}
+
+type UserAnime struct {
+	ID                 string   `json:"id"`
+	UserID             string   `json:"userID"`
 	AnimeID            string   `json:"animeID"`
 	Status             *Status  `json:"status,omitempty"`
 	Score              *float64 `json:"score,omitempty"`
@@ -57,6 +199,38 @@ type UserAnimeInput struct {
 	RewatchingEpisodes *int     `json:"rewatchingEpisodes,omitempty"`
 	Tags               []string `json:"tags,omitempty"`
 	ListID             *string  `json:"listID,omitempty"`
+	// Incremented on every change, pass as expectedVersion to guard updates
+	Version   int     `json:"version"`
+	CreatedAt *string `json:"createdAt,omitempty"`
+	UpdatedAt *string `json:"updatedAt,omitempty"`
+	DeletedAt *string `json:"deletedAt,omitempty"`
+}
+
+func (UserAnime) IsEntity() {}
+
+type UserAnimeConnection struct {
+	Edges      []*UserAnimeEdge `json:"edges"`
+	PageInfo   *PageInfo        `json:"pageInfo"`
+	TotalCount int              `json:"totalCount"`
+}
+
+type UserAnimeEdge struct {
+	Cursor string     `json:"cursor"`
+	Node   *UserAnime `json:"node"`
+}
+
+type UserAnimeFilter struct {
+	// Only entries carrying every one of these tags
+	Tags     []string `json:"tags,omitempty"`
+	MinScore *float64 `json:"minScore,omitempty"`
+	MaxScore *float64 `json:"maxScore,omitempty"`
+	// Only entries belonging to one of these lists
+	ListIDs []string `json:"listIDs,omitempty"`
+	// Only entries updated at or after this time
+	UpdatedSince *time.Time `json:"updatedSince,omitempty"`
+	Rewatching   *bool      `json:"rewatching,omitempty"`
+	// Only entries for one of these anime
+	AnimeIDs []string `json:"animeIDs,omitempty"`
 }
 
 type UserAnimePaginated struct {
@@ -66,10 +240,27 @@ type UserAnimePaginated struct {
 	Animes []*UserAnime `json:"animes"`
 }
 
+type UserAnimeSort struct {
+	Field     UserAnimeSortField `json:"field"`
+	Direction *SortDirection     `json:"direction,omitempty"`
+}
+
+type UserAnimesConnectionInput struct {
+	Status *Status          `json:"status,omitempty"`
+	Filter *UserAnimeFilter `json:"filter,omitempty"`
+	Sort   *UserAnimeSort   `json:"sort,omitempty"`
+	First  *int             `json:"first,omitempty"`
+	After  *string          `json:"after,omitempty"`
+	Last   *int             `json:"last,omitempty"`
+	Before *string          `json:"before,omitempty"`
+}
+
 type UserAnimesInput struct {
-	Status *Status `json:"status,omitempty"`
-	Page   int     `json:"page"`
-	Limit  int     `json:"limit"`
+	Status *Status          `json:"status,omitempty"`
+	Filter *UserAnimeFilter `json:"filter,omitempty"`
+	Sort   *UserAnimeSort   `json:"sort,omitempty"`
+	Page   int              `json:"page"`
+	Limit  int              `json:"limit"`
 }
 
 type UserList struct {
@@ -80,15 +271,28 @@ type UserList struct {
 	Type        *string  `json:"type,omitempty"`
 	Tags        []string `json:"tags,omitempty"`
 	IsPublic    *bool    `json:"isPublic,omitempty"`
-	CreatedAt   *string  `json:"createdAt,omitempty"`
-	UpdatedAt   *string  `json:"updatedAt,omitempty"`
-	DeletedAt   *string  `json:"deletedAt,omitempty"`
+	// Incremented on every change, pass as expectedVersion to guard updates
+	Version   int     `json:"version"`
+	CreatedAt *string `json:"createdAt,omitempty"`
+	UpdatedAt *string `json:"updatedAt,omitempty"`
+	DeletedAt *string `json:"deletedAt,omitempty"`
 }
 
 func (UserList) IsEntity() {}
 
+type UserListConnection struct {
+	Edges      []*UserListEdge `json:"edges"`
+	PageInfo   *PageInfo       `json:"pageInfo"`
+	TotalCount int             `json:"totalCount"`
+}
+
+type UserListEdge struct {
+	Cursor string    `json:"cursor"`
+	Node   *UserList `json:"node"`
+}
+
+// A new list. Existing lists are changed through UpdateList.
 type UserListInput struct {
-	ID          *string  `json:"id,omitempty"`
 	Name        string   `json:"name"`
 	Description *string  `json:"description,omitempty"`
 	Type        *string  `json:"type,omitempty"`
@@ -96,6 +300,93 @@ type UserListInput struct {
 	IsPublic    *bool    `json:"isPublic,omitempty"`
 }
 
+type UserStats struct {
+	TotalEntries int            `json:"totalEntries"`
+	ByStatus     []*StatusCount `json:"byStatus"`
+	// Average of the scored entries, null when none are scored
+	MeanScore       *float64 `json:"meanScore,omitempty"`
+	EpisodesWatched int      `json:"episodesWatched"`
+}
+
+type WebhookDelivery struct {
+	ID             string                `json:"id"`
+	SubscriptionID string                `json:"subscriptionID"`
+	EventID        string                `json:"eventID"`
+	EventType      string                `json:"eventType"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	Attempts       int                   `json:"attempts"`
+	// When the next attempt is scheduled, null once the delivery is final
+	NextAttemptAt  *time.Time `json:"nextAttemptAt,omitempty"`
+	LastStatusCode *int       `json:"lastStatusCode,omitempty"`
+	LastError      *string    `json:"lastError,omitempty"`
+	DeliveredAt    *time.Time `json:"deliveredAt,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+}
+
+// An endpoint notified of changes to the user's list. Deliveries are POSTed as
+// JSON and signed with the subscription secret in the X-Webhook-Signature
+// header.
+type WebhookSubscription struct {
+	ID         string   `json:"id"`
+	URL        string   `json:"url"`
+	EventTypes []string `json:"eventTypes"`
+	// Switched off automatically after repeated failed deliveries
+	Enabled             bool       `json:"enabled"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	DisabledAt          *time.Time `json:"disabledAt,omitempty"`
+	CreatedAt           time.Time  `json:"createdAt"`
+}
+
+type WebhookSubscriptionInput struct {
+	// Absolute http or https URL of a public host
+	URL string `json:"url"`
+	// Event types to deliver, e.g. user_anime.added or user_list.deleted
+	EventTypes []string `json:"eventTypes"`
+	// Secret used to sign deliveries, one is generated when omitted
+	Secret *string `json:"secret,omitempty"`
+}
+
+type SortDirection string
+
+const (
+	SortDirectionAsc  SortDirection = "ASC"
+	SortDirectionDesc SortDirection = "DESC"
+)
+
+var AllSortDirection = []SortDirection{
+	SortDirectionAsc,
+	SortDirectionDesc,
+}
+
+func (e SortDirection) IsValid() bool {
+	switch e {
+	case SortDirectionAsc, SortDirectionDesc:
+		return true
+	}
+	return false
+}
+
+func (e SortDirection) String() string {
+	return string(e)
+}
+
+func (e *SortDirection) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = SortDirection(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid SortDirection", str)
+	}
+	return nil
+}
+
+func (e SortDirection) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
 type Status string
 
 const (
@@ -142,3 +433,132 @@ func (e *Status) UnmarshalGQL(v interface{}) error {
 func (e Status) MarshalGQL(w io.Writer) {
 	fmt.Fprint(w, strconv.Quote(e.String()))
 }
+
+type SyncEntityType string
+
+const (
+	SyncEntityTypeUserAnime SyncEntityType = "USER_ANIME"
+	SyncEntityTypeUserList  SyncEntityType = "USER_LIST"
+)
+
+var AllSyncEntityType = []SyncEntityType{
+	SyncEntityTypeUserAnime,
+	SyncEntityTypeUserList,
+}
+
+func (e SyncEntityType) IsValid() bool {
+	switch e {
+	case SyncEntityTypeUserAnime, SyncEntityTypeUserList:
+		return true
+	}
+	return false
+}
+
+func (e SyncEntityType) String() string {
+	return string(e)
+}
+
+func (e *SyncEntityType) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = SyncEntityType(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid SyncEntityType", str)
+	}
+	return nil
+}
+
+func (e SyncEntityType) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type UserAnimeSortField string
+
+const (
+	UserAnimeSortFieldUpdatedAt UserAnimeSortField = "UPDATED_AT"
+	UserAnimeSortFieldCreatedAt UserAnimeSortField = "CREATED_AT"
+	UserAnimeSortFieldScore     UserAnimeSortField = "SCORE"
+	UserAnimeSortFieldProgress  UserAnimeSortField = "PROGRESS"
+)
+
+var AllUserAnimeSortField = []UserAnimeSortField{
+	UserAnimeSortFieldUpdatedAt,
+	UserAnimeSortFieldCreatedAt,
+	UserAnimeSortFieldScore,
+	UserAnimeSortFieldProgress,
+}
+
+func (e UserAnimeSortField) IsValid() bool {
+	switch e {
+	case UserAnimeSortFieldUpdatedAt, UserAnimeSortFieldCreatedAt, UserAnimeSortFieldScore, UserAnimeSortFieldProgress:
+		return true
+	}
+	return false
+}
+
+func (e UserAnimeSortField) String() string {
+	return string(e)
+}
+
+func (e *UserAnimeSortField) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = UserAnimeSortField(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid UserAnimeSortField", str)
+	}
+	return nil
+}
+
+func (e UserAnimeSortField) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "PENDING"
+	WebhookDeliveryStatusSucceeded WebhookDeliveryStatus = "SUCCEEDED"
+	WebhookDeliveryStatusFailed    WebhookDeliveryStatus = "FAILED"
+)
+
+var AllWebhookDeliveryStatus = []WebhookDeliveryStatus{
+	WebhookDeliveryStatusPending,
+	WebhookDeliveryStatusSucceeded,
+	WebhookDeliveryStatusFailed,
+}
+
+func (e WebhookDeliveryStatus) IsValid() bool {
+	switch e {
+	case WebhookDeliveryStatusPending, WebhookDeliveryStatusSucceeded, WebhookDeliveryStatusFailed:
+		return true
+	}
+	return false
+}
+
+func (e WebhookDeliveryStatus) String() string {
+	return string(e)
+}
+
+func (e *WebhookDeliveryStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = WebhookDeliveryStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid WebhookDeliveryStatus", str)
+	}
+	return nil
+}
+
+func (e WebhookDeliveryStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
@@ -3,8 +3,11 @@ package graph
 import (
 	"context"
 	"github.com/weeb-vip/list-service/config"
+	"github.com/weeb-vip/list-service/internal/services/delta_sync"
+	"github.com/weeb-vip/list-service/internal/services/privacy_settings"
 	"github.com/weeb-vip/list-service/internal/services/user_anime"
 	"github.com/weeb-vip/list-service/internal/services/user_list"
+	"github.com/weeb-vip/list-service/internal/services/webhook"
 )
 
 // This file will not be regenerated automatically.
@@ -12,8 +15,11 @@ import (
 // It serves as dependency injection for your app, add any dependencies you require here.
 
 type Resolver struct {
-	Config           config.Config
-	UserListService  user_list.UserListServiceImpl
-	UserAnimeService user_anime.UserAnimeServiceImpl
-	Context          context.Context
+	Config                 config.Config
+	UserListService        user_list.UserListServiceImpl
+	UserAnimeService       user_anime.UserAnimeServiceImpl
+	DeltaSyncService       delta_sync.DeltaSyncServiceImpl
+	WebhookService         webhook.WebhookServiceImpl
+	PrivacySettingsService privacy_settings.PrivacySettingsServiceImpl
+	Context                context.Context
 }
@@ -6,21 +6,26 @@ package graph
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/weeb-vip/list-service/graph/generated"
 	"github.com/weeb-vip/list-service/graph/model"
+	"github.com/weeb-vip/list-service/internal/pagination"
 	"github.com/weeb-vip/list-service/internal/resolvers"
 )
 
 // GolangTemplateAPI is the resolver for the GolangTemplateAPI field.
 func (r *apiInfoResolver) GolangTemplateAPI(ctx context.Context, obj *model.APIInfo) (*model.ListServiceAPI, error) {
-	panic(fmt.Errorf("not implemented: GolangTemplateAPI - GolangTemplateAPI"))
+	return resolvers.GetListServiceAPI(r.Config.AppConfig.Version), nil
 }
 
 // CreateList is the resolver for the CreateList field.
 func (r *mutationResolver) CreateList(ctx context.Context, input model.UserListInput) (*model.UserList, error) {
-	return resolvers.UpsertUserList(ctx, r.UserListService, input)
+	return resolvers.CreateUserList(ctx, r.UserListService, input)
+}
+
+// UpdateList is the resolver for the UpdateList field.
+func (r *mutationResolver) UpdateList(ctx context.Context, input model.UpdateListInput) (*model.UserList, error) {
+	return resolvers.UpdateUserList(ctx, r.UserListService, input)
 }
 
 // DeleteList is the resolver for the DeleteList field.
@@ -33,13 +38,13 @@ func (r *mutationResolver) DeleteList(ctx context.Context, id string) (bool, err
 }
 
 // AddAnime is the resolver for the AddAnime field.
-func (r *mutationResolver) AddAnime(ctx context.Context, input model.UserAnimeInput) (*model.UserAnime, error) {
-	return resolvers.UpsertUserAnime(ctx, r.UserAnimeService, input)
+func (r *mutationResolver) AddAnime(ctx context.Context, input model.AddAnimeInput) (*model.UserAnime, error) {
+	return resolvers.AddUserAnime(ctx, r.UserAnimeService, input)
 }
 
 // UpdateAnime is the resolver for the UpdateAnime field.
-func (r *mutationResolver) UpdateAnime(ctx context.Context, input model.UserAnimeInput) (*model.UserAnime, error) {
-	return resolvers.UpsertUserAnime(ctx, r.UserAnimeService, input)
+func (r *mutationResolver) UpdateAnime(ctx context.Context, input model.UpdateAnimeInput) (*model.UserAnime, error) {
+	return resolvers.UpdateUserAnime(ctx, r.UserAnimeService, input)
 }
 
 // DeleteAnime is the resolver for the DeleteAnime field.
@@ -51,16 +56,76 @@ func (r *mutationResolver) DeleteAnime(ctx context.Context, id string) (bool, er
 	return true, nil
 }
 
+// PushChanges is the resolver for the PushChanges field.
+func (r *mutationResolver) PushChanges(ctx context.Context, input model.PushChangesInput) (*model.PushChangesResult, error) {
+	return resolvers.PushChanges(ctx, r.DeltaSyncService, input)
+}
+
+// CreateWebhookSubscription is the resolver for the CreateWebhookSubscription field.
+func (r *mutationResolver) CreateWebhookSubscription(ctx context.Context, input model.WebhookSubscriptionInput) (*model.CreatedWebhookSubscription, error) {
+	return resolvers.CreateWebhookSubscription(ctx, r.WebhookService, input)
+}
+
+// DeleteWebhookSubscription is the resolver for the DeleteWebhookSubscription field.
+func (r *mutationResolver) DeleteWebhookSubscription(ctx context.Context, id string) (bool, error) {
+	return resolvers.DeleteWebhookSubscription(ctx, r.WebhookService, id)
+}
+
+// EnableWebhookSubscription is the resolver for the EnableWebhookSubscription field.
+func (r *mutationResolver) EnableWebhookSubscription(ctx context.Context, id string) (*model.WebhookSubscription, error) {
+	return resolvers.EnableWebhookSubscription(ctx, r.WebhookService, id)
+}
+
+// UpdatePrivacySettings is the resolver for the UpdatePrivacySettings field.
+func (r *mutationResolver) UpdatePrivacySettings(ctx context.Context, input model.PrivacySettingsInput) (*model.PrivacySettings, error) {
+	return resolvers.UpdatePrivacySettings(ctx, r.PrivacySettingsService, input)
+}
+
 // UserLists is the resolver for the UserLists field.
 func (r *queryResolver) UserLists(ctx context.Context) ([]*model.UserList, error) {
 	return resolvers.GetUserListsByID(ctx, r.UserListService)
 }
 
+// UserListsConnection is the resolver for the UserListsConnection field.
+func (r *queryResolver) UserListsConnection(ctx context.Context, first *int, after *string, last *int, before *string) (*model.UserListConnection, error) {
+	return resolvers.GetUserListsConnection(ctx, r.UserListService, pagination.Request{
+		First:  first,
+		After:  after,
+		Last:   last,
+		Before: before,
+	})
+}
+
 // UserAnimes is the resolver for the UserAnimes field.
 func (r *queryResolver) UserAnimes(ctx context.Context, input model.UserAnimesInput) (*model.UserAnimePaginated, error) {
 	return resolvers.GetUserAnimesByID(ctx, r.UserAnimeService, input)
 }
 
+// UserAnimesConnection is the resolver for the UserAnimesConnection field.
+func (r *queryResolver) UserAnimesConnection(ctx context.Context, input model.UserAnimesConnectionInput) (*model.UserAnimeConnection, error) {
+	return resolvers.GetUserAnimesConnection(ctx, r.UserAnimeService, input)
+}
+
+// ChangesSince is the resolver for the ChangesSince field.
+func (r *queryResolver) ChangesSince(ctx context.Context, token *string, limit *int) (*model.SyncChanges, error) {
+	return resolvers.GetChangesSince(ctx, r.DeltaSyncService, token, limit)
+}
+
+// WebhookSubscriptions is the resolver for the WebhookSubscriptions field.
+func (r *queryResolver) WebhookSubscriptions(ctx context.Context) ([]*model.WebhookSubscription, error) {
+	return resolvers.GetWebhookSubscriptions(ctx, r.WebhookService)
+}
+
+// WebhookDeliveries is the resolver for the WebhookDeliveries field.
+func (r *queryResolver) WebhookDeliveries(ctx context.Context, subscriptionID string, limit *int) ([]*model.WebhookDelivery, error) {
+	return resolvers.GetWebhookDeliveries(ctx, r.WebhookService, subscriptionID, limit)
+}
+
+// PrivacySettings is the resolver for the PrivacySettings field.
+func (r *queryResolver) PrivacySettings(ctx context.Context) (*model.PrivacySettings, error) {
+	return resolvers.GetPrivacySettings(ctx, r.PrivacySettingsService)
+}
+
 // ApiInfo returns generated.ApiInfoResolver implementation.
 func (r *Resolver) ApiInfo() generated.ApiInfoResolver { return &apiInfoResolver{r} }
 
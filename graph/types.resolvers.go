@@ -6,10 +6,10 @@ package graph
 
 import (
 	"context"
-	"github.com/weeb-vip/list-service/internal/resolvers"
 
 	"github.com/weeb-vip/list-service/graph/generated"
 	"github.com/weeb-vip/list-service/graph/model"
+	"github.com/weeb-vip/list-service/internal/resolvers"
 )
 
 // UserAnime is the resolver for the userAnime field.
@@ -17,7 +17,31 @@ func (r *animeResolver) UserAnime(ctx context.Context, obj *model.Anime) (*model
 	return resolvers.GetUserAnimeByAnimeIDWithLoader(ctx, obj.ID)
 }
 
+// Lists is the resolver for the lists field.
+func (r *userResolver) Lists(ctx context.Context, obj *model.User) ([]*model.UserList, error) {
+	return resolvers.GetUserLists(ctx, obj.ID)
+}
+
+// AnimeList is the resolver for the animeList field.
+func (r *userResolver) AnimeList(ctx context.Context, obj *model.User, filter *model.UserAnimeFilter, sort *model.UserAnimeSort, first *int, after *string) (*model.UserAnimeConnection, error) {
+	return resolvers.GetUserAnimeList(ctx, r.UserAnimeService, obj.ID, filter, sort, first, after)
+}
+
+// Stats is the resolver for the stats field.
+func (r *userResolver) Stats(ctx context.Context, obj *model.User) (*model.UserStats, error) {
+	return resolvers.GetUserStats(ctx, obj.ID)
+}
+
+// Favorites is the resolver for the favorites field.
+func (r *userResolver) Favorites(ctx context.Context, obj *model.User, first *int) ([]*model.UserAnime, error) {
+	return resolvers.GetUserFavorites(ctx, obj.ID, first)
+}
+
 // Anime returns generated.AnimeResolver implementation.
 func (r *Resolver) Anime() generated.AnimeResolver { return &animeResolver{r} }
 
+// User returns generated.UserResolver implementation.
+func (r *Resolver) User() generated.UserResolver { return &userResolver{r} }
+
 type animeResolver struct{ *Resolver }
+type userResolver struct{ *Resolver }
@@ -1,8 +1,98 @@
 package handlers
 
-import "net/http"
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
 
-func HealthCheckHandler() http.HandlerFunc {
+// Check reports whether a dependency the service needs is usable
+type Check func(ctx context.Context) error
+
+type readinessCheck struct {
+	name  string
+	check Check
+}
+
+// Readiness answers /readyz by running every registered check. It fails once
+// Drain is called so traffic moves elsewhere before the server shuts down.
+type Readiness struct {
+	timeout  time.Duration
+	checks   []readinessCheck
+	draining atomic.Bool
+}
+
+type readinessResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// NewReadiness returns a readiness probe that gives each check timeout to
+// answer
+func NewReadiness(timeout time.Duration) *Readiness {
+	return &Readiness{timeout: timeout}
+}
+
+// Add registers a check under name. Checks are added before serving starts.
+func (r *Readiness) Add(name string, check Check) {
+	r.checks = append(r.checks, readinessCheck{name: name, check: check})
+}
+
+// Drain makes the probe fail from now on
+func (r *Readiness) Drain() {
+	r.draining.Store(true)
+}
+
+// Handler runs the checks concurrently and returns 503 when any fails
+func (r *Readiness) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		response := readinessResponse{Status: "ok", Checks: map[string]string{}}
+		if r.draining.Load() {
+			response.Status = "draining"
+		}
+
+		ctx, cancel := context.WithTimeout(req.Context(), r.timeout)
+		defer cancel()
+
+		results := make([]error, len(r.checks))
+		var wg sync.WaitGroup
+		for i, c := range r.checks {
+			wg.Add(1)
+			go func(i int, c readinessCheck) {
+				defer wg.Done()
+				results[i] = c.check(ctx)
+			}(i, c)
+		}
+		wg.Wait()
+
+		for i, c := range r.checks {
+			if results[i] != nil {
+				response.Checks[c.name] = results[i].Error()
+				if response.Status == "ok" {
+					response.Status = "unavailable"
+				}
+				continue
+			}
+			response.Checks[c.name] = "ok"
+		}
+
+		status := http.StatusOK
+		if response.Status != "ok" {
+			status = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// LivenessHandler answers /livez. It only shows the process is serving, a
+// dependency being down must not get the service restarted.
+func LivenessHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
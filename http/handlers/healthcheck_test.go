@@ -0,0 +1,58 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weeb-vip/list-service/http/handlers"
+	"github.com/weeb-vip/list-service/internal/broker"
+	"github.com/weeb-vip/list-service/internal/db/dbtest"
+)
+
+func probe(t *testing.T, readiness *handlers.Readiness) (int, map[string]interface{}) {
+	recorder := httptest.NewRecorder()
+	readiness.Handler()(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	return recorder.Code, body
+}
+
+func TestReadiness(t *testing.T) {
+	database := dbtest.NewSQLite(t)
+	eventBroker := broker.NewMemory("events")
+
+	readiness := handlers.NewReadiness(time.Second)
+	readiness.Add("database", database.Ping)
+	readiness.Add("broker", eventBroker.Check)
+
+	t.Run("ready while every check passes", func(t *testing.T) {
+		code, body := probe(t, readiness)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "ok", body["status"])
+	})
+
+	t.Run("unavailable while a dependency is down", func(t *testing.T) {
+		eventBroker.SetErr(errors.New("broker unreachable"))
+		defer eventBroker.SetErr(nil)
+
+		code, body := probe(t, readiness)
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, "unavailable", body["status"])
+		assert.Equal(t, map[string]interface{}{"database": "ok", "broker": "broker unreachable"}, body["checks"])
+	})
+
+	t.Run("draining fails the probe", func(t *testing.T) {
+		readiness.Drain()
+
+		code, body := probe(t, readiness)
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, "draining", body["status"])
+	})
+}
@@ -17,6 +17,16 @@ func FromContext(ctx context.Context) RequestInfo {
 	return details
 }
 
+// UserID returns the user making the request in ctx, or "" when there is none
+func UserID(ctx context.Context) string {
+	details, found := ctx.Value(&ctxKey{}).(RequestInfo)
+	if !found || details.UserID == nil {
+		return ""
+	}
+
+	return *details.UserID
+}
+
 func Handler() func(http.Handler) http.Handler {
 	return getHandler
 }
@@ -11,27 +11,66 @@ import (
 	"github.com/weeb-vip/list-service/http/handlers/logger"
 	"github.com/weeb-vip/list-service/http/handlers/requestinfo"
 	"github.com/weeb-vip/list-service/http/middleware"
+	"github.com/weeb-vip/list-service/internal/cache"
 	"github.com/weeb-vip/list-service/internal/dataloader"
 	"github.com/weeb-vip/list-service/internal/db"
+	"github.com/weeb-vip/list-service/internal/db/repositories/outbox"
+	"github.com/weeb-vip/list-service/internal/db/repositories/privacy_settings"
 	"github.com/weeb-vip/list-service/internal/db/repositories/user_anime"
 	"github.com/weeb-vip/list-service/internal/db/repositories/user_list"
+	"github.com/weeb-vip/list-service/internal/db/repositories/webhook"
 	"github.com/weeb-vip/list-service/internal/directives"
+	logger2 "github.com/weeb-vip/list-service/internal/logger"
+	"github.com/weeb-vip/list-service/internal/services/delta_sync"
+	privacy_settings2 "github.com/weeb-vip/list-service/internal/services/privacy_settings"
 	user_anime2 "github.com/weeb-vip/list-service/internal/services/user_anime"
 	user_list2 "github.com/weeb-vip/list-service/internal/services/user_list"
+	webhook2 "github.com/weeb-vip/list-service/internal/services/webhook"
 	"net/http"
 )
 
-func BuildRootHandler(conf config.Config) http.Handler {
-	database := db.NewDatabase(conf.DBConfig)
+// buildListRepositories returns the list repositories, read through the cache
+// when it is enabled
+func buildListRepositories(conf config.Config, database *db.DB) (user_list.UserListRepositoryImpl, user_anime.UserAnimeRepositoryImpl) {
 	userListRepository := user_list.NewUserListRepository(database)
-	userListService := user_list2.NewUserListService(userListRepository)
 	userAnimeRepository := user_anime.NewUserAnimeRepository(database)
-	userAnimeService := user_anime2.NewUserAnimeService(userAnimeRepository)
+	if !conf.CacheConfig.Enabled {
+		return userListRepository, userAnimeRepository
+	}
+
+	versioned, err := cache.NewFromConfig(conf.CacheConfig)
+	if err != nil {
+		log := logger2.Get()
+		log.Error().Err(err).Msg("Error creating cache, serving lists uncached")
+		return userListRepository, userAnimeRepository
+	}
+	if conf.CacheConfig.RedisURL == "" {
+		log := logger2.Get()
+		log.Warn().Msg("Caching lists in process, run a single instance or set REDIS_URL")
+	}
+
+	return user_list.NewCachedUserListRepository(userListRepository, versioned), user_anime.NewCachedUserAnimeRepository(userAnimeRepository, versioned)
+}
+
+// BuildRootHandler returns the GraphQL handler serving from database, which
+// the caller closes on shutdown
+func BuildRootHandler(conf config.Config, database *db.DB) http.Handler {
+	database.ReadYourWrites(requestinfo.UserID)
+	outboxRepository := outbox.NewOutboxRepository(database)
+	userListRepository, userAnimeRepository := buildListRepositories(conf, database)
+	userListService := user_list2.NewUserListService(userListRepository, outboxRepository, database)
+	userAnimeService := user_anime2.NewUserAnimeService(userAnimeRepository, outboxRepository, database)
+	deltaSyncService := delta_sync.NewDeltaSyncService(userAnimeRepository, userListRepository, userAnimeService, userListService, database)
+	webhookService := webhook2.NewWebhookService(webhook.NewWebhookRepository(database))
+	privacySettingsService := privacy_settings2.NewPrivacySettingsService(privacy_settings.NewPrivacySettingsRepository(database))
 
 	resolvers := &graph.Resolver{
-		Config:           conf,
-		UserListService:  userListService,
-		UserAnimeService: userAnimeService,
+		Config:                 conf,
+		UserListService:        userListService,
+		UserAnimeService:       userAnimeService,
+		DeltaSyncService:       deltaSyncService,
+		WebhookService:         webhookService,
+		PrivacySettingsService: privacySettingsService,
 	}
 
 	cfg := generated.Config{Resolvers: resolvers, Directives: directives.GetDirectives()}
@@ -48,21 +87,27 @@ func BuildRootHandler(conf config.Config) http.Handler {
 
 	srv := handler.NewDefaultServer(generated.NewExecutableSchema(cfg))
 
-	return requestinfo.Handler()(logger.Handler()(dataloader.Middleware(userAnimeService)(srv)))
+	return requestinfo.Handler()(logger.Handler()(dataloader.Middleware(conf.ServerConfig, userAnimeService, userListService, privacySettingsService)(srv)))
 }
 
-func BuildRootHandlerWithContext(ctx context.Context, conf config.Config) http.Handler {
-	database := db.NewDatabase(conf.DBConfig)
-	userListRepository := user_list.NewUserListRepository(database)
-	userListService := user_list2.NewUserListService(userListRepository)
-	userAnimeRepository := user_anime.NewUserAnimeRepository(database)
-	userAnimeService := user_anime2.NewUserAnimeService(userAnimeRepository)
+func BuildRootHandlerWithContext(ctx context.Context, conf config.Config, database *db.DB) http.Handler {
+	database.ReadYourWrites(requestinfo.UserID)
+	outboxRepository := outbox.NewOutboxRepository(database)
+	userListRepository, userAnimeRepository := buildListRepositories(conf, database)
+	userListService := user_list2.NewUserListService(userListRepository, outboxRepository, database)
+	userAnimeService := user_anime2.NewUserAnimeService(userAnimeRepository, outboxRepository, database)
+	deltaSyncService := delta_sync.NewDeltaSyncService(userAnimeRepository, userListRepository, userAnimeService, userListService, database)
+	webhookService := webhook2.NewWebhookService(webhook.NewWebhookRepository(database))
+	privacySettingsService := privacy_settings2.NewPrivacySettingsService(privacy_settings.NewPrivacySettingsRepository(database))
 
 	resolvers := &graph.Resolver{
-		Config:           conf,
-		UserListService:  userListService,
-		UserAnimeService: userAnimeService,
-		Context:          ctx,
+		Config:                 conf,
+		UserListService:        userListService,
+		UserAnimeService:       userAnimeService,
+		DeltaSyncService:       deltaSyncService,
+		WebhookService:         webhookService,
+		PrivacySettingsService: privacySettingsService,
+		Context:                ctx,
 	}
 
 	cfg := generated.Config{Resolvers: resolvers, Directives: directives.GetDirectives()}
@@ -82,5 +127,5 @@ func BuildRootHandlerWithContext(ctx context.Context, conf config.Config) http.H
 	// Add GraphQL tracing extension
 	srv.Use(&middleware.GraphQLTracingExtension{})
 
-	return requestinfo.Handler()(logger.Handler()(dataloader.Middleware(userAnimeService)(srv)))
+	return requestinfo.Handler()(logger.Handler()(dataloader.Middleware(conf.ServerConfig, userAnimeService, userListService, privacySettingsService)(srv)))
 }
@@ -9,12 +9,14 @@ import (
 	"github.com/weeb-vip/list-service/config"
 	"github.com/weeb-vip/list-service/http/handlers"
 	"github.com/weeb-vip/list-service/http/middleware"
+	"github.com/weeb-vip/list-service/internal/db"
 	"github.com/weeb-vip/list-service/internal/logger"
 	"github.com/weeb-vip/list-service/metrics"
 	"net/http"
+	"time"
 )
 
-func SetupServer(cfg config.Config) *chi.Mux {
+func SetupServer(cfg config.Config, database *db.DB, readiness *handlers.Readiness) *chi.Mux {
 
 	router := chi.NewRouter()
 
@@ -22,20 +24,24 @@ func SetupServer(cfg config.Config) *chi.Mux {
 	router.Use(middleware.SelectiveGzipMiddleware("/metrics"))
 
 	router.Use(cors.New(cors.Options{
-		AllowedOrigins:   []string{"http://localhost:8081", "http://localhost:3000"},
+		AllowedOrigins:   cfg.ServerConfig.AllowedOrigins(),
 		AllowCredentials: true,
 		Debug:            false,
 	}).Handler)
 
 	router.Handle("/ui/playground", playground.Handler("GraphQL playground", "/graphql"))
-	router.Handle("/graphql", handlers.BuildRootHandler(cfg))
-	router.Handle("/healthcheck", handlers.HealthCheckHandler())
-	router.Handle("/metrics", metrics.NewPrometheusInstance().Handler())
+	router.Handle("/graphql", handlers.BuildRootHandler(cfg, database))
+	router.Handle("/livez", handlers.LivenessHandler())
+	router.Handle("/readyz", readiness.Handler())
+	router.Handle("/healthcheck", readiness.Handler())
+	if handler := metrics.Handler(); handler != nil {
+		router.Handle("/metrics", handler)
+	}
 
 	return router
 }
 
-func SetupServerWithContext(ctx context.Context, cfg config.Config) *chi.Mux {
+func SetupServerWithContext(ctx context.Context, cfg config.Config, database *db.DB, readiness *handlers.Readiness) *chi.Mux {
 
 	router := chi.NewRouter()
 
@@ -46,35 +52,40 @@ func SetupServerWithContext(ctx context.Context, cfg config.Config) *chi.Mux {
 	router.Use(middleware.TracingMiddleware())
 
 	router.Use(cors.New(cors.Options{
-		AllowedOrigins:   []string{"http://localhost:8081", "http://localhost:3000"},
+		AllowedOrigins:   cfg.ServerConfig.AllowedOrigins(),
 		AllowCredentials: true,
 		Debug:            false,
 	}).Handler)
 
 	router.Handle("/ui/playground", playground.Handler("GraphQL playground", "/graphql"))
-	router.Handle("/graphql", handlers.BuildRootHandlerWithContext(ctx, cfg))
-	router.Handle("/healthcheck", handlers.HealthCheckHandler())
-	router.Handle("/metrics", metrics.NewPrometheusInstance().Handler())
+	router.Handle("/graphql", handlers.BuildRootHandlerWithContext(ctx, cfg, database))
+	router.Handle("/livez", handlers.LivenessHandler())
+	router.Handle("/readyz", readiness.Handler())
+	router.Handle("/healthcheck", readiness.Handler())
+	if handler := metrics.Handler(); handler != nil {
+		router.Handle("/metrics", handler)
+	}
 
 	return router
 }
 
-func StartServer() error {
-	cfg := config.LoadConfigOrPanic()
-	router := SetupServer(cfg)
-
-	log := logger.Get()
-	log.Info().
-		Int("port", cfg.AppConfig.Port).
-		Str("playground_url", fmt.Sprintf("http://localhost:%d/", cfg.AppConfig.Port)).
-		Msg("Starting GraphQL server")
-
-	return http.ListenAndServe(fmt.Sprintf(":%d", cfg.AppConfig.Port), router)
+// NewServer returns a server for handler on port with the configured timeouts
+func NewServer(cfg config.ServerConfig, port int, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              fmt.Sprintf(":%d", port),
+		Handler:           handler,
+		ReadTimeout:       time.Duration(cfg.ReadTimeoutMs) * time.Millisecond,
+		ReadHeaderTimeout: time.Duration(cfg.ReadHeaderTimeoutMs) * time.Millisecond,
+		WriteTimeout:      time.Duration(cfg.WriteTimeoutMs) * time.Millisecond,
+		IdleTimeout:       time.Duration(cfg.IdleTimeoutMs) * time.Millisecond,
+	}
 }
 
-func StartServerWithContext(ctx context.Context) error {
-	cfg := config.LoadConfigOrPanic()
-	router := SetupServerWithContext(ctx, cfg)
+// StartServerWithContext serves until ctx is cancelled. It then fails
+// readiness, keeps serving for the shutdown delay so load balancers stop
+// routing here, and waits up to the shutdown timeout for in-flight requests.
+func StartServerWithContext(ctx context.Context, cfg config.Config, database *db.DB, readiness *handlers.Readiness) error {
+	srv := NewServer(cfg.ServerConfig, cfg.AppConfig.Port, SetupServerWithContext(ctx, cfg, database, readiness))
 
 	log := logger.FromCtx(ctx)
 	log.Info().
@@ -82,5 +93,35 @@ func StartServerWithContext(ctx context.Context) error {
 		Str("playground_url", fmt.Sprintf("http://localhost:%d/", cfg.AppConfig.Port)).
 		Msg("Starting GraphQL server")
 
-	return http.ListenAndServe(fmt.Sprintf(":%d", cfg.AppConfig.Port), router)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	readiness.Drain()
+	log.Info().Int("delay_ms", cfg.ServerConfig.ShutdownDelayMs).Msg("Shutting down, draining traffic")
+
+	select {
+	case err := <-errs:
+		return err
+	case <-time.After(time.Duration(cfg.ServerConfig.ShutdownDelayMs) * time.Millisecond):
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Duration(cfg.ServerConfig.ShutdownTimeoutMs)*time.Millisecond)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		// requests still running are cut off
+		srv.Close()
+		return fmt.Errorf("shutting down server: %w", err)
+	}
+
+	log.Info().Msg("GraphQL server stopped")
+	return nil
 }
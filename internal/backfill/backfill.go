@@ -0,0 +1,242 @@
+package backfill
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/weeb-vip/list-service/internal/db"
+	"github.com/weeb-vip/list-service/internal/db/repositories/backfill"
+	"github.com/weeb-vip/list-service/internal/logger"
+	"github.com/weeb-vip/list-service/metrics"
+)
+
+const (
+	DefaultBatchSize = 500
+	DefaultThrottle  = 100 * time.Millisecond
+)
+
+// Job is a backfill that walks a table in primary key order
+type Job interface {
+	// Name identifies the job and its checkpoint
+	Name() string
+	Description() string
+	// Batch processes up to limit rows with ids after afterId. Changes are
+	// skipped on a dry run. A batch that scans no rows finishes the job.
+	Batch(ctx context.Context, afterId string, limit int, dryRun bool) (Batch, error)
+}
+
+// Batch is the outcome of one batch of a job
+type Batch struct {
+	// LastID is the id of the last row scanned
+	LastID  string
+	Scanned int
+	Changed int
+}
+
+type RunnerImpl interface {
+	// Run processes batches until the job is complete or ctx is cancelled,
+	// continuing from the job's checkpoint. It returns the progress made.
+	Run(ctx context.Context, job Job) (*backfill.Checkpoint, error)
+}
+
+type Runner struct {
+	Repository backfill.BackfillRepositoryImpl
+	Transactor db.Transactor
+	BatchSize  int
+	// Throttle is the pause between batches, to leave the database room for
+	// regular traffic
+	Throttle time.Duration
+	// DryRun reports what would change without writing anything, including
+	// the checkpoint
+	DryRun bool
+	// Restart discards the checkpoint and starts from the first row
+	Restart bool
+}
+
+type Option func(*Runner)
+
+func WithBatchSize(batchSize int) Option {
+	return func(r *Runner) {
+		r.BatchSize = batchSize
+	}
+}
+
+func WithThrottle(throttle time.Duration) Option {
+	return func(r *Runner) {
+		r.Throttle = throttle
+	}
+}
+
+func WithDryRun(dryRun bool) Option {
+	return func(r *Runner) {
+		r.DryRun = dryRun
+	}
+}
+
+func WithRestart(restart bool) Option {
+	return func(r *Runner) {
+		r.Restart = restart
+	}
+}
+
+func NewRunner(repository backfill.BackfillRepositoryImpl, transactor db.Transactor, opts ...Option) RunnerImpl {
+	runner := &Runner{
+		Repository: repository,
+		Transactor: transactor,
+		BatchSize:  DefaultBatchSize,
+		Throttle:   DefaultThrottle,
+	}
+	for _, opt := range opts {
+		opt(runner)
+	}
+
+	return runner
+}
+
+func (r *Runner) Run(ctx context.Context, job Job) (*backfill.Checkpoint, error) {
+	log := logger.FromCtx(ctx).With().Str("backfill", job.Name()).Bool("dry_run", r.DryRun).Logger()
+
+	if r.Restart && !r.DryRun {
+		if err := r.Repository.DeleteCheckpoint(ctx, job.Name()); err != nil {
+			return nil, err
+		}
+	}
+
+	checkpoint, err := r.Repository.FindCheckpoint(ctx, job.Name())
+	if err != nil {
+		return nil, err
+	}
+	if checkpoint == nil || r.Restart {
+		checkpoint = &backfill.Checkpoint{Name: job.Name()}
+	}
+	if checkpoint.CompletedAt != nil {
+		log.Info().Time("completed_at", *checkpoint.CompletedAt).Msg("Backfill already completed")
+		return checkpoint, nil
+	}
+
+	log.Info().Str("after_id", checkpoint.LastID).Int64("scanned", checkpoint.Scanned).Msg("Starting backfill")
+	for {
+		// a batch that has started is finished even when ctx is cancelled,
+		// the loop stops after it
+		if r.DryRun {
+			err = r.dryRunBatch(context.WithoutCancel(ctx), job, checkpoint)
+		} else {
+			err = r.batch(context.WithoutCancel(ctx), job, checkpoint)
+		}
+		if err != nil {
+			metrics.GetAppMetrics().BackfillBatchMetric(job.Name(), metrics.Error)
+			return checkpoint, fmt.Errorf("backfill %s after id %q: %w", job.Name(), checkpoint.LastID, err)
+		}
+		metrics.GetAppMetrics().BackfillBatchMetric(job.Name(), metrics.Success)
+		metrics.GetAppMetrics().BackfillProgressMetric(job.Name(), checkpoint.Scanned, checkpoint.Changed)
+
+		if checkpoint.CompletedAt != nil {
+			log.Info().Int64("scanned", checkpoint.Scanned).Int64("changed", checkpoint.Changed).Msg("Backfill completed")
+			return checkpoint, nil
+		}
+		log.Info().Str("last_id", checkpoint.LastID).Int64("scanned", checkpoint.Scanned).Int64("changed", checkpoint.Changed).Msg("Backfill batch done")
+
+		// checked first, select picks at random when the throttle is zero
+		if ctx.Err() == nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(r.Throttle):
+			}
+		}
+		if ctx.Err() != nil {
+			log.Info().Str("last_id", checkpoint.LastID).Msg("Backfill stopped, run again to resume")
+			return checkpoint, ctx.Err()
+		}
+	}
+}
+
+// batch runs one batch and saves the checkpoint in the same transaction, so
+// progress is only recorded for changes that were committed
+func (r *Runner) batch(ctx context.Context, job Job, checkpoint *backfill.Checkpoint) error {
+	var next backfill.Checkpoint
+	err := r.Transactor.Transaction(ctx, func(ctx context.Context) error {
+		// reading the checkpoint again locks it, so a second run of the same
+		// job waits instead of repeating the batch
+		saved, err := r.Repository.FindCheckpoint(ctx, job.Name())
+		if err != nil {
+			return err
+		}
+		next = *checkpoint
+		if saved != nil {
+			next = *saved
+		}
+
+		batch, err := job.Batch(ctx, next.LastID, r.BatchSize, false)
+		if err != nil {
+			return err
+		}
+		advance(&next, batch)
+
+		return r.Repository.SaveCheckpoint(ctx, &next)
+	})
+	if err != nil {
+		return err
+	}
+
+	*checkpoint = next
+	return nil
+}
+
+// dryRunBatch runs one batch without writing, keeping progress in memory
+func (r *Runner) dryRunBatch(ctx context.Context, job Job, checkpoint *backfill.Checkpoint) error {
+	batch, err := job.Batch(ctx, checkpoint.LastID, r.BatchSize, true)
+	if err != nil {
+		return err
+	}
+
+	advance(checkpoint, batch)
+	return nil
+}
+
+func advance(checkpoint *backfill.Checkpoint, batch Batch) {
+	if batch.Scanned == 0 {
+		now := time.Now()
+		checkpoint.CompletedAt = &now
+		return
+	}
+
+	checkpoint.LastID = batch.LastID
+	checkpoint.Scanned += int64(batch.Scanned)
+	checkpoint.Changed += int64(batch.Changed)
+}
+
+// Registry holds the jobs that can be run by name
+type Registry map[string]Job
+
+// NewRegistry returns a registry of jobs
+func NewRegistry(jobs ...Job) Registry {
+	registry := Registry{}
+	for _, job := range jobs {
+		registry[job.Name()] = job
+	}
+
+	return registry
+}
+
+// Get returns the job named name
+func (r Registry) Get(name string) (Job, error) {
+	job, ok := r[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown backfill %q", name)
+	}
+
+	return job, nil
+}
+
+// Jobs returns every job ordered by name
+func (r Registry) Jobs() []Job {
+	jobs := make([]Job, 0, len(r))
+	for _, job := range r {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Name() < jobs[j].Name() })
+
+	return jobs
+}
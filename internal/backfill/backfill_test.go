@@ -0,0 +1,138 @@
+package backfill_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weeb-vip/list-service/internal/backfill"
+	"github.com/weeb-vip/list-service/internal/db"
+	"github.com/weeb-vip/list-service/internal/db/dbtest"
+	backfill2 "github.com/weeb-vip/list-service/internal/db/repositories/backfill"
+	"github.com/weeb-vip/list-service/internal/db/repositories/user_anime"
+)
+
+func TestNormalizeStatus(t *testing.T) {
+	tests := []struct {
+		status   string
+		expected string
+		known    bool
+	}{
+		{"watchlist", "PLANTOWATCH", true},
+		{"plantowatch", "PLANTOWATCH", true},
+		{"on_hold", "ONHOLD", true},
+		{"Watching", "WATCHING", true},
+		{"COMPLETED", "COMPLETED", true},
+		{"rewatching", "REWATCHING", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			status, known := backfill.NormalizeStatus(tt.status)
+			assert.Equal(t, tt.expected, status)
+			assert.Equal(t, tt.known, known)
+		})
+	}
+}
+
+// seed stores one entry per status with ids in the given order
+func seed(t *testing.T, database *db.DB, statuses ...string) {
+	for i, status := range statuses {
+		status := status
+		userID := "user_1"
+		animeID := fmt.Sprintf("anime_%d", i)
+		row := &user_anime.UserAnime{ID: fmt.Sprintf("id_%02d", i), UserID: &userID, AnimeID: &animeID, Status: &status, Version: 1}
+		require.NoError(t, database.DB.Create(row).Error)
+	}
+}
+
+func statuses(t *testing.T, database *db.DB) []string {
+	var rows []*user_anime.UserAnime
+	require.NoError(t, database.DB.Unscoped().Order("id asc").Find(&rows).Error)
+
+	result := make([]string, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, *row.Status)
+	}
+	return result
+}
+
+func TestNormalizeStatusesJob(t *testing.T) {
+	ctx := context.Background()
+	database := dbtest.NewSQLite(t)
+	seed(t, database, "watchlist", "WATCHING", "plantowatch", "bogus", "completed")
+	require.NoError(t, database.DB.Where("id = ?", "id_04").Delete(&user_anime.UserAnime{}).Error)
+
+	job := backfill.NewNormalizeStatusesJob(user_anime.NewUserAnimeRepository(database))
+	repository := backfill2.NewBackfillRepository(database)
+
+	t.Run("dry runs count changes without writing", func(t *testing.T) {
+		runner := backfill.NewRunner(repository, database, backfill.WithBatchSize(2), backfill.WithThrottle(0), backfill.WithDryRun(true))
+		checkpoint, err := runner.Run(ctx, job)
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), checkpoint.Scanned)
+		assert.Equal(t, int64(3), checkpoint.Changed)
+
+		assert.Equal(t, []string{"watchlist", "WATCHING", "plantowatch", "bogus", "completed"}, statuses(t, database))
+		saved, err := repository.FindCheckpoint(ctx, job.Name())
+		require.NoError(t, err)
+		assert.Nil(t, saved)
+	})
+
+	t.Run("runs resume from the checkpoint", func(t *testing.T) {
+		stopping, stop := context.WithCancel(ctx)
+		runner := backfill.NewRunner(repository, database, backfill.WithBatchSize(2), backfill.WithThrottle(0))
+		checkpoint, err := runner.Run(stopping, &stopAfterBatch{Job: job, stop: stop})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, "id_01", checkpoint.LastID)
+		assert.Nil(t, checkpoint.CompletedAt)
+		assert.Equal(t, []string{"PLANTOWATCH", "WATCHING", "plantowatch", "bogus", "completed"}, statuses(t, database))
+
+		checkpoint, err = runner.Run(ctx, job)
+		require.NoError(t, err)
+		assert.NotNil(t, checkpoint.CompletedAt)
+		assert.Equal(t, int64(5), checkpoint.Scanned)
+		assert.Equal(t, int64(3), checkpoint.Changed)
+		assert.Equal(t, []string{"PLANTOWATCH", "WATCHING", "PLANTOWATCH", "bogus", "COMPLETED"}, statuses(t, database))
+	})
+
+	t.Run("changed rows are versioned", func(t *testing.T) {
+		var row user_anime.UserAnime
+		require.NoError(t, database.DB.Where("id = ?", "id_00").First(&row).Error)
+		assert.Equal(t, 2, row.Version)
+
+		var deleted user_anime.UserAnime
+		require.NoError(t, database.DB.Unscoped().Where("id = ?", "id_04").First(&deleted).Error)
+		assert.True(t, deleted.DeletedAt.Valid)
+	})
+
+	t.Run("completed jobs only run again on restart", func(t *testing.T) {
+		userID, animeID, status := "user_1", "anime_9", "dropped"
+		require.NoError(t, database.DB.Create(&user_anime.UserAnime{ID: "id_09", UserID: &userID, AnimeID: &animeID, Status: &status, Version: 1}).Error)
+
+		runner := backfill.NewRunner(repository, database, backfill.WithThrottle(0))
+		_, err := runner.Run(ctx, job)
+		require.NoError(t, err)
+		assert.Equal(t, "dropped", statuses(t, database)[5])
+
+		runner = backfill.NewRunner(repository, database, backfill.WithThrottle(0), backfill.WithRestart(true))
+		checkpoint, err := runner.Run(ctx, job)
+		require.NoError(t, err)
+		assert.Equal(t, int64(6), checkpoint.Scanned)
+		assert.Equal(t, int64(1), checkpoint.Changed)
+		assert.Equal(t, "DROPPED", statuses(t, database)[5])
+	})
+}
+
+// stopAfterBatch cancels the run once its first batch is done
+type stopAfterBatch struct {
+	backfill.Job
+	stop context.CancelFunc
+}
+
+func (s *stopAfterBatch) Batch(ctx context.Context, afterId string, limit int, dryRun bool) (backfill.Batch, error) {
+	defer s.stop()
+	return s.Job.Batch(ctx, afterId, limit, dryRun)
+}
@@ -0,0 +1,96 @@
+package backfill
+
+import (
+	"context"
+	"strings"
+
+	"github.com/weeb-vip/list-service/internal/db/repositories/user_anime"
+	"github.com/weeb-vip/list-service/internal/logger"
+)
+
+// canonicalStatuses are the stored status values, matching the Status enum of
+// the GraphQL schema
+var canonicalStatuses = map[string]bool{
+	"WATCHING":    true,
+	"COMPLETED":   true,
+	"ONHOLD":      true,
+	"DROPPED":     true,
+	"PLANTOWATCH": true,
+}
+
+// legacyStatuses are older spellings that do not follow from upper casing
+var legacyStatuses = map[string]string{
+	"WATCHLIST":     "PLANTOWATCH",
+	"PLAN_TO_WATCH": "PLANTOWATCH",
+	"ON_HOLD":       "ONHOLD",
+}
+
+// NormalizeStatus returns the canonical spelling of a stored status, and
+// false when it is not a status the service knows
+func NormalizeStatus(status string) (string, bool) {
+	normalized := strings.ToUpper(strings.TrimSpace(status))
+	if legacy, ok := legacyStatuses[normalized]; ok {
+		normalized = legacy
+	}
+
+	return normalized, canonicalStatuses[normalized]
+}
+
+// NormalizeStatusesJob rewrites user_anime statuses stored with legacy
+// spellings, such as the lowercase watchlist written by an early migration,
+// to the values of the GraphQL Status enum
+type NormalizeStatusesJob struct {
+	Repository user_anime.UserAnimeRepositoryImpl
+}
+
+func NewNormalizeStatusesJob(repository user_anime.UserAnimeRepositoryImpl) Job {
+	return &NormalizeStatusesJob{Repository: repository}
+}
+
+func (j *NormalizeStatusesJob) Name() string {
+	return "normalize_user_anime_statuses"
+}
+
+func (j *NormalizeStatusesJob) Description() string {
+	return "Rewrite legacy user_anime statuses such as watchlist and plantowatch to the GraphQL enum values"
+}
+
+func (j *NormalizeStatusesJob) Batch(ctx context.Context, afterId string, limit int, dryRun bool) (Batch, error) {
+	rows, err := j.Repository.FindBatchAfterId(ctx, afterId, limit)
+	if err != nil || len(rows) == 0 {
+		return Batch{}, err
+	}
+
+	batch := Batch{LastID: rows[len(rows)-1].ID, Scanned: len(rows)}
+	byStatus := map[string][]*user_anime.UserAnime{}
+	for _, row := range rows {
+		if row.Status == nil {
+			continue
+		}
+		status, ok := NormalizeStatus(*row.Status)
+		if !ok {
+			log := logger.FromCtx(ctx)
+			log.Warn().Str("id", row.ID).Str("status", *row.Status).Msg("Unknown user_anime status left unchanged")
+			continue
+		}
+		if status == *row.Status {
+			continue
+		}
+		byStatus[status] = append(byStatus[status], row)
+	}
+
+	for status, changed := range byStatus {
+		if dryRun {
+			batch.Changed += len(changed)
+			continue
+		}
+
+		n, err := j.Repository.UpdateStatuses(ctx, changed, status)
+		if err != nil {
+			return Batch{}, err
+		}
+		batch.Changed += int(n)
+	}
+
+	return batch, nil
+}
@@ -0,0 +1,103 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrClosed = errors.New("broker is closed")
+
+// Message is a record handed to a broker. Every backend carries the key and
+// headers, so consumers see the same message whichever broker is used.
+type Message struct {
+	// Topic overrides the backend's configured topic
+	Topic   string
+	Key     string
+	Payload []byte
+	Headers map[string]string
+	// EventTime is when the event happened, zero leaves it unset
+	EventTime time.Time
+}
+
+type Broker interface {
+	// Publish sends a message and waits until the broker has accepted it.
+	// Messages with the same key are delivered in publish order.
+	Publish(ctx context.Context, message Message) error
+	// PublishAsync sends a message in the background and reports the outcome
+	// to callback
+	PublishAsync(ctx context.Context, message Message, callback func(err error))
+	// Flush waits for messages sent with PublishAsync
+	Flush() error
+	Close() error
+	// System names the backend, as used for messaging.system on spans
+	System() string
+	// DefaultTopic is where messages without a Topic are published
+	DefaultTopic() string
+	// Check reports whether the broker can currently be reached
+	Check(ctx context.Context) error
+}
+
+// Handler processes a received message. Returning an error stops the
+// subscription and leaves the message to be redelivered.
+type Handler func(ctx context.Context, message Message) error
+
+type Subscriber interface {
+	// Subscribe hands messages on topic to handler until ctx is cancelled or
+	// handler fails. Subscribers sharing a group split the messages between
+	// them and resume where the group left off. An empty topic subscribes to
+	// DefaultTopic.
+	Subscribe(ctx context.Context, topic string, group string, handler Handler) error
+	System() string
+	DefaultTopic() string
+}
+
+type asyncRequest struct {
+	ctx      context.Context
+	message  Message
+	callback func(err error)
+}
+
+// asyncQueue gives backends without a native async API an in order
+// PublishAsync by publishing from a single goroutine
+type asyncQueue struct {
+	publish  func(ctx context.Context, message Message) error
+	requests chan asyncRequest
+	pending  sync.WaitGroup
+	once     sync.Once
+}
+
+func newAsyncQueue(publish func(ctx context.Context, message Message) error) *asyncQueue {
+	queue := &asyncQueue{
+		publish:  publish,
+		requests: make(chan asyncRequest, 1024),
+	}
+	go queue.run()
+
+	return queue
+}
+
+func (q *asyncQueue) run() {
+	for request := range q.requests {
+		request.callback(q.publish(request.ctx, request.message))
+		q.pending.Done()
+	}
+}
+
+func (q *asyncQueue) enqueue(ctx context.Context, message Message, callback func(err error)) {
+	q.pending.Add(1)
+	q.requests <- asyncRequest{ctx: ctx, message: message, callback: callback}
+}
+
+// wait blocks until everything enqueued so far has been published
+func (q *asyncQueue) wait() {
+	q.pending.Wait()
+}
+
+func (q *asyncQueue) close() {
+	q.once.Do(func() {
+		q.pending.Wait()
+		close(q.requests)
+	})
+}
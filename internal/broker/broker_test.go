@@ -0,0 +1,105 @@
+package broker_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weeb-vip/list-service/config"
+	"github.com/weeb-vip/list-service/internal/broker"
+)
+
+func TestMemory(t *testing.T) {
+	memory := broker.NewMemory("events")
+
+	assert.NoError(t, memory.Publish(context.Background(), broker.Message{Key: "user_1", Payload: []byte(`{}`)}))
+	assert.NoError(t, memory.Publish(context.Background(), broker.Message{Topic: "other", Key: "user_1", Payload: []byte(`{}`)}))
+
+	messages := memory.Messages()
+	assert.Len(t, messages, 2)
+	assert.Equal(t, "events", messages[0].Topic)
+	assert.Equal(t, "other", messages[1].Topic)
+
+	assert.NoError(t, memory.Close())
+	assert.ErrorIs(t, memory.Publish(context.Background(), broker.Message{}), broker.ErrClosed)
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	file, err := broker.NewFile(path, "events")
+	assert.NoError(t, err)
+
+	eventTime := time.Date(2025, 10, 19, 12, 0, 0, 0, time.UTC)
+	assert.NoError(t, file.Publish(context.Background(), broker.Message{
+		Key:       "user_1",
+		Payload:   []byte(`{"id":"a"}`),
+		Headers:   map[string]string{"event_type": "user_anime.added"},
+		EventTime: eventTime,
+	}))
+	assert.NoError(t, file.Publish(context.Background(), broker.Message{Key: "user_1", Payload: []byte{0x02, 0xff}}))
+	assert.NoError(t, file.Close())
+
+	handle, err := os.Open(path)
+	assert.NoError(t, err)
+	defer handle.Close()
+
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(handle)
+	for scanner.Scan() {
+		var line map[string]interface{}
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+	}
+
+	assert.Len(t, lines, 2)
+	assert.Equal(t, "events", lines[0]["topic"])
+	assert.Equal(t, map[string]interface{}{"id": "a"}, lines[0]["payload"])
+	assert.Equal(t, map[string]interface{}{"event_type": "user_anime.added"}, lines[0]["headers"])
+	assert.Equal(t, "2025-10-19T12:00:00Z", lines[0]["event_time"])
+	// non JSON payloads are kept as base64
+	assert.Equal(t, "Av8=", lines[1]["data"])
+}
+
+func TestNewFromConfig(t *testing.T) {
+	t.Run("memory", func(t *testing.T) {
+		b, err := broker.NewFromConfig(config.Config{BrokerConfig: config.BrokerConfig{Backend: broker.BackendMemory}}, nil)
+		assert.NoError(t, err)
+		assert.IsType(t, &broker.Memory{}, b)
+	})
+
+	t.Run("unknown backend", func(t *testing.T) {
+		_, err := broker.NewFromConfig(config.Config{BrokerConfig: config.BrokerConfig{Backend: "carrier-pigeon"}}, nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestMemorySubscribe(t *testing.T) {
+	memory := broker.NewMemory("events")
+	for _, key := range []string{"a", "b"} {
+		assert.NoError(t, memory.Publish(context.Background(), broker.Message{Key: key}))
+	}
+
+	// a failing handler leaves the message for the next subscription
+	err := memory.Subscribe(context.Background(), "", "group", func(ctx context.Context, message broker.Message) error {
+		return errors.New("handler failed")
+	})
+	assert.EqualError(t, err, "handler failed")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var keys []string
+	err = memory.Subscribe(ctx, "", "group", func(ctx context.Context, message broker.Message) error {
+		keys = append(keys, message.Key)
+		if len(keys) == 2 {
+			cancel()
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, keys)
+}
@@ -0,0 +1,35 @@
+package broker
+
+import (
+	"fmt"
+
+	"github.com/weeb-vip/list-service/config"
+)
+
+const (
+	BackendPulsar = "pulsar"
+	BackendKafka  = "kafka"
+	BackendNATS   = "nats"
+	BackendMemory = "memory"
+	BackendFile   = "file"
+)
+
+// NewFromConfig builds the backend selected by BrokerConfig.Backend. codec is
+// what payloads are encoded with, Pulsar registers its schema when it is a
+// SchemaDescriber and the other backends ignore it.
+func NewFromConfig(cfg config.Config, codec any) (Broker, error) {
+	switch cfg.BrokerConfig.Backend {
+	case BackendPulsar:
+		return NewPulsar(cfg.PulsarConfig, codec)
+	case BackendKafka:
+		return NewKafka(cfg.KafkaConfig), nil
+	case BackendNATS:
+		return NewNATS(cfg.NATSConfig)
+	case BackendMemory:
+		return NewMemory(cfg.BrokerConfig.Topic), nil
+	case BackendFile:
+		return NewFile(cfg.BrokerConfig.FilePath, cfg.BrokerConfig.Topic)
+	default:
+		return nil, fmt.Errorf("unknown broker backend %q", cfg.BrokerConfig.Backend)
+	}
+}
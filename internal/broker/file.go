@@ -0,0 +1,126 @@
+package broker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// fileRecord is one line of the file backend
+type fileRecord struct {
+	Topic     string            `json:"topic"`
+	Key       string            `json:"key,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	EventTime *time.Time        `json:"event_time,omitempty"`
+	// Payload is kept as text when it is valid JSON so the file is easy to read
+	Payload json.RawMessage `json:"payload,omitempty"`
+	// Data holds payloads that are not JSON, e.g. avro
+	Data []byte `json:"data,omitempty"`
+}
+
+// File appends messages as JSON lines to a local file, for local development
+// without a broker
+type File struct {
+	topic  string
+	mutex  sync.Mutex
+	file   *os.File
+	writer *bufio.Writer
+	closed bool
+}
+
+func NewFile(path string, topic string) (*File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{
+		topic:  topic,
+		file:   file,
+		writer: bufio.NewWriter(file),
+	}, nil
+}
+
+func (f *File) Publish(ctx context.Context, message Message) error {
+	record := fileRecord{
+		Topic:   message.Topic,
+		Key:     message.Key,
+		Headers: message.Headers,
+	}
+	if record.Topic == "" {
+		record.Topic = f.topic
+	}
+	if !message.EventTime.IsZero() {
+		record.EventTime = &message.EventTime
+	}
+	if json.Valid(message.Payload) {
+		record.Payload = message.Payload
+	} else {
+		record.Data = message.Payload
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.closed {
+		return ErrClosed
+	}
+	if _, err := f.writer.Write(append(line, '\n')); err != nil {
+		return err
+	}
+
+	// publish only returns once the line is on disk, like a broker ack
+	if err := f.writer.Flush(); err != nil {
+		return err
+	}
+	return f.file.Sync()
+}
+
+func (f *File) PublishAsync(ctx context.Context, message Message, callback func(err error)) {
+	callback(f.Publish(ctx, message))
+}
+
+func (f *File) Flush() error {
+	return nil
+}
+
+func (f *File) Close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+
+	if err := f.writer.Flush(); err != nil {
+		return err
+	}
+	return f.file.Close()
+}
+
+func (f *File) Check(ctx context.Context) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.closed {
+		return ErrClosed
+	}
+	return nil
+}
+
+func (f *File) System() string {
+	return BackendFile
+}
+
+func (f *File) DefaultTopic() string {
+	return f.topic
+}
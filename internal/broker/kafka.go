@@ -0,0 +1,178 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/weeb-vip/list-service/config"
+)
+
+// Kafka publishes through a single writer that routes messages to partitions
+// by key hash, which keeps per key ordering
+type Kafka struct {
+	writer  *kafka.Writer
+	brokers []string
+	topic   string
+	async   *asyncQueue
+	mutex   sync.Mutex
+	closed  bool
+}
+
+func NewKafka(cfg config.KafkaConfig) *Kafka {
+	brokers := strings.Split(cfg.Brokers, ",")
+	k := &Kafka{
+		brokers: brokers,
+		topic:   cfg.Topic,
+		writer: &kafka.Writer{
+			Addr:                   kafka.TCP(brokers...),
+			Balancer:               &kafka.Hash{},
+			BatchTimeout:           time.Duration(cfg.BatchTimeoutMs) * time.Millisecond,
+			RequiredAcks:           kafka.RequireAll,
+			AllowAutoTopicCreation: cfg.AllowAutoTopicCreation,
+		},
+	}
+	k.async = newAsyncQueue(k.publish)
+
+	return k
+}
+
+func (k *Kafka) Publish(ctx context.Context, message Message) error {
+	k.mutex.Lock()
+	closed := k.closed
+	k.mutex.Unlock()
+	if closed {
+		return ErrClosed
+	}
+
+	return k.publish(ctx, message)
+}
+
+// publish writes message without checking whether k is closed, the async
+// queue keeps using it while Close drains
+func (k *Kafka) publish(ctx context.Context, message Message) error {
+	record := kafka.Message{
+		Topic: message.Topic,
+		Value: message.Payload,
+		Time:  message.EventTime,
+	}
+	if record.Topic == "" {
+		record.Topic = k.topic
+	}
+	if message.Key != "" {
+		record.Key = []byte(message.Key)
+	}
+	for name, value := range message.Headers {
+		record.Headers = append(record.Headers, kafka.Header{Key: name, Value: []byte(value)})
+	}
+
+	return k.writer.WriteMessages(ctx, record)
+}
+
+func (k *Kafka) PublishAsync(ctx context.Context, message Message, callback func(err error)) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if k.closed {
+		callback(ErrClosed)
+		return
+	}
+	k.async.enqueue(ctx, message, callback)
+}
+
+func (k *Kafka) Flush() error {
+	k.async.wait()
+	return nil
+}
+
+func (k *Kafka) Close() error {
+	k.mutex.Lock()
+	if k.closed {
+		k.mutex.Unlock()
+		return nil
+	}
+	// refuse new messages, then drain the queued ones
+	k.closed = true
+	k.mutex.Unlock()
+
+	k.async.close()
+
+	return k.writer.Close()
+}
+
+// Subscribe reads topic as consumer group group, committing each message once
+// handler has processed it
+func (k *Kafka) Subscribe(ctx context.Context, topic string, group string, handler Handler) error {
+	if topic == "" {
+		topic = k.topic
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: k.brokers,
+		Topic:   topic,
+		GroupID: group,
+	})
+	defer reader.Close()
+
+	for {
+		received, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		headers := make(map[string]string, len(received.Headers))
+		for _, header := range received.Headers {
+			headers[header.Key] = string(header.Value)
+		}
+
+		err = handler(ctx, Message{
+			Topic:     received.Topic,
+			Key:       string(received.Key),
+			Payload:   received.Value,
+			Headers:   headers,
+			EventTime: received.Time,
+		})
+		if err != nil {
+			// the offset is not committed, so the group sees the message again
+			return err
+		}
+		if err := reader.CommitMessages(ctx, received); err != nil {
+			return err
+		}
+	}
+}
+
+// Check succeeds once any of the brokers accepts a connection
+func (k *Kafka) Check(ctx context.Context) error {
+	k.mutex.Lock()
+	closed := k.closed
+	k.mutex.Unlock()
+	if closed {
+		return ErrClosed
+	}
+
+	var errs []error
+	for _, address := range k.brokers {
+		conn, err := kafka.DialContext(ctx, "tcp", address)
+		if err == nil {
+			return conn.Close()
+		}
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+func (k *Kafka) System() string {
+	return BackendKafka
+}
+
+func (k *Kafka) DefaultTopic() string {
+	return k.topic
+}
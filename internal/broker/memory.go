@@ -0,0 +1,150 @@
+package broker
+
+import (
+	"context"
+	"sync"
+)
+
+// Memory keeps published messages in memory, for tests and local runs
+type Memory struct {
+	topic    string
+	mutex    sync.Mutex
+	messages []Message
+	// offsets is the next message index per topic and group
+	offsets map[string]int
+	// published is closed and replaced whenever a message is added
+	published chan struct{}
+	err       error
+	closed    bool
+}
+
+func NewMemory(topic string) *Memory {
+	return &Memory{
+		topic:     topic,
+		offsets:   map[string]int{},
+		published: make(chan struct{}),
+	}
+}
+
+func (m *Memory) Publish(ctx context.Context, message Message) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+	if m.err != nil {
+		return m.err
+	}
+
+	if message.Topic == "" {
+		message.Topic = m.topic
+	}
+	m.messages = append(m.messages, message)
+	close(m.published)
+	m.published = make(chan struct{})
+	return nil
+}
+
+func (m *Memory) PublishAsync(ctx context.Context, message Message, callback func(err error)) {
+	callback(m.Publish(ctx, message))
+}
+
+// Subscribe delivers every message on topic, starting with those published
+// before the group first subscribed
+func (m *Memory) Subscribe(ctx context.Context, topic string, group string, handler Handler) error {
+	if topic == "" {
+		topic = m.topic
+	}
+	offsetKey := topic + "/" + group
+
+	for {
+		m.mutex.Lock()
+		if m.closed {
+			m.mutex.Unlock()
+			return ErrClosed
+		}
+		offset := m.offsets[offsetKey]
+		var next *Message
+		for offset < len(m.messages) {
+			if m.messages[offset].Topic == topic {
+				message := m.messages[offset]
+				next = &message
+				break
+			}
+			offset++
+		}
+		m.offsets[offsetKey] = offset
+		published := m.published
+		m.mutex.Unlock()
+
+		if next == nil {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-published:
+			}
+			continue
+		}
+
+		if err := handler(ctx, *next); err != nil {
+			return err
+		}
+
+		m.mutex.Lock()
+		m.offsets[offsetKey] = offset + 1
+		m.mutex.Unlock()
+	}
+}
+
+func (m *Memory) Flush() error {
+	return nil
+}
+
+func (m *Memory) Close() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if !m.closed {
+		m.closed = true
+		close(m.published)
+	}
+	return nil
+}
+
+// Check fails once closed or while an error is set with SetErr
+func (m *Memory) Check(ctx context.Context) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+	return m.err
+}
+
+func (m *Memory) System() string {
+	return BackendMemory
+}
+
+func (m *Memory) DefaultTopic() string {
+	return m.topic
+}
+
+// Messages returns a copy of everything published so far
+func (m *Memory) Messages() []Message {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	messages := make([]Message, len(m.messages))
+	copy(messages, m.messages)
+	return messages
+}
+
+// SetErr makes subsequent publishes fail with err, or succeed again when nil
+func (m *Memory) SetErr(err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.err = err
+}
@@ -0,0 +1,227 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/weeb-vip/list-service/config"
+)
+
+// KeyHeader carries the message key on NATS, which has no key of its own
+const KeyHeader = "Message-Key"
+
+// EventTimeHeader carries the event time on NATS as RFC 3339
+const EventTimeHeader = "Event-Time"
+
+// NATS publishes to a subject, through JetStream when enabled so publishes
+// are acknowledged once stored
+type NATS struct {
+	conn    *nats.Conn
+	js      nats.JetStreamContext
+	subject string
+	async   *asyncQueue
+	mutex   sync.Mutex
+	closed  bool
+}
+
+func NewNATS(cfg config.NATSConfig) (*NATS, error) {
+	conn, err := nats.Connect(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &NATS{
+		conn:    conn,
+		subject: cfg.Subject,
+	}
+	if cfg.JetStream {
+		n.js, err = conn.JetStream()
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	n.async = newAsyncQueue(n.publish)
+
+	return n, nil
+}
+
+func (n *NATS) Publish(ctx context.Context, message Message) error {
+	n.mutex.Lock()
+	closed := n.closed
+	n.mutex.Unlock()
+	if closed {
+		return ErrClosed
+	}
+
+	return n.publish(ctx, message)
+}
+
+// publish writes message without checking whether n is closed, the async
+// queue keeps using it while Close drains
+func (n *NATS) publish(ctx context.Context, message Message) error {
+	msg := nats.NewMsg(message.Topic)
+	if msg.Subject == "" {
+		msg.Subject = n.subject
+	}
+	msg.Data = message.Payload
+	for name, value := range message.Headers {
+		msg.Header.Set(name, value)
+	}
+	if message.Key != "" {
+		msg.Header.Set(KeyHeader, message.Key)
+	}
+	if !message.EventTime.IsZero() {
+		msg.Header.Set(EventTimeHeader, message.EventTime.UTC().Format(time.RFC3339Nano))
+	}
+
+	if n.js != nil {
+		_, err := n.js.PublishMsg(msg, nats.Context(ctx))
+		return err
+	}
+
+	if err := n.conn.PublishMsg(msg); err != nil {
+		return err
+	}
+	// core NATS is fire and forget, a flush round trip at least confirms the
+	// server has read the message
+	return n.conn.FlushWithContext(ctx)
+}
+
+func (n *NATS) PublishAsync(ctx context.Context, message Message, callback func(err error)) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if n.closed {
+		callback(ErrClosed)
+		return
+	}
+	n.async.enqueue(ctx, message, callback)
+}
+
+func (n *NATS) Flush() error {
+	n.async.wait()
+	return n.conn.Flush()
+}
+
+func (n *NATS) Close() error {
+	n.mutex.Lock()
+	if n.closed {
+		n.mutex.Unlock()
+		return nil
+	}
+	// refuse new messages, then drain the queued ones
+	n.closed = true
+	n.mutex.Unlock()
+
+	n.async.close()
+
+	return n.conn.Drain()
+}
+
+// Subscribe consumes subject through a durable JetStream pull consumer named
+// after group, or a core queue group when JetStream is disabled. Core NATS
+// keeps no position, so messages published while nobody subscribes are lost.
+func (n *NATS) Subscribe(ctx context.Context, topic string, group string, handler Handler) error {
+	if topic == "" {
+		topic = n.subject
+	}
+
+	if n.js != nil {
+		sub, err := n.js.PullSubscribe(topic, group)
+		if err != nil {
+			return err
+		}
+		defer sub.Unsubscribe()
+
+		for {
+			received, err := sub.Fetch(1, nats.Context(ctx))
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				if errors.Is(err, nats.ErrTimeout) {
+					continue
+				}
+				return err
+			}
+
+			for _, msg := range received {
+				if err := handler(ctx, natsMessage(msg)); err != nil {
+					_ = msg.Nak()
+					return err
+				}
+				if err := msg.Ack(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	sub, err := n.conn.QueueSubscribeSync(topic, group)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	for {
+		msg, err := sub.NextMsgWithContext(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		if err := handler(ctx, natsMessage(msg)); err != nil {
+			return err
+		}
+	}
+}
+
+// natsMessage moves the key and event time back out of the headers
+func natsMessage(msg *nats.Msg) Message {
+	message := Message{
+		Topic:   msg.Subject,
+		Payload: msg.Data,
+		Headers: map[string]string{},
+	}
+	for name := range msg.Header {
+		switch name {
+		case KeyHeader:
+			message.Key = msg.Header.Get(name)
+		case EventTimeHeader:
+			message.EventTime, _ = time.Parse(time.RFC3339Nano, msg.Header.Get(name))
+		default:
+			message.Headers[name] = msg.Header.Get(name)
+		}
+	}
+
+	return message
+}
+
+func (n *NATS) Check(ctx context.Context) error {
+	n.mutex.Lock()
+	closed := n.closed
+	n.mutex.Unlock()
+	if closed {
+		return ErrClosed
+	}
+
+	if status := n.conn.Status(); status != nats.CONNECTED {
+		return fmt.Errorf("nats connection is %s", status)
+	}
+	return nil
+}
+
+func (n *NATS) System() string {
+	return BackendNATS
+}
+
+func (n *NATS) DefaultTopic() string {
+	return n.subject
+}
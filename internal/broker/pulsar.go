@@ -0,0 +1,251 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/weeb-vip/list-service/config"
+)
+
+// Pulsar keeps one long lived producer per topic
+type Pulsar struct {
+	client    pulsar.Client
+	config    config.PulsarConfig
+	schema    pulsar.Schema
+	mutex     sync.Mutex
+	producers map[string]pulsar.Producer
+	closed    bool
+}
+
+// SchemaEncoding is how payloads described by a schema are encoded
+type SchemaEncoding string
+
+const (
+	SchemaJSON SchemaEncoding = "json"
+	SchemaAvro SchemaEncoding = "avro"
+)
+
+// SchemaDescriber is implemented by codecs describing their payloads with an
+// Avro record definition
+type SchemaDescriber interface {
+	// Schema returns how payloads are encoded and their definition, "" when
+	// they are not described
+	Schema() (SchemaEncoding, string)
+}
+
+// NewPulsar connects to Pulsar. When codec is a SchemaDescriber its schema is
+// registered for every topic written to, otherwise raw bytes are published.
+func NewPulsar(cfg config.PulsarConfig, codec any) (*Pulsar, error) {
+	schema, err := pulsarSchema(codec)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := pulsar.NewClient(pulsar.ClientOptions{
+		URL: cfg.URL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Pulsar{
+		client:    client,
+		config:    cfg,
+		schema:    schema,
+		producers: map[string]pulsar.Producer{},
+	}, nil
+}
+
+// pulsarSchema returns the schema described by codec, nil when there is none
+func pulsarSchema(codec any) (pulsar.Schema, error) {
+	describer, ok := codec.(SchemaDescriber)
+	if !ok {
+		return nil, nil
+	}
+
+	encoding, definition := describer.Schema()
+	if definition == "" {
+		return nil, nil
+	}
+	if encoding == SchemaAvro {
+		schema, err := pulsar.NewAvroSchemaWithValidation(definition, nil)
+		if err != nil {
+			return nil, err
+		}
+		return schema, nil
+	}
+
+	schema, err := pulsar.NewJSONSchemaWithValidation(definition, nil)
+	if err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// producer returns the producer for a topic, creating it on first use
+func (p *Pulsar) producer(topic string) (pulsar.Producer, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.closed {
+		return nil, ErrClosed
+	}
+	if topic == "" {
+		topic = p.config.ProducerTopic
+	}
+	if producer, ok := p.producers[topic]; ok {
+		return producer, nil
+	}
+
+	options := pulsar.ProducerOptions{
+		Topic:                   topic,
+		SendTimeout:             time.Duration(p.config.SendTimeoutMs) * time.Millisecond,
+		DisableBatching:         p.config.DisableBatching,
+		BatchingMaxPublishDelay: time.Duration(p.config.BatchingMaxPublishDelayMs) * time.Millisecond,
+		BatchingMaxMessages:     p.config.BatchingMaxMessages,
+		// keeps messages with the same key in the same batch, so per key
+		// ordering survives key shared subscriptions
+		BatcherBuilderType: pulsar.KeyBasedBatchBuilder,
+	}
+	if p.schema != nil {
+		options.Schema = p.schema
+	}
+
+	producer, err := p.client.CreateProducer(options)
+	if err != nil {
+		return nil, err
+	}
+	p.producers[topic] = producer
+
+	return producer, nil
+}
+
+func producerMessage(message Message) *pulsar.ProducerMessage {
+	return &pulsar.ProducerMessage{
+		Payload:    message.Payload,
+		Key:        message.Key,
+		Properties: message.Headers,
+		EventTime:  message.EventTime,
+	}
+}
+
+func (p *Pulsar) Publish(ctx context.Context, message Message) error {
+	producer, err := p.producer(message.Topic)
+	if err != nil {
+		return err
+	}
+
+	_, err = producer.Send(ctx, producerMessage(message))
+	return err
+}
+
+func (p *Pulsar) PublishAsync(ctx context.Context, message Message, callback func(err error)) {
+	producer, err := p.producer(message.Topic)
+	if err != nil {
+		callback(err)
+		return
+	}
+
+	producer.SendAsync(ctx, producerMessage(message), func(_ pulsar.MessageID, _ *pulsar.ProducerMessage, err error) {
+		callback(err)
+	})
+}
+
+func (p *Pulsar) Flush() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	var errs []error
+	for _, producer := range p.producers {
+		errs = append(errs, producer.Flush())
+	}
+
+	return errors.Join(errs...)
+}
+
+func (p *Pulsar) Close() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+
+	var errs []error
+	for topic, producer := range p.producers {
+		errs = append(errs, producer.Flush())
+		producer.Close()
+		delete(p.producers, topic)
+	}
+	p.client.Close()
+
+	return errors.Join(errs...)
+}
+
+// Subscribe uses a key shared subscription named after group, so consumers in
+// the group split the keys while each key stays in order
+func (p *Pulsar) Subscribe(ctx context.Context, topic string, group string, handler Handler) error {
+	if topic == "" {
+		topic = p.config.ProducerTopic
+	}
+
+	consumer, err := p.client.Subscribe(pulsar.ConsumerOptions{
+		Topic:            topic,
+		SubscriptionName: group,
+		Type:             pulsar.KeyShared,
+	})
+	if err != nil {
+		return err
+	}
+	defer consumer.Close()
+
+	for {
+		received, err := consumer.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		err = handler(ctx, Message{
+			Topic:     received.Topic(),
+			Key:       received.Key(),
+			Payload:   received.Payload(),
+			Headers:   received.Properties(),
+			EventTime: received.EventTime(),
+		})
+		if err != nil {
+			consumer.Nack(received)
+			return err
+		}
+		if err := consumer.Ack(received); err != nil {
+			return err
+		}
+	}
+}
+
+// Check looks up the default topic, which needs a broker to answer
+func (p *Pulsar) Check(ctx context.Context) error {
+	p.mutex.Lock()
+	closed := p.closed
+	p.mutex.Unlock()
+	if closed {
+		return ErrClosed
+	}
+
+	_, err := p.client.TopicPartitions(p.config.ProducerTopic)
+	return err
+}
+
+func (p *Pulsar) System() string {
+	return BackendPulsar
+}
+
+func (p *Pulsar) DefaultTopic() string {
+	return p.config.ProducerTopic
+}
@@ -0,0 +1,86 @@
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// Set at link time, e.g.
+//
+//	go build -ldflags "-X github.com/weeb-vip/list-service/internal/buildinfo.Commit=$(git rev-parse HEAD)"
+//
+// Commit falls back to the revision Go stamps into the binary and BuildTime to
+// the time of that commit.
+var (
+	Version   string
+	Commit    string
+	BuildTime string
+)
+
+// Info describes the running binary
+type Info struct {
+	Version   string
+	Commit    *string
+	BuildTime *string
+	GoVersion string
+	// Modified is set when the binary was built from a tree with
+	// uncommitted changes
+	Modified bool
+}
+
+var (
+	once sync.Once
+	info Info
+)
+
+// Get returns the build details of the running binary. fallbackVersion is
+// used when no version was set at link time.
+func Get(fallbackVersion string) Info {
+	once.Do(func() {
+		info = read()
+	})
+
+	current := info
+	if current.Version == "" {
+		current.Version = fallbackVersion
+	}
+
+	return current
+}
+
+func read() Info {
+	read := Info{
+		Version:   Version,
+		GoVersion: runtime.Version(),
+	}
+
+	var vcsRevision, vcsTime string
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				vcsRevision = setting.Value
+			case "vcs.time":
+				vcsTime = setting.Value
+			case "vcs.modified":
+				read.Modified = setting.Value == "true"
+			}
+		}
+	}
+
+	read.Commit = firstSet(Commit, vcsRevision)
+	read.BuildTime = firstSet(BuildTime, vcsTime)
+
+	return read
+}
+
+func firstSet(values ...string) *string {
+	for _, value := range values {
+		if value != "" {
+			return &value
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,20 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Cache stores opaque values by key. Implementations must be safe for
+// concurrent use.
+type Cache interface {
+	// Get returns the value stored at key and whether there was one
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// GetMany returns the values found for keys, missing keys are left out
+	GetMany(ctx context.Context, keys []string) (map[string][]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	SetMany(ctx context.Context, values map[string][]byte, ttl time.Duration) error
+	// SetNX stores value only when key is not set and reports whether it did
+	SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	Close() error
+}
@@ -0,0 +1,79 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weeb-vip/list-service/internal/cache"
+)
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("expires entries", func(t *testing.T) {
+		lru, err := cache.NewLRU(10)
+		assert.NoError(t, err)
+
+		assert.NoError(t, lru.Set(ctx, "short", []byte("a"), 10*time.Millisecond))
+		assert.NoError(t, lru.Set(ctx, "long", []byte("b"), time.Minute))
+		time.Sleep(20 * time.Millisecond)
+
+		values, err := lru.GetMany(ctx, []string{"short", "long"})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string][]byte{"long": []byte("b")}, values)
+	})
+
+	t.Run("set nx only sets missing keys", func(t *testing.T) {
+		lru, err := cache.NewLRU(10)
+		assert.NoError(t, err)
+
+		created, err := lru.SetNX(ctx, "key", []byte("a"), time.Minute)
+		assert.NoError(t, err)
+		assert.True(t, created)
+
+		created, err = lru.SetNX(ctx, "key", []byte("b"), time.Minute)
+		assert.NoError(t, err)
+		assert.False(t, created)
+
+		value, ok, err := lru.Get(ctx, "key")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, []byte("a"), value)
+	})
+}
+
+func TestLoad(t *testing.T) {
+	ctx := context.Background()
+
+	lru, err := cache.NewLRU(100)
+	assert.NoError(t, err)
+	versioned := cache.NewVersioned(lru, "test", time.Minute)
+
+	loads := 0
+	load := func() ([]string, error) {
+		loads++
+		return []string{"a", "b"}, nil
+	}
+
+	value, err := cache.Load(ctx, versioned, "lists", "Find", "user_1", "args", load)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, value)
+
+	value, err = cache.Load(ctx, versioned, "lists", "Find", "user_1", "args", load)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, value)
+	assert.Equal(t, 1, loads)
+
+	// other arguments and other users are cached separately
+	_, _ = cache.Load(ctx, versioned, "lists", "Find", "user_1", "other", load)
+	_, _ = cache.Load(ctx, versioned, "lists", "Find", "user_2", "args", load)
+	assert.Equal(t, 3, loads)
+
+	assert.NoError(t, versioned.Invalidate(ctx, "lists", "user_1"))
+
+	_, _ = cache.Load(ctx, versioned, "lists", "Find", "user_1", "args", load)
+	_, _ = cache.Load(ctx, versioned, "lists", "Find", "user_2", "args", load)
+	assert.Equal(t, 4, loads)
+}
@@ -0,0 +1,24 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/weeb-vip/list-service/config"
+)
+
+// NewFromConfig returns the cache selected by cfg, Redis when a URL is set and
+// an in-process LRU otherwise
+func NewFromConfig(cfg config.CacheConfig) (*Versioned, error) {
+	var cache Cache
+	var err error
+	if cfg.RedisURL != "" {
+		cache, err = NewRedis(cfg.RedisURL)
+	} else {
+		cache, err = NewLRU(cfg.LRUSize)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return NewVersioned(cache, cfg.KeyPrefix, time.Duration(cfg.TTLSeconds)*time.Second), nil
+}
@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+type lruEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// LRU is an in-process cache. Invalidations only reach the process that made
+// them, so with several instances other instances may serve stale entries
+// until their TTL runs out.
+type LRU struct {
+	// mutex makes SetNX atomic, the lru itself is safe for concurrent use
+	mutex   sync.Mutex
+	entries *lru.Cache[string, lruEntry]
+	now     func() time.Time
+}
+
+func NewLRU(size int) (*LRU, error) {
+	entries, err := lru.New[string, lruEntry](size)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LRU{entries: entries, now: time.Now}, nil
+}
+
+func (l *LRU) get(key string) ([]byte, bool) {
+	entry, ok := l.entries.Get(key)
+	if !ok {
+		return nil, false
+	}
+	if l.now().After(entry.expiresAt) {
+		l.entries.Remove(key)
+		return nil, false
+	}
+	return entry.value, true
+}
+
+func (l *LRU) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, ok := l.get(key)
+	return value, ok, nil
+}
+
+func (l *LRU) GetMany(ctx context.Context, keys []string) (map[string][]byte, error) {
+	values := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		if value, ok := l.get(key); ok {
+			values[key] = value
+		}
+	}
+	return values, nil
+}
+
+func (l *LRU) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	l.entries.Add(key, lruEntry{value: value, expiresAt: l.now().Add(ttl)})
+	return nil
+}
+
+func (l *LRU) SetMany(ctx context.Context, values map[string][]byte, ttl time.Duration) error {
+	expiresAt := l.now().Add(ttl)
+	for key, value := range values {
+		l.entries.Add(key, lruEntry{value: value, expiresAt: expiresAt})
+	}
+	return nil
+}
+
+func (l *LRU) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, ok := l.get(key); ok {
+		return false, nil
+	}
+	l.entries.Add(key, lruEntry{value: value, expiresAt: l.now().Add(ttl)})
+	return true, nil
+}
+
+func (l *LRU) Close() error {
+	l.entries.Purge()
+	return nil
+}
@@ -0,0 +1,138 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/weeb-vip/list-service/internal/logger"
+	"github.com/weeb-vip/list-service/metrics"
+)
+
+// Versioned namespaces keys by user and a generation token, so all of a user's
+// entries are invalidated at once by replacing the token. Entries under old
+// tokens are never read again and expire with their TTL.
+type Versioned struct {
+	Cache  Cache
+	Prefix string
+	TTL    time.Duration
+}
+
+func NewVersioned(cache Cache, prefix string, ttl time.Duration) *Versioned {
+	return &Versioned{Cache: cache, Prefix: prefix, TTL: ttl}
+}
+
+func (v *Versioned) generationKey(namespace string, userId string) string {
+	return v.Prefix + ":" + namespace + ":" + userId + ":gen"
+}
+
+// generationTTL outlives the entries, a token that expires anyway just starts
+// the user with an empty cache
+func (v *Versioned) generationTTL() time.Duration {
+	return 4 * v.TTL
+}
+
+func (v *Versioned) generation(ctx context.Context, namespace string, userId string) (string, error) {
+	key := v.generationKey(namespace, userId)
+
+	generation, ok, err := v.Cache.Get(ctx, key)
+	if err != nil || ok {
+		return string(generation), err
+	}
+
+	// first use, or the token expired. Tokens are random rather than counters
+	// so a recreated token can never match entries written under an old one.
+	token := uuid.New().String()
+	created, err := v.Cache.SetNX(ctx, key, []byte(token), v.generationTTL())
+	if err != nil {
+		return "", err
+	}
+	if created {
+		return token, nil
+	}
+
+	// another request created it first
+	generation, _, err = v.Cache.Get(ctx, key)
+	return string(generation), err
+}
+
+// Key returns the key for parts within the user's current generation
+func (v *Versioned) Key(ctx context.Context, namespace string, userId string, parts ...string) (string, error) {
+	generation, err := v.generation(ctx, namespace, userId)
+	if err != nil {
+		return "", err
+	}
+
+	return v.Prefix + ":" + namespace + ":" + userId + ":" + generation + ":" + strings.Join(parts, ":"), nil
+}
+
+// Invalidate drops every entry of the user in namespace
+func (v *Versioned) Invalidate(ctx context.Context, namespace string, userId string) error {
+	return v.Cache.Set(ctx, v.generationKey(namespace, userId), []byte(uuid.New().String()), v.generationTTL())
+}
+
+// Hash returns a short stable key part for query arguments
+func Hash(args interface{}) (string, error) {
+	encoded, err := json.Marshal(args)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha1.Sum(encoded)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Load returns the cached result of operation for the user and args, calling
+// load and caching its result on a miss. The cache is best effort, any cache
+// failure is logged and falls through to load.
+func Load[T any](ctx context.Context, v *Versioned, namespace string, operation string, userId string, args interface{}, load func() (T, error)) (T, error) {
+	log := logger.FromCtx(ctx)
+
+	hash, err := Hash(args)
+	if err != nil {
+		return load()
+	}
+
+	key, err := v.Key(ctx, namespace, userId, operation, hash)
+	if err != nil {
+		log.Warn().Err(err).Str("cache", namespace).Msg("Error reading cache generation")
+		metrics.GetAppMetrics().CacheMetric(namespace, operation, metrics.Error)
+		return load()
+	}
+
+	cached, ok, err := v.Cache.Get(ctx, key)
+	if err != nil {
+		log.Warn().Err(err).Str("cache", namespace).Msg("Error reading cache")
+		metrics.GetAppMetrics().CacheMetric(namespace, operation, metrics.Error)
+		return load()
+	}
+	if ok {
+		var value T
+		if err := json.Unmarshal(cached, &value); err == nil {
+			metrics.GetAppMetrics().CacheMetric(namespace, operation, metrics.CacheHit)
+			return value, nil
+		}
+		log.Warn().Err(err).Str("cache", namespace).Msg("Error decoding cached value")
+	}
+
+	metrics.GetAppMetrics().CacheMetric(namespace, operation, metrics.CacheMiss)
+
+	value, err := load()
+	if err != nil {
+		return value, err
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return value, nil
+	}
+	if err := v.Cache.Set(ctx, key, encoded, v.TTL); err != nil {
+		log.Warn().Err(err).Str("cache", namespace).Msg("Error writing cache")
+	}
+
+	return value, nil
+}
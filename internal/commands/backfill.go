@@ -0,0 +1,155 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/weeb-vip/list-service/config"
+	"github.com/weeb-vip/list-service/internal/backfill"
+	"github.com/weeb-vip/list-service/internal/cache"
+	"github.com/weeb-vip/list-service/internal/db"
+	backfill2 "github.com/weeb-vip/list-service/internal/db/repositories/backfill"
+	"github.com/weeb-vip/list-service/internal/db/repositories/user_anime"
+	"github.com/weeb-vip/list-service/internal/logger"
+
+	"github.com/spf13/cobra"
+)
+
+// backfillCmd represents the backfill command
+var backfillCmd = &cobra.Command{
+	Use:   "backfill",
+	Short: "List and run data backfills",
+	Long: `Backfills rewrite existing rows in batches walked in primary key order.
+Each batch commits together with a checkpoint, so a stopped run resumes where it
+left off. Without a subcommand every backfill and its progress is listed.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+
+		database, err := db.NewDatabase(cfg.DBConfig)
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		checkpoints, err := backfill2.NewBackfillRepository(database).FindCheckpoints(context.Background())
+		if err != nil {
+			return err
+		}
+		byName := map[string]*backfill2.Checkpoint{}
+		for _, checkpoint := range checkpoints {
+			byName[checkpoint.Name] = checkpoint
+		}
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSTATE\tSCANNED\tCHANGED\tDESCRIPTION")
+		for _, job := range buildBackfills(cfg, database).Jobs() {
+			state, scanned, changed := "pending", int64(0), int64(0)
+			if checkpoint, ok := byName[job.Name()]; ok {
+				state, scanned, changed = "in progress", checkpoint.Scanned, checkpoint.Changed
+				if checkpoint.CompletedAt != nil {
+					state = "completed " + checkpoint.CompletedAt.Format("2006-01-02 15:04:05")
+				}
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\n", job.Name(), state, scanned, changed, job.Description())
+		}
+		return w.Flush()
+	},
+}
+
+// backfillRunCmd represents the backfill run command
+var backfillRunCmd = &cobra.Command{
+	Use:   "run NAME",
+	Short: "Run a backfill until it completes",
+	Long: `Runs a backfill from its checkpoint until every row is processed. Stop it
+with an interrupt at any time, the batch in flight is finished and the next run
+continues after it.`,
+	Args: usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+
+		logger.Logger(
+			logger.WithServerName(cfg.AppConfig.APPName),
+			logger.WithVersion(cfg.AppConfig.Version),
+			logger.WithEnvironment(cfg.AppConfig.Env),
+		)
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		database, err := db.NewDatabase(cfg.DBConfig)
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		job, err := buildBackfills(cfg, database).Get(args[0])
+		if err != nil {
+			return withExitCode(exitUsage, err)
+		}
+
+		batchSize, _ := cmd.Flags().GetInt("batch-size")
+		throttle, _ := cmd.Flags().GetDuration("throttle")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		restart, _ := cmd.Flags().GetBool("restart")
+		runner := backfill.NewRunner(
+			backfill2.NewBackfillRepository(database),
+			database,
+			backfill.WithBatchSize(batchSize),
+			backfill.WithThrottle(throttle),
+			backfill.WithDryRun(dryRun),
+			backfill.WithRestart(restart),
+		)
+
+		checkpoint, err := runner.Run(ctx, job)
+		if checkpoint != nil {
+			verb := "changed"
+			if dryRun {
+				verb = "would change"
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s: scanned %d rows, %s %d\n", job.Name(), checkpoint.Scanned, verb, checkpoint.Changed)
+		}
+		return err
+	},
+}
+
+// buildBackfills returns every backfill that can be run
+func buildBackfills(cfg config.Config, database *db.DB) backfill.Registry {
+	userAnimeRepository := user_anime.NewUserAnimeRepository(database)
+	// an in-process cache is not shared with the serving instances, so only
+	// Redis needs invalidating
+	if cfg.CacheConfig.Enabled && cfg.CacheConfig.RedisURL != "" {
+		// drop cached entries of the users whose rows change
+		versioned, err := cache.NewFromConfig(cfg.CacheConfig)
+		if err != nil {
+			log := logger.Get()
+			log.Error().Err(err).Msg("Error creating cache, changed entries stay cached until they expire")
+		} else {
+			userAnimeRepository = user_anime.NewCachedUserAnimeRepository(userAnimeRepository, versioned)
+		}
+	}
+
+	return backfill.NewRegistry(
+		backfill.NewNormalizeStatusesJob(userAnimeRepository),
+	)
+}
+
+func init() {
+	rootCmd.AddCommand(backfillCmd)
+	backfillCmd.AddCommand(backfillRunCmd)
+
+	backfillRunCmd.Flags().Int("batch-size", backfill.DefaultBatchSize, "rows processed per batch")
+	backfillRunCmd.Flags().Duration("throttle", backfill.DefaultThrottle, "pause between batches")
+	backfillRunCmd.Flags().Bool("dry-run", false, "count the rows that would change without writing anything")
+	backfillRunCmd.Flags().Bool("restart", false, "discard the checkpoint and start from the first row")
+}
@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/weeb-vip/list-service/config"
+	"github.com/weeb-vip/list-service/metrics"
+
+	"github.com/spf13/cobra"
+)
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the service configuration",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// error need to call subcommand
+		return withExitCode(exitUsage, fmt.Errorf("please call subcommand"))
+	},
+}
+
+// configPrintCmd represents the config print command
+var configPrintCmd = &cobra.Command{
+	Use:   "print",
+	Short: "Print the effective configuration",
+	Long: `Prints every setting with its effective value and where it came from:
+its default, the --config file or an environment variable. Use --redact before
+sharing the output, it masks passwords and credentials in URLs.
+
+The configuration is validated after printing, invalid settings are listed
+and the command fails.`,
+	Args: usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		output, _ := cmd.Flags().GetString("output")
+		if output != "text" && output != "json" {
+			return withExitCode(exitUsage, fmt.Errorf("unknown output %q, use text or json", output))
+		}
+
+		file, _ := cmd.Flags().GetString("config")
+		cfg, err := config.Read(file)
+		if err != nil {
+			return err
+		}
+
+		redact, _ := cmd.Flags().GetBool("redact")
+		settings := config.Describe(cfg, file, redact)
+		err = printOutput(cmd, settings, func(out io.Writer) {
+			w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+			for _, setting := range settings {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", setting.Key, setting.Value, setting.Source)
+			}
+			w.Flush()
+		})
+		if err != nil {
+			return err
+		}
+
+		return cfg.Validate()
+	},
+}
+
+// loadConfig loads and validates the configuration, from the --config file
+// when one is given, and configures the packages that label their output
+// with it. Commands call it once and pass the result on.
+func loadConfig(cmd *cobra.Command) (config.Config, error) {
+	// flags and arguments are valid by now, config errors are not usage errors
+	cmd.SilenceUsage = true
+
+	file, _ := cmd.Flags().GetString("config")
+	cfg, err := config.Load(file)
+	if err != nil {
+		return config.Config{}, err
+	}
+
+	if err := metrics.Configure(cfg); err != nil {
+		return config.Config{}, err
+	}
+
+	return cfg, nil
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configPrintCmd)
+
+	configPrintCmd.Flags().Bool("redact", false, "mask secrets in the output")
+	configPrintCmd.Flags().StringP("output", "o", "text", "output format, text or json")
+}
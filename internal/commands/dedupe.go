@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/weeb-vip/list-service/internal/db"
+	"github.com/weeb-vip/list-service/internal/db/repositories/user_anime"
+
+	"github.com/spf13/cobra"
+)
+
+// dedupeCmd represents the dedupe command
+var dedupeCmd = &cobra.Command{
+	Use:   "dedupe",
+	Short: "Report duplicate user anime entries",
+	Long: `Lists every user and anime pair that is stored more than once and shows
+which row the unique key migration keeps and which it deletes. Nothing is
+changed, run this before migrating up to review the rows that will be removed.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+
+		database, err := db.NewDatabase(cfg.DBConfig)
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		repository := user_anime.NewUserAnimeRepository(database)
+
+		groups, err := repository.FindDuplicates(context.Background())
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		if len(groups) == 0 {
+			fmt.Fprintln(out, "no duplicate entries found")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "USER\tANIME\tACTION\tID\tUPDATED AT\tDELETED")
+		removed := 0
+		for _, group := range groups {
+			rows := append([]*user_anime.UserAnime{group.Keep}, group.Remove...)
+			for i, row := range rows {
+				action := "delete"
+				if i == 0 {
+					action = "keep"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%t\n", group.UserID, group.AnimeID, action, row.ID, row.UpdatedAt.Format("2006-01-02 15:04:05"), row.DeletedAt.Valid)
+			}
+			removed += len(group.Remove)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "\n%d duplicated pairs, %d rows would be deleted\n", len(groups), removed)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(dedupeCmd)
+}
@@ -4,7 +4,7 @@ Copyright © 2023 NAME HERE <EMAIL ADDRESS>
 package commands
 
 import (
-	"github.com/weeb-vip/list-service/db"
+	migrations "github.com/weeb-vip/list-service/db"
 
 	"github.com/spf13/cobra"
 )
@@ -12,28 +12,17 @@ import (
 // downCmd represents the down command
 var downCmd = &cobra.Command{
 	Use:   "down",
-	Short: "A brief description of your command",
-	Long: `A longer description that spans multiple lines and likely contains examples
-and usage of using your command. For example:
-
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
+	Short: "Revert every applied migration",
+	Long: `Reverts every applied migration, dropping all tables. Use steps -N or
+goto to revert only the latest migrations.`,
+	Args: usageArgs(cobra.NoArgs),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return db.MigrateDown()
+		return runMigration(cmd, migrations.Nothing())
 	},
 }
 
 func init() {
 	migrateCmd.AddCommand(downCmd)
 
-	// Here you will define your flags and configuration settings.
-
-	// Cobra supports Persistent Flags which will work for this command
-	// and all subcommands, e.g.:
-	// downCmd.PersistentFlags().String("foo", "", "A help for foo")
-
-	// Cobra supports local flags which will only run when this command
-	// is called directly, e.g.:
-	// downCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	downCmd.Flags().Bool("dry-run", false, "print the SQL that would run without applying it")
 }
@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"errors"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/spf13/cobra"
+)
+
+// Process exit codes, see the migrate command for what they mean
+const (
+	exitFailure = 1
+	exitUsage   = 2
+	exitDirty   = 3
+	exitPending = 4
+)
+
+// exitError is an error that ends the process with a specific exit code
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+func withExitCode(code int, err error) error {
+	return &exitError{code: code, err: err}
+}
+
+// exitCode returns the process exit code for an error returned by a command
+func exitCode(err error) int {
+	var exit *exitError
+	if errors.As(err, &exit) {
+		return exit.code
+	}
+
+	var dirty migrate.ErrDirty
+	if errors.As(err, &dirty) {
+		return exitDirty
+	}
+
+	return exitFailure
+}
+
+// usageArgs reports argument errors with the usage exit code
+func usageArgs(validate cobra.PositionalArgs) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := validate(cmd, args); err != nil {
+			return withExitCode(exitUsage, err)
+		}
+		return nil
+	}
+}
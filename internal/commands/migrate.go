@@ -4,7 +4,13 @@ Copyright © 2023 NAME HERE <EMAIL ADDRESS>
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"strconv"
+
+	migrations "github.com/weeb-vip/list-service/db"
+	"github.com/weeb-vip/list-service/internal/db"
 
 	"github.com/spf13/cobra"
 )
@@ -12,29 +18,117 @@ import (
 // migrateCmd represents the migrate command
 var migrateCmd = &cobra.Command{
 	Use:   "migrate",
-	Short: "A brief description of your command",
-	Long: `A longer description that spans multiple lines and likely contains examples
-and usage of using your command. For example:
+	Short: "Inspect and run database migrations",
+	Long: `Runs the migrations embedded for the configured database driver.
+
+up, down, goto and steps accept --dry-run to print the SQL they would run
+without changing anything. Every command prints text, or JSON with
+--output json.
+
+Exit codes:
+  0  success
+  1  the command failed
+  2  invalid arguments
+  3  the database is dirty, fix the failed migration and run migrate force
+  4  migrations are pending (status --check only)`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// arguments are valid by now, later errors are not usage errors
+		cmd.SilenceUsage = true
 
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
+		output, _ := cmd.Flags().GetString("output")
+		if output != "text" && output != "json" {
+			return withExitCode(exitUsage, fmt.Errorf("unknown output %q, use text or json", output))
+		}
+		return nil
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// error need to call subcommand
-		return fmt.Errorf("please call subcommand")
+		return withExitCode(exitUsage, fmt.Errorf("please call subcommand"))
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(migrateCmd)
 
-	// Here you will define your flags and configuration settings.
+	migrateCmd.PersistentFlags().StringP("output", "o", "text", "output format, text or json")
+}
+
+// newMigrator connects to the configured database
+func newMigrator(cmd *cobra.Command) (*migrations.Migrator, error) {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	database, err := db.NewDatabase(cfg.DBConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	migrator, err := migrations.NewMigrator(cfg.DBConfig, database)
+	if err != nil {
+		database.Close()
+		return nil, err
+	}
+
+	return migrator, nil
+}
+
+// runMigration migrates to target, or prints the SQL it would run with
+// --dry-run
+func runMigration(cmd *cobra.Command, target migrations.Target) error {
+	migrator, err := newMigrator(cmd)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	var steps []migrations.Step
+	if dryRun {
+		steps, err = migrator.Plan(target)
+	} else {
+		steps, err = migrator.Migrate(target)
+	}
+	if err != nil {
+		return err
+	}
+
+	return printOutput(cmd, map[string]interface{}{"dry_run": dryRun, "steps": steps}, func(out io.Writer) {
+		if len(steps) == 0 {
+			fmt.Fprintln(out, "no change")
+			return
+		}
+
+		for _, step := range steps {
+			if dryRun {
+				fmt.Fprintf(out, "-- %s %d %s\n%s\n", step.Direction, step.Version, step.Name, step.SQL)
+			} else {
+				fmt.Fprintf(out, "applied %s %d %s\n", step.Direction, step.Version, step.Name)
+			}
+		}
+	})
+}
+
+// printOutput writes value as JSON with --output json, or calls text
+func printOutput(cmd *cobra.Command, value interface{}, text func(out io.Writer)) error {
+	out := cmd.OutOrStdout()
+	if output, _ := cmd.Flags().GetString("output"); output == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	}
+
+	text(out)
+	return nil
+}
 
-	// Cobra supports Persistent Flags which will work for this command
-	// and all subcommands, e.g.:
-	// migrateCmd.PersistentFlags().String("foo", "", "A help for foo")
+// parseVersionArg parses a migration version argument
+func parseVersionArg(arg string) (uint, error) {
+	version, err := strconv.ParseUint(arg, 10, 64)
+	if err != nil {
+		return 0, withExitCode(exitUsage, fmt.Errorf("invalid version %q", arg))
+	}
 
-	// Cobra supports local flags which will only run when this command
-	// is called directly, e.g.:
-	// migrateCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	return uint(version), nil
 }
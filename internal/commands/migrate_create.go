@@ -0,0 +1,41 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	migrations "github.com/weeb-vip/list-service/db"
+
+	"github.com/spf13/cobra"
+)
+
+// createCmd represents the migrate create command
+var createCmd = &cobra.Command{
+	Use:   "create NAME",
+	Short: "Create empty migration files for every dialect",
+	Long: `Creates empty up and down files named NAME for the mysql, postgres and
+sqlite migrations, all with the same UTC timestamp version. Run it from the
+repository root, or point --dir at the migrations directory.`,
+	Example: `  ./main migrate create add_user_anime_notes`,
+	Args:    usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, _ := cmd.Flags().GetString("dir")
+		files, err := migrations.CreateMigration(dir, args[0], time.Now())
+		if err != nil {
+			return err
+		}
+
+		return printOutput(cmd, map[string]interface{}{"files": files}, func(out io.Writer) {
+			for _, file := range files {
+				fmt.Fprintf(out, "created %s\n", file)
+			}
+		})
+	},
+}
+
+func init() {
+	migrateCmd.AddCommand(createCmd)
+
+	createCmd.Flags().String("dir", "db/migrations", "directory holding a migrations folder per dialect")
+}
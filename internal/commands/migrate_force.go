@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+// forceCmd represents the migrate force command
+var forceCmd = &cobra.Command{
+	Use:   "force VERSION",
+	Short: "Mark a version as applied and clear the dirty flag",
+	Long: `Records VERSION as the current version and clears the dirty flag without
+running any SQL. After a migration fails part way, undo or finish its changes by
+hand, then force the version the schema now matches. -1 marks no migration as
+applied.`,
+	Example: `  ./main migrate force 20251019140000
+  ./main migrate force -- -1`,
+	Args: usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		version, err := strconv.Atoi(args[0])
+		if err != nil || version < -1 {
+			return withExitCode(exitUsage, fmt.Errorf("invalid version %q", args[0]))
+		}
+
+		migrator, err := newMigrator(cmd)
+		if err != nil {
+			return err
+		}
+		defer migrator.Close()
+
+		if err := migrator.Force(version); err != nil {
+			return err
+		}
+
+		return printOutput(cmd, map[string]interface{}{"version": version, "dirty": false}, func(out io.Writer) {
+			fmt.Fprintf(out, "forced version %d\n", version)
+		})
+	},
+}
+
+func init() {
+	migrateCmd.AddCommand(forceCmd)
+}
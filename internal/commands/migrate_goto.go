@@ -0,0 +1,28 @@
+package commands
+
+import (
+	migrations "github.com/weeb-vip/list-service/db"
+
+	"github.com/spf13/cobra"
+)
+
+// gotoCmd represents the migrate goto command
+var gotoCmd = &cobra.Command{
+	Use:   "goto VERSION",
+	Short: "Migrate up or down to a version",
+	Args:  usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		version, err := parseVersionArg(args[0])
+		if err != nil {
+			return err
+		}
+
+		return runMigration(cmd, migrations.Version(version))
+	},
+}
+
+func init() {
+	migrateCmd.AddCommand(gotoCmd)
+
+	gotoCmd.Flags().Bool("dry-run", false, "print the SQL that would run without applying it")
+}
@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+// statusCmd represents the migrate status command
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the current version and pending migrations",
+	Long: `Shows the version the database is at, whether a migration failed part way
+and left it dirty, and the migrations that have not run yet. Exits 3 when the
+database is dirty, and with --check exits 4 when migrations are pending.`,
+	Args: usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		migrator, err := newMigrator(cmd)
+		if err != nil {
+			return err
+		}
+		defer migrator.Close()
+
+		status, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+
+		err = printOutput(cmd, status, func(out io.Writer) {
+			fmt.Fprintf(out, "driver:   %s\n", status.Driver)
+			if status.Version == nil {
+				fmt.Fprintln(out, "version:  none")
+			} else {
+				fmt.Fprintf(out, "version:  %d\n", *status.Version)
+			}
+			fmt.Fprintf(out, "dirty:    %t\n", status.Dirty)
+			fmt.Fprintf(out, "applied:  %d\n", len(status.Applied))
+			fmt.Fprintf(out, "pending:  %d\n", len(status.Pending))
+			for _, migration := range status.Pending {
+				fmt.Fprintf(out, "  %d %s\n", migration.Version, migration.Name)
+			}
+		})
+		if err != nil {
+			return err
+		}
+
+		if status.Dirty {
+			return withExitCode(exitDirty, fmt.Errorf("database is dirty at version %d", *status.Version))
+		}
+		if check, _ := cmd.Flags().GetBool("check"); check && len(status.Pending) > 0 {
+			return withExitCode(exitPending, fmt.Errorf("%d migrations are pending", len(status.Pending)))
+		}
+		return nil
+	},
+}
+
+func init() {
+	migrateCmd.AddCommand(statusCmd)
+
+	statusCmd.Flags().Bool("check", false, "exit 4 when migrations are pending")
+}
@@ -0,0 +1,33 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+
+	migrations "github.com/weeb-vip/list-service/db"
+
+	"github.com/spf13/cobra"
+)
+
+// stepsCmd represents the migrate steps command
+var stepsCmd = &cobra.Command{
+	Use:   "steps N",
+	Short: "Apply the next N migrations, or revert the last N when negative",
+	Example: `  ./main migrate steps 2
+  ./main migrate steps -- -1`,
+	Args: usageArgs(cobra.ExactArgs(1)),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n == 0 {
+			return withExitCode(exitUsage, fmt.Errorf("invalid step count %q", args[0]))
+		}
+
+		return runMigration(cmd, migrations.Steps(n))
+	},
+}
+
+func init() {
+	migrateCmd.AddCommand(stepsCmd)
+
+	stepsCmd.Flags().Bool("dry-run", false, "print the SQL that would run without applying it")
+}
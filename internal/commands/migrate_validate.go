@@ -0,0 +1,33 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+
+	migrations "github.com/weeb-vip/list-service/db"
+
+	"github.com/spf13/cobra"
+)
+
+// validateCmd represents the migrate validate command
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the embedded migration files",
+	Long: `Checks that every dialect has the same migrations, each with an up and a
+down file, and that versions after the early sequential ones are UTC
+timestamps. No database connection is needed.`,
+	Args: usageArgs(cobra.NoArgs),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := migrations.ValidateMigrations(); err != nil {
+			return err
+		}
+
+		return printOutput(cmd, map[string]interface{}{"valid": true}, func(out io.Writer) {
+			fmt.Fprintln(out, "migrations are valid")
+		})
+	},
+}
+
+func init() {
+	migrateCmd.AddCommand(validateCmd)
+}
@@ -0,0 +1,98 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/weeb-vip/list-service/config"
+	"github.com/weeb-vip/list-service/internal/broker"
+	"github.com/weeb-vip/list-service/internal/db"
+	"github.com/weeb-vip/list-service/internal/db/repositories/outbox"
+	"github.com/weeb-vip/list-service/internal/events"
+	"github.com/weeb-vip/list-service/internal/logger"
+	"github.com/weeb-vip/list-service/internal/producer"
+	"github.com/weeb-vip/list-service/internal/relay"
+
+	"github.com/spf13/cobra"
+)
+
+// relayCmd represents the relay command
+var relayCmd = &cobra.Command{
+	Use:   "relay",
+	Short: "Publish outbox events",
+	Long: `Runs only the outbox relay, publishing recorded list change events to
+the configured broker in order. Use this when the relay is disabled in serve with
+OUTBOX_RELAY_ENABLED=false.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+
+		logger.Logger(
+			logger.WithServerName(cfg.AppConfig.APPName),
+			logger.WithVersion(cfg.AppConfig.Version),
+			logger.WithEnvironment(cfg.AppConfig.Env),
+		)
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		database, err := db.NewDatabase(cfg.DBConfig)
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		eventBroker, codec, err := buildBroker(cfg)
+		if err != nil {
+			return err
+		}
+		defer eventBroker.Close()
+
+		return buildRelay(cfg, database, eventBroker, codec).Run(ctx)
+	},
+}
+
+// buildBroker connects to the configured broker. The caller closes it on
+// shutdown.
+func buildBroker(cfg config.Config) (broker.Broker, producer.Codec[events.Event], error) {
+	codec, err := events.NewCodec(cfg.BrokerConfig.Schema)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	eventBroker, err := broker.NewFromConfig(cfg, codec)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return eventBroker, codec, nil
+}
+
+// buildRelay wires the outbox relay to publish through eventBroker
+func buildRelay(cfg config.Config, database *db.DB, eventBroker broker.Broker, codec producer.Codec[events.Event]) relay.RelayImpl {
+	eventProducer := producer.NewProducer[events.Event](
+		eventBroker,
+		codec,
+		producer.WithMaxRetries(cfg.BrokerConfig.MaxRetries),
+		producer.WithRetryBackoff(time.Duration(cfg.BrokerConfig.RetryBackoffMs)*time.Millisecond),
+	)
+
+	return relay.NewRelay(
+		outbox.NewOutboxRepository(database),
+		eventProducer,
+		relay.WithBatchSize(cfg.OutboxConfig.BatchSize),
+		relay.WithPollInterval(time.Duration(cfg.OutboxConfig.PollIntervalMs)*time.Millisecond),
+		relay.WithLease(time.Duration(cfg.OutboxConfig.LeaseMs)*time.Millisecond),
+		relay.WithRetention(time.Duration(cfg.OutboxConfig.RetentionMs)*time.Millisecond),
+		relay.WithMaxAttempts(cfg.OutboxConfig.MaxAttempts),
+	)
+}
+
+func init() {
+	rootCmd.AddCommand(relayCmd)
+}
@@ -4,7 +4,12 @@ Copyright © 2023 NAME HERE <EMAIL ADDRESS>
 package commands
 
 import (
+	"context"
 	"os"
+	"time"
+
+	"github.com/weeb-vip/list-service/internal/logger"
+	"github.com/weeb-vip/list-service/metrics"
 
 	"github.com/spf13/cobra"
 )
@@ -28,8 +33,17 @@ to quickly create a Cobra application.`,
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	err := rootCmd.Execute()
+
+	// push the metrics buffered for Datadog or OTLP before exiting
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if shutdownErr := metrics.Shutdown(ctx); shutdownErr != nil {
+		log := logger.Get()
+		log.Error().Err(shutdownErr).Msg("Error flushing metrics")
+	}
+	cancel()
+
 	if err != nil {
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
 
@@ -39,8 +53,13 @@ func init() {
 	// will be global for your application.
 
 	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.scraper-api.yaml)")
+	rootCmd.PersistentFlags().String("config", "", "config file, JSON, YAML or TOML, overridden by environment variables")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return withExitCode(exitUsage, err)
+	})
 }
@@ -5,10 +5,19 @@ package commands
 
 import (
 	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
-	"github.com/weeb-vip/list-service/config"
+	migrations "github.com/weeb-vip/list-service/db"
 	"github.com/weeb-vip/list-service/http"
+	"github.com/weeb-vip/list-service/http/handlers"
+	"github.com/weeb-vip/list-service/internal/db"
+	user_anime_repository "github.com/weeb-vip/list-service/internal/db/repositories/user_anime"
 	"github.com/weeb-vip/list-service/internal/logger"
+	user_anime_service "github.com/weeb-vip/list-service/internal/services/user_anime"
 	"github.com/weeb-vip/list-service/tracing"
 
 	"github.com/spf13/cobra"
@@ -26,7 +35,10 @@ This application is a tool to generate the needed files
 to quickly create a Cobra application.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Load config first to get service name and version
-		cfg := config.LoadConfigOrPanic()
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
 
 		// Initialize logger
 		logger.Logger(
@@ -37,7 +49,7 @@ to quickly create a Cobra application.`,
 
 		// Initialize tracing
 		ctx := context.Background()
-		tracedCtx, err := tracing.InitTracing(ctx)
+		tracedCtx, err := tracing.InitTracing(ctx, cfg)
 		if err != nil {
 			log := logger.FromCtx(ctx)
 			log.Error().Err(err).Msg("Failed to initialize tracing")
@@ -54,8 +66,84 @@ to quickly create a Cobra application.`,
 			log.Info().Msg("Tracing initialized successfully")
 		}
 
-		// Start the server with traced context
-		return http.StartServerWithContext(tracedCtx)
+		// SIGINT and SIGTERM start a graceful shutdown, the deferred closes
+		// below then run in reverse: workers stop, the broker flushes, the
+		// database closes and tracing flushes last
+		ctx, stop := signal.NotifyContext(tracedCtx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		database, err := db.NewDatabase(cfg.DBConfig)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := database.Close(); err != nil {
+				log := logger.FromCtx(tracedCtx)
+				log.Error().Err(err).Msg("Error closing database")
+			}
+		}()
+
+		readiness := handlers.NewReadiness(time.Duration(cfg.ServerConfig.ProbeTimeoutMs) * time.Millisecond)
+		readiness.Add("database", database.Ping)
+		readiness.Add("migrations", func(ctx context.Context) error {
+			return migrations.CheckSchema(ctx, cfg.DBConfig, database)
+		})
+
+		var workers sync.WaitGroup
+		if interval := cfg.MetricsConfig.TrackedEntriesIntervalMs; interval > 0 {
+			repository := user_anime_repository.NewUserAnimeRepository(database)
+			workers.Add(1)
+			go func() {
+				defer workers.Done()
+				user_anime_service.ReportTrackedEntries(ctx, repository, time.Duration(interval)*time.Millisecond)
+			}()
+		}
+
+		if cfg.OutboxConfig.RelayEnabled || cfg.WebhookConfig.WorkerEnabled {
+			eventBroker, codec, err := buildBroker(cfg)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if err := eventBroker.Close(); err != nil {
+					log := logger.FromCtx(tracedCtx)
+					log.Error().Err(err).Msg("Error closing event broker")
+				}
+			}()
+			readiness.Add("broker", eventBroker.Check)
+
+			if cfg.OutboxConfig.RelayEnabled {
+				eventRelay := buildRelay(cfg, database, eventBroker, codec)
+				workers.Add(1)
+				go func() {
+					defer workers.Done()
+					if err := eventRelay.Run(ctx); err != nil {
+						log := logger.FromCtx(tracedCtx)
+						log.Error().Err(err).Msg("Outbox relay stopped")
+					}
+				}()
+			}
+
+			if cfg.WebhookConfig.WorkerEnabled {
+				workers.Add(1)
+				go func() {
+					defer workers.Done()
+					if err := runWebhooks(ctx, cfg, database, eventBroker, codec); err != nil {
+						log := logger.FromCtx(tracedCtx)
+						log.Error().Err(err).Msg("Webhooks stopped")
+					}
+				}()
+			}
+		}
+
+		// Serve until a signal arrives and in-flight requests have finished
+		err = http.StartServerWithContext(ctx, cfg, database, readiness)
+
+		// the server may have failed on its own, stop the workers either way
+		stop()
+		workers.Wait()
+
+		return err
 	},
 }
 
@@ -0,0 +1,121 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/weeb-vip/list-service/config"
+	"github.com/weeb-vip/list-service/internal/broker"
+	"github.com/weeb-vip/list-service/internal/db"
+	webhook2 "github.com/weeb-vip/list-service/internal/db/repositories/webhook"
+	"github.com/weeb-vip/list-service/internal/events"
+	"github.com/weeb-vip/list-service/internal/logger"
+	"github.com/weeb-vip/list-service/internal/producer"
+	"github.com/weeb-vip/list-service/internal/webhook"
+
+	"github.com/spf13/cobra"
+)
+
+// webhooksCmd represents the webhooks command
+var webhooksCmd = &cobra.Command{
+	Use:   "webhooks",
+	Short: "Deliver user webhooks",
+	Long: `Consumes published list change events, queues a delivery for every
+matching webhook subscription and POSTs due deliveries to their endpoints. Use
+this when webhooks are disabled in serve with WEBHOOK_WORKER_ENABLED=false.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+
+		logger.Logger(
+			logger.WithServerName(cfg.AppConfig.APPName),
+			logger.WithVersion(cfg.AppConfig.Version),
+			logger.WithEnvironment(cfg.AppConfig.Env),
+		)
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		database, err := db.NewDatabase(cfg.DBConfig)
+		if err != nil {
+			return err
+		}
+		defer database.Close()
+
+		eventBroker, codec, err := buildBroker(cfg)
+		if err != nil {
+			return err
+		}
+		defer eventBroker.Close()
+
+		return runWebhooks(ctx, cfg, database, eventBroker, codec)
+	},
+}
+
+// runWebhooks fans events out to subscriptions and delivers them until ctx is
+// cancelled
+func runWebhooks(ctx context.Context, cfg config.Config, database *db.DB, eventBroker broker.Broker, codec producer.Codec[events.Event]) error {
+	subscriber, ok := eventBroker.(broker.Subscriber)
+	if !ok {
+		return fmt.Errorf("webhooks need a broker that can be subscribed to, %s cannot", cfg.BrokerConfig.Backend)
+	}
+
+	repository := webhook2.NewWebhookRepository(database)
+	dispatcher := webhook.NewDispatcher(repository)
+	worker := webhook.NewWorker(
+		repository,
+		webhook.WithHTTPClient(webhook.NewClient(time.Duration(cfg.WebhookConfig.TimeoutMs)*time.Millisecond)),
+		webhook.WithBatchSize(cfg.WebhookConfig.BatchSize),
+		webhook.WithPollInterval(time.Duration(cfg.WebhookConfig.PollIntervalMs)*time.Millisecond),
+		webhook.WithMaxAttempts(cfg.WebhookConfig.MaxAttempts),
+		webhook.WithRetryBackoff(
+			time.Duration(cfg.WebhookConfig.RetryBackoffMs)*time.Millisecond,
+			time.Duration(cfg.WebhookConfig.MaxBackoffMs)*time.Millisecond,
+		),
+		webhook.WithDisableAfter(cfg.WebhookConfig.DisableAfter),
+	)
+
+	// wait for the delivery in flight before returning, the caller closes the
+	// database next
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := worker.Run(ctx); err != nil {
+			log := logger.FromCtx(ctx)
+			log.Error().Err(err).Msg("Webhook worker stopped")
+		}
+	}()
+
+	consumer := producer.NewConsumer[events.Event](subscriber, codec)
+	for {
+		err := consumer.Consume(ctx, "", cfg.WebhookConfig.ConsumerGroup, func(ctx context.Context, message producer.Message[events.Event]) error {
+			return dispatcher.Dispatch(ctx, &message.Value)
+		})
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		// the failed event is redelivered once the subscription is back
+		log := logger.FromCtx(ctx)
+		log.Error().Err(err).Msg("Error fanning out webhook events, resubscribing")
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Duration(cfg.WebhookConfig.PollIntervalMs) * time.Millisecond):
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(webhooksCmd)
+}
@@ -3,25 +3,49 @@ package dataloader
 import (
 	"context"
 	"net/http"
+	"time"
+
+	"github.com/weeb-vip/list-service/config"
+	"github.com/weeb-vip/list-service/internal/services/privacy_settings"
 	"github.com/weeb-vip/list-service/internal/services/user_anime"
+	"github.com/weeb-vip/list-service/internal/services/user_list"
 )
 
 type contextKey string
 
 const (
-	userAnimeLoaderKey contextKey = "userAnimeLoader"
+	userAnimeLoaderKey       contextKey = "userAnimeLoader"
+	userAnimeByIDLoaderKey   contextKey = "userAnimeByIDLoader"
+	userListLoaderKey        contextKey = "userListLoader"
+	privacySettingsLoaderKey contextKey = "privacySettingsLoader"
+	userListsByUserLoaderKey contextKey = "userListsByUserLoader"
+	userStatsLoaderKey       contextKey = "userStatsLoader"
+	favoritesLoaderKey       contextKey = "favoritesLoader"
 )
 
-// Middleware adds dataloaders to the request context
-func Middleware(userAnimeService user_anime.UserAnimeServiceImpl) func(http.Handler) http.Handler {
+// Middleware adds dataloaders to the request context. Anime catalog data is
+// served by the catalog service, this service only resolves anime by id, so
+// there is no catalog loader.
+func Middleware(cfg config.ServerConfig, userAnimeService user_anime.UserAnimeServiceImpl, userListService user_list.UserListServiceImpl, privacySettingsService privacy_settings.PrivacySettingsServiceImpl) func(http.Handler) http.Handler {
+	opts := []Option{
+		WithWait(time.Duration(cfg.DataLoaderWaitMs) * time.Millisecond),
+		WithMaxBatch(cfg.DataLoaderMaxBatch),
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			ctx := r.Context()
-			
+
 			// Create fresh dataloaders for each request
-			userAnimeLoader := NewUserAnimeLoader(userAnimeService)
-			ctx = context.WithValue(ctx, userAnimeLoaderKey, userAnimeLoader)
-			
+			ctx = context.WithValue(ctx, userAnimeLoaderKey, NewUserAnimeLoader(userAnimeService, opts...))
+			ctx = context.WithValue(ctx, userAnimeByIDLoaderKey, NewUserAnimeByIDLoader(userAnimeService, opts...))
+			ctx = context.WithValue(ctx, userListLoaderKey, NewUserListLoader(userListService, opts...))
+			ctx = context.WithValue(ctx, privacySettingsLoaderKey, NewPrivacySettingsLoader(privacySettingsService, opts...))
+			userListsByUserLoader := NewUserListsByUserLoader(userListService, opts...)
+			ctx = context.WithValue(ctx, userListsByUserLoaderKey, userListsByUserLoader)
+			ctx = context.WithValue(ctx, userStatsLoaderKey, NewUserStatsLoader(userAnimeService, userListsByUserLoader, opts...))
+			ctx = context.WithValue(ctx, favoritesLoaderKey, NewFavoritesLoader(userAnimeService, userListsByUserLoader, opts...))
+
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -31,4 +55,40 @@ func Middleware(userAnimeService user_anime.UserAnimeServiceImpl) func(http.Hand
 func GetUserAnimeLoader(ctx context.Context) (*UserAnimeLoader, bool) {
 	loader, ok := ctx.Value(userAnimeLoaderKey).(*UserAnimeLoader)
 	return loader, ok
-}
\ No newline at end of file
+}
+
+// GetUserAnimeByIDLoader retrieves the user anime by id loader from context
+func GetUserAnimeByIDLoader(ctx context.Context) (*UserAnimeByIDLoader, bool) {
+	loader, ok := ctx.Value(userAnimeByIDLoaderKey).(*UserAnimeByIDLoader)
+	return loader, ok
+}
+
+// GetUserListLoader retrieves the user list loader from context
+func GetUserListLoader(ctx context.Context) (*UserListLoader, bool) {
+	loader, ok := ctx.Value(userListLoaderKey).(*UserListLoader)
+	return loader, ok
+}
+
+// GetPrivacySettingsLoader retrieves the privacy settings loader from context
+func GetPrivacySettingsLoader(ctx context.Context) (*PrivacySettingsLoader, bool) {
+	loader, ok := ctx.Value(privacySettingsLoaderKey).(*PrivacySettingsLoader)
+	return loader, ok
+}
+
+// GetUserListsByUserLoader retrieves the lists by user loader from context
+func GetUserListsByUserLoader(ctx context.Context) (*UserListsByUserLoader, bool) {
+	loader, ok := ctx.Value(userListsByUserLoaderKey).(*UserListsByUserLoader)
+	return loader, ok
+}
+
+// GetUserStatsLoader retrieves the user stats loader from context
+func GetUserStatsLoader(ctx context.Context) (*UserStatsLoader, bool) {
+	loader, ok := ctx.Value(userStatsLoaderKey).(*UserStatsLoader)
+	return loader, ok
+}
+
+// GetFavoritesLoader retrieves the favorites loader from context
+func GetFavoritesLoader(ctx context.Context) (*FavoritesLoader, bool) {
+	loader, ok := ctx.Value(favoritesLoaderKey).(*FavoritesLoader)
+	return loader, ok
+}
@@ -0,0 +1,209 @@
+package dataloader
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultWait     = 5 * time.Millisecond
+	DefaultMaxBatch = 100
+)
+
+// BatchFunc loads the values of keys in one go. Keys missing from the
+// returned map load as the zero value of V, an error fails every key of the
+// batch.
+type BatchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// Option configures a Loader
+type Option func(*options)
+
+type options struct {
+	wait     time.Duration
+	maxBatch int
+}
+
+// WithWait sets how long a batch collects keys after its first Load before
+// it is fetched
+func WithWait(wait time.Duration) Option {
+	return func(o *options) {
+		o.wait = wait
+	}
+}
+
+// WithMaxBatch caps the keys fetched at once, a full batch is fetched
+// straight away and later keys start a new one. 0 leaves batches unbounded.
+func WithMaxBatch(maxBatch int) Option {
+	return func(o *options) {
+		o.maxBatch = maxBatch
+	}
+}
+
+// Loader batches the keys loaded within a short window into one fetch and
+// memoizes every result, errors included, for its lifetime. Loaders are
+// made per request so results are never shared between users.
+type Loader[K comparable, V any] struct {
+	fetch    BatchFunc[K, V]
+	wait     time.Duration
+	maxBatch int
+
+	mu    sync.Mutex
+	cache map[K]*result[V]
+	batch *batch[K, V]
+}
+
+type result[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+type batch[K comparable, V any] struct {
+	ctx        context.Context
+	keys       []K
+	results    []*result[V]
+	timer      *time.Timer
+	dispatched bool
+}
+
+func NewLoader[K comparable, V any](fetch BatchFunc[K, V], opts ...Option) *Loader[K, V] {
+	o := &options{
+		wait:     DefaultWait,
+		maxBatch: DefaultMaxBatch,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return &Loader[K, V]{
+		fetch:    fetch,
+		wait:     o.wait,
+		maxBatch: o.maxBatch,
+		cache:    map[K]*result[V]{},
+	}
+}
+
+// Load returns the value of key, fetched together with the other keys loaded
+// in the same window. The batch is fetched with the values of the context of
+// the Load that started it, but is not cancelled with it. Load returns early
+// with the context error when ctx is done first, the fetch carries on for the
+// other callers and later loads of key.
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+	r, ok := l.cache[key]
+	if !ok {
+		r = &result[V]{done: make(chan struct{})}
+		l.cache[key] = r
+		l.add(ctx, key, r)
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-r.done:
+		return r.value, r.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// LoadMany loads keys in the same batch and returns their values in order,
+// failing with the first error
+func (l *Loader[K, V]) LoadMany(ctx context.Context, keys []K) ([]V, error) {
+	results := make([]*result[V], len(keys))
+	l.mu.Lock()
+	for i, key := range keys {
+		r, ok := l.cache[key]
+		if !ok {
+			r = &result[V]{done: make(chan struct{})}
+			l.cache[key] = r
+			l.add(ctx, key, r)
+		}
+		results[i] = r
+	}
+	l.mu.Unlock()
+
+	values := make([]V, len(keys))
+	for i, r := range results {
+		select {
+		case <-r.done:
+			if r.err != nil {
+				return nil, r.err
+			}
+			values[i] = r.value
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return values, nil
+}
+
+// add queues key on the open batch, starting one if needed. l.mu is held.
+func (l *Loader[K, V]) add(ctx context.Context, key K, r *result[V]) {
+	b := l.batch
+	if b == nil {
+		b = &batch[K, V]{ctx: ctx}
+		l.batch = b
+		b.timer = time.AfterFunc(l.wait, func() {
+			l.mu.Lock()
+			ready := l.take(b)
+			l.mu.Unlock()
+
+			if ready {
+				l.run(b)
+			}
+		})
+	}
+
+	b.keys = append(b.keys, key)
+	b.results = append(b.results, r)
+
+	if l.maxBatch > 0 && len(b.keys) >= l.maxBatch && l.take(b) {
+		b.timer.Stop()
+		go l.run(b)
+	}
+}
+
+// take closes b to new keys, reporting false when it was already taken.
+// l.mu is held.
+func (l *Loader[K, V]) take(b *batch[K, V]) bool {
+	if b.dispatched {
+		return false
+	}
+	b.dispatched = true
+	if l.batch == b {
+		l.batch = nil
+	}
+
+	return true
+}
+
+// run fetches a batch and hands every key its result. The fetch outlives the
+// Load that started the batch, as its result is shared with every key in the
+// batch and memoized.
+func (l *Loader[K, V]) run(b *batch[K, V]) {
+	values, err := l.safeFetch(context.WithoutCancel(b.ctx), b.keys)
+
+	for i, key := range b.keys {
+		r := b.results[i]
+		if err != nil {
+			r.err = err
+		} else {
+			r.value = values[key]
+		}
+		close(r.done)
+	}
+}
+
+func (l *Loader[K, V]) safeFetch(ctx context.Context, keys []K) (values map[K]V, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("dataloader batch panicked: %v", recovered)
+		}
+	}()
+
+	return l.fetch(ctx, keys)
+}
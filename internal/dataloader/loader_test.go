@@ -0,0 +1,151 @@
+package dataloader_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weeb-vip/list-service/internal/dataloader"
+)
+
+// recorder is a batch function doubling its keys that records every batch
+type recorder struct {
+	mu      sync.Mutex
+	batches [][]int
+	err     error
+	block   chan struct{}
+}
+
+func (r *recorder) fetch(ctx context.Context, keys []int) (map[int]int, error) {
+	r.mu.Lock()
+	r.batches = append(r.batches, append([]int{}, keys...))
+	r.mu.Unlock()
+
+	if r.block != nil {
+		<-r.block
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	values := map[int]int{}
+	for _, key := range keys {
+		values[key] = key * 2
+	}
+	return values, nil
+}
+
+func (r *recorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.batches)
+}
+
+// loadAll loads keys concurrently and returns their values in order
+func loadAll(t *testing.T, loader *dataloader.Loader[int, int], keys ...int) []int {
+	values := make([]int, len(keys))
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		go func(i int, key int) {
+			defer wg.Done()
+			value, err := loader.Load(context.Background(), key)
+			assert.NoError(t, err)
+			values[i] = value
+		}(i, key)
+	}
+	wg.Wait()
+
+	return values
+}
+
+func TestLoader(t *testing.T) {
+	t.Run("keys loaded together are fetched in one batch", func(t *testing.T) {
+		r := &recorder{}
+		loader := dataloader.NewLoader(r.fetch, dataloader.WithWait(10*time.Millisecond))
+
+		assert.Equal(t, []int{2, 4, 6}, loadAll(t, loader, 1, 2, 3))
+		require.Equal(t, 1, r.count())
+		assert.ElementsMatch(t, []int{1, 2, 3}, r.batches[0])
+	})
+
+	t.Run("loads after a batch has run start a new one", func(t *testing.T) {
+		r := &recorder{}
+		loader := dataloader.NewLoader(r.fetch, dataloader.WithWait(time.Millisecond))
+
+		assert.Equal(t, []int{2}, loadAll(t, loader, 1))
+		assert.Equal(t, []int{4}, loadAll(t, loader, 2))
+		assert.Equal(t, 2, r.count())
+	})
+
+	t.Run("results are memoized", func(t *testing.T) {
+		r := &recorder{}
+		loader := dataloader.NewLoader(r.fetch, dataloader.WithWait(time.Millisecond))
+
+		assert.Equal(t, []int{2, 2}, loadAll(t, loader, 1, 1))
+		assert.Equal(t, []int{2}, loadAll(t, loader, 1))
+		assert.Equal(t, 1, r.count())
+	})
+
+	t.Run("load many fetches its keys in one batch", func(t *testing.T) {
+		r := &recorder{}
+		loader := dataloader.NewLoader(r.fetch, dataloader.WithWait(time.Millisecond))
+
+		values, err := loader.LoadMany(context.Background(), []int{3, 1, 3})
+		require.NoError(t, err)
+		assert.Equal(t, []int{6, 2, 6}, values)
+		require.Equal(t, 1, r.count())
+		assert.ElementsMatch(t, []int{3, 1}, r.batches[0])
+	})
+
+	t.Run("full batches are fetched straight away", func(t *testing.T) {
+		r := &recorder{}
+		loader := dataloader.NewLoader(r.fetch, dataloader.WithWait(time.Hour), dataloader.WithMaxBatch(2))
+
+		assert.Equal(t, []int{2, 4}, loadAll(t, loader, 1, 2))
+		assert.Equal(t, 1, r.count())
+	})
+
+	t.Run("errors fail every key of the batch", func(t *testing.T) {
+		r := &recorder{err: errors.New("database down")}
+		loader := dataloader.NewLoader(r.fetch, dataloader.WithWait(time.Millisecond))
+
+		_, err := loader.Load(context.Background(), 1)
+		assert.EqualError(t, err, "database down")
+	})
+
+	t.Run("a cancelled load returns without waiting for the batch", func(t *testing.T) {
+		r := &recorder{block: make(chan struct{})}
+		defer close(r.block)
+		loader := dataloader.NewLoader(r.fetch, dataloader.WithWait(time.Millisecond))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		_, err := loader.Load(ctx, 1)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("cancelling the load that started a batch fails no other key", func(t *testing.T) {
+		r := &recorder{}
+		loader := dataloader.NewLoader(r.fetch, dataloader.WithWait(50*time.Millisecond))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := loader.Load(ctx, 1)
+		assert.ErrorIs(t, err, context.Canceled)
+
+		// joins the batch started by the cancelled load
+		values, err := loader.LoadMany(context.Background(), []int{1, 2})
+		require.NoError(t, err)
+		assert.Equal(t, []int{2, 4}, values)
+		assert.Equal(t, 1, r.count())
+	})
+}
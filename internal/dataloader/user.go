@@ -0,0 +1,163 @@
+package dataloader
+
+import (
+	"context"
+
+	privacy_settings_repo "github.com/weeb-vip/list-service/internal/db/repositories/privacy_settings"
+	user_anime_repo "github.com/weeb-vip/list-service/internal/db/repositories/user_anime"
+	"github.com/weeb-vip/list-service/internal/db/repositories/user_list"
+	"github.com/weeb-vip/list-service/internal/pagination"
+	"github.com/weeb-vip/list-service/internal/services/privacy_settings"
+	"github.com/weeb-vip/list-service/internal/services/user_anime"
+	user_list_service "github.com/weeb-vip/list-service/internal/services/user_list"
+)
+
+// PrivacySettingsLoader loads the privacy settings of users
+type PrivacySettingsLoader = Loader[string, *privacy_settings_repo.PrivacySettings]
+
+// NewPrivacySettingsLoader returns a loader fetching the settings of a batch
+// of users in one query
+func NewPrivacySettingsLoader(privacySettingsService privacy_settings.PrivacySettingsServiceImpl, opts ...Option) *PrivacySettingsLoader {
+	return NewLoader(func(ctx context.Context, userIDs []string) (map[string]*privacy_settings_repo.PrivacySettings, error) {
+		return privacySettingsService.FindByUserIds(ctx, userIDs)
+	}, opts...)
+}
+
+// UserListsByUserLoader loads every list of users, private ones included.
+// Callers check visibility.
+type UserListsByUserLoader = Loader[string, []*user_list.UserList]
+
+// NewUserListsByUserLoader returns a loader fetching the lists of a batch of
+// users in one query
+func NewUserListsByUserLoader(userListService user_list_service.UserListServiceImpl, opts ...Option) *UserListsByUserLoader {
+	return NewLoader(func(ctx context.Context, userIDs []string) (map[string][]*user_list.UserList, error) {
+		userLists, err := userListService.FindByUserIds(ctx, userIDs)
+		if err != nil {
+			return nil, err
+		}
+
+		results := make(map[string][]*user_list.UserList, len(userIDs))
+		for _, userList := range userLists {
+			if userList.UserID == nil {
+				continue
+			}
+			results[*userList.UserID] = append(results[*userList.UserID], userList)
+		}
+
+		// users without lists stay nil
+		return results, nil
+	}, opts...)
+}
+
+type UserStatsKey struct {
+	UserID string
+	// Public leaves out the entries on the user's private lists
+	Public bool
+}
+
+// UserStatsLoader loads the stats of users
+type UserStatsLoader = Loader[UserStatsKey, *user_anime.UserStats]
+
+// NewUserStatsLoader returns a loader summing up the entries of a batch of
+// users in one query per kind of key. The lists of users whose stats are shown
+// publicly are loaded through userListsLoader to leave out their private
+// lists.
+func NewUserStatsLoader(userAnimeService user_anime.UserAnimeServiceImpl, userListsLoader *UserListsByUserLoader, opts ...Option) *UserStatsLoader {
+	return NewLoader(func(ctx context.Context, keys []UserStatsKey) (map[UserStatsKey]*user_anime.UserStats, error) {
+		var ownerUserIDs, publicUserIDs []string
+		for _, key := range keys {
+			if key.Public {
+				publicUserIDs = append(publicUserIDs, key.UserID)
+			} else {
+				ownerUserIDs = append(ownerUserIDs, key.UserID)
+			}
+		}
+
+		results := make(map[UserStatsKey]*user_anime.UserStats, len(keys))
+		if len(ownerUserIDs) > 0 {
+			stats, err := userAnimeService.StatsByUserIds(ctx, ownerUserIDs, nil)
+			if err != nil {
+				return nil, err
+			}
+			for _, userID := range ownerUserIDs {
+				results[UserStatsKey{UserID: userID}] = stats[userID]
+			}
+		}
+
+		if len(publicUserIDs) > 0 {
+			userLists, err := userListsLoader.LoadMany(ctx, publicUserIDs)
+			if err != nil {
+				return nil, err
+			}
+			privateListIDs := make(map[string][]string, len(publicUserIDs))
+			for i, userID := range publicUserIDs {
+				privateListIDs[userID] = user_list_service.PrivateListIds(userLists[i], userID)
+			}
+
+			stats, err := userAnimeService.StatsByUserIds(ctx, publicUserIDs, privateListIDs)
+			if err != nil {
+				return nil, err
+			}
+			for _, userID := range publicUserIDs {
+				results[UserStatsKey{UserID: userID, Public: true}] = stats[userID]
+			}
+		}
+
+		return results, nil
+	}, opts...)
+}
+
+type FavoritesKey struct {
+	UserID string
+	Limit  int
+	// Public leaves out the entries on the user's private lists
+	Public bool
+}
+
+// FavoritesLoader loads the highest scored entries of users
+type FavoritesLoader = Loader[FavoritesKey, []*user_anime_repo.UserAnime]
+
+// NewFavoritesLoader returns a loader fetching favorites with one query per
+// key in the batch. The lists of users whose favorites are shown publicly are
+// loaded through userListsLoader to leave out their private lists.
+func NewFavoritesLoader(userAnimeService user_anime.UserAnimeServiceImpl, userListsLoader *UserListsByUserLoader, opts ...Option) *FavoritesLoader {
+	return NewLoader(func(ctx context.Context, keys []FavoritesKey) (map[FavoritesKey][]*user_anime_repo.UserAnime, error) {
+		var publicUserIDs []string
+		for _, key := range keys {
+			if key.Public {
+				publicUserIDs = append(publicUserIDs, key.UserID)
+			}
+		}
+
+		privateListIDs := make(map[string][]string, len(publicUserIDs))
+		if len(publicUserIDs) > 0 {
+			userLists, err := userListsLoader.LoadMany(ctx, publicUserIDs)
+			if err != nil {
+				return nil, err
+			}
+			for i, userID := range publicUserIDs {
+				privateListIDs[userID] = user_list_service.PrivateListIds(userLists[i], userID)
+			}
+		}
+
+		// entries without a score are never favorites
+		scored := 0.0
+		sort := user_anime_repo.Sort{Field: user_anime_repo.SortByScore, Direction: user_anime_repo.SortDesc}
+
+		results := make(map[FavoritesKey][]*user_anime_repo.UserAnime, len(keys))
+		for _, key := range keys {
+			filter := user_anime_repo.Filter{MinScore: &scored}
+			if key.Public {
+				filter.ExcludeListIDs = privateListIDs[key.UserID]
+			}
+
+			userAnimes, _, _, err := userAnimeService.FindPageByUserId(ctx, key.UserID, filter, sort, pagination.Page{Limit: key.Limit})
+			if err != nil {
+				return nil, err
+			}
+			results[key] = userAnimes
+		}
+
+		return results, nil
+	}, opts...)
+}
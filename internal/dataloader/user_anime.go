@@ -2,12 +2,10 @@ package dataloader
 
 import (
 	"context"
-	"sync"
-	"time"
-	"strings"
-	"github.com/weeb-vip/list-service/internal/services/user_anime"
-	user_anime_repo "github.com/weeb-vip/list-service/internal/db/repositories/user_anime"
 	"github.com/weeb-vip/list-service/graph/model"
+	user_anime_repo "github.com/weeb-vip/list-service/internal/db/repositories/user_anime"
+	"github.com/weeb-vip/list-service/internal/services/user_anime"
+	"strings"
 )
 
 type UserAnimeKey struct {
@@ -15,22 +13,40 @@ type UserAnimeKey struct {
 	AnimeID string
 }
 
-type UserAnimeLoader struct {
-	userAnimeService user_anime.UserAnimeServiceImpl
-	batch           []UserAnimeKey
-	batchResult     []*model.UserAnime
-	batchError      []error
-	batchOnce       sync.Once
-	batchChannels   []chan struct{}
-	mutex           sync.Mutex
-	batchTimeout    time.Duration
-}
+// UserAnimeLoader loads the entries of users by anime
+type UserAnimeLoader = Loader[UserAnimeKey, *model.UserAnime]
 
-func NewUserAnimeLoader(userAnimeService user_anime.UserAnimeServiceImpl) *UserAnimeLoader {
-	return &UserAnimeLoader{
-		userAnimeService: userAnimeService,
-		batchTimeout:     time.Millisecond * 16, // Small timeout to batch requests
-	}
+// NewUserAnimeLoader returns a loader fetching entries with one query per
+// user in the batch
+func NewUserAnimeLoader(userAnimeService user_anime.UserAnimeServiceImpl, opts ...Option) *UserAnimeLoader {
+	return NewLoader(func(ctx context.Context, keys []UserAnimeKey) (map[UserAnimeKey]*model.UserAnime, error) {
+		animeIDsByUser := make(map[string][]string)
+		for _, key := range keys {
+			animeIDsByUser[key.UserID] = append(animeIDsByUser[key.UserID], key.AnimeID)
+		}
+
+		results := make(map[UserAnimeKey]*model.UserAnime, len(keys))
+		for userID, animeIDs := range animeIDsByUser {
+			userAnimes, err := userAnimeService.FindByUserIdAndAnimeIds(ctx, userID, animeIDs)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, userAnime := range userAnimes {
+				if userAnime.AnimeID == nil {
+					continue
+				}
+				converted, err := convertUserAnimeToGraphql(userAnime)
+				if err != nil {
+					return nil, err
+				}
+				results[UserAnimeKey{UserID: userID, AnimeID: *userAnime.AnimeID}] = converted
+			}
+		}
+
+		// entries that are not on the list stay nil
+		return results, nil
+	}, opts...)
 }
 
 // convertUserAnimeToGraphql converts UserAnime entity to GraphQL model
@@ -61,112 +77,6 @@ func convertUserAnimeToGraphql(userAnimeEntity *user_anime_repo.UserAnime) (*mod
 		RewatchingEpisodes: userAnimeEntity.RewatchingEpisodes,
 		Tags:               tags,
 		ListID:             userAnimeEntity.ListID,
+		Version:            userAnimeEntity.Version,
 	}, nil
 }
-
-// Load loads a single user anime, batching the request with others
-func (l *UserAnimeLoader) Load(ctx context.Context, key UserAnimeKey) (*model.UserAnime, error) {
-	l.mutex.Lock()
-	
-	// Add this key to the batch
-	index := len(l.batch)
-	l.batch = append(l.batch, key)
-	
-	// Create a channel to wait for the batch result
-	resultChan := make(chan struct{})
-	l.batchChannels = append(l.batchChannels, resultChan)
-	
-	// If this is the first request in the batch, start the timer
-	if index == 0 {
-		go l.startBatchTimer(ctx)
-	}
-	
-	l.mutex.Unlock()
-	
-	// Wait for the batch to complete
-	<-resultChan
-	
-	// Return the result for this specific key
-	if index < len(l.batchError) && l.batchError[index] != nil {
-		return nil, l.batchError[index]
-	}
-	
-	if index < len(l.batchResult) {
-		return l.batchResult[index], nil
-	}
-	
-	return nil, nil
-}
-
-func (l *UserAnimeLoader) startBatchTimer(ctx context.Context) {
-	time.Sleep(l.batchTimeout)
-	l.executeBatch(ctx)
-}
-
-func (l *UserAnimeLoader) executeBatch(ctx context.Context) {
-	l.batchOnce.Do(func() {
-		l.mutex.Lock()
-		keys := make([]UserAnimeKey, len(l.batch))
-		copy(keys, l.batch)
-		channels := make([]chan struct{}, len(l.batchChannels))
-		copy(channels, l.batchChannels)
-		l.mutex.Unlock()
-		
-		// Group keys by userID for efficient querying
-		userAnimeMap := make(map[string][]string) // userID -> animeIDs
-		keyToIndex := make(map[UserAnimeKey]int)  // key -> index in batch
-		
-		for i, key := range keys {
-			userAnimeMap[key.UserID] = append(userAnimeMap[key.UserID], key.AnimeID)
-			keyToIndex[key] = i
-		}
-		
-		results := make([]*model.UserAnime, len(keys))
-		errors := make([]error, len(keys))
-		
-		// Process each user's anime IDs
-		for userID, animeIDs := range userAnimeMap {
-			userAnimes, err := l.userAnimeService.FindByUserIdAndAnimeIds(ctx, userID, animeIDs)
-			if err != nil {
-				// Set error for all keys belonging to this user
-				for i, key := range keys {
-					if key.UserID == userID {
-						errors[i] = err
-					}
-				}
-				continue
-			}
-			
-			// Create a map for quick lookup
-			userAnimeByAnimeID := make(map[string]*user_anime_repo.UserAnime)
-			for _, ua := range userAnimes {
-				if ua.AnimeID != nil {
-					userAnimeByAnimeID[*ua.AnimeID] = ua
-				}
-			}
-			
-			// Fill results in the order of original keys
-			for i, key := range keys {
-				if key.UserID == userID {
-					if userAnime, exists := userAnimeByAnimeID[key.AnimeID]; exists {
-						converted, convertErr := convertUserAnimeToGraphql(userAnime)
-						if convertErr != nil {
-							errors[i] = convertErr
-						} else {
-							results[i] = converted
-						}
-					}
-					// If userAnime doesn't exist, results[i] stays nil (which is correct)
-				}
-			}
-		}
-		
-		l.batchResult = results
-		l.batchError = errors
-		
-		// Notify all waiting goroutines
-		for _, ch := range channels {
-			close(ch)
-		}
-	})
-}
\ No newline at end of file
@@ -0,0 +1,31 @@
+package dataloader
+
+import (
+	"context"
+
+	user_anime_repo "github.com/weeb-vip/list-service/internal/db/repositories/user_anime"
+	"github.com/weeb-vip/list-service/internal/services/user_anime"
+)
+
+// UserAnimeByIDLoader loads entries by id. Entries are returned whoever owns
+// them, callers check the owner and visibility.
+type UserAnimeByIDLoader = Loader[string, *user_anime_repo.UserAnime]
+
+// NewUserAnimeByIDLoader returns a loader fetching the entries of a batch in
+// one query
+func NewUserAnimeByIDLoader(userAnimeService user_anime.UserAnimeServiceImpl, opts ...Option) *UserAnimeByIDLoader {
+	return NewLoader(func(ctx context.Context, ids []string) (map[string]*user_anime_repo.UserAnime, error) {
+		userAnimes, err := userAnimeService.FindByIds(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+
+		results := make(map[string]*user_anime_repo.UserAnime, len(userAnimes))
+		for _, userAnime := range userAnimes {
+			results[userAnime.ID] = userAnime
+		}
+
+		// entries that do not exist stay nil
+		return results, nil
+	}, opts...)
+}
@@ -0,0 +1,31 @@
+package dataloader
+
+import (
+	"context"
+
+	"github.com/weeb-vip/list-service/internal/db/repositories/user_list"
+	user_list_service "github.com/weeb-vip/list-service/internal/services/user_list"
+)
+
+// UserListLoader loads lists by id. Lists are returned whoever owns them,
+// callers check the owner and visibility.
+type UserListLoader = Loader[string, *user_list.UserList]
+
+// NewUserListLoader returns a loader fetching the lists of a batch in one
+// query
+func NewUserListLoader(userListService user_list_service.UserListServiceImpl, opts ...Option) *UserListLoader {
+	return NewLoader(func(ctx context.Context, ids []string) (map[string]*user_list.UserList, error) {
+		userLists, err := userListService.FindByIds(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+
+		results := make(map[string]*user_list.UserList, len(userLists))
+		for _, userList := range userLists {
+			results[userList.ID] = userList
+		}
+
+		// lists that do not exist stay nil
+		return results, nil
+	}, opts...)
+}
@@ -1,51 +1,136 @@
 package db
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
+
 	"github.com/weeb-vip/list-service/config"
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"time"
 )
 
 type DB struct {
-	DB *gorm.DB
+	DB       *gorm.DB
+	replicas *replicaSet
+	stats    *poolStats
 }
 
-func NewDatabase(cfg config.DBConfig) *DB {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local&tls=%s&interpolateParams=true&multiStatements=true", cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.DataBase, cfg.SSLMode)
+// NewDatabase connects to the configured primary and registers the tracing
+// and metrics plugins and any replicas. It fails when the primary cannot be
+// reached.
+func NewDatabase(cfg config.DBConfig) (*DB, error) {
+	dialector, err := Dialector(cfg)
+	if err != nil {
+		return nil, err
+	}
 
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(dialector, gormConfig(cfg))
 	if err != nil {
-		panic("failed to connect database")
+		return nil, fmt.Errorf("failed to connect database: %w", err)
 	}
 
 	sqlDB, err := db.DB()
 	if err != nil {
-		panic("failed to get database connection")
+		return nil, fmt.Errorf("failed to get database connection: %w", err)
+	}
+
+	// Automatic pings are disabled so replicas may be down at startup, the
+	// primary still has to be up
+	if err := sqlDB.Ping(); err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("failed to connect database: %w", err)
+	}
+
+	configurePool(sqlDB, cfg)
+
+	// Add tracing plugin
+	err = db.Use(&TracingPlugin{})
+	if err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("failed to register tracing plugin: %w", err)
+	}
+
+	// Add metrics plugin
+	err = db.Use(&MetricsPlugin{SlowThreshold: time.Duration(cfg.SlowQueryMs) * time.Millisecond})
+	if err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("failed to register metrics plugin: %w", err)
+	}
+
+	// Route reads to replicas when any are configured
+	replicas, err := registerReplicas(cfg, db)
+	if err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+
+	pools := []namedPool{{name: "primary", conn: sqlDB}}
+	if replicas != nil {
+		for _, r := range replicas.replicas {
+			pools = append(pools, namedPool{name: r.name, conn: r.conn})
+		}
 	}
+	stats := startPoolStats(pools, time.Duration(cfg.StatsIntervalMs)*time.Millisecond)
+
+	return &DB{DB: db, replicas: replicas, stats: stats}, nil
+}
 
+// configurePool sizes a connection pool from cfg
+func configurePool(sqlDB *sql.DB, cfg config.DBConfig) {
 	// Set maximum number of open connections
 	// This prevents too many connections to the database
-	sqlDB.SetMaxOpenConns(25)
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
 
 	// Set maximum number of idle connections
 	// This maintains a pool of reusable connections
-	sqlDB.SetMaxIdleConns(10)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
 
 	// Set maximum lifetime of a connection
 	// MySQL wait_timeout is typically 8 hours, so we set this lower
-	sqlDB.SetConnMaxLifetime(5 * time.Minute)
+	sqlDB.SetConnMaxLifetime(time.Duration(cfg.ConnMaxLifetimeMs) * time.Millisecond)
 
 	// Set maximum idle time for a connection
 	// This helps clean up idle connections
-	sqlDB.SetConnMaxIdleTime(90 * time.Second)
+	sqlDB.SetConnMaxIdleTime(time.Duration(cfg.ConnMaxIdleTimeMs) * time.Millisecond)
+}
 
-	// Add tracing plugin
-	err = db.Use(&TracingPlugin{})
+// Ping checks that the primary answers
+func (d *DB) Ping(ctx context.Context) error {
+	sqlDB, err := d.DB.DB()
+	if err != nil {
+		return err
+	}
+
+	return sqlDB.PingContext(ctx)
+}
+
+// ReadYourWrites keeps a user's reads on the primary for a short window after
+// they write. userKey returns the user making the request in ctx, or "" when
+// there is none.
+func (d *DB) ReadYourWrites(userKey func(ctx context.Context) string) {
+	if d.replicas != nil {
+		d.replicas.SetUserKey(userKey)
+	}
+}
+
+// Close stops the replica health checks and pool stats and closes every
+// connection
+func (d *DB) Close() error {
+	if d.stats != nil {
+		d.stats.Close()
+	}
+
+	if d.replicas != nil {
+		if err := d.replicas.Close(); err != nil {
+			return err
+		}
+	}
+
+	sqlDB, err := d.DB.DB()
 	if err != nil {
-		panic("failed to register tracing plugin")
+		return err
 	}
 
-	return &DB{DB: db}
+	return sqlDB.Close()
 }
@@ -0,0 +1,57 @@
+// Package dbtest provides migrated databases for repository tests.
+package dbtest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/weeb-vip/list-service/config"
+	migrations "github.com/weeb-vip/list-service/db"
+	"github.com/weeb-vip/list-service/internal/db"
+)
+
+// NewSQLite returns a database migrated to the latest version, stored in a
+// file that is removed when the test ends
+func NewSQLite(t testing.TB) *db.DB {
+	t.Helper()
+
+	return NewSQLiteWithConfig(t, SQLiteConfig(filepath.Join(t.TempDir(), "list-service.db")))
+}
+
+// SQLiteConfig returns the config of a SQLite database stored at path
+func SQLiteConfig(path string) config.DBConfig {
+	return config.DBConfig{
+		Driver:             db.DriverSQLite,
+		DataBase:           path,
+		MigrationTableName: "__migrations_list-service",
+		MaxOpenConns:       25,
+		MaxIdleConns:       10,
+		StatsIntervalMs:    15000,
+	}
+}
+
+// NewSQLiteWithConfig returns a database opened with cfg and migrated to the
+// latest version, closed when the test ends
+func NewSQLiteWithConfig(t testing.TB, cfg config.DBConfig) *db.DB {
+	t.Helper()
+
+	database, err := db.NewDatabase(cfg)
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+
+	migration, err := migrations.NewMigration(cfg, database)
+	if err != nil {
+		t.Fatalf("creating migration: %v", err)
+	}
+	if err := migration.Up(); err != nil && err != migrate.ErrNoChange {
+		t.Fatalf("migrating: %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = database.Close()
+	})
+
+	return database
+}
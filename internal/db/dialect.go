@@ -0,0 +1,92 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/weeb-vip/list-service/config"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Supported values of DBConfig.Driver, matching the gorm dialector names
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// sqlitePragmas wait on locks held by other connections instead of failing,
+// and take the write lock when a transaction starts so it never has to be
+// upgraded halfway through
+var sqlitePragmas = url.Values{
+	"_pragma":      {"busy_timeout(5000)", "journal_mode(WAL)", "foreign_keys(1)"},
+	"_txlock":      {"immediate"},
+	"_time_format": {"sqlite"},
+}
+
+// Dialector returns the gorm dialector for the configured driver
+func Dialector(cfg config.DBConfig) (gorm.Dialector, error) {
+	switch cfg.Driver {
+	case DriverMySQL, "":
+		dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local&tls=%s&interpolateParams=true&multiStatements=true", cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.DataBase, cfg.SSLMode)
+		return mysql.Open(dsn), nil
+	case DriverPostgres:
+		dsn := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(cfg.User, cfg.Password),
+			Host:     fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+			Path:     cfg.DataBase,
+			RawQuery: url.Values{"sslmode": {postgresSSLMode(cfg.SSLMode)}}.Encode(),
+		}
+		return postgres.Open(dsn.String()), nil
+	case DriverSQLite:
+		// the database name is the path of the database file
+		return sqlite.Open(cfg.DataBase + "?" + sqlitePragmas.Encode()), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", cfg.Driver)
+	}
+}
+
+// postgresSSLMode maps the MySQL style true/false DBSSL values onto sslmode,
+// anything else is passed through
+func postgresSSLMode(sslMode string) string {
+	switch sslMode {
+	case "false", "":
+		return "disable"
+	case "true":
+		return "require"
+	default:
+		return sslMode
+	}
+}
+
+// gormConfig returns the gorm settings for the configured driver
+func gormConfig(cfg config.DBConfig) *gorm.Config {
+	// the resolver opens replicas with these settings, NewDatabase pings the
+	// primary itself
+	gormCfg := &gorm.Config{DisableAutomaticPing: true}
+	if cfg.Driver == DriverSQLite {
+		// SQLite compares timestamps as text, so they all have to be written
+		// in the same zone
+		gormCfg.NowFunc = func() time.Time { return time.Now().UTC() }
+	}
+
+	return gormCfg
+}
+
+// InSet matches rows whose comma separated column contains value
+func InSet(tx *gorm.DB, column string, value string) clause.Expr {
+	switch tx.Dialector.Name() {
+	case DriverPostgres:
+		return gorm.Expr("? = ANY(string_to_array("+column+", ','))", value)
+	case DriverSQLite:
+		return gorm.Expr("instr(',' || "+column+" || ',', ',' || ? || ',') > 0", value)
+	default:
+		return gorm.Expr("FIND_IN_SET(?, "+column+") > 0", value)
+	}
+}
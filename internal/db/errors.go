@@ -0,0 +1,41 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/glebarez/go-sqlite"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrVersionConflict is wrapped by repository errors raised when a write was
+// based on a version of a row that has since been changed.
+var ErrVersionConflict = errors.New("version conflict")
+
+// mysqlDuplicateEntry is ER_DUP_ENTRY
+const mysqlDuplicateEntry = 1062
+
+// postgresUniqueViolation is the unique_violation SQLSTATE
+const postgresUniqueViolation = "23505"
+
+// SQLITE_CONSTRAINT_PRIMARYKEY and SQLITE_CONSTRAINT_UNIQUE
+const (
+	sqlitePrimaryKeyViolation = 1555
+	sqliteUniqueViolation     = 2067
+)
+
+// IsDuplicateKey reports whether err is a unique key violation
+func IsDuplicateKey(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == mysqlDuplicateEntry
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code() == sqliteUniqueViolation || sqliteErr.Code() == sqlitePrimaryKeyViolation
+	}
+
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == postgresUniqueViolation
+}
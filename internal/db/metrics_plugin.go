@@ -0,0 +1,135 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	metrics_lib "github.com/weeb-vip/go-metrics-lib"
+	"github.com/weeb-vip/list-service/internal/logger"
+	"github.com/weeb-vip/list-service/metrics"
+	"gorm.io/gorm"
+)
+
+const (
+	callbackMetricsBeforeCreate = "metrics:before_create"
+	callbackMetricsAfterCreate  = "metrics:after_create"
+	callbackMetricsBeforeQuery  = "metrics:before_query"
+	callbackMetricsAfterQuery   = "metrics:after_query"
+	callbackMetricsBeforeUpdate = "metrics:before_update"
+	callbackMetricsAfterUpdate  = "metrics:after_update"
+	callbackMetricsBeforeDelete = "metrics:before_delete"
+	callbackMetricsAfterDelete  = "metrics:after_delete"
+	callbackMetricsBeforeRow    = "metrics:before_row"
+	callbackMetricsAfterRow     = "metrics:after_row"
+	callbackMetricsBeforeRaw    = "metrics:before_raw"
+	callbackMetricsAfterRaw     = "metrics:after_raw"
+
+	metricsStartKey = "metrics:start"
+
+	// methodRaw labels statements run with Exec, which may be of any kind
+	methodRaw = "raw"
+)
+
+// MetricsPlugin records the duration and rows affected of every statement by
+// table and operation, and logs statements slower than SlowThreshold. A
+// record that is not found is not counted as an error.
+type MetricsPlugin struct {
+	// SlowThreshold is the duration from which statements are logged, 0
+	// turns slow query logging off
+	SlowThreshold time.Duration
+}
+
+func (mp *MetricsPlugin) Name() string {
+	return "MetricsPlugin"
+}
+
+func (mp *MetricsPlugin) Initialize(db *gorm.DB) error {
+	// Register callbacks for Create operations
+	err := db.Callback().Create().Before("gorm:create").Register(callbackMetricsBeforeCreate, mp.before)
+	if err == nil {
+		err = db.Callback().Create().After("gorm:create").Register(callbackMetricsAfterCreate, mp.after(metrics_lib.DatabaseMetricMethodInsert))
+	}
+
+	// Register callbacks for Query operations
+	if err == nil {
+		err = db.Callback().Query().Before("gorm:query").Register(callbackMetricsBeforeQuery, mp.before)
+	}
+	if err == nil {
+		err = db.Callback().Query().After("gorm:query").Register(callbackMetricsAfterQuery, mp.after(metrics_lib.DatabaseMetricMethodSelect))
+	}
+
+	// Register callbacks for Update operations
+	if err == nil {
+		err = db.Callback().Update().Before("gorm:update").Register(callbackMetricsBeforeUpdate, mp.before)
+	}
+	if err == nil {
+		err = db.Callback().Update().After("gorm:update").Register(callbackMetricsAfterUpdate, mp.after(metrics_lib.DatabaseMetricMethodUpdate))
+	}
+
+	// Register callbacks for Delete operations
+	if err == nil {
+		err = db.Callback().Delete().Before("gorm:delete").Register(callbackMetricsBeforeDelete, mp.before)
+	}
+	if err == nil {
+		err = db.Callback().Delete().After("gorm:delete").Register(callbackMetricsAfterDelete, mp.after(metrics_lib.DatabaseMetricMethodDelete))
+	}
+
+	// Register callbacks for Row and Raw operations, used by Scan and Exec
+	if err == nil {
+		err = db.Callback().Row().Before("gorm:row").Register(callbackMetricsBeforeRow, mp.before)
+	}
+	if err == nil {
+		err = db.Callback().Row().After("gorm:row").Register(callbackMetricsAfterRow, mp.after(metrics_lib.DatabaseMetricMethodSelect))
+	}
+	if err == nil {
+		err = db.Callback().Raw().Before("gorm:raw").Register(callbackMetricsBeforeRaw, mp.before)
+	}
+	if err == nil {
+		err = db.Callback().Raw().After("gorm:raw").Register(callbackMetricsAfterRaw, mp.after(methodRaw))
+	}
+
+	return err
+}
+
+func (mp *MetricsPlugin) before(db *gorm.DB) {
+	db.InstanceSet(metricsStartKey, time.Now())
+}
+
+func (mp *MetricsPlugin) after(method string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(metricsStartKey)
+		if !ok {
+			return
+		}
+		startTime, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+		elapsed := time.Since(startTime)
+
+		table := db.Statement.Table
+		if table == "" {
+			table = methodRaw
+		}
+
+		result := metrics.Success
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			result = metrics.Error
+		}
+
+		appMetrics := metrics.GetAppMetrics()
+		appMetrics.DatabaseMetric(float64(elapsed.Milliseconds()), table, method, result)
+		appMetrics.DatabaseRowsMetric(table, method, db.RowsAffected)
+
+		if mp.SlowThreshold > 0 && elapsed >= mp.SlowThreshold {
+			log := logger.FromCtx(db.Statement.Context)
+			log.Warn().
+				Str("table", table).
+				Str("operation", method).
+				Dur("duration", elapsed).
+				Int64("rows", db.RowsAffected).
+				Str("sql", db.Statement.SQL.String()).
+				Msg("Slow query")
+		}
+	}
+}
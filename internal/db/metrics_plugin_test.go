@@ -0,0 +1,63 @@
+package db_test
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weeb-vip/list-service/internal/db"
+	"github.com/weeb-vip/list-service/internal/logger"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+type metricsRow struct {
+	ID   int
+	Name string
+}
+
+func TestMetricsPluginLogsSlowQueries(t *testing.T) {
+	open := func(t *testing.T, threshold time.Duration) *gorm.DB {
+		gormDB, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "metrics.db")), &gorm.Config{Logger: gormlogger.Discard})
+		require.NoError(t, err)
+		require.NoError(t, gormDB.Use(&db.MetricsPlugin{SlowThreshold: threshold}))
+		require.NoError(t, gormDB.AutoMigrate(&metricsRow{}))
+		t.Cleanup(func() {
+			sqlDB, _ := gormDB.DB()
+			sqlDB.Close()
+		})
+
+		return gormDB
+	}
+
+	t.Run("statements over the threshold are logged", func(t *testing.T) {
+		var out bytes.Buffer
+		ctx := logger.WithCtx(context.Background(), zerolog.New(&out))
+		gormDB := open(t, time.Nanosecond)
+
+		require.NoError(t, gormDB.WithContext(ctx).Create(&metricsRow{ID: 1, Name: "one"}).Error)
+
+		assert.Contains(t, out.String(), `"message":"Slow query"`)
+		assert.Contains(t, out.String(), `"table":"metrics_rows"`)
+		assert.Contains(t, out.String(), `"operation":"insert"`)
+		assert.NotContains(t, out.String(), `"one"`)
+	})
+
+	t.Run("a zero threshold logs nothing", func(t *testing.T) {
+		var out bytes.Buffer
+		ctx := logger.WithCtx(context.Background(), zerolog.New(&out))
+		gormDB := open(t, 0)
+
+		require.NoError(t, gormDB.WithContext(ctx).Create(&metricsRow{ID: 1, Name: "one"}).Error)
+		var row metricsRow
+		assert.ErrorIs(t, gormDB.WithContext(ctx).First(&row, 2).Error, gorm.ErrRecordNotFound)
+
+		assert.Empty(t, out.String())
+	})
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/weeb-vip/list-service/metrics"
+)
+
+// namedPool is a connection pool and the label its stats are published with
+type namedPool struct {
+	name string
+	conn *sql.DB
+}
+
+// poolStats publishes the connection pool stats of the primary and every
+// replica as gauges until Close is called
+type poolStats struct {
+	pools    []namedPool
+	interval time.Duration
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// startPoolStats publishes the stats of pools straight away and then every
+// interval
+func startPoolStats(pools []namedPool, interval time.Duration) *poolStats {
+	stats := &poolStats{
+		pools:    pools,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	stats.publish()
+	go stats.run()
+
+	return stats
+}
+
+func (p *poolStats) run() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.publish()
+		}
+	}
+}
+
+func (p *poolStats) publish() {
+	appMetrics := metrics.GetAppMetrics()
+	for _, pool := range p.pools {
+		appMetrics.DatabasePoolMetric(pool.name, pool.conn.Stats())
+	}
+}
+
+// Close stops publishing, it does not close the pools
+func (p *poolStats) Close() {
+	close(p.stop)
+	<-p.done
+}
@@ -0,0 +1,371 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/weeb-vip/list-service/config"
+	"github.com/weeb-vip/list-service/internal/logger"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+const callbackMarkWrite = "replicas:mark_write"
+
+// replica is a read replica and whether it is currently serving reads
+type replica struct {
+	name    string
+	conn    *sql.DB
+	healthy atomic.Bool
+}
+
+// replicaSet routes reads to healthy replicas. Replicas are ejected while
+// they are unreachable or lag the primary by more than maxLag, and a user's
+// reads stay on the primary for a short window after they write so they see
+// their own changes. Writes are remembered in this process only, other
+// instances of the service do not know about them.
+type replicaSet struct {
+	driver   string
+	replicas []*replica
+	maxLag   time.Duration
+	interval time.Duration
+	sticky   time.Duration
+
+	userKey atomic.Value
+	mu      sync.Mutex
+	writes  map[string]time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// registerReplicas opens the configured replicas and installs the resolver
+// on db. It returns nil when no replicas are configured.
+func registerReplicas(cfg config.DBConfig, db *gorm.DB) (*replicaSet, error) {
+	var dsns []string
+	for _, dsn := range strings.Split(cfg.Replicas, ",") {
+		if dsn = strings.TrimSpace(dsn); dsn != "" {
+			dsns = append(dsns, dsn)
+		}
+	}
+	if len(dsns) == 0 {
+		return nil, nil
+	}
+
+	set := &replicaSet{
+		driver:   db.Dialector.Name(),
+		maxLag:   time.Duration(cfg.ReplicaMaxLagMs) * time.Millisecond,
+		interval: time.Duration(cfg.ReplicaCheckIntervalMs) * time.Millisecond,
+		sticky:   time.Duration(cfg.ReadYourWritesMs) * time.Millisecond,
+		writes:   map[string]time.Time{},
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	dialectors := make([]gorm.Dialector, 0, len(dsns))
+	for i, dsn := range dsns {
+		conn, dialector, err := openReplica(set.driver, dsn)
+		if err != nil {
+			set.closeReplicas()
+			return nil, fmt.Errorf("failed to open replica %d: %w", i, err)
+		}
+		configurePool(conn, cfg)
+
+		set.replicas = append(set.replicas, &replica{name: fmt.Sprintf("replica-%d", i), conn: conn})
+		dialectors = append(dialectors, dialector)
+	}
+
+	err := db.Use(dbresolver.Register(dbresolver.Config{
+		Replicas: dialectors,
+		Policy:   set,
+	}))
+	if err != nil {
+		set.closeReplicas()
+		return nil, err
+	}
+
+	err = db.Callback().Create().After("gorm:create").Register(callbackMarkWrite, set.markWrite)
+	if err == nil {
+		err = db.Callback().Update().After("gorm:update").Register(callbackMarkWrite, set.markWrite)
+	}
+	if err == nil {
+		err = db.Callback().Delete().After("gorm:delete").Register(callbackMarkWrite, set.markWrite)
+	}
+	if err == nil {
+		err = db.Callback().Raw().After("gorm:raw").Register(callbackMarkWrite, set.markWrite)
+	}
+	if err != nil {
+		set.closeReplicas()
+		return nil, err
+	}
+
+	// check once up front so healthy replicas serve reads straight away,
+	// until then every read goes to the primary
+	set.checkAll()
+	go set.run()
+
+	return set, nil
+}
+
+// openReplica connects to a replica without pinging it, so a replica that is
+// down at startup is ejected by the health checks instead of failing the
+// service. The returned dialector reuses the connection so the resolver and
+// the health checks share one pool.
+func openReplica(driver string, dsn string) (*sql.DB, gorm.Dialector, error) {
+	var dialector gorm.Dialector
+	switch driver {
+	case DriverMySQL:
+		dialector = mysql.New(mysql.Config{DSN: dsn, SkipInitializeWithVersion: true})
+	case DriverPostgres:
+		dialector = postgres.Open(dsn)
+	case DriverSQLite:
+		dialector = sqlite.Open(dsn)
+	default:
+		return nil, nil, fmt.Errorf("unsupported database driver %q", driver)
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conn, err := db.DB()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch driver {
+	case DriverMySQL:
+		return conn, mysql.New(mysql.Config{Conn: conn, SkipInitializeWithVersion: true}), nil
+	case DriverPostgres:
+		return conn, postgres.New(postgres.Config{Conn: conn}), nil
+	default:
+		return conn, &sqlite.Dialector{Conn: conn}, nil
+	}
+}
+
+// Resolve picks a random healthy replica. Reads are sent to the primary
+// before they get here when none is healthy, so the fallback to any replica
+// only covers one being ejected in between.
+func (s *replicaSet) Resolve(connPools []gorm.ConnPool) gorm.ConnPool {
+	healthy := make([]gorm.ConnPool, 0, len(connPools))
+	for i, connPool := range connPools {
+		if i < len(s.replicas) && s.replicas[i].healthy.Load() {
+			healthy = append(healthy, connPool)
+		}
+	}
+
+	if len(healthy) == 0 {
+		return connPools[rand.Intn(len(connPools))]
+	}
+
+	return healthy[rand.Intn(len(healthy))]
+}
+
+// SetUserKey sets how the user making a request is found in its context,
+// users are kept on the primary after they write
+func (s *replicaSet) SetUserKey(userKey func(ctx context.Context) string) {
+	s.userKey.Store(userKey)
+}
+
+func (s *replicaSet) user(ctx context.Context) string {
+	userKey, ok := s.userKey.Load().(func(ctx context.Context) string)
+	if !ok || ctx == nil {
+		return ""
+	}
+
+	return userKey(ctx)
+}
+
+// markWrite starts the read-your-writes window for the user who made a write,
+// once the transaction it ran in has committed
+func (s *replicaSet) markWrite(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Context == nil {
+		return
+	}
+
+	user := s.user(db.Statement.Context)
+	if user == "" {
+		return
+	}
+
+	AfterCommit(db.Statement.Context, func() {
+		s.mu.Lock()
+		s.writes[user] = time.Now()
+		s.mu.Unlock()
+	})
+}
+
+// usePrimary reports whether reads made with ctx have to go to the primary,
+// either because the user wrote recently or no replica is healthy
+func (s *replicaSet) usePrimary(ctx context.Context) bool {
+	if user := s.user(ctx); user != "" {
+		s.mu.Lock()
+		wroteAt, ok := s.writes[user]
+		s.mu.Unlock()
+
+		if ok && time.Since(wroteAt) < s.sticky {
+			return true
+		}
+	}
+
+	for _, r := range s.replicas {
+		if r.healthy.Load() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// run checks the replicas until Close is called
+func (s *replicaSet) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.checkAll()
+			s.forgetWrites()
+		}
+	}
+}
+
+// checkAll ejects replicas that are unreachable or lagging and restores ones
+// that have caught up
+func (s *replicaSet) checkAll() {
+	log := logger.Get()
+
+	for _, r := range s.replicas {
+		ctx, cancel := context.WithTimeout(context.Background(), s.interval)
+		lag, err := replicaLag(ctx, s.driver, r.conn)
+		cancel()
+
+		healthy := err == nil && lag <= s.maxLag
+		if r.healthy.Swap(healthy) == healthy {
+			continue
+		}
+
+		if healthy {
+			log.Info().Str("replica", r.name).Dur("lag", lag).Msg("Replica restored")
+		} else if err != nil {
+			log.Warn().Err(err).Str("replica", r.name).Msg("Replica ejected, health check failed")
+		} else {
+			log.Warn().Str("replica", r.name).Dur("lag", lag).Dur("max_lag", s.maxLag).Msg("Replica ejected, lagging behind the primary")
+		}
+	}
+}
+
+// forgetWrites drops users whose read-your-writes window has passed
+func (s *replicaSet) forgetWrites() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for user, wroteAt := range s.writes {
+		if time.Since(wroteAt) >= s.sticky {
+			delete(s.writes, user)
+		}
+	}
+}
+
+// Close stops the health checks and closes the replica connections
+func (s *replicaSet) Close() error {
+	close(s.stop)
+	<-s.done
+
+	return s.closeReplicas()
+}
+
+func (s *replicaSet) closeReplicas() error {
+	var firstErr error
+	for _, r := range s.replicas {
+		if err := r.conn.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// replicaLag returns how far a replica is behind its primary. A replica that
+// is not replicating, such as a SQLite copy, reports no lag once it answers.
+func replicaLag(ctx context.Context, driver string, conn *sql.DB) (time.Duration, error) {
+	switch driver {
+	case DriverMySQL:
+		return mysqlReplicaLag(ctx, conn)
+	case DriverPostgres:
+		var seconds float64
+		err := conn.QueryRowContext(ctx, `SELECT CASE
+			WHEN NOT pg_is_in_recovery() THEN 0
+			WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+			ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+		END`).Scan(&seconds)
+		if err != nil {
+			return 0, err
+		}
+
+		return time.Duration(seconds * float64(time.Second)), nil
+	default:
+		return 0, conn.PingContext(ctx)
+	}
+}
+
+// mysqlReplicaLag reads Seconds_Behind_Source from SHOW REPLICA STATUS, which
+// is NULL while replication is stopped
+func mysqlReplicaLag(ctx context.Context, conn *sql.DB) (time.Duration, error) {
+	rows, err := conn.QueryContext(ctx, "SHOW REPLICA STATUS")
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return 0, rows.Err()
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return 0, err
+	}
+
+	for i, column := range columns {
+		if column != "Seconds_Behind_Source" && column != "Seconds_Behind_Master" {
+			continue
+		}
+		if !values[i].Valid {
+			return 0, fmt.Errorf("replication is not running")
+		}
+
+		var seconds int64
+		if _, err := fmt.Sscan(values[i].String, &seconds); err != nil {
+			return 0, err
+		}
+
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	return 0, fmt.Errorf("replica status has no lag column")
+}
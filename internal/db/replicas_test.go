@@ -0,0 +1,108 @@
+package db_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weeb-vip/list-service/internal/db"
+	"github.com/weeb-vip/list-service/internal/db/dbtest"
+	"github.com/weeb-vip/list-service/internal/db/repositories/user_list"
+	"gorm.io/plugin/dbresolver"
+)
+
+type userKey struct{}
+
+func userFromContext(ctx context.Context) string {
+	user, _ := ctx.Value(userKey{}).(string)
+	return user
+}
+
+// newReplicated returns a primary whose reads go to a separate database that
+// never receives its writes, so the tests can tell where a read was served
+func newReplicated(t *testing.T) (*db.DB, *db.DB) {
+	dir := t.TempDir()
+	replicaPath := filepath.Join(dir, "replica.db")
+	replica := dbtest.NewSQLiteWithConfig(t, dbtest.SQLiteConfig(replicaPath))
+
+	cfg := dbtest.SQLiteConfig(filepath.Join(dir, "primary.db"))
+	cfg.Replicas = replicaPath + "?_pragma=busy_timeout(5000)"
+	cfg.ReplicaMaxLagMs = 1000
+	cfg.ReplicaCheckIntervalMs = 50
+	cfg.ReadYourWritesMs = 200
+	primary := dbtest.NewSQLiteWithConfig(t, cfg)
+	primary.ReadYourWrites(userFromContext)
+
+	return primary, replica
+}
+
+func createList(t *testing.T, ctx context.Context, database *db.DB, repository user_list.UserListRepositoryImpl, userId string) {
+	name := "Favourites"
+	err := database.Transaction(ctx, func(ctx context.Context) error {
+		_, err := repository.Upsert(ctx, &user_list.UserList{UserID: &userId, Name: &name})
+		return err
+	})
+	require.NoError(t, err)
+}
+
+func TestReplicaRouting(t *testing.T) {
+	primary, replica := newReplicated(t)
+	repository := user_list.NewUserListRepository(primary)
+
+	t.Run("writes go to the primary and reads to the replica", func(t *testing.T) {
+		ctx := context.Background()
+		createList(t, ctx, primary, repository, "user_1")
+
+		lists, err := repository.FindByUserId(ctx, "user_1")
+		require.NoError(t, err)
+		assert.Empty(t, lists)
+
+		lists, err = user_list.NewUserListRepository(replica).FindByUserId(ctx, "user_1")
+		require.NoError(t, err)
+		assert.Empty(t, lists)
+
+		var count int64
+		require.NoError(t, primary.DB.Clauses(dbresolver.Write).Table("user_list").Where("user_id = ?", "user_1").Count(&count).Error)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("users read their own writes from the primary for a while", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), userKey{}, "user_2")
+		createList(t, ctx, primary, repository, "user_2")
+
+		lists, err := repository.FindByUserId(ctx, "user_2")
+		require.NoError(t, err)
+		assert.Len(t, lists, 1)
+
+		otherUser := context.WithValue(context.Background(), userKey{}, "user_3")
+		lists, err = repository.FindByUserId(otherUser, "user_2")
+		require.NoError(t, err)
+		assert.Empty(t, lists)
+
+		assert.Eventually(t, func() bool {
+			lists, err := repository.FindByUserId(ctx, "user_2")
+			return err == nil && len(lists) == 0
+		}, 2*time.Second, 20*time.Millisecond)
+	})
+
+	t.Run("rolled back writes do not pin the user", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), userKey{}, "user_4")
+		_ = primary.Transaction(ctx, func(ctx context.Context) error {
+			_, err := repository.Upsert(ctx, &user_list.UserList{UserID: ptr("user_4"), Name: ptr("Dropped")})
+			require.NoError(t, err)
+			return assert.AnError
+		})
+
+		createList(t, context.Background(), primary, repository, "user_4")
+		lists, err := repository.FindByUserId(ctx, "user_4")
+		require.NoError(t, err)
+		assert.Empty(t, lists)
+	})
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
@@ -0,0 +1,23 @@
+package backfill
+
+import (
+	"time"
+)
+
+// Checkpoint is how far a backfill got, saved after every committed batch
+type Checkpoint struct {
+	Name string `gorm:"column:name;primaryKey" json:"name"`
+	// LastID is the primary key of the last row processed, the next batch
+	// starts after it
+	LastID      string     `gorm:"column:last_id" json:"last_id"`
+	Scanned     int64      `gorm:"column:scanned" json:"scanned"`
+	Changed     int64      `gorm:"column:changed" json:"changed"`
+	CompletedAt *time.Time `gorm:"column:completed_at" json:"completed_at"`
+	CreatedAt   time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+// set table name
+func (Checkpoint) TableName() string {
+	return "backfill_checkpoint"
+}
@@ -0,0 +1,74 @@
+package backfill
+
+import (
+	"context"
+	"errors"
+
+	"github.com/weeb-vip/list-service/internal/db"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BackfillRepositoryImpl interface {
+	// FindCheckpoint returns the saved progress of a backfill, nil when it has
+	// never run
+	FindCheckpoint(ctx context.Context, name string) (*Checkpoint, error)
+	// FindCheckpoints returns the progress of every backfill that has run
+	FindCheckpoints(ctx context.Context) ([]*Checkpoint, error)
+	// SaveCheckpoint creates or overwrites the progress of a backfill
+	SaveCheckpoint(ctx context.Context, checkpoint *Checkpoint) error
+	// DeleteCheckpoint forgets a backfill's progress so it starts over
+	DeleteCheckpoint(ctx context.Context, name string) error
+}
+
+type BackfillRepository struct {
+	db *db.DB
+}
+
+func NewBackfillRepository(db *db.DB) BackfillRepositoryImpl {
+	return &BackfillRepository{db: db}
+}
+
+func (a *BackfillRepository) FindCheckpoint(ctx context.Context, name string) (*Checkpoint, error) {
+	var checkpoint Checkpoint
+	err := a.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Where("name = ?", name).First(&checkpoint).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if err != nil {
+		return nil, nil
+	}
+	return &checkpoint, nil
+}
+
+func (a *BackfillRepository) FindCheckpoints(ctx context.Context) ([]*Checkpoint, error) {
+	var checkpoints []*Checkpoint
+	err := a.db.WithContext(ctx).Order("name asc").Find(&checkpoints).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return checkpoints, nil
+}
+
+func (a *BackfillRepository) SaveCheckpoint(ctx context.Context, checkpoint *Checkpoint) error {
+	err := a.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"last_id", "scanned", "changed", "completed_at", "updated_at"}),
+	}).Create(checkpoint).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (a *BackfillRepository) DeleteCheckpoint(ctx context.Context, name string) error {
+	err := a.db.WithContext(ctx).Where("name = ?", name).Delete(&Checkpoint{}).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
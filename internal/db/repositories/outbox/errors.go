@@ -0,0 +1,8 @@
+package outbox
+
+import "errors"
+
+// ErrFailed, wrapped in the error returned by the function handed to
+// ProcessUnsent, gives up on the message. It is marked failed and kept, but
+// no longer published.
+var ErrFailed = errors.New("outbox message failed")
@@ -0,0 +1,63 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/weeb-vip/list-service/internal/events"
+	"github.com/weeb-vip/list-service/tracing"
+)
+
+// OutboxMessage is an event waiting to be published. Sequence is assigned by
+// the database and gives the publish order.
+type OutboxMessage struct {
+	Sequence    uint64     `gorm:"column:sequence;primaryKey;autoIncrement" json:"sequence"`
+	EventID     string     `gorm:"column:event_id" json:"event_id"`
+	EventType   string     `gorm:"column:event_type" json:"event_type"`
+	AggregateID string     `gorm:"column:aggregate_id" json:"aggregate_id"`
+	UserID      string     `gorm:"column:user_id" json:"user_id"`
+	Payload     []byte     `gorm:"column:payload" json:"payload"`
+	OccurredAt  time.Time  `gorm:"column:occurred_at" json:"occurred_at"`
+	Attempts    int        `gorm:"column:attempts;default:0" json:"attempts"`
+	LastError   *string    `gorm:"column:last_error" json:"last_error"`
+	SentAt      *time.Time `gorm:"column:sent_at" json:"sent_at"`
+	// ClaimedUntil is set while a relay publishes the message
+	ClaimedUntil *time.Time `gorm:"column:claimed_until" json:"claimed_until"`
+	// FailedAt is set once the relay gave up on publishing the message
+	FailedAt *time.Time `gorm:"column:failed_at" json:"failed_at"`
+	// TraceContext holds the W3C trace headers of the recording request
+	TraceContext []byte    `gorm:"column:trace_context" json:"trace_context"`
+	CreatedAt    time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+}
+
+// set table name
+func (OutboxMessage) TableName() string {
+	return "outbox"
+}
+
+// Event rebuilds the recorded domain event
+func (m *OutboxMessage) Event() *events.Event {
+	return &events.Event{
+		ID:          m.EventID,
+		Type:        events.Type(m.EventType),
+		AggregateID: m.AggregateID,
+		UserID:      m.UserID,
+		OccurredAt:  m.OccurredAt,
+		Payload:     m.Payload,
+	}
+}
+
+// Context returns ctx continuing the trace the event was recorded in
+func (m *OutboxMessage) Context(ctx context.Context) context.Context {
+	if len(m.TraceContext) == 0 {
+		return ctx
+	}
+
+	var headers map[string]string
+	if err := json.Unmarshal(m.TraceContext, &headers); err != nil {
+		return ctx
+	}
+
+	return tracing.ExtractMessage(ctx, headers)
+}
@@ -0,0 +1,164 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/weeb-vip/list-service/internal/db"
+	"github.com/weeb-vip/list-service/internal/events"
+	"github.com/weeb-vip/list-service/tracing"
+	"gorm.io/gorm/clause"
+)
+
+type OutboxRepositoryImpl interface {
+	// Add records an event, joining the transaction carried by ctx if any
+	Add(ctx context.Context, event *events.Event) error
+	// ProcessUnsent claims the oldest unsent messages for lease and hands them
+	// to fn in order, outside of any transaction. Messages are marked sent as
+	// fn succeeds. The first failure is recorded on its message and releases
+	// the rest of the batch, so later messages are not published ahead of it,
+	// unless fn gave up on the message with ErrFailed: it is then marked
+	// failed and the batch goes on. Nothing is claimed while another relay
+	// holds the oldest message. Returns how many messages were sent or failed.
+	ProcessUnsent(ctx context.Context, limit int, lease time.Duration, fn func(ctx context.Context, message *OutboxMessage) error) (int, error)
+	// PruneSent deletes messages sent before before, returning how many.
+	// Failed messages are kept.
+	PruneSent(ctx context.Context, before time.Time) (int64, error)
+}
+
+type OutboxRepository struct {
+	db *db.DB
+}
+
+func NewOutboxRepository(db *db.DB) OutboxRepositoryImpl {
+	return &OutboxRepository{db: db}
+}
+
+func (a *OutboxRepository) Add(ctx context.Context, event *events.Event) error {
+	message := &OutboxMessage{
+		EventID:     event.ID,
+		EventType:   string(event.Type),
+		AggregateID: event.AggregateID,
+		UserID:      event.UserID,
+		Payload:     event.Payload,
+		OccurredAt:  event.OccurredAt,
+	}
+	// keep the trace of the change so the relay can continue it
+	if headers := tracing.InjectMessage(ctx, nil); len(headers) > 0 {
+		traceContext, err := json.Marshal(headers)
+		if err != nil {
+			return err
+		}
+		message.TraceContext = traceContext
+	}
+
+	err := a.db.WithContext(ctx).Create(message).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (a *OutboxRepository) ProcessUnsent(ctx context.Context, limit int, lease time.Duration, fn func(ctx context.Context, message *OutboxMessage) error) (int, error) {
+	messages, err := a.claim(ctx, limit, lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, message := range messages {
+		if fnErr := fn(ctx, message); fnErr != nil {
+			columns := map[string]interface{}{
+				"attempts":   message.Attempts + 1,
+				"last_error": fnErr.Error(),
+			}
+			if errors.Is(fnErr, ErrFailed) {
+				columns["failed_at"] = time.Now()
+				columns["claimed_until"] = nil
+				if err := a.update(ctx, message, columns); err != nil {
+					return i, err
+				}
+				continue
+			}
+
+			if err := a.update(ctx, message, columns); err != nil {
+				return i, err
+			}
+			if err := a.release(ctx, messages[i:]); err != nil {
+				return i, err
+			}
+			return i, fnErr
+		}
+
+		if err := a.update(ctx, message, map[string]interface{}{
+			"attempts":      message.Attempts + 1,
+			"sent_at":       time.Now(),
+			"claimed_until": nil,
+		}); err != nil {
+			return i, err
+		}
+	}
+
+	return len(messages), nil
+}
+
+// claim leases the oldest unsent messages. The row locks only last for the
+// claim itself and serialize relays running in several instances, a relay
+// finding the oldest message claimed by another leaves the batch to it, which
+// keeps the publish order intact.
+func (a *OutboxRepository) claim(ctx context.Context, limit int, lease time.Duration) ([]*OutboxMessage, error) {
+	var messages []*OutboxMessage
+	err := a.db.Transaction(ctx, func(ctx context.Context) error {
+		err := a.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Where("sent_at IS NULL AND failed_at IS NULL").Order("sequence asc").Limit(limit).Find(&messages).Error
+		if err != nil || len(messages) == 0 {
+			return err
+		}
+
+		now := time.Now()
+		if claimedUntil := messages[0].ClaimedUntil; claimedUntil != nil && claimedUntil.After(now) {
+			messages = nil
+			return nil
+		}
+
+		return a.db.WithContext(ctx).Model(&OutboxMessage{}).Where("sequence IN ?", sequences(messages)).Update("claimed_until", now.Add(lease)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// release hands claimed messages back to be picked up by the next batch
+func (a *OutboxRepository) release(ctx context.Context, messages []*OutboxMessage) error {
+	return a.db.WithContext(ctx).Model(&OutboxMessage{}).Where("sequence IN ? AND sent_at IS NULL", sequences(messages)).Update("claimed_until", nil).Error
+}
+
+func (a *OutboxRepository) PruneSent(ctx context.Context, before time.Time) (int64, error) {
+	result := a.db.WithContext(ctx).Where("sent_at < ?", before).Delete(&OutboxMessage{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
+
+func sequences(messages []*OutboxMessage) []uint64 {
+	sequences := make([]uint64, len(messages))
+	for i, message := range messages {
+		sequences[i] = message.Sequence
+	}
+
+	return sequences
+}
+
+func (a *OutboxRepository) update(ctx context.Context, message *OutboxMessage, columns map[string]interface{}) error {
+	err := a.db.WithContext(ctx).Model(message).Updates(columns).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weeb-vip/list-service/internal/db/dbtest"
+	"github.com/weeb-vip/list-service/internal/db/repositories/outbox"
+	"github.com/weeb-vip/list-service/internal/events"
+)
+
+func addEvents(t *testing.T, repository outbox.OutboxRepositoryImpl, aggregateIDs ...string) {
+	for _, aggregateID := range aggregateIDs {
+		event, err := events.New(events.UserAnimeAdded, aggregateID, "user_1", map[string]string{"id": aggregateID})
+		require.NoError(t, err)
+		require.NoError(t, repository.Add(context.Background(), event))
+	}
+}
+
+func TestOutboxRepository(t *testing.T) {
+	ctx := context.Background()
+	repository := outbox.NewOutboxRepository(dbtest.NewSQLite(t))
+	addEvents(t, repository, "a", "b", "c")
+
+	t.Run("a failure releases the rest of the batch", func(t *testing.T) {
+		var seen []string
+		sent, err := repository.ProcessUnsent(ctx, 10, time.Minute, func(ctx context.Context, message *outbox.OutboxMessage) error {
+			seen = append(seen, message.AggregateID)
+			if message.AggregateID == "b" {
+				return errors.New("broker unavailable")
+			}
+			return nil
+		})
+		assert.EqualError(t, err, "broker unavailable")
+		assert.Equal(t, 1, sent)
+		assert.Equal(t, []string{"a", "b"}, seen)
+	})
+
+	t.Run("claimed messages are left to their relay", func(t *testing.T) {
+		var seen []string
+		sent, err := repository.ProcessUnsent(ctx, 10, time.Minute, func(ctx context.Context, message *outbox.OutboxMessage) error {
+			if message.AggregateID == "b" {
+				assert.NotNil(t, message.LastError)
+				assert.Equal(t, 1, message.Attempts)
+
+				// a second relay finds the batch claimed
+				nested, err := repository.ProcessUnsent(ctx, 10, time.Minute, func(ctx context.Context, message *outbox.OutboxMessage) error {
+					t.Errorf("published claimed message %s", message.AggregateID)
+					return nil
+				})
+				assert.NoError(t, err)
+				assert.Zero(t, nested)
+			}
+			seen = append(seen, message.AggregateID)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, sent)
+		assert.Equal(t, []string{"b", "c"}, seen)
+	})
+
+	t.Run("an expired claim is taken over", func(t *testing.T) {
+		addEvents(t, repository, "d")
+		_, err := repository.ProcessUnsent(ctx, 10, -time.Minute, func(ctx context.Context, message *outbox.OutboxMessage) error {
+			sent, err := repository.ProcessUnsent(ctx, 10, time.Minute, func(ctx context.Context, message *outbox.OutboxMessage) error {
+				return nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, 1, sent)
+			return nil
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("a failed message no longer holds back the batch", func(t *testing.T) {
+		addEvents(t, repository, "f", "g")
+
+		var seen []string
+		sent, err := repository.ProcessUnsent(ctx, 10, time.Minute, func(ctx context.Context, message *outbox.OutboxMessage) error {
+			seen = append(seen, message.AggregateID)
+			if message.AggregateID == "f" {
+				return fmt.Errorf("%w: payload does not match the schema", outbox.ErrFailed)
+			}
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, sent)
+		assert.Equal(t, []string{"f", "g"}, seen)
+
+		_, err = repository.ProcessUnsent(ctx, 10, time.Minute, func(ctx context.Context, message *outbox.OutboxMessage) error {
+			t.Errorf("published failed message %s", message.AggregateID)
+			return nil
+		})
+		assert.NoError(t, err)
+	})
+
+	t.Run("prune deletes sent messages only", func(t *testing.T) {
+		addEvents(t, repository, "e")
+
+		pruned, err := repository.PruneSent(ctx, time.Now().Add(-time.Hour))
+		require.NoError(t, err)
+		assert.Zero(t, pruned)
+
+		// failed messages are kept
+		pruned, err = repository.PruneSent(ctx, time.Now().Add(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), pruned)
+
+		var seen []string
+		_, err = repository.ProcessUnsent(ctx, 10, time.Minute, func(ctx context.Context, message *outbox.OutboxMessage) error {
+			seen = append(seen, message.AggregateID)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"e"}, seen)
+	})
+}
@@ -0,0 +1,29 @@
+package privacy_settings
+
+import (
+	"time"
+)
+
+// PrivacySettings is what other users may see of a user's profile. Lists
+// carry their own visibility.
+type PrivacySettings struct {
+	UserID          string    `gorm:"column:user_id;primaryKey" json:"user_id"`
+	AnimeListPublic bool      `gorm:"column:anime_list_public" json:"anime_list_public"`
+	StatsPublic     bool      `gorm:"column:stats_public" json:"stats_public"`
+	FavoritesPublic bool      `gorm:"column:favorites_public" json:"favorites_public"`
+	CreatedAt       time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt       time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+// set table name
+func (PrivacySettings) TableName() string {
+	return "user_privacy_settings"
+}
+
+// Default returns the settings of a user who never changed them, nothing is
+// shared until they opt in
+func Default(userId string) *PrivacySettings {
+	return &PrivacySettings{
+		UserID: userId,
+	}
+}
@@ -0,0 +1,64 @@
+package privacy_settings
+
+import (
+	"context"
+	"errors"
+
+	"github.com/weeb-vip/list-service/internal/db"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PrivacySettingsRepositoryImpl interface {
+	// FindByUserId returns the settings of a user, nil when they never saved
+	// any
+	FindByUserId(ctx context.Context, userId string) (*PrivacySettings, error)
+	// FindByUserIds returns the saved settings of the given users, users who
+	// never saved any are left out
+	FindByUserIds(ctx context.Context, userIds []string) ([]*PrivacySettings, error)
+	// Save creates or overwrites the settings of a user
+	Save(ctx context.Context, settings *PrivacySettings) (*PrivacySettings, error)
+}
+
+type PrivacySettingsRepository struct {
+	db *db.DB
+}
+
+func NewPrivacySettingsRepository(db *db.DB) PrivacySettingsRepositoryImpl {
+	return &PrivacySettingsRepository{db: db}
+}
+
+func (a *PrivacySettingsRepository) FindByUserId(ctx context.Context, userId string) (*PrivacySettings, error) {
+	var settings PrivacySettings
+	err := a.db.WithContext(ctx).Where("user_id = ?", userId).First(&settings).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &settings, nil
+}
+
+func (a *PrivacySettingsRepository) FindByUserIds(ctx context.Context, userIds []string) ([]*PrivacySettings, error) {
+	var settings []*PrivacySettings
+	err := a.db.WithContext(ctx).Where("user_id IN ?", userIds).Find(&settings).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+func (a *PrivacySettingsRepository) Save(ctx context.Context, settings *PrivacySettings) (*PrivacySettings, error) {
+	err := a.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"anime_list_public", "stats_public", "favorites_public", "updated_at"}),
+	}).Create(settings).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return a.FindByUserId(ctx, settings.UserID)
+}
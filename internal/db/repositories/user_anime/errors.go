@@ -0,0 +1,28 @@
+package user_anime
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/weeb-vip/list-service/internal/db"
+)
+
+var ErrUserAnimeExists = errors.New("anime is already on the user's list")
+var ErrUserAnimeNotFound = errors.New("anime is not on the user's list")
+
+// VersionConflictError is returned when an update was based on a stale version
+// of the entry. Current holds the entry as it is now stored.
+type VersionConflictError struct {
+	Current *UserAnime
+}
+
+func (e *VersionConflictError) Error() string {
+	if e.Current == nil {
+		return "user anime was modified concurrently"
+	}
+	return fmt.Sprintf("user anime %s was modified concurrently, current version is %d", e.Current.ID, e.Current.Version)
+}
+
+func (e *VersionConflictError) Unwrap() error {
+	return db.ErrVersionConflict
+}
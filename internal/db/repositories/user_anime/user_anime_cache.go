@@ -0,0 +1,220 @@
+package user_anime
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/weeb-vip/list-service/internal/cache"
+	"github.com/weeb-vip/list-service/internal/db"
+	"github.com/weeb-vip/list-service/internal/logger"
+	"github.com/weeb-vip/list-service/internal/pagination"
+	"github.com/weeb-vip/list-service/metrics"
+)
+
+// cacheNamespace groups the user's cached user_anime queries
+const cacheNamespace = "user_anime"
+
+// CachedUserAnimeRepository reads a user's entries through a cache and drops
+// them once a write of that user commits. Queries inside a transaction go
+// straight to the database so they see the transaction's own writes.
+type CachedUserAnimeRepository struct {
+	UserAnimeRepositoryImpl
+	cache *cache.Versioned
+}
+
+func NewCachedUserAnimeRepository(repository UserAnimeRepositoryImpl, versioned *cache.Versioned) UserAnimeRepositoryImpl {
+	return &CachedUserAnimeRepository{UserAnimeRepositoryImpl: repository, cache: versioned}
+}
+
+func (c *CachedUserAnimeRepository) invalidate(ctx context.Context, userId *string) {
+	if userId == nil {
+		return
+	}
+
+	db.AfterCommit(ctx, func() {
+		if err := c.cache.Invalidate(ctx, cacheNamespace, *userId); err != nil {
+			log := logger.FromCtx(ctx)
+			log.Error().Err(err).Str("user_id", *userId).Msg("Error invalidating user_anime cache")
+		}
+	})
+}
+
+func (c *CachedUserAnimeRepository) Upsert(ctx context.Context, userAnime *UserAnime) (*UserAnime, error) {
+	upserted, err := c.UserAnimeRepositoryImpl.Upsert(ctx, userAnime)
+	if err != nil {
+		return nil, err
+	}
+
+	c.invalidate(ctx, upserted.UserID)
+	return upserted, nil
+}
+
+func (c *CachedUserAnimeRepository) UpdateStatuses(ctx context.Context, rows []*UserAnime, status string) (int64, error) {
+	changed, err := c.UserAnimeRepositoryImpl.UpdateStatuses(ctx, rows, status)
+	if err != nil {
+		return 0, err
+	}
+
+	invalidated := map[string]bool{}
+	for _, row := range rows {
+		if row.UserID != nil && !invalidated[*row.UserID] {
+			invalidated[*row.UserID] = true
+			c.invalidate(ctx, row.UserID)
+		}
+	}
+	return changed, nil
+}
+
+func (c *CachedUserAnimeRepository) Create(ctx context.Context, userAnime *UserAnime) (*UserAnime, error) {
+	created, err := c.UserAnimeRepositoryImpl.Create(ctx, userAnime)
+	if err != nil {
+		return nil, err
+	}
+
+	c.invalidate(ctx, created.UserID)
+	return created, nil
+}
+
+func (c *CachedUserAnimeRepository) Update(ctx context.Context, userId string, animeId string, columns map[string]interface{}, expectedVersion int) (*UserAnime, error) {
+	updated, err := c.UserAnimeRepositoryImpl.Update(ctx, userId, animeId, columns, expectedVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	c.invalidate(ctx, &userId)
+	return updated, nil
+}
+
+func (c *CachedUserAnimeRepository) Delete(ctx context.Context, userAnime *UserAnime) error {
+	if err := c.UserAnimeRepositoryImpl.Delete(ctx, userAnime); err != nil {
+		return err
+	}
+
+	c.invalidate(ctx, userAnime.UserID)
+	return nil
+}
+
+type userAnimePage struct {
+	UserAnimes []*UserAnime `json:"user_animes"`
+	HasMore    bool         `json:"has_more"`
+	Total      int64        `json:"total"`
+}
+
+func (c *CachedUserAnimeRepository) FindByUserId(ctx context.Context, userId string, filter Filter, sort Sort, page int, limit int) ([]*UserAnime, int64, error) {
+	if db.InTransaction(ctx) {
+		return c.UserAnimeRepositoryImpl.FindByUserId(ctx, userId, filter, sort, page, limit)
+	}
+
+	args := []interface{}{filter, sort, page, limit}
+	result, err := cache.Load(ctx, c.cache, cacheNamespace, "FindByUserId", userId, args, func() (userAnimePage, error) {
+		userAnimes, total, err := c.UserAnimeRepositoryImpl.FindByUserId(ctx, userId, filter, sort, page, limit)
+		return userAnimePage{UserAnimes: userAnimes, Total: total}, err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return result.UserAnimes, result.Total, nil
+}
+
+func (c *CachedUserAnimeRepository) FindByUserIdKeyset(ctx context.Context, userId string, filter Filter, sort Sort, page pagination.Page) ([]*UserAnime, bool, int64, error) {
+	if db.InTransaction(ctx) {
+		return c.UserAnimeRepositoryImpl.FindByUserIdKeyset(ctx, userId, filter, sort, page)
+	}
+
+	args := []interface{}{filter, sort, page}
+	result, err := cache.Load(ctx, c.cache, cacheNamespace, "FindByUserIdKeyset", userId, args, func() (userAnimePage, error) {
+		userAnimes, hasMore, total, err := c.UserAnimeRepositoryImpl.FindByUserIdKeyset(ctx, userId, filter, sort, page)
+		return userAnimePage{UserAnimes: userAnimes, HasMore: hasMore, Total: total}, err
+	})
+	if err != nil {
+		return nil, false, 0, err
+	}
+
+	return result.UserAnimes, result.HasMore, result.Total, nil
+}
+
+func (c *CachedUserAnimeRepository) FindByUserIdAndAnimeId(ctx context.Context, userId string, animeId string) (*UserAnime, error) {
+	if db.InTransaction(ctx) {
+		return c.UserAnimeRepositoryImpl.FindByUserIdAndAnimeId(ctx, userId, animeId)
+	}
+
+	// not found is returned as an error and so is never cached
+	return cache.Load(ctx, c.cache, cacheNamespace, "FindByUserIdAndAnimeId", userId, animeId, func() (*UserAnime, error) {
+		return c.UserAnimeRepositoryImpl.FindByUserIdAndAnimeId(ctx, userId, animeId)
+	})
+}
+
+// FindByUserIdAndAnimeIds caches each anime separately, including the ones
+// the user has no entry for, so overlapping batches share entries
+func (c *CachedUserAnimeRepository) FindByUserIdAndAnimeIds(ctx context.Context, userId string, animeIds []string) ([]*UserAnime, error) {
+	if db.InTransaction(ctx) || len(animeIds) == 0 {
+		return c.UserAnimeRepositoryImpl.FindByUserIdAndAnimeIds(ctx, userId, animeIds)
+	}
+
+	log := logger.FromCtx(ctx)
+	const operation = "FindByUserIdAndAnimeIds"
+
+	keys := make(map[string]string, len(animeIds))
+	lookup := make([]string, 0, len(animeIds))
+	for _, animeId := range animeIds {
+		key, err := c.cache.Key(ctx, cacheNamespace, userId, "anime", animeId)
+		if err != nil {
+			log.Warn().Err(err).Str("cache", cacheNamespace).Msg("Error reading cache generation")
+			metrics.GetAppMetrics().CacheMetric(cacheNamespace, operation, metrics.Error)
+			return c.UserAnimeRepositoryImpl.FindByUserIdAndAnimeIds(ctx, userId, animeIds)
+		}
+		keys[animeId] = key
+		lookup = append(lookup, key)
+	}
+
+	cached, err := c.cache.Cache.GetMany(ctx, lookup)
+	if err != nil {
+		log.Warn().Err(err).Str("cache", cacheNamespace).Msg("Error reading cache")
+		metrics.GetAppMetrics().CacheMetric(cacheNamespace, operation, metrics.Error)
+		cached = map[string][]byte{}
+	}
+
+	userAnimes := make([]*UserAnime, 0, len(animeIds))
+	var missing []string
+	for _, animeId := range animeIds {
+		// a cached null means the user has no entry for the anime
+		var userAnime *UserAnime
+		value, ok := cached[keys[animeId]]
+		if !ok || json.Unmarshal(value, &userAnime) != nil {
+			metrics.GetAppMetrics().CacheMetric(cacheNamespace, operation, metrics.CacheMiss)
+			missing = append(missing, animeId)
+			continue
+		}
+
+		metrics.GetAppMetrics().CacheMetric(cacheNamespace, operation, metrics.CacheHit)
+		if userAnime != nil {
+			userAnimes = append(userAnimes, userAnime)
+		}
+	}
+	if len(missing) == 0 {
+		return userAnimes, nil
+	}
+
+	loaded, err := c.UserAnimeRepositoryImpl.FindByUserIdAndAnimeIds(ctx, userId, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string][]byte, len(missing))
+	for _, animeId := range missing {
+		values[keys[animeId]] = []byte("null")
+	}
+	for _, userAnime := range loaded {
+		encoded, err := json.Marshal(userAnime)
+		if err != nil || userAnime.AnimeID == nil {
+			continue
+		}
+		values[keys[*userAnime.AnimeID]] = encoded
+	}
+	if err := c.cache.Cache.SetMany(ctx, values, c.cache.TTL); err != nil {
+		log.Warn().Err(err).Str("cache", cacheNamespace).Msg("Error writing cache")
+	}
+
+	return append(userAnimes, loaded...), nil
+}
@@ -0,0 +1,67 @@
+package user_anime_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weeb-vip/list-service/internal/cache"
+	"github.com/weeb-vip/list-service/internal/db/repositories/user_anime"
+)
+
+// countingRepository serves entries from memory and counts lookups
+type countingRepository struct {
+	user_anime.UserAnimeRepositoryImpl
+	entries map[string]*user_anime.UserAnime
+	lookups []string
+}
+
+func (r *countingRepository) FindByUserIdAndAnimeIds(ctx context.Context, userId string, animeIds []string) ([]*user_anime.UserAnime, error) {
+	var userAnimes []*user_anime.UserAnime
+	for _, animeId := range animeIds {
+		r.lookups = append(r.lookups, animeId)
+		if userAnime, ok := r.entries[animeId]; ok {
+			userAnimes = append(userAnimes, userAnime)
+		}
+	}
+	return userAnimes, nil
+}
+
+func (r *countingRepository) Update(ctx context.Context, userId string, animeId string, columns map[string]interface{}, expectedVersion int) (*user_anime.UserAnime, error) {
+	userAnime := r.entries[animeId]
+	userAnime.Version++
+	return userAnime, nil
+}
+
+func TestCachedUserAnimeRepository(t *testing.T) {
+	ctx := context.Background()
+	userId := "user_1"
+	animeId := "anime_1"
+
+	inner := &countingRepository{entries: map[string]*user_anime.UserAnime{
+		animeId: {ID: "entry_1", UserID: &userId, AnimeID: &animeId, Version: 1},
+	}}
+	lru, err := cache.NewLRU(100)
+	assert.NoError(t, err)
+	repository := user_anime.NewCachedUserAnimeRepository(inner, cache.NewVersioned(lru, "test", time.Minute))
+
+	userAnimes, err := repository.FindByUserIdAndAnimeIds(ctx, userId, []string{animeId, "anime_2"})
+	assert.NoError(t, err)
+	assert.Len(t, userAnimes, 1)
+	assert.Equal(t, []string{animeId, "anime_2"}, inner.lookups)
+
+	// both the entry and the missing anime are served from the cache
+	userAnimes, err = repository.FindByUserIdAndAnimeIds(ctx, userId, []string{"anime_2", animeId, "anime_3"})
+	assert.NoError(t, err)
+	assert.Len(t, userAnimes, 1)
+	assert.Equal(t, []string{animeId, "anime_2", "anime_3"}, inner.lookups)
+
+	_, err = repository.Update(ctx, userId, animeId, map[string]interface{}{"score": 9.0}, 0)
+	assert.NoError(t, err)
+
+	userAnimes, err = repository.FindByUserIdAndAnimeIds(ctx, userId, []string{animeId})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, userAnimes[0].Version)
+	assert.Equal(t, []string{animeId, "anime_2", "anime_3", animeId}, inner.lookups)
+}
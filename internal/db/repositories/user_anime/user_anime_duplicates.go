@@ -0,0 +1,69 @@
+package user_anime
+
+// DuplicateGroup is a set of rows stored for the same user and anime. Keep is
+// the row the unique key migration leaves in place.
+type DuplicateGroup struct {
+	UserID  string
+	AnimeID string
+	Keep    *UserAnime
+	Remove  []*UserAnime
+}
+
+// GroupDuplicates groups rows by user and anime, using the same preference
+// order as the unique key migration. Pairs with a single row are skipped.
+func GroupDuplicates(rows []*UserAnime) []*DuplicateGroup {
+	type pair struct {
+		userID  string
+		animeID string
+	}
+
+	var order []pair
+	byPair := map[pair][]*UserAnime{}
+	for _, row := range rows {
+		if row.UserID == nil || row.AnimeID == nil {
+			continue
+		}
+		key := pair{userID: *row.UserID, animeID: *row.AnimeID}
+		if _, ok := byPair[key]; !ok {
+			order = append(order, key)
+		}
+		byPair[key] = append(byPair[key], row)
+	}
+
+	groups := []*DuplicateGroup{}
+	for _, key := range order {
+		members := byPair[key]
+		if len(members) < 2 {
+			continue
+		}
+
+		keep := members[0]
+		for _, member := range members[1:] {
+			if preferred(member, keep) {
+				keep = member
+			}
+		}
+
+		group := &DuplicateGroup{UserID: key.userID, AnimeID: key.animeID, Keep: keep}
+		for _, member := range members {
+			if member != keep {
+				group.Remove = append(group.Remove, member)
+			}
+		}
+		groups = append(groups, group)
+	}
+
+	return groups
+}
+
+// preferred reports whether a should be kept over b: live rows first, then the
+// most recently updated, then the highest id
+func preferred(a, b *UserAnime) bool {
+	if a.DeletedAt.Valid != b.DeletedAt.Valid {
+		return !a.DeletedAt.Valid
+	}
+	if !a.UpdatedAt.Equal(b.UpdatedAt) {
+		return a.UpdatedAt.After(b.UpdatedAt)
+	}
+	return a.ID > b.ID
+}
@@ -0,0 +1,64 @@
+package user_anime_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weeb-vip/list-service/internal/db/repositories/user_anime"
+	"gorm.io/gorm"
+)
+
+func entry(id string, userID string, animeID string, updatedAt time.Time, deleted bool) *user_anime.UserAnime {
+	userAnime := &user_anime.UserAnime{ID: id, UserID: &userID, AnimeID: &animeID, UpdatedAt: updatedAt}
+	if deleted {
+		userAnime.DeletedAt = gorm.DeletedAt{Time: updatedAt, Valid: true}
+	}
+	return userAnime
+}
+
+func TestGroupDuplicates(t *testing.T) {
+	older := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+
+	t.Run("skips pairs stored once", func(t *testing.T) {
+		groups := user_anime.GroupDuplicates([]*user_anime.UserAnime{
+			entry("a", "user_1", "anime_1", older, false),
+			entry("b", "user_1", "anime_2", older, false),
+		})
+
+		assert.Empty(t, groups)
+	})
+
+	t.Run("keeps the most recently updated row", func(t *testing.T) {
+		groups := user_anime.GroupDuplicates([]*user_anime.UserAnime{
+			entry("a", "user_1", "anime_1", older, false),
+			entry("b", "user_1", "anime_1", newer, false),
+		})
+
+		assert.Len(t, groups, 1)
+		assert.Equal(t, "b", groups[0].Keep.ID)
+		assert.Len(t, groups[0].Remove, 1)
+		assert.Equal(t, "a", groups[0].Remove[0].ID)
+	})
+
+	t.Run("prefers live rows over newer deleted ones", func(t *testing.T) {
+		groups := user_anime.GroupDuplicates([]*user_anime.UserAnime{
+			entry("a", "user_1", "anime_1", newer, true),
+			entry("b", "user_1", "anime_1", older, false),
+		})
+
+		assert.Equal(t, "b", groups[0].Keep.ID)
+	})
+
+	t.Run("breaks ties on the highest id", func(t *testing.T) {
+		groups := user_anime.GroupDuplicates([]*user_anime.UserAnime{
+			entry("b", "user_1", "anime_1", older, false),
+			entry("c", "user_1", "anime_1", older, false),
+			entry("a", "user_1", "anime_1", older, false),
+		})
+
+		assert.Equal(t, "c", groups[0].Keep.ID)
+		assert.Len(t, groups[0].Remove, 2)
+	})
+}
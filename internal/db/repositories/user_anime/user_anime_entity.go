@@ -16,6 +16,7 @@ type UserAnime struct {
 	RewatchingEpisodes *int           `gorm:"column:rewatching_episodes" json:"rewatching_episodes"`
 	Tags               *string        `gorm:"column:tags" json:"tags"`
 	ListID             *string        `gorm:"column:list_id" json:"list_id"`
+	Version            int            `gorm:"column:version;default:1" json:"version"`
 	CreatedAt          time.Time      `gorm:"column:created_at;autoCreateTime" json:"created_at"`
 	UpdatedAt          time.Time      `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
 	DeletedAt          gorm.DeletedAt `gorm:"column:deleted_at;index" json:"deleted_at"`
@@ -0,0 +1,254 @@
+package user_anime
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/weeb-vip/list-service/internal/db"
+	"github.com/weeb-vip/list-service/internal/pagination"
+	"gorm.io/gorm"
+)
+
+type SortField string
+
+const (
+	SortByUpdatedAt SortField = "updated_at"
+	SortByCreatedAt SortField = "created_at"
+	SortByScore     SortField = "score"
+	SortByProgress  SortField = "episodes"
+)
+
+type SortDirection string
+
+const (
+	SortAsc  SortDirection = "asc"
+	SortDesc SortDirection = "desc"
+)
+
+// sortExpressions maps each sort field to the SQL expression it orders by.
+// Nullable columns are coalesced so that every row has a comparable value.
+var sortExpressions = map[SortField]string{
+	SortByUpdatedAt: "updated_at",
+	SortByCreatedAt: "created_at",
+	SortByScore:     "COALESCE(score, 0)",
+	SortByProgress:  "COALESCE(episodes, 0)",
+}
+
+type Sort struct {
+	Field     SortField
+	Direction SortDirection
+}
+
+// DefaultSort keeps the ordering FindByUserId has always used
+var DefaultSort = Sort{Field: SortByCreatedAt, Direction: SortDesc}
+
+func (s Sort) field() SortField {
+	if _, ok := sortExpressions[s.Field]; ok {
+		return s.Field
+	}
+	return DefaultSort.Field
+}
+
+func (s Sort) expression() string {
+	return sortExpressions[s.field()]
+}
+
+func (s Sort) direction() SortDirection {
+	if s.Direction == SortAsc {
+		return SortAsc
+	}
+	return SortDesc
+}
+
+// Scope orders the query by the sort field, breaking ties on id so that
+// results are stable between page fetches.
+func (s Sort) Scope() func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		direction := string(s.direction())
+		return tx.Order(s.expression() + " " + direction).Order("id " + direction)
+	}
+}
+
+// Reversed returns the same sort in the opposite direction, used to fetch
+// pages backwards from a cursor
+func (s Sort) Reversed() Sort {
+	if s.direction() == SortAsc {
+		return Sort{Field: s.field(), Direction: SortDesc}
+	}
+	return Sort{Field: s.field(), Direction: SortAsc}
+}
+
+// Key identifies the sort order a cursor was issued for
+func (s Sort) Key() string {
+	return string(s.field()) + " " + string(s.direction())
+}
+
+// Cursor returns the position of userAnime within this sort order
+func (s Sort) Cursor(userAnime *UserAnime) pagination.Cursor {
+	cursor := pagination.Cursor{Sort: s.Key(), ID: userAnime.ID}
+
+	switch s.field() {
+	case SortByUpdatedAt:
+		cursor.Value = userAnime.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	case SortByCreatedAt:
+		cursor.Value = userAnime.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case SortByScore:
+		var score float64
+		if userAnime.Score != nil {
+			score = *userAnime.Score
+		}
+		cursor.Value = strconv.FormatFloat(score, 'f', -1, 64)
+	case SortByProgress:
+		var episodes int
+		if userAnime.Episodes != nil {
+			episodes = *userAnime.Episodes
+		}
+		cursor.Value = strconv.Itoa(episodes)
+	}
+
+	return cursor
+}
+
+// Keyset restricts a query to the rows past cursor. backward selects the rows
+// before the cursor instead of after it.
+func (s Sort) Keyset(cursor *pagination.Cursor, backward bool) (func(*gorm.DB) *gorm.DB, error) {
+	if cursor.Sort != s.Key() {
+		return nil, pagination.ErrCursorSortMismatch
+	}
+
+	var value interface{}
+	switch s.field() {
+	case SortByUpdatedAt, SortByCreatedAt:
+		parsed, err := time.Parse(time.RFC3339Nano, cursor.Value)
+		if err != nil {
+			return nil, pagination.ErrInvalidCursor
+		}
+		value = parsed
+	default:
+		parsed, err := strconv.ParseFloat(cursor.Value, 64)
+		if err != nil {
+			return nil, pagination.ErrInvalidCursor
+		}
+		value = parsed
+	}
+
+	descending := s.direction() == SortDesc
+	if backward {
+		descending = !descending
+	}
+
+	return pagination.Keyset(s.expression(), value, cursor.ID, descending), nil
+}
+
+// Filter narrows the entries returned for a user. Zero values are ignored.
+type Filter struct {
+	Status *string
+	// Tags matches entries carrying every one of the given tags
+	Tags         []string
+	MinScore     *float64
+	MaxScore     *float64
+	ListIDs      []string
+	UpdatedSince *time.Time
+	Rewatching   *bool
+	AnimeIDs     []string
+	// ExcludeListIDs leaves out entries on any of the given lists, entries on
+	// no list are kept
+	ExcludeListIDs []string
+}
+
+// Scopes returns one gorm scope per populated filter so they can be combined
+// with any other query on the user_anime table.
+func (f Filter) Scopes() []func(*gorm.DB) *gorm.DB {
+	var scopes []func(*gorm.DB) *gorm.DB
+
+	if f.Status != nil {
+		scopes = append(scopes, WithStatus(*f.Status))
+	}
+	for _, tag := range f.Tags {
+		scopes = append(scopes, WithTag(tag))
+	}
+	if f.MinScore != nil || f.MaxScore != nil {
+		scopes = append(scopes, WithScoreRange(f.MinScore, f.MaxScore))
+	}
+	if len(f.ListIDs) > 0 {
+		scopes = append(scopes, WithListIDs(f.ListIDs))
+	}
+	if len(f.ExcludeListIDs) > 0 {
+		scopes = append(scopes, WithoutListIDs(f.ExcludeListIDs))
+	}
+	if f.UpdatedSince != nil {
+		scopes = append(scopes, WithUpdatedSince(*f.UpdatedSince))
+	}
+	if f.Rewatching != nil {
+		scopes = append(scopes, WithRewatching(*f.Rewatching))
+	}
+	if len(f.AnimeIDs) > 0 {
+		scopes = append(scopes, WithAnimeIDs(f.AnimeIDs))
+	}
+
+	return scopes
+}
+
+func WithUserID(userId string) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("user_id = ?", userId)
+	}
+}
+
+func WithStatus(status string) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("status = ?", status)
+	}
+}
+
+// WithTag matches a single tag in the comma separated tags column
+func WithTag(tag string) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where(db.InSet(tx, "tags", tag))
+	}
+}
+
+func WithScoreRange(min *float64, max *float64) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		if min != nil {
+			tx = tx.Where("score >= ?", *min)
+		}
+		if max != nil {
+			tx = tx.Where("score <= ?", *max)
+		}
+		return tx
+	}
+}
+
+func WithListIDs(listIds []string) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("list_id IN ?", listIds)
+	}
+}
+
+func WithoutListIDs(listIds []string) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("(list_id IS NULL OR list_id NOT IN ?)", listIds)
+	}
+}
+
+func WithUpdatedSince(since time.Time) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("updated_at >= ?", since)
+	}
+}
+
+func WithRewatching(rewatching bool) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		if rewatching {
+			return tx.Where("rewatching > 0")
+		}
+		return tx.Where("rewatching IS NULL OR rewatching = 0")
+	}
+}
+
+func WithAnimeIDs(animeIds []string) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("anime_id IN ?", animeIds)
+	}
+}
@@ -3,22 +3,43 @@ package user_anime
 import (
 	"context"
 	"errors"
-	"time"
 	"github.com/google/uuid"
 	"github.com/weeb-vip/list-service/internal/db"
-	"github.com/weeb-vip/list-service/metrics"
-	metrics_lib "github.com/weeb-vip/go-metrics-lib"
+	"github.com/weeb-vip/list-service/internal/pagination"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"slices"
 )
 
 type UserAnimeRepositoryImpl interface {
 	Upsert(ctx context.Context, userAnime *UserAnime) (*UserAnime, error)
+	Create(ctx context.Context, userAnime *UserAnime) (*UserAnime, error)
+	Update(ctx context.Context, userId string, animeId string, columns map[string]interface{}, expectedVersion int) (*UserAnime, error)
 	Delete(ctx context.Context, userAnime *UserAnime) error
-	FindByUserId(ctx context.Context, userId string, status *string, page int, limit int) ([]*UserAnime, int64, error)
+	FindByUserId(ctx context.Context, userId string, filter Filter, sort Sort, page int, limit int) ([]*UserAnime, int64, error)
+	FindByUserIdKeyset(ctx context.Context, userId string, filter Filter, sort Sort, page pagination.Page) ([]*UserAnime, bool, int64, error)
 	FindByAnimeId(ctx context.Context, animeId string) ([]*UserAnime, error)
+	// FindByIds returns the entries with the given ids, in no particular order
+	FindByIds(ctx context.Context, ids []string) ([]*UserAnime, error)
 	FindByUserIdAndAnimeId(ctx context.Context, userId string, animeId string) (*UserAnime, error)
 	FindByUserIdAndAnimeIds(ctx context.Context, userId string, animeIds []string) ([]*UserAnime, error)
 	FindByListId(ctx context.Context, listId string) ([]*UserAnime, error)
+	FindByUserIdAndAnimeIdWithDeleted(ctx context.Context, userId string, animeId string) (*UserAnime, error)
+	FindChangedByUserId(ctx context.Context, userId string, window pagination.ChangeWindow) ([]*UserAnime, error)
+	FindDuplicates(ctx context.Context) ([]*DuplicateGroup, error)
+	// FindBatchAfterId returns up to limit rows, soft deleted ones included,
+	// with ids after afterId in id order, for backfills walking the table
+	FindBatchAfterId(ctx context.Context, afterId string, limit int) ([]*UserAnime, error)
+	// UpdateStatuses changes the status of rows still holding the status they
+	// were read with, returning how many changed
+	UpdateStatuses(ctx context.Context, rows []*UserAnime, status string) (int64, error)
+	// CountByStatus returns how many entries are stored with each status,
+	// entries without a status are counted under ""
+	CountByStatus(ctx context.Context) (map[string]int64, error)
+	// StatsByUserIds sums the entries of the given users by status, users
+	// without entries are left out. Entries a user has on the lists given for
+	// them in excludeListIds are not counted.
+	StatsByUserIds(ctx context.Context, userIds []string, excludeListIds map[string][]string) ([]*StatusStats, error)
 }
 
 type UserAnimeRepository struct {
@@ -29,220 +50,250 @@ func NewUserAnimeRepository(db *db.DB) UserAnimeRepositoryImpl {
 	return &UserAnimeRepository{db: db}
 }
 
+// upsertColumns are overwritten when an upsert hits the entry already stored
+// for the same user and anime
+var upsertColumns = []string{"status", "score", "episodes", "rewatching", "rewatching_episodes", "tags", "updated_at", "deleted_at"}
+
+// upsertOnConflict overwrites columns of the entry already stored for the same
+// user and anime, reviving it if it was soft deleted
+func upsertOnConflict(columns []string) clause.OnConflict {
+	return clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "anime_id"}},
+		DoUpdates: append(
+			clause.AssignmentColumns(columns),
+			clause.Assignment{Column: clause.Column{Name: "version"}, Value: gorm.Expr("version + 1")},
+		),
+	}
+}
+
+// UpsertScope applies the conflict handling used by Upsert to an insert.
+// list_id is left as it is.
+func UpsertScope(tx *gorm.DB) *gorm.DB {
+	return tx.Clauses(upsertOnConflict(upsertColumns))
+}
+
+// Upsert writes the entry for the user and anime in a single statement, relying
+// on the unique (user_id, anime_id) key. The entry moves to ListID when one is
+// given and stays on its list otherwise. When the caller passes the version it
+// read, the write goes through the version checked Update instead.
 func (a *UserAnimeRepository) Upsert(ctx context.Context, userAnime *UserAnime) (*UserAnime, error) {
-	startTime := time.Now()
+	if userAnime.Version != 0 {
+		return a.Update(ctx, *userAnime.UserID, *userAnime.AnimeID, replaceColumns(userAnime), userAnime.Version)
+	}
 
-	// check if animeid and userid already exist
-	var existing *UserAnime
-	err := a.db.DB.WithContext(ctx).Where("user_id = ? AND anime_id = ?", userAnime.UserID, userAnime.AnimeID).First(&existing).Error
+	userAnime.ID = uuid.New().String()
+	userAnime.Version = 1
+	columns := upsertColumns
+	if userAnime.ListID != nil {
+		columns = append(slices.Clone(upsertColumns), "list_id")
+	}
+	err := a.db.WithContext(ctx).Clauses(upsertOnConflict(columns)).Create(userAnime).Error
+	if err == nil {
+		// the row id is only known here when the insert won, so read it back
+		var stored UserAnime
+		err = a.db.WithContext(ctx).Where("user_id = ? AND anime_id = ?", userAnime.UserID, userAnime.AnimeID).First(&stored).Error
+		userAnime = &stored
+	}
 	if err != nil {
-		if err.Error() != "record not found" && !errors.Is(err, gorm.ErrRecordNotFound) {
-			_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-				Service: metrics.GetServiceName(),
-				Table:   "user_anime",
-				Method:  metrics_lib.DatabaseMetricMethodSelect,
-				Result:  metrics_lib.Error,
-			})
-			return nil, err
-		}
+		return nil, err
 	}
-	// if err is gorm.ErrRecordNotFound, create new userAnime
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		userAnime.ID = uuid.New().String()
-		err := a.db.DB.WithContext(ctx).Create(userAnime).Error
-		if err != nil {
-			_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-				Service: metrics.GetServiceName(),
-				Table:   "user_anime",
-				Method:  metrics_lib.DatabaseMetricMethodInsert,
-				Result:  metrics_lib.Error,
-				Env:     metrics.GetCurrentEnv(),
-			})
-			return nil, err
-		}
 
-		_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-			Service: metrics.GetServiceName(),
-			Table:   "user_anime",
-			Method:  metrics_lib.DatabaseMetricMethodInsert,
-			Result:  metrics_lib.Success,
-			Env:     metrics.GetCurrentEnv(),
-		})
-		return userAnime, nil
+	return userAnime, nil
+}
+
+// replaceColumns are the columns a full upsert overwrites, list_id only when
+// a list is given
+func replaceColumns(userAnime *UserAnime) map[string]interface{} {
+	columns := map[string]interface{}{
+		"status":              userAnime.Status,
+		"score":               userAnime.Score,
+		"episodes":            userAnime.Episodes,
+		"rewatching":          userAnime.Rewatching,
+		"rewatching_episodes": userAnime.RewatchingEpisodes,
+		"tags":                userAnime.Tags,
+	}
+	if userAnime.ListID != nil {
+		columns["list_id"] = userAnime.ListID
 	}
 
-	// if found, update
-	userAnime.ID = existing.ID
-	userAnime.CreatedAt = existing.CreatedAt
-	userAnime.UpdatedAt = existing.UpdatedAt
-	userAnime.ListID = existing.ListID
-	userAnime.CreatedAt = existing.CreatedAt
-	userAnime.UpdatedAt = existing.UpdatedAt
-	err = a.db.DB.WithContext(ctx).Save(userAnime).Error
+	return columns
+}
+
+// Create adds a new entry and fails with ErrUserAnimeExists if the anime is
+// already on the user's list. A soft deleted entry is revived in place.
+func (a *UserAnimeRepository) Create(ctx context.Context, userAnime *UserAnime) (*UserAnime, error) {
+	var existing UserAnime
+	err := a.db.WithContext(ctx).Unscoped().Where("user_id = ? AND anime_id = ?", userAnime.UserID, userAnime.AnimeID).First(&existing).Error
+	switch {
+	case err == nil && !existing.DeletedAt.Valid:
+		err = ErrUserAnimeExists
+	case err == nil:
+		userAnime.ID = existing.ID
+		userAnime.CreatedAt = existing.CreatedAt
+		userAnime.Version = existing.Version + 1
+		result := a.db.WithContext(ctx).Unscoped().Model(userAnime).Where("version = ?", existing.Version).Select("*").Omit("id", "created_at").Updates(userAnime)
+		err = result.Error
+		if err == nil && result.RowsAffected == 0 {
+			// revived by a concurrent add
+			err = ErrUserAnimeExists
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		userAnime.ID = uuid.New().String()
+		userAnime.Version = 1
+		err = a.db.WithContext(ctx).Create(userAnime).Error
+		if db.IsDuplicateKey(err) {
+			err = ErrUserAnimeExists
+		}
+	}
 	if err != nil {
-		_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-			Service: metrics.GetServiceName(),
-			Table:   "user_anime",
-			Method:  metrics_lib.DatabaseMetricMethodUpdate,
-			Result:  metrics_lib.Error,
-			Env:     metrics.GetCurrentEnv(),
-		})
 		return nil, err
 	}
 
-	_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-		Service: metrics.GetServiceName(),
-		Table:   "user_anime",
-		Method:  metrics_lib.DatabaseMetricMethodUpdate,
-		Result:  metrics_lib.Success,
-		Env:     metrics.GetCurrentEnv(),
-	})
 	return userAnime, nil
 }
 
-func (a *UserAnimeRepository) Delete(ctx context.Context, userAnime *UserAnime) error {
-	startTime := time.Now()
+func (a *UserAnimeRepository) Update(ctx context.Context, userId string, animeId string, columns map[string]interface{}, expectedVersion int) (*UserAnime, error) {
+	var existing UserAnime
+	err := a.db.WithContext(ctx).Where("user_id = ? AND anime_id = ?", userId, animeId).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = ErrUserAnimeNotFound
+	}
+	if err == nil {
+		if expectedVersion == 0 {
+			expectedVersion = existing.Version
+		}
+		updates := make(map[string]interface{}, len(columns)+1)
+		for column, value := range columns {
+			updates[column] = value
+		}
+		updates["version"] = expectedVersion + 1
+
+		result := a.db.WithContext(ctx).Model(&existing).Where("version = ?", expectedVersion).Updates(updates)
+		err = result.Error
+		if err == nil {
+			conflict := result.RowsAffected == 0
+			err = a.db.WithContext(ctx).Where("id = ?", existing.ID).First(&existing).Error
+			if err == nil && conflict {
+				err = &VersionConflictError{Current: &existing}
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
 
-	err := a.db.DB.WithContext(ctx).Delete(userAnime).Error
+	return &existing, nil
+}
+
+func (a *UserAnimeRepository) Delete(ctx context.Context, userAnime *UserAnime) error {
+	err := a.db.WithContext(ctx).Delete(userAnime).Error
 	if err != nil {
-		_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-			Service: metrics.GetServiceName(),
-			Table:   "user_anime",
-			Method:  metrics_lib.DatabaseMetricMethodDelete,
-			Result:  metrics_lib.Error,
-			Env:     metrics.GetCurrentEnv(),
-		})
 		return err
 	}
 
-	_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-		Service: metrics.GetServiceName(),
-		Table:   "user_anime",
-		Method:  metrics_lib.DatabaseMetricMethodDelete,
-		Result:  metrics_lib.Success,
-		Env:     metrics.GetCurrentEnv(),
-	})
 	return nil
 }
 
-func (a *UserAnimeRepository) FindByUserId(ctx context.Context, userId string, status *string, page int, limit int) ([]*UserAnime, int64, error) {
-	startTime := time.Now()
-
+func (a *UserAnimeRepository) FindByUserId(ctx context.Context, userId string, filter Filter, sort Sort, page int, limit int) ([]*UserAnime, int64, error) {
 	var userAnimes []*UserAnime
 	var total int64
-	var err error
-	if status != nil {
-		// sort by created_at desc
-		err = a.db.DB.WithContext(ctx).Where("user_id = ? AND status = ?", userId, *status).Offset((page - 1) * limit).Limit(limit).Order("created_at desc").Find(&userAnimes).Error
-	} else {
-		err = a.db.DB.WithContext(ctx).Where("user_id = ?", userId).Offset((page - 1) * limit).Limit(limit).Order("created_at desc").Find(&userAnimes).Error
-	}
+	scopes := append([]func(*gorm.DB) *gorm.DB{WithUserID(userId)}, filter.Scopes()...)
+
+	err := a.db.WithContext(ctx).Scopes(scopes...).Scopes(sort.Scope()).Offset((page - 1) * limit).Limit(limit).Find(&userAnimes).Error
 
 	if err != nil {
-		_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-			Service: metrics.GetServiceName(),
-			Table:   "user_anime",
-			Method:  metrics_lib.DatabaseMetricMethodSelect,
-			Result:  metrics_lib.Error,
-			Env:     metrics.GetCurrentEnv(),
-		})
 		return nil, 0, err
 	}
 
-	// count based on status
-	if status != nil {
-		err = a.db.DB.WithContext(ctx).Model(&UserAnime{}).Where("user_id = ? AND status = ?", userId, *status).Count(&total).Error
-	} else {
-		err = a.db.DB.WithContext(ctx).Model(&UserAnime{}).Where("user_id = ?", userId).Count(&total).Error
-	}
+	// count with the same filters
+	err = a.db.WithContext(ctx).Model(&UserAnime{}).Scopes(scopes...).Count(&total).Error
 
 	if err != nil {
-		_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-			Service: metrics.GetServiceName(),
-			Table:   "user_anime",
-			Method:  metrics_lib.DatabaseMetricMethodSelect,
-			Result:  metrics_lib.Error,
-			Env:     metrics.GetCurrentEnv(),
-		})
 		return nil, 0, err
 	}
 
 	// check if total is 0
 	if total == 0 {
-		_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-			Service: metrics.GetServiceName(),
-			Table:   "user_anime",
-			Method:  metrics_lib.DatabaseMetricMethodSelect,
-			Result:  metrics_lib.Success,
-		})
 		return nil, 0, nil
 	}
 
-	_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-		Service: metrics.GetServiceName(),
-		Table:   "user_anime",
-		Method:  metrics_lib.DatabaseMetricMethodSelect,
-		Result:  metrics_lib.Success,
-		Env:     metrics.GetCurrentEnv(),
-	})
 	return userAnimes, total, nil
 }
 
+// FindByUserIdKeyset returns one page of a user's entries positioned by a cursor
+// rather than an offset, along with whether more rows exist past the page and
+// the total number of matching entries.
+func (a *UserAnimeRepository) FindByUserIdKeyset(ctx context.Context, userId string, filter Filter, sort Sort, page pagination.Page) ([]*UserAnime, bool, int64, error) {
+	var userAnimes []*UserAnime
+	var total int64
+	scopes := append([]func(*gorm.DB) *gorm.DB{WithUserID(userId)}, filter.Scopes()...)
+
+	query := a.db.WithContext(ctx).Scopes(scopes...)
+	if page.Cursor != nil {
+		keyset, err := sort.Keyset(page.Cursor, page.Backward)
+		if err != nil {
+			return nil, false, 0, err
+		}
+		query = query.Scopes(keyset)
+	}
+
+	order := sort
+	if page.Backward {
+		order = sort.Reversed()
+	}
+
+	// fetch one extra row to know whether another page exists
+	err := query.Scopes(order.Scope()).Limit(page.Limit + 1).Find(&userAnimes).Error
+	if err == nil {
+		err = a.db.WithContext(ctx).Model(&UserAnime{}).Scopes(scopes...).Count(&total).Error
+	}
+
+	if err != nil {
+		return nil, false, 0, err
+	}
+
+	hasMore := len(userAnimes) > page.Limit
+	if hasMore {
+		userAnimes = userAnimes[:page.Limit]
+	}
+	if page.Backward {
+		pagination.Reverse(userAnimes)
+	}
+
+	return userAnimes, hasMore, total, nil
+}
+
 func (a *UserAnimeRepository) FindByAnimeId(ctx context.Context, animeId string) ([]*UserAnime, error) {
-	startTime := time.Now()
+	var userAnimes []*UserAnime
+	err := a.db.WithContext(ctx).Where("anime_id = ?", animeId).Find(&userAnimes).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return userAnimes, nil
+}
 
+func (a *UserAnimeRepository) FindByIds(ctx context.Context, ids []string) ([]*UserAnime, error) {
 	var userAnimes []*UserAnime
-	err := a.db.DB.WithContext(ctx).Where("anime_id = ?", animeId).Find(&userAnimes).Error
+	err := a.db.WithContext(ctx).Where("id IN ?", ids).Find(&userAnimes).Error
 	if err != nil {
-		_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-			Service: metrics.GetServiceName(),
-			Table:   "user_anime",
-			Method:  metrics_lib.DatabaseMetricMethodSelect,
-			Result:  metrics_lib.Error,
-			Env:     metrics.GetCurrentEnv(),
-		})
 		return nil, err
 	}
 
-	_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-		Service: metrics.GetServiceName(),
-		Table:   "user_anime",
-		Method:  metrics_lib.DatabaseMetricMethodSelect,
-		Result:  metrics_lib.Success,
-		Env:     metrics.GetCurrentEnv(),
-	})
 	return userAnimes, nil
 }
 
 func (a *UserAnimeRepository) FindByUserIdAndAnimeId(ctx context.Context, userId string, animeId string) (*UserAnime, error) {
-	startTime := time.Now()
-
 	var userAnime UserAnime
-	err := a.db.DB.WithContext(ctx).Where("user_id = ? AND anime_id = ?", userId, animeId).First(&userAnime).Error
+	err := a.db.WithContext(ctx).Where("user_id = ? AND anime_id = ?", userId, animeId).First(&userAnime).Error
 	if err != nil {
-		_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-			Service: metrics.GetServiceName(),
-			Table:   "user_anime",
-			Method:  metrics_lib.DatabaseMetricMethodSelect,
-			Result:  metrics_lib.Error,
-			Env:     metrics.GetCurrentEnv(),
-		})
 		return nil, err
 	}
 
-	_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-		Service: metrics.GetServiceName(),
-		Table:   "user_anime",
-		Method:  metrics_lib.DatabaseMetricMethodSelect,
-		Result:  metrics_lib.Success,
-		Env:     metrics.GetCurrentEnv(),
-	})
 	return &userAnime, nil
 }
 
 func (a *UserAnimeRepository) FindByUserIdAndAnimeIds(ctx context.Context, userId string, animeIds []string) ([]*UserAnime, error) {
-	startTime := time.Now()
-
 	if len(animeIds) == 0 {
 		return []*UserAnime{}, nil
 	}
@@ -259,65 +310,158 @@ func (a *UserAnimeRepository) FindByUserIdAndAnimeIds(ctx context.Context, userI
 			}
 
 			var batch []*UserAnime
-			err := a.db.DB.WithContext(ctx).Where("user_id = ? AND anime_id IN ?", userId, animeIds[i:end]).Find(&batch).Error
+			err := a.db.WithContext(ctx).Where("user_id = ? AND anime_id IN ?", userId, animeIds[i:end]).Find(&batch).Error
 			if err != nil {
-				_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-					Service: metrics.GetServiceName(),
-					Table:   "user_anime",
-					Method:  metrics_lib.DatabaseMetricMethodSelect,
-					Result:  metrics_lib.Error,
-					Env:     metrics.GetCurrentEnv(),
-				})
 				return nil, err
 			}
 			userAnimes = append(userAnimes, batch...)
 		}
 	} else {
-		err := a.db.DB.WithContext(ctx).Where("user_id = ? AND anime_id IN ?", userId, animeIds).Find(&userAnimes).Error
+		err := a.db.WithContext(ctx).Where("user_id = ? AND anime_id IN ?", userId, animeIds).Find(&userAnimes).Error
 		if err != nil {
-			_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-				Service: metrics.GetServiceName(),
-				Table:   "user_anime",
-				Method:  metrics_lib.DatabaseMetricMethodSelect,
-				Result:  metrics_lib.Error,
-				Env:     metrics.GetCurrentEnv(),
-			})
 			return nil, err
 		}
 	}
 
-	_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-		Service: metrics.GetServiceName(),
-		Table:   "user_anime",
-		Method:  metrics_lib.DatabaseMetricMethodSelect,
-		Result:  metrics_lib.Success,
-		Env:     metrics.GetCurrentEnv(),
-	})
 	return userAnimes, nil
 }
 
 func (a *UserAnimeRepository) FindByListId(ctx context.Context, listId string) ([]*UserAnime, error) {
-	startTime := time.Now()
+	var userAnimes []*UserAnime
+	err := a.db.WithContext(ctx).Where("list_id = ?", listId).Find(&userAnimes).Error
+	if err != nil {
+		return nil, err
+	}
 
+	return userAnimes, nil
+}
+
+// FindByUserIdAndAnimeIdWithDeleted also returns the entry when it has been soft deleted
+func (a *UserAnimeRepository) FindByUserIdAndAnimeIdWithDeleted(ctx context.Context, userId string, animeId string) (*UserAnime, error) {
+	var userAnime UserAnime
+	err := a.db.WithContext(ctx).Unscoped().Where("user_id = ? AND anime_id = ?", userId, animeId).Order("updated_at desc").First(&userAnime).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &userAnime, nil
+}
+
+// FindChangedByUserId returns a user's entries, including soft deleted ones when
+// the window asks for them, that changed within the window
+func (a *UserAnimeRepository) FindChangedByUserId(ctx context.Context, userId string, window pagination.ChangeWindow) ([]*UserAnime, error) {
 	var userAnimes []*UserAnime
-	err := a.db.DB.WithContext(ctx).Where("list_id = ?", listId).Find(&userAnimes).Error
+	err := a.db.WithContext(ctx).Unscoped().Scopes(WithUserID(userId), window.Scope()).Find(&userAnimes).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return userAnimes, nil
+}
+
+// FindDuplicates returns every user and anime pair stored more than once,
+// including soft deleted rows
+func (a *UserAnimeRepository) FindDuplicates(ctx context.Context) ([]*DuplicateGroup, error) {
+	duplicated := a.db.DB.Unscoped().Model(&UserAnime{}).Select("user_id, anime_id").Group("user_id, anime_id").Having("COUNT(*) > 1")
+
+	var userAnimes []*UserAnime
+	err := a.db.WithContext(ctx).Unscoped().Where("(user_id, anime_id) IN (?)", duplicated).Order("user_id asc").Order("anime_id asc").Find(&userAnimes).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return GroupDuplicates(userAnimes), nil
+}
+
+func (a *UserAnimeRepository) FindBatchAfterId(ctx context.Context, afterId string, limit int) ([]*UserAnime, error) {
+	var userAnimes []*UserAnime
+	err := a.db.WithContext(ctx).Unscoped().Where("id > ?", afterId).Order("id asc").Limit(limit).Find(&userAnimes).Error
 	if err != nil {
-		_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-			Service: metrics.GetServiceName(),
-			Table:   "user_anime",
-			Method:  metrics_lib.DatabaseMetricMethodSelect,
-			Result:  metrics_lib.Error,
-			Env:     metrics.GetCurrentEnv(),
-		})
 		return nil, err
 	}
 
-	_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-		Service: metrics.GetServiceName(),
-		Table:   "user_anime",
-		Method:  metrics_lib.DatabaseMetricMethodSelect,
-		Result:  metrics_lib.Success,
-		Env:     metrics.GetCurrentEnv(),
-	})
 	return userAnimes, nil
 }
+
+func (a *UserAnimeRepository) UpdateStatuses(ctx context.Context, rows []*UserAnime, status string) (int64, error) {
+	// one statement per status the rows are moving away from
+	idsByStatus := map[string][]string{}
+	for _, row := range rows {
+		if row.Status != nil {
+			idsByStatus[*row.Status] = append(idsByStatus[*row.Status], row.ID)
+		}
+	}
+
+	var changed int64
+	var err error
+	for from, ids := range idsByStatus {
+		result := a.db.WithContext(ctx).Unscoped().Model(&UserAnime{}).Where("id IN ? AND status = ?", ids, from).Updates(map[string]interface{}{
+			"status":  status,
+			"version": gorm.Expr("version + 1"),
+		})
+		if result.Error != nil {
+			err = result.Error
+			break
+		}
+		changed += result.RowsAffected
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return changed, nil
+}
+
+func (a *UserAnimeRepository) CountByStatus(ctx context.Context) (map[string]int64, error) {
+	var rows []struct {
+		Status  *string
+		Entries int64
+	}
+	err := a.db.WithContext(ctx).Model(&UserAnime{}).Select("status, COUNT(*) AS entries").Group("status").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		var status string
+		if row.Status != nil {
+			status = *row.Status
+		}
+		counts[status] += row.Entries
+	}
+
+	return counts, nil
+}
+
+// StatusStats sums the entries of a user holding one status
+type StatusStats struct {
+	UserID  string
+	Status  *string
+	Entries int64
+	// Scored counts the entries with a score, ScoreSum adds their scores up
+	Scored   int64
+	ScoreSum float64
+	Episodes int64
+}
+
+func (a *UserAnimeRepository) StatsByUserIds(ctx context.Context, userIds []string, excludeListIds map[string][]string) ([]*StatusStats, error) {
+	query := a.db.WithContext(ctx).Model(&UserAnime{}).
+		Select("user_id, status, COUNT(*) AS entries, COUNT(score) AS scored, COALESCE(SUM(score), 0) AS score_sum, COALESCE(SUM(episodes), 0) AS episodes").
+		Where("user_id IN ?", userIds)
+	for userId, listIds := range excludeListIds {
+		if len(listIds) > 0 {
+			query = query.Where("(user_id <> ? OR list_id IS NULL OR list_id NOT IN ?)", userId, listIds)
+		}
+	}
+
+	var stats []*StatusStats
+	err := query.
+		Group("user_id, status").
+		Scan(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,334 @@
+package user_anime_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weeb-vip/list-service/internal/db"
+	"github.com/weeb-vip/list-service/internal/db/dbtest"
+	"github.com/weeb-vip/list-service/internal/db/repositories/user_anime"
+	"github.com/weeb-vip/list-service/internal/pagination"
+	"gorm.io/gorm"
+)
+
+func ptr[T any](value T) *T {
+	return &value
+}
+
+func newEntry(userId string, animeId string, status string, score float64) *user_anime.UserAnime {
+	return &user_anime.UserAnime{
+		UserID:  ptr(userId),
+		AnimeID: ptr(animeId),
+		Status:  ptr(status),
+		Score:   ptr(score),
+		Tags:    ptr("isekai,romance"),
+	}
+}
+
+func TestUserAnimeRepositoryWrites(t *testing.T) {
+	ctx := context.Background()
+	repository := user_anime.NewUserAnimeRepository(dbtest.NewSQLite(t))
+
+	t.Run("create adds an entry once", func(t *testing.T) {
+		created, err := repository.Create(ctx, newEntry("user_1", "anime_1", "watching", 7))
+		require.NoError(t, err)
+		assert.NotEmpty(t, created.ID)
+		assert.Equal(t, 1, created.Version)
+
+		_, err = repository.Create(ctx, newEntry("user_1", "anime_1", "completed", 8))
+		assert.ErrorIs(t, err, user_anime.ErrUserAnimeExists)
+	})
+
+	t.Run("delete is soft and create revives the entry", func(t *testing.T) {
+		stored, err := repository.FindByUserIdAndAnimeId(ctx, "user_1", "anime_1")
+		require.NoError(t, err)
+		require.NoError(t, repository.Delete(ctx, stored))
+
+		_, err = repository.FindByUserIdAndAnimeId(ctx, "user_1", "anime_1")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		deleted, err := repository.FindByUserIdAndAnimeIdWithDeleted(ctx, "user_1", "anime_1")
+		require.NoError(t, err)
+		assert.True(t, deleted.DeletedAt.Valid)
+
+		revived, err := repository.Create(ctx, newEntry("user_1", "anime_1", "completed", 9))
+		require.NoError(t, err)
+		assert.Equal(t, stored.ID, revived.ID)
+		assert.Equal(t, stored.Version+1, revived.Version)
+
+		found, err := repository.FindByUserIdAndAnimeId(ctx, "user_1", "anime_1")
+		require.NoError(t, err)
+		assert.Equal(t, "completed", *found.Status)
+	})
+
+	t.Run("upsert inserts then overwrites keeping the list", func(t *testing.T) {
+		entry := newEntry("user_1", "anime_2", "watching", 5)
+		entry.ListID = ptr("list_1")
+		inserted, err := repository.Upsert(ctx, entry)
+		require.NoError(t, err)
+		assert.Equal(t, 1, inserted.Version)
+
+		overwritten, err := repository.Upsert(ctx, newEntry("user_1", "anime_2", "dropped", 2))
+		require.NoError(t, err)
+		assert.Equal(t, inserted.ID, overwritten.ID)
+		assert.Equal(t, 2, overwritten.Version)
+		assert.Equal(t, "dropped", *overwritten.Status)
+		assert.Equal(t, "list_1", *overwritten.ListID)
+	})
+
+	t.Run("upsert with a stale version conflicts", func(t *testing.T) {
+		entry := newEntry("user_1", "anime_2", "completed", 10)
+		entry.Version = 1
+		_, err := repository.Upsert(ctx, entry)
+
+		var conflict *user_anime.VersionConflictError
+		require.ErrorAs(t, err, &conflict)
+		assert.ErrorIs(t, err, db.ErrVersionConflict)
+		assert.Equal(t, 2, conflict.Current.Version)
+	})
+
+	t.Run("update writes only the given columns", func(t *testing.T) {
+		updated, err := repository.Update(ctx, "user_1", "anime_2", map[string]interface{}{"episodes": 12}, 2)
+		require.NoError(t, err)
+		assert.Equal(t, 12, *updated.Episodes)
+		assert.Equal(t, "dropped", *updated.Status)
+		assert.Equal(t, 3, updated.Version)
+
+		_, err = repository.Update(ctx, "user_1", "anime_2", map[string]interface{}{"episodes": 1}, 2)
+		assert.ErrorIs(t, err, db.ErrVersionConflict)
+
+		_, err = repository.Update(ctx, "user_1", "missing", map[string]interface{}{"episodes": 1}, 0)
+		assert.ErrorIs(t, err, user_anime.ErrUserAnimeNotFound)
+	})
+
+	t.Run("upsert with a list moves the entry", func(t *testing.T) {
+		entry := newEntry("user_1", "anime_3", "watching", 6)
+		entry.ListID = ptr("list_1")
+		_, err := repository.Upsert(ctx, entry)
+		require.NoError(t, err)
+
+		entry = newEntry("user_1", "anime_3", "watching", 6)
+		entry.ListID = ptr("list_2")
+		moved, err := repository.Upsert(ctx, entry)
+		require.NoError(t, err)
+		assert.Equal(t, 2, moved.Version)
+		assert.Equal(t, "list_2", *moved.ListID)
+
+		entry = newEntry("user_1", "anime_3", "completed", 6)
+		entry.ListID = ptr("list_1")
+		entry.Version = 2
+		moved, err = repository.Upsert(ctx, entry)
+		require.NoError(t, err)
+		assert.Equal(t, 3, moved.Version)
+		assert.Equal(t, "list_1", *moved.ListID)
+	})
+}
+
+func TestUserAnimeRepositoryQueries(t *testing.T) {
+	ctx := context.Background()
+	database := dbtest.NewSQLite(t)
+	repository := user_anime.NewUserAnimeRepository(database)
+
+	statuses := []string{"watching", "completed", "dropped"}
+	for i := 0; i < 9; i++ {
+		entry := newEntry("user_1", fmt.Sprintf("anime_%d", i), statuses[i%3], float64(i))
+		if i%2 == 0 {
+			entry.Tags = ptr("action")
+			entry.ListID = ptr("list_1")
+		}
+		_, err := repository.Create(ctx, entry)
+		require.NoError(t, err)
+	}
+	_, err := repository.Create(ctx, newEntry("user_2", "anime_0", "watching", 1))
+	require.NoError(t, err)
+
+	t.Run("find by user filters and pages by offset", func(t *testing.T) {
+		filter := user_anime.Filter{Status: ptr("watching")}
+		sort := user_anime.Sort{Field: user_anime.SortByScore, Direction: user_anime.SortAsc}
+
+		userAnimes, total, err := repository.FindByUserId(ctx, "user_1", filter, sort, 2, 2)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), total)
+		require.Len(t, userAnimes, 1)
+		assert.Equal(t, "anime_6", *userAnimes[0].AnimeID)
+
+		userAnimes, total, err = repository.FindByUserId(ctx, "user_1", user_anime.Filter{Tags: []string{"action"}}, sort, 1, 10)
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), total)
+		assert.Len(t, userAnimes, 5)
+
+		userAnimes, total, err = repository.FindByUserId(ctx, "user_3", user_anime.Filter{}, sort, 1, 10)
+		require.NoError(t, err)
+		assert.Zero(t, total)
+		assert.Empty(t, userAnimes)
+	})
+
+	t.Run("keyset pages forward and back", func(t *testing.T) {
+		sort := user_anime.Sort{Field: user_anime.SortByScore, Direction: user_anime.SortDesc}
+
+		var seen []string
+		page := pagination.Page{Limit: 4}
+		for {
+			userAnimes, hasMore, total, err := repository.FindByUserIdKeyset(ctx, "user_1", user_anime.Filter{}, sort, page)
+			require.NoError(t, err)
+			assert.Equal(t, int64(9), total)
+			for _, userAnime := range userAnimes {
+				seen = append(seen, *userAnime.AnimeID)
+			}
+			if !hasMore {
+				break
+			}
+			cursor := sort.Cursor(userAnimes[len(userAnimes)-1])
+			page = pagination.Page{Limit: 4, Cursor: &cursor}
+		}
+		assert.Equal(t, []string{"anime_8", "anime_7", "anime_6", "anime_5", "anime_4", "anime_3", "anime_2", "anime_1", "anime_0"}, seen)
+
+		cursor := sort.Cursor(&user_anime.UserAnime{ID: "", Score: ptr(4.0)})
+		stored, err := repository.FindByUserIdAndAnimeId(ctx, "user_1", "anime_4")
+		require.NoError(t, err)
+		cursor.ID = stored.ID
+		userAnimes, hasMore, _, err := repository.FindByUserIdKeyset(ctx, "user_1", user_anime.Filter{}, sort, pagination.Page{Limit: 2, Cursor: &cursor, Backward: true})
+		require.NoError(t, err)
+		assert.True(t, hasMore)
+		require.Len(t, userAnimes, 2)
+		assert.Equal(t, "anime_6", *userAnimes[0].AnimeID)
+		assert.Equal(t, "anime_5", *userAnimes[1].AnimeID)
+	})
+
+	t.Run("find by anime and list", func(t *testing.T) {
+		userAnimes, err := repository.FindByAnimeId(ctx, "anime_0")
+		require.NoError(t, err)
+		assert.Len(t, userAnimes, 2)
+
+		userAnimes, err = repository.FindByListId(ctx, "list_1")
+		require.NoError(t, err)
+		assert.Len(t, userAnimes, 5)
+
+		sort := user_anime.Sort{Field: user_anime.SortByScore, Direction: user_anime.SortAsc}
+		userAnimes, total, err := repository.FindByUserId(ctx, "user_1", user_anime.Filter{ExcludeListIDs: []string{"list_1"}}, sort, 1, 10)
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), total)
+		assert.Equal(t, "anime_1", *userAnimes[0].AnimeID)
+	})
+
+	t.Run("find by anime ids", func(t *testing.T) {
+		userAnimes, err := repository.FindByUserIdAndAnimeIds(ctx, "user_1", []string{"anime_1", "anime_2", "missing"})
+		require.NoError(t, err)
+		assert.Len(t, userAnimes, 2)
+
+		userAnimes, err = repository.FindByUserIdAndAnimeIds(ctx, "user_1", nil)
+		require.NoError(t, err)
+		assert.Empty(t, userAnimes)
+	})
+
+	t.Run("find by ids across users", func(t *testing.T) {
+		first, err := repository.FindByUserIdAndAnimeId(ctx, "user_1", "anime_0")
+		require.NoError(t, err)
+		second, err := repository.FindByUserIdAndAnimeId(ctx, "user_2", "anime_0")
+		require.NoError(t, err)
+
+		userAnimes, err := repository.FindByIds(ctx, []string{first.ID, second.ID, "missing"})
+		require.NoError(t, err)
+		require.Len(t, userAnimes, 2)
+		assert.ElementsMatch(t, []string{"user_1", "user_2"}, []string{*userAnimes[0].UserID, *userAnimes[1].UserID})
+	})
+
+	t.Run("changed entries include tombstones on request", func(t *testing.T) {
+		stored, err := repository.FindByUserIdAndAnimeId(ctx, "user_1", "anime_8")
+		require.NoError(t, err)
+		require.NoError(t, repository.Delete(ctx, stored))
+
+		window := pagination.ChangeWindow{Before: time.Now().UTC().Add(time.Minute), Limit: 100}
+		changed, err := repository.FindChangedByUserId(ctx, "user_1", window)
+		require.NoError(t, err)
+		assert.Len(t, changed, 8)
+
+		window.IncludeDeleted = true
+		changed, err = repository.FindChangedByUserId(ctx, "user_1", window)
+		require.NoError(t, err)
+		assert.Len(t, changed, 9)
+
+		// pick up after the first change
+		window.After = changed[0].UpdatedAt
+		window.AfterID = changed[0].ID
+		changed, err = repository.FindChangedByUserId(ctx, "user_1", window)
+		require.NoError(t, err)
+		assert.Len(t, changed, 8)
+	})
+
+	t.Run("the unique key leaves no duplicates", func(t *testing.T) {
+		duplicates, err := repository.FindDuplicates(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, duplicates)
+	})
+
+	t.Run("count by status skips deleted entries", func(t *testing.T) {
+		counts, err := repository.CountByStatus(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int64{"watching": 4, "completed": 3, "dropped": 2}, counts)
+	})
+
+	t.Run("stats by user sum each status", func(t *testing.T) {
+		stats, err := repository.StatsByUserIds(ctx, []string{"user_1", "user_2", "user_3"}, nil)
+		require.NoError(t, err)
+
+		sums := map[string]user_anime.StatusStats{}
+		for _, row := range stats {
+			sums[row.UserID+"/"+*row.Status] = *row
+		}
+		assert.Len(t, sums, 4)
+		assert.Equal(t, user_anime.StatusStats{UserID: "user_1", Status: ptr("watching"), Entries: 3, Scored: 3, ScoreSum: 9}, sums["user_1/watching"])
+		assert.Equal(t, user_anime.StatusStats{UserID: "user_1", Status: ptr("dropped"), Entries: 2, Scored: 2, ScoreSum: 7}, sums["user_1/dropped"])
+		assert.Equal(t, int64(1), sums["user_2/watching"].Entries)
+	})
+
+	t.Run("stats by user leave out the excluded lists", func(t *testing.T) {
+		stats, err := repository.StatsByUserIds(ctx, []string{"user_1", "user_2"}, map[string][]string{"user_1": {"list_1"}})
+		require.NoError(t, err)
+
+		sums := map[string]user_anime.StatusStats{}
+		for _, row := range stats {
+			sums[row.UserID+"/"+*row.Status] = *row
+		}
+		assert.Equal(t, user_anime.StatusStats{UserID: "user_1", Status: ptr("watching"), Entries: 1, Scored: 1, ScoreSum: 3}, sums["user_1/watching"])
+		assert.Equal(t, user_anime.StatusStats{UserID: "user_1", Status: ptr("dropped"), Entries: 1, Scored: 1, ScoreSum: 5}, sums["user_1/dropped"])
+		assert.Equal(t, int64(1), sums["user_2/watching"].Entries)
+	})
+}
+
+func TestUserAnimeRepositoryBatches(t *testing.T) {
+	ctx := context.Background()
+	database := dbtest.NewSQLite(t)
+	repository := user_anime.NewUserAnimeRepository(database)
+
+	// every other anime of 1200 is on the list, spreading matches over the
+	// batches of 500 ids
+	var animeIds []string
+	var entries []*user_anime.UserAnime
+	for i := 0; i < 1200; i++ {
+		animeId := fmt.Sprintf("anime_%04d", i)
+		animeIds = append(animeIds, animeId)
+		if i%2 == 0 {
+			entry := newEntry("user_1", animeId, "watching", 1)
+			entry.ID = fmt.Sprintf("entry_%04d", i)
+			entries = append(entries, entry)
+		}
+	}
+	require.NoError(t, database.DB.CreateInBatches(entries, 200).Error)
+
+	userAnimes, err := repository.FindByUserIdAndAnimeIds(ctx, "user_1", animeIds)
+	require.NoError(t, err)
+	assert.Len(t, userAnimes, 600)
+
+	found := map[string]bool{}
+	for _, userAnime := range userAnimes {
+		found[*userAnime.AnimeID] = true
+	}
+	assert.True(t, found["anime_0000"])
+	assert.True(t, found["anime_1198"])
+	assert.False(t, found["anime_1199"])
+}
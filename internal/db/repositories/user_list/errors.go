@@ -0,0 +1,27 @@
+package user_list
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/weeb-vip/list-service/internal/db"
+)
+
+var ErrUserListNotFound = errors.New("user list not found")
+
+// VersionConflictError is returned when an update was based on a stale version
+// of the list. Current holds the list as it is now stored.
+type VersionConflictError struct {
+	Current *UserList
+}
+
+func (e *VersionConflictError) Error() string {
+	if e.Current == nil {
+		return "user list was modified concurrently"
+	}
+	return fmt.Sprintf("user list %s was modified concurrently, current version is %d", e.Current.ID, e.Current.Version)
+}
+
+func (e *VersionConflictError) Unwrap() error {
+	return db.ErrVersionConflict
+}
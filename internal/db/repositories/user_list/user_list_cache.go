@@ -0,0 +1,103 @@
+package user_list
+
+import (
+	"context"
+
+	"github.com/weeb-vip/list-service/internal/cache"
+	"github.com/weeb-vip/list-service/internal/db"
+	"github.com/weeb-vip/list-service/internal/logger"
+	"github.com/weeb-vip/list-service/internal/pagination"
+)
+
+// cacheNamespace groups the user's cached user_list queries
+const cacheNamespace = "user_list"
+
+// CachedUserListRepository reads a user's lists through a cache and drops
+// them once a write of that user commits. Queries inside a transaction go
+// straight to the database so they see the transaction's own writes.
+type CachedUserListRepository struct {
+	UserListRepositoryImpl
+	cache *cache.Versioned
+}
+
+func NewCachedUserListRepository(repository UserListRepositoryImpl, versioned *cache.Versioned) UserListRepositoryImpl {
+	return &CachedUserListRepository{UserListRepositoryImpl: repository, cache: versioned}
+}
+
+func (c *CachedUserListRepository) invalidate(ctx context.Context, userId *string) {
+	if userId == nil {
+		return
+	}
+
+	db.AfterCommit(ctx, func() {
+		if err := c.cache.Invalidate(ctx, cacheNamespace, *userId); err != nil {
+			log := logger.FromCtx(ctx)
+			log.Error().Err(err).Str("user_id", *userId).Msg("Error invalidating user_list cache")
+		}
+	})
+}
+
+// Upsert drops the cached lists of the user the write is scoped to. Upserts
+// only change lists of that user and never move a list to another owner, so
+// no other user's lists go stale.
+func (c *CachedUserListRepository) Upsert(ctx context.Context, userList *UserList) (*UserList, error) {
+	userId := userList.UserID
+	upserted, err := c.UserListRepositoryImpl.Upsert(ctx, userList)
+	if err != nil {
+		return nil, err
+	}
+
+	c.invalidate(ctx, userId)
+	return upserted, nil
+}
+
+func (c *CachedUserListRepository) Update(ctx context.Context, userId string, id string, columns map[string]interface{}, expectedVersion int) (*UserList, error) {
+	updated, err := c.UserListRepositoryImpl.Update(ctx, userId, id, columns, expectedVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	c.invalidate(ctx, &userId)
+	return updated, nil
+}
+
+func (c *CachedUserListRepository) Delete(ctx context.Context, userList *UserList) error {
+	if err := c.UserListRepositoryImpl.Delete(ctx, userList); err != nil {
+		return err
+	}
+
+	c.invalidate(ctx, userList.UserID)
+	return nil
+}
+
+type userListPage struct {
+	UserLists []*UserList `json:"user_lists"`
+	HasMore   bool        `json:"has_more"`
+	Total     int64       `json:"total"`
+}
+
+func (c *CachedUserListRepository) FindByUserId(ctx context.Context, userId string) ([]*UserList, error) {
+	if db.InTransaction(ctx) {
+		return c.UserListRepositoryImpl.FindByUserId(ctx, userId)
+	}
+
+	return cache.Load(ctx, c.cache, cacheNamespace, "FindByUserId", userId, nil, func() ([]*UserList, error) {
+		return c.UserListRepositoryImpl.FindByUserId(ctx, userId)
+	})
+}
+
+func (c *CachedUserListRepository) FindByUserIdKeyset(ctx context.Context, userId string, page pagination.Page) ([]*UserList, bool, int64, error) {
+	if db.InTransaction(ctx) {
+		return c.UserListRepositoryImpl.FindByUserIdKeyset(ctx, userId, page)
+	}
+
+	result, err := cache.Load(ctx, c.cache, cacheNamespace, "FindByUserIdKeyset", userId, page, func() (userListPage, error) {
+		userLists, hasMore, total, err := c.UserListRepositoryImpl.FindByUserIdKeyset(ctx, userId, page)
+		return userListPage{UserLists: userLists, HasMore: hasMore, Total: total}, err
+	})
+	if err != nil {
+		return nil, false, 0, err
+	}
+
+	return result.UserLists, result.HasMore, result.Total, nil
+}
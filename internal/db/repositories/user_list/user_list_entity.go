@@ -11,7 +11,8 @@ type UserList struct {
 	Name        *string        `gorm:"column:name;not null" json:"name"`
 	Description *string        `gorm:"column:description" json:"description"`
 	Tags        *string        `gorm:"column:tags" json:"tags"`
-	IsPublic    *bool          `gorm:"column:is_public" json:"is_public"`
+	IsPublic    *bool          `gorm:"column:is_public;default:true" json:"is_public"`
+	Version     int            `gorm:"column:version;default:1" json:"version"`
 	CreatedAt   time.Time      `gorm:"column:created_at;autoCreateTime" json:"created_at"`
 	UpdatedAt   time.Time      `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"column:deleted_at;index" json:"deleted_at"`
@@ -2,21 +2,31 @@ package user_list
 
 import (
 	"context"
+	"errors"
 	"time"
 	"github.com/google/uuid"
 	"github.com/weeb-vip/list-service/internal/db"
-	"github.com/weeb-vip/list-service/metrics"
-	metrics_lib "github.com/weeb-vip/go-metrics-lib"
+	"github.com/weeb-vip/list-service/internal/pagination"
+	"gorm.io/gorm"
 )
 
 type UserListRepositoryImpl interface {
 	FindAll(ctx context.Context) ([]*UserList, error)
 	FindById(ctx context.Context, id string) (*UserList, error)
+	// FindByIds returns the lists with the given ids, in no particular order
+	FindByIds(ctx context.Context, ids []string) ([]*UserList, error)
 	FindByUserId(ctx context.Context, userId string) ([]*UserList, error)
+	// FindByUserIds returns the lists of the given users, in no particular
+	// order
+	FindByUserIds(ctx context.Context, userIds []string) ([]*UserList, error)
+	FindByUserIdKeyset(ctx context.Context, userId string, page pagination.Page) ([]*UserList, bool, int64, error)
 	Upsert(ctx context.Context, userList *UserList) (*UserList, error)
+	Update(ctx context.Context, userId string, id string, columns map[string]interface{}, expectedVersion int) (*UserList, error)
 	Delete(ctx context.Context, userList *UserList) error
 	FindByName(ctx context.Context, name string) ([]*UserList, error)
 	FindByNameAndUserId(ctx context.Context, name string, userId string) ([]*UserList, error)
+	FindByIdWithDeleted(ctx context.Context, id string) (*UserList, error)
+	FindChangedByUserId(ctx context.Context, userId string, window pagination.ChangeWindow) ([]*UserList, error)
 }
 
 type UserListRepository struct {
@@ -28,206 +38,245 @@ func NewUserListRepository(db *db.DB) UserListRepositoryImpl {
 }
 
 func (a *UserListRepository) FindAll(ctx context.Context) ([]*UserList, error) {
-	startTime := time.Now()
-
 	var userLists []*UserList
-	err := a.db.DB.WithContext(ctx).Find(&userLists).Error
+	err := a.db.WithContext(ctx).Find(&userLists).Error
 	if err != nil {
-		_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-			Service: metrics.GetServiceName(),
-			Table:   "user_lists",
-			Method:  metrics_lib.DatabaseMetricMethodSelect,
-			Result:  metrics_lib.Error,
-			Env:     metrics.GetCurrentEnv(),
-		})
 		return nil, err
 	}
 
-	_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-		Service: metrics.GetServiceName(),
-		Table:   "user_lists",
-		Method:  metrics_lib.DatabaseMetricMethodSelect,
-		Result:  metrics_lib.Success,
-		Env:     metrics.GetCurrentEnv(),
-	})
 	return userLists, nil
 }
 
 func (a *UserListRepository) FindById(ctx context.Context, id string) (*UserList, error) {
-	startTime := time.Now()
-
 	var userList UserList
-	err := a.db.DB.WithContext(ctx).Where("id = ?", id).First(&userList).Error
+	err := a.db.WithContext(ctx).Where("id = ?", id).First(&userList).Error
 	if err != nil {
-		_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-			Service: metrics.GetServiceName(),
-			Table:   "user_lists",
-			Method:  metrics_lib.DatabaseMetricMethodSelect,
-			Result:  metrics_lib.Error,
-			Env:     metrics.GetCurrentEnv(),
-		})
 		return nil, err
 	}
 
-	_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-		Service: metrics.GetServiceName(),
-		Table:   "user_lists",
-		Method:  metrics_lib.DatabaseMetricMethodSelect,
-		Result:  metrics_lib.Success,
-		Env:     metrics.GetCurrentEnv(),
-	})
 	return &userList, nil
 }
 
+func (a *UserListRepository) FindByIds(ctx context.Context, ids []string) ([]*UserList, error) {
+	var userLists []*UserList
+	err := a.db.WithContext(ctx).Where("id IN ?", ids).Find(&userLists).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return userLists, nil
+}
+
 func (a *UserListRepository) FindByUserId(ctx context.Context, userId string) ([]*UserList, error) {
-	startTime := time.Now()
+	var userLists []*UserList
+	err := a.db.WithContext(ctx).Where("user_id = ?", userId).Find(&userLists).Error
+	if err != nil {
+		return nil, err
+	}
 
+	return userLists, nil
+}
+
+func (a *UserListRepository) FindByUserIds(ctx context.Context, userIds []string) ([]*UserList, error) {
 	var userLists []*UserList
-	err := a.db.DB.WithContext(ctx).Where("user_id = ?", userId).Find(&userLists).Error
+	err := a.db.WithContext(ctx).Where("user_id IN ?", userIds).Find(&userLists).Error
 	if err != nil {
-		_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-			Service: metrics.GetServiceName(),
-			Table:   "user_lists",
-			Method:  metrics_lib.DatabaseMetricMethodSelect,
-			Result:  metrics_lib.Error,
-			Env:     metrics.GetCurrentEnv(),
-		})
 		return nil, err
 	}
 
-	_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-		Service: metrics.GetServiceName(),
-		Table:   "user_lists",
-		Method:  metrics_lib.DatabaseMetricMethodSelect,
-		Result:  metrics_lib.Success,
-		Env:     metrics.GetCurrentEnv(),
-	})
 	return userLists, nil
 }
 
-func (a *UserListRepository) Upsert(ctx context.Context, userList *UserList) (*UserList, error) {
-	startTime := time.Now()
+// keysetSort is the only order lists are paged in
+const keysetSort = "created_at desc"
+
+// Cursor returns the position of userList within a user's paged lists
+func Cursor(userList *UserList) pagination.Cursor {
+	return pagination.Cursor{
+		Sort:  keysetSort,
+		Value: userList.CreatedAt.UTC().Format(time.RFC3339Nano),
+		ID:    userList.ID,
+	}
+}
+
+// FindByUserIdKeyset returns one page of a user's lists, newest first,
+// positioned by a cursor along with whether more rows exist past the page and
+// the total number of lists.
+func (a *UserListRepository) FindByUserIdKeyset(ctx context.Context, userId string, page pagination.Page) ([]*UserList, bool, int64, error) {
+	var userLists []*UserList
+	var total int64
+
+	query := a.db.WithContext(ctx).Where("user_id = ?", userId)
+	if page.Cursor != nil {
+		if page.Cursor.Sort != keysetSort {
+			return nil, false, 0, pagination.ErrCursorSortMismatch
+		}
+		createdAt, err := time.Parse(time.RFC3339Nano, page.Cursor.Value)
+		if err != nil {
+			return nil, false, 0, pagination.ErrInvalidCursor
+		}
+		query = query.Scopes(pagination.Keyset("created_at", createdAt, page.Cursor.ID, !page.Backward))
+	}
+
+	direction := "desc"
+	if page.Backward {
+		direction = "asc"
+	}
+
+	// fetch one extra row to know whether another page exists
+	err := query.Scopes(func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("created_at " + direction).Order("id " + direction)
+	}).Limit(page.Limit + 1).Find(&userLists).Error
+	if err == nil {
+		err = a.db.WithContext(ctx).Model(&UserList{}).Where("user_id = ?", userId).Count(&total).Error
+	}
+
+	if err != nil {
+		return nil, false, 0, err
+	}
+
+	hasMore := len(userLists) > page.Limit
+	if hasMore {
+		userLists = userLists[:page.Limit]
+	}
+	if page.Backward {
+		pagination.Reverse(userLists)
+	}
 
+	return userLists, hasMore, total, nil
+}
+
+func (a *UserListRepository) Upsert(ctx context.Context, userList *UserList) (*UserList, error) {
 	if userList.ID == "" {
 		userList.ID = uuid.New().String()
-		err := a.db.DB.WithContext(ctx).Save(userList).Error
+		userList.Version = 1
+		err := a.db.WithContext(ctx).Create(userList).Error
+		if err == nil && userList.IsPublic == nil {
+			// reload to pick up the column default
+			err = a.db.WithContext(ctx).Where("id = ?", userList.ID).First(userList).Error
+		}
 		if err != nil {
-			_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-				Service: metrics.GetServiceName(),
-				Table:   "user_lists",
-				Method:  metrics_lib.DatabaseMetricMethodInsert,
-				Result:  metrics_lib.Error,
-				Env:     metrics.GetCurrentEnv(),
-			})
 			return nil, err
 		}
 
-		_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-			Service: metrics.GetServiceName(),
-			Table:   "user_lists",
-			Method:  metrics_lib.DatabaseMetricMethodInsert,
-			Result:  metrics_lib.Success,
-			Env:     metrics.GetCurrentEnv(),
-		})
 		return userList, nil
 	}
 
-	// update existing user list
-	err := a.db.DB.WithContext(ctx).Model(userList).Where("id = ?", userList.ID).Updates(userList).Error
+	// update existing user list. Only the owner's lists are found and the owner
+	// never changes. The write only applies if the list is still at the
+	// version the caller read (or the one just loaded when none was given)
+	var existing UserList
+	err := a.db.WithContext(ctx).Where("id = ? AND user_id = ?", userList.ID, userList.UserID).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = ErrUserListNotFound
+	}
+	if err == nil {
+		expectedVersion := existing.Version
+		if userList.Version != 0 {
+			expectedVersion = userList.Version
+		}
+		userList.Version = expectedVersion + 1
+
+		result := a.db.WithContext(ctx).Model(userList).Omit("user_id").Where("id = ? AND user_id = ? AND version = ?", userList.ID, userList.UserID, expectedVersion).Updates(userList)
+		err = result.Error
+		if err == nil {
+			conflict := result.RowsAffected == 0
+			err = a.db.WithContext(ctx).Where("id = ?", userList.ID).First(userList).Error
+			if err == nil && conflict {
+				err = &VersionConflictError{Current: userList}
+			}
+		}
+	}
 	if err != nil {
-		_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-			Service: metrics.GetServiceName(),
-			Table:   "user_lists",
-			Method:  metrics_lib.DatabaseMetricMethodUpdate,
-			Result:  metrics_lib.Error,
-			Env:     metrics.GetCurrentEnv(),
-		})
 		return nil, err
 	}
 
-	_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-		Service: metrics.GetServiceName(),
-		Table:   "user_lists",
-		Method:  metrics_lib.DatabaseMetricMethodUpdate,
-		Result:  metrics_lib.Success,
-		Env:     metrics.GetCurrentEnv(),
-	})
 	return userList, nil
 }
 
-func (a *UserListRepository) Delete(ctx context.Context, userList *UserList) error {
-	startTime := time.Now()
+// Update writes only the given columns of a list owned by the user. An
+// expectedVersion of 0 skips the version check.
+func (a *UserListRepository) Update(ctx context.Context, userId string, id string, columns map[string]interface{}, expectedVersion int) (*UserList, error) {
+	var existing UserList
+	err := a.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userId).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = ErrUserListNotFound
+	}
+	if err == nil {
+		if expectedVersion == 0 {
+			expectedVersion = existing.Version
+		}
+		updates := make(map[string]interface{}, len(columns)+1)
+		for column, value := range columns {
+			updates[column] = value
+		}
+		updates["version"] = expectedVersion + 1
+
+		result := a.db.WithContext(ctx).Model(&existing).Where("version = ?", expectedVersion).Updates(updates)
+		err = result.Error
+		if err == nil {
+			conflict := result.RowsAffected == 0
+			err = a.db.WithContext(ctx).Where("id = ?", id).First(&existing).Error
+			if err == nil && conflict {
+				err = &VersionConflictError{Current: &existing}
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
 
-	err := a.db.DB.WithContext(ctx).Delete(userList).Error
+	return &existing, nil
+}
+
+func (a *UserListRepository) Delete(ctx context.Context, userList *UserList) error {
+	err := a.db.WithContext(ctx).Delete(userList).Error
 	if err != nil {
-		_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-			Service: metrics.GetServiceName(),
-			Table:   "user_lists",
-			Method:  metrics_lib.DatabaseMetricMethodDelete,
-			Result:  metrics_lib.Error,
-			Env:     metrics.GetCurrentEnv(),
-		})
 		return err
 	}
 
-	_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-		Service: metrics.GetServiceName(),
-		Table:   "user_lists",
-		Method:  metrics_lib.DatabaseMetricMethodDelete,
-		Result:  metrics_lib.Success,
-		Env:     metrics.GetCurrentEnv(),
-	})
 	return nil
 }
 
 func (a *UserListRepository) FindByName(ctx context.Context, name string) ([]*UserList, error) {
-	startTime := time.Now()
-
 	var userLists []*UserList
-	err := a.db.DB.WithContext(ctx).Where("name = ?", name).Find(&userLists).Error
+	err := a.db.WithContext(ctx).Where("name = ?", name).Find(&userLists).Error
 	if err != nil {
-		_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-			Service: metrics.GetServiceName(),
-			Table:   "user_lists",
-			Method:  metrics_lib.DatabaseMetricMethodSelect,
-			Result:  metrics_lib.Error,
-			Env:     metrics.GetCurrentEnv(),
-		})
 		return nil, err
 	}
 
-	_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-		Service: metrics.GetServiceName(),
-		Table:   "user_lists",
-		Method:  metrics_lib.DatabaseMetricMethodSelect,
-		Result:  metrics_lib.Success,
-		Env:     metrics.GetCurrentEnv(),
-	})
 	return userLists, nil
 }
 
 func (a *UserListRepository) FindByNameAndUserId(ctx context.Context, name string, userId string) ([]*UserList, error) {
-	startTime := time.Now()
+	var userLists []*UserList
+	err := a.db.WithContext(ctx).Where("name = ? AND user_id = ?", name, userId).Find(&userLists).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return userLists, nil
+}
+
+// FindByIdWithDeleted also returns the list when it has been soft deleted
+func (a *UserListRepository) FindByIdWithDeleted(ctx context.Context, id string) (*UserList, error) {
+	var userList UserList
+	err := a.db.WithContext(ctx).Unscoped().Where("id = ?", id).First(&userList).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &userList, nil
+}
 
+// FindChangedByUserId returns a user's lists, including soft deleted ones when
+// the window asks for them, that changed within the window
+func (a *UserListRepository) FindChangedByUserId(ctx context.Context, userId string, window pagination.ChangeWindow) ([]*UserList, error) {
 	var userLists []*UserList
-	err := a.db.DB.WithContext(ctx).Where("name = ? AND user_id = ?", name, userId).Find(&userLists).Error
+	err := a.db.WithContext(ctx).Unscoped().Where("user_id = ?", userId).Scopes(window.Scope()).Find(&userLists).Error
 	if err != nil {
-		_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-			Service: metrics.GetServiceName(),
-			Table:   "user_lists",
-			Method:  metrics_lib.DatabaseMetricMethodSelect,
-			Result:  metrics_lib.Error,
-			Env:     metrics.GetCurrentEnv(),
-		})
 		return nil, err
 	}
 
-	_ = metrics.NewMetricsInstance().DatabaseMetric(float64(time.Since(startTime).Milliseconds()), metrics_lib.DatabaseMetricLabels{
-		Service: metrics.GetServiceName(),
-		Table:   "user_lists",
-		Method:  metrics_lib.DatabaseMetricMethodSelect,
-		Result:  metrics_lib.Success,
-		Env:     metrics.GetCurrentEnv(),
-	})
 	return userLists, nil
 }
@@ -0,0 +1,215 @@
+package user_list_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weeb-vip/list-service/internal/cache"
+	"github.com/weeb-vip/list-service/internal/db"
+	"github.com/weeb-vip/list-service/internal/db/dbtest"
+	"github.com/weeb-vip/list-service/internal/db/repositories/user_list"
+	"github.com/weeb-vip/list-service/internal/pagination"
+	"gorm.io/gorm"
+)
+
+func ptr[T any](value T) *T {
+	return &value
+}
+
+func newList(userId string, name string) *user_list.UserList {
+	return &user_list.UserList{
+		UserID: ptr(userId),
+		Name:   ptr(name),
+		Tags:   ptr("favourites"),
+	}
+}
+
+func TestUserListRepositoryWrites(t *testing.T) {
+	ctx := context.Background()
+	repository := user_list.NewUserListRepository(dbtest.NewSQLite(t))
+
+	created, err := repository.Upsert(ctx, newList("user_1", "Favourites"))
+	require.NoError(t, err)
+
+	t.Run("upsert creates with the column defaults", func(t *testing.T) {
+		assert.NotEmpty(t, created.ID)
+		assert.Equal(t, 1, created.Version)
+		require.NotNil(t, created.IsPublic)
+		assert.True(t, *created.IsPublic)
+	})
+
+	t.Run("upsert updates an existing list", func(t *testing.T) {
+		list := newList("user_1", "Renamed")
+		list.ID = created.ID
+		updated, err := repository.Upsert(ctx, list)
+		require.NoError(t, err)
+		assert.Equal(t, "Renamed", *updated.Name)
+		assert.Equal(t, 2, updated.Version)
+
+		stale := newList("user_1", "Stale")
+		stale.ID = created.ID
+		stale.Version = 1
+		_, err = repository.Upsert(ctx, stale)
+		assert.ErrorIs(t, err, db.ErrVersionConflict)
+	})
+
+	t.Run("upsert leaves lists of other users alone", func(t *testing.T) {
+		list := newList("user_2", "Taken")
+		list.ID = created.ID
+		_, err := repository.Upsert(ctx, list)
+		assert.ErrorIs(t, err, user_list.ErrUserListNotFound)
+
+		unchanged, err := repository.FindById(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "user_1", *unchanged.UserID)
+		assert.Equal(t, "Renamed", *unchanged.Name)
+		assert.Equal(t, 2, unchanged.Version)
+	})
+
+	t.Run("update writes only the given columns", func(t *testing.T) {
+		updated, err := repository.Update(ctx, "user_1", created.ID, map[string]interface{}{"description": "best of"}, 0)
+		require.NoError(t, err)
+		assert.Equal(t, "best of", *updated.Description)
+		assert.Equal(t, "Renamed", *updated.Name)
+		assert.Equal(t, 3, updated.Version)
+
+		_, err = repository.Update(ctx, "user_1", created.ID, map[string]interface{}{"description": "stale"}, 2)
+		assert.ErrorIs(t, err, db.ErrVersionConflict)
+
+		_, err = repository.Update(ctx, "user_2", created.ID, map[string]interface{}{"description": "not mine"}, 0)
+		assert.ErrorIs(t, err, user_list.ErrUserListNotFound)
+	})
+
+	t.Run("delete is soft", func(t *testing.T) {
+		require.NoError(t, repository.Delete(ctx, created))
+
+		_, err := repository.FindById(ctx, created.ID)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		deleted, err := repository.FindByIdWithDeleted(ctx, created.ID)
+		require.NoError(t, err)
+		assert.True(t, deleted.DeletedAt.Valid)
+	})
+}
+
+func TestCachedUserListRepository(t *testing.T) {
+	ctx := context.Background()
+	lru, err := cache.NewLRU(100)
+	require.NoError(t, err)
+	repository := user_list.NewCachedUserListRepository(user_list.NewUserListRepository(dbtest.NewSQLite(t)), cache.NewVersioned(lru, "test", time.Minute))
+
+	created, err := repository.Upsert(ctx, newList("user_1", "Favourites"))
+	require.NoError(t, err)
+	_, err = repository.FindByUserId(ctx, "user_1")
+	require.NoError(t, err)
+
+	t.Run("upsert drops the owner's cached lists", func(t *testing.T) {
+		list := newList("user_1", "Renamed")
+		list.ID = created.ID
+		_, err := repository.Upsert(ctx, list)
+		require.NoError(t, err)
+
+		userLists, err := repository.FindByUserId(ctx, "user_1")
+		require.NoError(t, err)
+		require.Len(t, userLists, 1)
+		assert.Equal(t, "Renamed", *userLists[0].Name)
+	})
+
+	t.Run("upsert by another user leaves the owner's lists", func(t *testing.T) {
+		list := newList("user_2", "Taken")
+		list.ID = created.ID
+		_, err := repository.Upsert(ctx, list)
+		assert.ErrorIs(t, err, user_list.ErrUserListNotFound)
+
+		userLists, err := repository.FindByUserId(ctx, "user_1")
+		require.NoError(t, err)
+		assert.Len(t, userLists, 1)
+
+		userLists, err = repository.FindByUserId(ctx, "user_2")
+		require.NoError(t, err)
+		assert.Empty(t, userLists)
+	})
+}
+
+func TestUserListRepositoryQueries(t *testing.T) {
+	ctx := context.Background()
+	repository := user_list.NewUserListRepository(dbtest.NewSQLite(t))
+
+	var created []*user_list.UserList
+	for i := 0; i < 5; i++ {
+		list, err := repository.Upsert(ctx, newList("user_1", fmt.Sprintf("List %d", i)))
+		require.NoError(t, err)
+		created = append(created, list)
+	}
+	_, err := repository.Upsert(ctx, newList("user_2", "List 0"))
+	require.NoError(t, err)
+
+	t.Run("find all and by user", func(t *testing.T) {
+		userLists, err := repository.FindAll(ctx)
+		require.NoError(t, err)
+		assert.Len(t, userLists, 6)
+
+		userLists, err = repository.FindByUserId(ctx, "user_1")
+		require.NoError(t, err)
+		assert.Len(t, userLists, 5)
+
+		userLists, err = repository.FindByUserIds(ctx, []string{"user_1", "user_2", "user_3"})
+		require.NoError(t, err)
+		assert.Len(t, userLists, 6)
+	})
+
+	t.Run("find by name", func(t *testing.T) {
+		userLists, err := repository.FindByName(ctx, "List 0")
+		require.NoError(t, err)
+		assert.Len(t, userLists, 2)
+
+		userLists, err = repository.FindByNameAndUserId(ctx, "List 0", "user_2")
+		require.NoError(t, err)
+		assert.Len(t, userLists, 1)
+	})
+
+	t.Run("keyset pages newest first and back", func(t *testing.T) {
+		var seen []string
+		page := pagination.Page{Limit: 2}
+		for {
+			userLists, hasMore, total, err := repository.FindByUserIdKeyset(ctx, "user_1", page)
+			require.NoError(t, err)
+			assert.Equal(t, int64(5), total)
+			for _, userList := range userLists {
+				seen = append(seen, *userList.Name)
+			}
+			if !hasMore {
+				break
+			}
+			cursor := user_list.Cursor(userLists[len(userLists)-1])
+			page = pagination.Page{Limit: 2, Cursor: &cursor}
+		}
+		assert.Equal(t, []string{"List 4", "List 3", "List 2", "List 1", "List 0"}, seen)
+
+		cursor := user_list.Cursor(created[1])
+		userLists, hasMore, _, err := repository.FindByUserIdKeyset(ctx, "user_1", pagination.Page{Limit: 2, Cursor: &cursor, Backward: true})
+		require.NoError(t, err)
+		assert.True(t, hasMore)
+		require.Len(t, userLists, 2)
+		assert.Equal(t, "List 3", *userLists[0].Name)
+		assert.Equal(t, "List 2", *userLists[1].Name)
+	})
+
+	t.Run("changed lists include tombstones on request", func(t *testing.T) {
+		require.NoError(t, repository.Delete(ctx, created[0]))
+
+		window := pagination.ChangeWindow{Before: time.Now().UTC().Add(time.Minute), Limit: 100}
+		changed, err := repository.FindChangedByUserId(ctx, "user_1", window)
+		require.NoError(t, err)
+		assert.Len(t, changed, 4)
+
+		window.IncludeDeleted = true
+		changed, err = repository.FindChangedByUserId(ctx, "user_1", window)
+		require.NoError(t, err)
+		assert.Len(t, changed, 5)
+	})
+}
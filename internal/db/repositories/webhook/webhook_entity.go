@@ -0,0 +1,62 @@
+package webhook
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type DeliveryStatus string
+
+const (
+	// DeliveryPending is waiting for its first or next attempt
+	DeliveryPending DeliveryStatus = "pending"
+	// DeliverySucceeded was accepted by the endpoint
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	// DeliveryFailed ran out of attempts or its subscription was disabled
+	DeliveryFailed DeliveryStatus = "failed"
+)
+
+type Subscription struct {
+	ID     string `gorm:"column:id;primaryKey" json:"id"`
+	UserID string `gorm:"column:user_id;not null" json:"user_id"`
+	URL    string `gorm:"column:url;not null" json:"url"`
+	// EventTypes is a comma separated list of the event types delivered
+	EventTypes          string         `gorm:"column:event_types;not null" json:"event_types"`
+	Secret              string         `gorm:"column:secret;not null" json:"-"`
+	Enabled             bool           `gorm:"column:enabled;default:true" json:"enabled"`
+	ConsecutiveFailures int            `gorm:"column:consecutive_failures;default:0" json:"consecutive_failures"`
+	DisabledAt          *time.Time     `gorm:"column:disabled_at" json:"disabled_at"`
+	CreatedAt           time.Time      `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt           time.Time      `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+	DeletedAt           gorm.DeletedAt `gorm:"column:deleted_at;index" json:"deleted_at"`
+}
+
+// set table name
+func (Subscription) TableName() string {
+	return "webhook_subscription"
+}
+
+type Delivery struct {
+	ID             string         `gorm:"column:id;primaryKey" json:"id"`
+	SubscriptionID string         `gorm:"column:subscription_id;not null" json:"subscription_id"`
+	UserID         string         `gorm:"column:user_id;not null" json:"user_id"`
+	EventID        string         `gorm:"column:event_id;not null" json:"event_id"`
+	EventType      string         `gorm:"column:event_type;not null" json:"event_type"`
+	Payload        []byte         `gorm:"column:payload;not null" json:"payload"`
+	Status         DeliveryStatus `gorm:"column:status;default:pending" json:"status"`
+	Attempts       int            `gorm:"column:attempts;default:0" json:"attempts"`
+	NextAttemptAt  *time.Time     `gorm:"column:next_attempt_at" json:"next_attempt_at"`
+	LastStatusCode *int           `gorm:"column:last_status_code" json:"last_status_code"`
+	LastError      *string        `gorm:"column:last_error" json:"last_error"`
+	DeliveredAt    *time.Time     `gorm:"column:delivered_at" json:"delivered_at"`
+	CreatedAt      time.Time      `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time      `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+	// Subscription is loaded for claimed deliveries
+	Subscription *Subscription `gorm:"foreignKey:SubscriptionID" json:"-"`
+}
+
+// set table name
+func (Delivery) TableName() string {
+	return "webhook_delivery"
+}
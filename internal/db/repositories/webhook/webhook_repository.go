@@ -0,0 +1,231 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/weeb-vip/list-service/internal/db"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+
+type WebhookRepositoryImpl interface {
+	CreateSubscription(ctx context.Context, subscription *Subscription) (*Subscription, error)
+	FindSubscriptionsByUserId(ctx context.Context, userId string) ([]*Subscription, error)
+	FindSubscription(ctx context.Context, userId string, id string) (*Subscription, error)
+	DeleteSubscription(ctx context.Context, userId string, id string) error
+	// EnableSubscription turns a subscription back on and clears its failures
+	EnableSubscription(ctx context.Context, userId string, id string) (*Subscription, error)
+	// FindEnabledSubscriptions returns the user's subscriptions to eventType
+	// that are still enabled
+	FindEnabledSubscriptions(ctx context.Context, userId string, eventType string) ([]*Subscription, error)
+	// CreateDeliveries records deliveries, skipping ones already recorded for
+	// the same subscription and event
+	CreateDeliveries(ctx context.Context, deliveries []*Delivery) error
+	// ClaimDue returns pending deliveries whose next attempt is due, with their
+	// subscription loaded. Claimed deliveries are pushed back by lease, so
+	// other workers skip them while they are in flight.
+	ClaimDue(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*Delivery, error)
+	// RecordAttempt stores the outcome of an attempt on the delivery and its
+	// subscription. A failure counts towards disabling the subscription once
+	// disableAfter consecutive attempts failed, 0 never disables. Reports
+	// whether this attempt disabled the subscription.
+	RecordAttempt(ctx context.Context, delivery *Delivery, columns map[string]interface{}, succeeded bool, disableAfter int) (bool, error)
+	// UpdateDelivery changes a delivery without counting an attempt
+	UpdateDelivery(ctx context.Context, delivery *Delivery, columns map[string]interface{}) error
+	// FindDeliveries returns the most recent deliveries of a subscription
+	FindDeliveries(ctx context.Context, userId string, subscriptionId string, limit int) ([]*Delivery, error)
+}
+
+type WebhookRepository struct {
+	db *db.DB
+}
+
+func NewWebhookRepository(db *db.DB) WebhookRepositoryImpl {
+	return &WebhookRepository{db: db}
+}
+
+func (a *WebhookRepository) CreateSubscription(ctx context.Context, subscription *Subscription) (*Subscription, error) {
+	subscription.ID = uuid.New().String()
+	subscription.Enabled = true
+	err := a.db.WithContext(ctx).Create(subscription).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+func (a *WebhookRepository) FindSubscriptionsByUserId(ctx context.Context, userId string) ([]*Subscription, error) {
+	var subscriptions []*Subscription
+	err := a.db.WithContext(ctx).Where("user_id = ?", userId).Order("created_at desc").Find(&subscriptions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+func (a *WebhookRepository) FindSubscription(ctx context.Context, userId string, id string) (*Subscription, error) {
+	var subscription Subscription
+	err := a.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userId).First(&subscription).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &subscription, nil
+}
+
+func (a *WebhookRepository) DeleteSubscription(ctx context.Context, userId string, id string) error {
+	result := a.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userId).Delete(&Subscription{})
+	err := result.Error
+	if err == nil && result.RowsAffected == 0 {
+		err = ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (a *WebhookRepository) EnableSubscription(ctx context.Context, userId string, id string) (*Subscription, error) {
+	var subscription Subscription
+	result := a.db.WithContext(ctx).Model(&Subscription{}).Where("id = ? AND user_id = ?", id, userId).Updates(map[string]interface{}{
+		"enabled":              true,
+		"consecutive_failures": 0,
+		"disabled_at":          nil,
+	})
+	err := result.Error
+	if err == nil && result.RowsAffected == 0 {
+		err = ErrSubscriptionNotFound
+	}
+	if err == nil {
+		err = a.db.WithContext(ctx).Where("id = ?", id).First(&subscription).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &subscription, nil
+}
+
+func (a *WebhookRepository) FindEnabledSubscriptions(ctx context.Context, userId string, eventType string) ([]*Subscription, error) {
+	var subscriptions []*Subscription
+	err := a.db.WithContext(ctx).Where("user_id = ? AND enabled = ?", userId, true).Where(db.InSet(a.db.DB, "event_types", eventType)).Find(&subscriptions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+func (a *WebhookRepository) CreateDeliveries(ctx context.Context, deliveries []*Delivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	for _, delivery := range deliveries {
+		delivery.ID = uuid.New().String()
+	}
+	// an event handed over twice must not be delivered twice
+	err := a.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&deliveries).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (a *WebhookRepository) ClaimDue(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*Delivery, error) {
+	var deliveries []*Delivery
+	err := a.db.Transaction(ctx, func(ctx context.Context) error {
+		err := a.db.WithContext(ctx).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", DeliveryPending, now).
+			Order("next_attempt_at asc").
+			Limit(limit).
+			Preload("Subscription").
+			Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		ids := make([]string, len(deliveries))
+		for i, delivery := range deliveries {
+			ids[i] = delivery.ID
+		}
+		return a.db.WithContext(ctx).Model(&Delivery{}).Where("id IN ?", ids).Update("next_attempt_at", now.Add(lease)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (a *WebhookRepository) RecordAttempt(ctx context.Context, delivery *Delivery, columns map[string]interface{}, succeeded bool, disableAfter int) (bool, error) {
+	disabled := false
+	err := a.db.Transaction(ctx, func(ctx context.Context) error {
+		if err := a.db.WithContext(ctx).Model(delivery).Updates(columns).Error; err != nil {
+			return err
+		}
+
+		subscription := a.db.WithContext(ctx).Model(&Subscription{}).Where("id = ?", delivery.SubscriptionID)
+		if succeeded {
+			return subscription.Update("consecutive_failures", 0).Error
+		}
+
+		if err := subscription.Update("consecutive_failures", gorm.Expr("consecutive_failures + 1")).Error; err != nil {
+			return err
+		}
+		if disableAfter <= 0 {
+			return nil
+		}
+
+		// the row stays locked until commit, so only the attempt crossing the
+		// threshold disables it
+		result := a.db.WithContext(ctx).Model(&Subscription{}).
+			Where("id = ? AND enabled = ? AND consecutive_failures >= ?", delivery.SubscriptionID, true, disableAfter).
+			Updates(map[string]interface{}{
+				"enabled":     false,
+				"disabled_at": time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		disabled = result.RowsAffected > 0
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return disabled, nil
+}
+
+func (a *WebhookRepository) UpdateDelivery(ctx context.Context, delivery *Delivery, columns map[string]interface{}) error {
+	err := a.db.WithContext(ctx).Model(delivery).Updates(columns).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (a *WebhookRepository) FindDeliveries(ctx context.Context, userId string, subscriptionId string, limit int) ([]*Delivery, error) {
+	var deliveries []*Delivery
+	err := a.db.WithContext(ctx).Where("subscription_id = ? AND user_id = ?", subscriptionId, userId).Order("created_at desc, id desc").Limit(limit).Find(&deliveries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
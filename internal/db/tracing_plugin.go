@@ -137,11 +137,13 @@ func (tp *TracingPlugin) after(db *gorm.DB) {
 		)
 	}
 
-	// Add rows affected and performance metrics
+	// Add rows affected and performance metrics. The statement is empty when
+	// it failed before being built, e.g. on a cancelled context.
 	if db.Statement != nil {
+		statement := db.Statement.SQL.String()
 		span.SetAttributes(
 			attribute.Int64("db.rows_affected", db.RowsAffected),
-			attribute.String("db.operation.name", db.Statement.Table+"."+db.Statement.SQL.String()[:20]),
+			attribute.String("db.operation.name", db.Statement.Table+"."+statement[:min(len(statement), 20)]),
 		)
 	}
 
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+type txKey struct{}
+
+// txState is the transaction carried in a context
+type txState struct {
+	tx          *gorm.DB
+	afterCommit []func()
+}
+
+// Transactor runs a function inside a database transaction. Repositories pick
+// the transaction up from the context passed to fn.
+type Transactor interface {
+	Transaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// Transaction runs fn in a transaction, committing if it returns nil. Calls
+// nested inside fn join the outer transaction.
+func (d *DB) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*txState); ok {
+		return fn(ctx)
+	}
+
+	state := &txState{}
+	err := d.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		state.tx = tx
+		return fn(context.WithValue(ctx, txKey{}, state))
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, callback := range state.afterCommit {
+		callback()
+	}
+	return nil
+}
+
+// InTransaction reports whether ctx carries a transaction
+func InTransaction(ctx context.Context) bool {
+	_, ok := ctx.Value(txKey{}).(*txState)
+	return ok
+}
+
+// AfterCommit runs fn once the transaction carried by ctx has committed, or
+// straight away when there is none. fn is dropped if the transaction rolls
+// back.
+func AfterCommit(ctx context.Context, fn func()) {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		state.afterCommit = append(state.afterCommit, fn)
+		return
+	}
+
+	fn()
+}
+
+// WithContext returns the transaction carried by ctx, or a new session on the
+// connection pool when there is none. Sessions read from a replica unless the
+// user wrote recently or no replica is healthy.
+func (d *DB) WithContext(ctx context.Context) *gorm.DB {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		return state.tx.WithContext(ctx)
+	}
+
+	if d.replicas != nil && d.replicas.usePrimary(ctx) {
+		// a fresh session, so the write clause carries over like the context
+		// does when one call chains several queries off it
+		return d.DB.WithContext(ctx).Clauses(dbresolver.Write).Session(&gorm.Session{})
+	}
+
+	return d.DB.WithContext(ctx)
+}
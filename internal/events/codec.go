@@ -0,0 +1,77 @@
+package events
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/weeb-vip/list-service/internal/producer"
+)
+
+// AvroSchema describes Event for the schema registry. The payload differs per
+// event type, so it is carried as a JSON string.
+const AvroSchema = `{
+  "type": "record",
+  "name": "ListEvent",
+  "namespace": "vip.weeb.list",
+  "fields": [
+    {"name": "id", "type": "string"},
+    {"name": "type", "type": "string"},
+    {"name": "aggregate_id", "type": "string"},
+    {"name": "user_id", "type": "string"},
+    {"name": "occurred_at", "type": {"type": "long", "logicalType": "timestamp-micros"}},
+    {"name": "payload", "type": "string"}
+  ]
+}`
+
+// NewCodec returns the codec for the configured schema, json or avro. JSON
+// events are published without a registered schema since their payload is a
+// nested object that Avro cannot describe generically.
+func NewCodec(schema string) (producer.Codec[Event], error) {
+	switch schema {
+	case "", "json":
+		return producer.NewJSONCodec[Event]("")
+	case "avro":
+		return producer.NewAvroCodec[Event](AvroSchema, toAvro, fromAvro)
+	default:
+		return nil, fmt.Errorf("unknown event schema %q", schema)
+	}
+}
+
+func toAvro(event Event) (map[string]interface{}, error) {
+	return map[string]interface{}{
+		"id":           event.ID,
+		"type":         string(event.Type),
+		"aggregate_id": event.AggregateID,
+		"user_id":      event.UserID,
+		"occurred_at":  event.OccurredAt,
+		"payload":      string(event.Payload),
+	}, nil
+}
+
+func fromAvro(record map[string]interface{}) (Event, error) {
+	event := Event{}
+	var ok bool
+	var eventType, payload string
+	if event.ID, ok = record["id"].(string); !ok {
+		return event, fmt.Errorf("avro event is missing id")
+	}
+	if eventType, ok = record["type"].(string); !ok {
+		return event, fmt.Errorf("avro event is missing type")
+	}
+	if event.AggregateID, ok = record["aggregate_id"].(string); !ok {
+		return event, fmt.Errorf("avro event is missing aggregate_id")
+	}
+	if event.UserID, ok = record["user_id"].(string); !ok {
+		return event, fmt.Errorf("avro event is missing user_id")
+	}
+	if event.OccurredAt, ok = record["occurred_at"].(time.Time); !ok {
+		return event, fmt.Errorf("avro event is missing occurred_at")
+	}
+	if payload, ok = record["payload"].(string); !ok {
+		return event, fmt.Errorf("avro event is missing payload")
+	}
+	event.Type = Type(eventType)
+	event.Payload = []byte(payload)
+
+	return event, nil
+}
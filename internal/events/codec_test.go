@@ -0,0 +1,56 @@
+package events_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weeb-vip/list-service/internal/broker"
+	"github.com/weeb-vip/list-service/internal/events"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	event, err := events.New(events.UserListCreated, "list_1", "user_1", map[string]string{"name": "favourites"})
+	assert.NoError(t, err)
+
+	for _, schema := range []string{"json", "avro"} {
+		t.Run(schema, func(t *testing.T) {
+			codec, err := events.NewCodec(schema)
+			assert.NoError(t, err)
+
+			data, err := codec.Encode(*event)
+			assert.NoError(t, err)
+
+			decoded, err := codec.Decode(data)
+			assert.NoError(t, err)
+			assert.Equal(t, event.ID, decoded.ID)
+			assert.Equal(t, event.Type, decoded.Type)
+			assert.Equal(t, event.UserID, decoded.UserID)
+			assert.True(t, event.OccurredAt.Equal(decoded.OccurredAt))
+			assert.JSONEq(t, string(event.Payload), string(decoded.Payload))
+		})
+	}
+
+	t.Run("unknown schema", func(t *testing.T) {
+		_, err := events.NewCodec("protobuf")
+		assert.Error(t, err)
+	})
+}
+
+func TestCodecSchema(t *testing.T) {
+	codec, err := events.NewCodec("avro")
+	require.NoError(t, err)
+	describer, ok := codec.(broker.SchemaDescriber)
+	require.True(t, ok)
+	encoding, definition := describer.Schema()
+	assert.Equal(t, broker.SchemaAvro, encoding)
+	assert.Equal(t, events.AvroSchema, definition)
+
+	// JSON events are published without a schema
+	codec, err = events.NewCodec("json")
+	require.NoError(t, err)
+	describer, ok = codec.(broker.SchemaDescriber)
+	require.True(t, ok)
+	_, definition = describer.Schema()
+	assert.Empty(t, definition)
+}
@@ -0,0 +1,70 @@
+package events
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Type string
+
+const (
+	UserAnimeAdded   Type = "user_anime.added"
+	UserAnimeUpdated Type = "user_anime.updated"
+	UserAnimeDeleted Type = "user_anime.deleted"
+	UserListCreated  Type = "user_list.created"
+	UserListUpdated  Type = "user_list.updated"
+	UserListDeleted  Type = "user_list.deleted"
+)
+
+// Types lists every event type in the order they are documented
+var Types = []Type{
+	UserAnimeAdded,
+	UserAnimeUpdated,
+	UserAnimeDeleted,
+	UserListCreated,
+	UserListUpdated,
+	UserListDeleted,
+}
+
+// Valid reports whether t is a known event type
+func (t Type) Valid() bool {
+	for _, known := range Types {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is a domain event describing a change to a user's list. It is written
+// to the outbox together with the change and published by the relay.
+type Event struct {
+	ID   string `json:"id"`
+	Type Type   `json:"type"`
+	// AggregateID is the id of the changed entry or list, events for the same
+	// aggregate are published in the order they were recorded
+	AggregateID string          `json:"aggregate_id"`
+	UserID      string          `json:"user_id"`
+	OccurredAt  time.Time       `json:"occurred_at"`
+	Payload     json.RawMessage `json:"payload"`
+}
+
+// New builds an event with payload serialized as JSON
+func New(eventType Type, aggregateID string, userID string, payload interface{}) (*Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Event{
+		ID:          uuid.New().String(),
+		Type:        eventType,
+		AggregateID: aggregateID,
+		UserID:      userID,
+		// microseconds, the precision of the outbox column and the avro schema
+		OccurredAt: time.Now().UTC().Truncate(time.Microsecond),
+		Payload:    data,
+	}, nil
+}
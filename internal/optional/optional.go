@@ -0,0 +1,26 @@
+package optional
+
+// Value is a field of a partial update. It tells a field that was not provided
+// apart from one that was explicitly set to its zero value or null.
+type Value[T any] struct {
+	value T
+	set   bool
+}
+
+// Of returns a Value that is set to value
+func Of[T any](value T) Value[T] {
+	return Value[T]{value: value, set: true}
+}
+
+// Get returns the value and whether it was provided
+func (v Value[T]) Get() (T, bool) {
+	if !v.set {
+		var zero T
+		return zero, false
+	}
+	return v.value, true
+}
+
+func (v Value[T]) IsSet() bool {
+	return v.set
+}
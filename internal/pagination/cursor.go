@@ -0,0 +1,161 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+var ErrCursorSortMismatch = errors.New("cursor does not match the requested sort order")
+var ErrFirstAndLast = errors.New("first and last cannot be used together")
+
+// Cursor identifies a row by the value of the column the result set is sorted
+// by plus the row id, which breaks ties between equal sort values.
+type Cursor struct {
+	// Sort is the sort order the cursor was issued for, e.g. "updated_at desc"
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+// Encode returns the opaque representation handed to clients
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(encoded string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
+
+// Request is a relay style page request
+type Request struct {
+	First  *int
+	After  *string
+	Last   *int
+	Before *string
+}
+
+// Page is a decoded Request ready to be applied as a keyset query
+type Page struct {
+	Cursor *Cursor
+	Limit  int
+	// Backward pages towards the start of the result set (last/before)
+	Backward bool
+}
+
+// Resolve validates the request and decodes its cursor
+func (r Request) Resolve() (Page, error) {
+	if r.First != nil && r.Last != nil {
+		return Page{}, ErrFirstAndLast
+	}
+
+	page := Page{Limit: DefaultPageSize}
+	cursor := r.After
+	if r.Last != nil || (r.Before != nil && r.First == nil) {
+		page.Backward = true
+		cursor = r.Before
+		if r.Last != nil {
+			page.Limit = *r.Last
+		}
+	} else if r.First != nil {
+		page.Limit = *r.First
+	}
+
+	if page.Limit <= 0 {
+		page.Limit = DefaultPageSize
+	}
+	if page.Limit > MaxPageSize {
+		page.Limit = MaxPageSize
+	}
+
+	if cursor != nil {
+		decoded, err := DecodeCursor(*cursor)
+		if err != nil {
+			return Page{}, err
+		}
+		page.Cursor = decoded
+	}
+
+	return page, nil
+}
+
+// Keyset restricts a query to the rows strictly after the cursor position for
+// the given sort expression. descending is the effective direction of the
+// query, i.e. already flipped for backward pages.
+func Keyset(expression string, value interface{}, id string, descending bool) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		operator := ">"
+		if descending {
+			operator = "<"
+		}
+
+		return tx.Where("("+expression+" "+operator+" ?) OR ("+expression+" = ? AND id "+operator+" ?)", value, value, id)
+	}
+}
+
+// Reverse restores the natural order of rows fetched for a backward page
+func Reverse[T any](items []T) {
+	for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+		items[i], items[j] = items[j], items[i]
+	}
+}
+
+// Info mirrors the relay PageInfo for a fetched page
+type Info struct {
+	HasNextPage     bool
+	HasPreviousPage bool
+}
+
+// NewInfo derives page info from whether more rows existed past the page
+func NewInfo(page Page, hasMore bool) Info {
+	if page.Backward {
+		return Info{HasPreviousPage: hasMore, HasNextPage: page.Cursor != nil}
+	}
+
+	return Info{HasNextPage: hasMore, HasPreviousPage: page.Cursor != nil}
+}
+
+// ChangeWindow selects rows updated after a watermark and strictly before a
+// cutoff, oldest first. It is applied to unscoped queries so soft deleted rows
+// can be returned as tombstones.
+type ChangeWindow struct {
+	After          time.Time
+	AfterID        string
+	Before         time.Time
+	IncludeDeleted bool
+	Limit          int
+}
+
+func (w ChangeWindow) Scope() func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		tx = tx.Where("updated_at < ?", w.Before)
+		if !w.After.IsZero() {
+			tx = Keyset("updated_at", w.After, w.AfterID, false)(tx)
+		}
+		if !w.IncludeDeleted {
+			tx = tx.Where("deleted_at IS NULL")
+		}
+
+		return tx.Order("updated_at asc").Order("id asc").Limit(w.Limit)
+	}
+}
@@ -0,0 +1,71 @@
+package pagination_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weeb-vip/list-service/internal/pagination"
+)
+
+func intPtr(i int) *int { return &i }
+
+func strPtr(s string) *string { return &s }
+
+func TestCursor(t *testing.T) {
+	t.Run("round trips through its encoded form", func(t *testing.T) {
+		cursor := pagination.Cursor{Sort: "updated_at desc", Value: "2025-01-01T00:00:00Z", ID: "id_1"}
+
+		decoded, err := pagination.DecodeCursor(cursor.Encode())
+		assert.NoError(t, err)
+		assert.Equal(t, cursor, *decoded)
+	})
+
+	t.Run("rejects garbage", func(t *testing.T) {
+		_, err := pagination.DecodeCursor("not a cursor")
+		assert.ErrorIs(t, err, pagination.ErrInvalidCursor)
+	})
+}
+
+func TestRequestResolve(t *testing.T) {
+	after := pagination.Cursor{Sort: "created_at desc", ID: "id_1"}.Encode()
+
+	t.Run("defaults to a forward page", func(t *testing.T) {
+		page, err := pagination.Request{}.Resolve()
+		assert.NoError(t, err)
+		assert.Equal(t, pagination.Page{Limit: pagination.DefaultPageSize}, page)
+	})
+
+	t.Run("first and after page forward", func(t *testing.T) {
+		page, err := pagination.Request{First: intPtr(5), After: &after}.Resolve()
+		assert.NoError(t, err)
+		assert.False(t, page.Backward)
+		assert.Equal(t, 5, page.Limit)
+		assert.Equal(t, "id_1", page.Cursor.ID)
+	})
+
+	t.Run("last and before page backward", func(t *testing.T) {
+		page, err := pagination.Request{Last: intPtr(500), Before: &after}.Resolve()
+		assert.NoError(t, err)
+		assert.True(t, page.Backward)
+		assert.Equal(t, pagination.MaxPageSize, page.Limit)
+		assert.Equal(t, "id_1", page.Cursor.ID)
+	})
+
+	t.Run("first and last together are rejected", func(t *testing.T) {
+		_, err := pagination.Request{First: intPtr(1), Last: intPtr(1)}.Resolve()
+		assert.ErrorIs(t, err, pagination.ErrFirstAndLast)
+	})
+
+	t.Run("invalid cursors are rejected", func(t *testing.T) {
+		_, err := pagination.Request{After: strPtr("%%%")}.Resolve()
+		assert.ErrorIs(t, err, pagination.ErrInvalidCursor)
+	})
+}
+
+func TestNewInfo(t *testing.T) {
+	cursor := &pagination.Cursor{ID: "id_1"}
+
+	assert.Equal(t, pagination.Info{HasNextPage: true}, pagination.NewInfo(pagination.Page{}, true))
+	assert.Equal(t, pagination.Info{HasPreviousPage: true}, pagination.NewInfo(pagination.Page{Cursor: cursor}, false))
+	assert.Equal(t, pagination.Info{HasPreviousPage: true, HasNextPage: true}, pagination.NewInfo(pagination.Page{Cursor: cursor, Backward: true}, true))
+}
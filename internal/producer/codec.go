@@ -0,0 +1,101 @@
+package producer
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/weeb-vip/list-service/internal/broker"
+)
+
+// Codec turns message values into bytes. Codecs describing their payloads
+// also implement broker.SchemaDescriber.
+type Codec[T any] interface {
+	Encode(value T) ([]byte, error)
+	Decode(data []byte) (T, error)
+}
+
+type JSONCodec[T any] struct {
+	definition string
+}
+
+// NewJSONCodec encodes values as JSON. definition is the Avro record
+// definition describing the JSON, leave it empty to publish without a
+// registered schema.
+func NewJSONCodec[T any](definition string) (Codec[T], error) {
+	if definition != "" {
+		if _, err := goavro.NewCodec(definition); err != nil {
+			return nil, err
+		}
+	}
+
+	return &JSONCodec[T]{definition: definition}, nil
+}
+
+func (c *JSONCodec[T]) Encode(value T) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+func (c *JSONCodec[T]) Decode(data []byte) (T, error) {
+	var value T
+	err := json.Unmarshal(data, &value)
+	return value, err
+}
+
+func (c *JSONCodec[T]) Schema() (broker.SchemaEncoding, string) {
+	return broker.SchemaJSON, c.definition
+}
+
+// AvroCodec encodes values as binary Avro. ToNative and FromNative map values
+// to and from the generic form goavro works with.
+type AvroCodec[T any] struct {
+	codec      *goavro.Codec
+	definition string
+	toNative   func(T) (map[string]interface{}, error)
+	fromNative func(map[string]interface{}) (T, error)
+}
+
+var ErrUnexpectedAvroValue = errors.New("decoded avro value is not a record")
+
+func NewAvroCodec[T any](definition string, toNative func(T) (map[string]interface{}, error), fromNative func(map[string]interface{}) (T, error)) (Codec[T], error) {
+	codec, err := goavro.NewCodec(definition)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AvroCodec[T]{
+		codec:      codec,
+		definition: definition,
+		toNative:   toNative,
+		fromNative: fromNative,
+	}, nil
+}
+
+func (c *AvroCodec[T]) Encode(value T) ([]byte, error) {
+	native, err := c.toNative(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.codec.BinaryFromNative(nil, native)
+}
+
+func (c *AvroCodec[T]) Decode(data []byte) (T, error) {
+	native, _, err := c.codec.NativeFromBinary(data)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	record, ok := native.(map[string]interface{})
+	if !ok {
+		var zero T
+		return zero, ErrUnexpectedAvroValue
+	}
+
+	return c.fromNative(record)
+}
+
+func (c *AvroCodec[T]) Schema() (broker.SchemaEncoding, string) {
+	return broker.SchemaAvro, c.definition
+}
@@ -0,0 +1,63 @@
+package producer
+
+import (
+	"context"
+
+	"github.com/weeb-vip/list-service/internal/broker"
+	"github.com/weeb-vip/list-service/tracing"
+	"go.opentelemetry.io/otel/codes"
+)
+
+type Consumer[T any] interface {
+	// Consume decodes messages on topic and hands them to handler, see
+	// broker.Subscriber for the delivery guarantees. handler runs in a span
+	// continuing the trace of the publisher.
+	Consume(ctx context.Context, topic string, group string, handler func(ctx context.Context, message Message[T]) error) error
+}
+
+type ConsumerImpl[T any] struct {
+	subscriber broker.Subscriber
+	codec      Codec[T]
+}
+
+func NewConsumer[T any](subscriber broker.Subscriber, codec Codec[T]) Consumer[T] {
+	return &ConsumerImpl[T]{
+		subscriber: subscriber,
+		codec:      codec,
+	}
+}
+
+func (c *ConsumerImpl[T]) Consume(ctx context.Context, topic string, group string, handler func(ctx context.Context, message Message[T]) error) error {
+	return c.subscriber.Subscribe(ctx, topic, group, func(ctx context.Context, received broker.Message) error {
+		destination := received.Topic
+		if destination == "" {
+			destination = c.subscriber.DefaultTopic()
+		}
+
+		ctx, span := tracing.StartProcessSpan(ctx, c.subscriber.System(), destination, group, received.Headers)
+		defer span.End()
+
+		value, err := c.codec.Decode(received.Payload)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return err
+		}
+
+		err = handler(ctx, Message[T]{
+			Topic:      received.Topic,
+			Key:        received.Key,
+			Value:      value,
+			Properties: received.Headers,
+			EventTime:  received.EventTime,
+		})
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return err
+		}
+
+		span.SetStatus(codes.Ok, "")
+		return nil
+	})
+}
@@ -2,59 +2,199 @@ package producer
 
 import (
 	"context"
-	"github.com/apache/pulsar-client-go/pulsar"
-	"github.com/weeb-vip/list-service/config"
+	"errors"
+	"time"
+
+	"github.com/weeb-vip/list-service/internal/broker"
 	"github.com/weeb-vip/list-service/internal/logger"
+	"github.com/weeb-vip/list-service/tracing"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// Message is a value to publish. Messages with the same key are delivered in
+// the order they were sent.
+type Message[T any] struct {
+	// Topic overrides the configured producer topic
+	Topic      string
+	Key        string
+	Value      T
+	Properties map[string]string
+	EventTime  time.Time
+}
+
 type Producer[T any] interface {
-	Send(ctx context.Context, data []byte) error
+	// Send publishes a message and waits for the broker to acknowledge it
+	Send(ctx context.Context, message Message[T]) error
+	// SendAsync publishes a message in the background and calls callback with
+	// the outcome once all retries are exhausted
+	SendAsync(ctx context.Context, message Message[T], callback func(err error))
+	// Flush waits for queued messages to be published
+	Flush() error
+	// Close flushes and releases the broker. Sends after Close fail.
+	Close() error
+}
+
+type Option func(*options)
+
+type options struct {
+	maxRetries   int
+	retryBackoff time.Duration
+}
+
+// WithMaxRetries sets how often a failed publish is retried
+func WithMaxRetries(maxRetries int) Option {
+	return func(o *options) {
+		o.maxRetries = maxRetries
+	}
+}
+
+// WithRetryBackoff sets the wait before the first retry, it doubles with every
+// further retry
+func WithRetryBackoff(backoff time.Duration) Option {
+	return func(o *options) {
+		o.retryBackoff = backoff
+	}
 }
 
+// ProducerImpl encodes values with a codec and publishes them through a
+// broker, retrying failed publishes
 type ProducerImpl[T any] struct {
-	client pulsar.Client
-	config config.PulsarConfig
+	broker  broker.Broker
+	codec   Codec[T]
+	options options
 }
 
-func NewProducer[T any](ctx context.Context, cfg config.PulsarConfig) Producer[T] {
-	log := logger.FromCtx(ctx)
-	client, err := pulsar.NewClient(pulsar.ClientOptions{
-		URL: cfg.URL,
-	})
+func NewProducer[T any](b broker.Broker, codec Codec[T], opts ...Option) Producer[T] {
+	o := options{
+		maxRetries:   3,
+		retryBackoff: 200 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
 
+	return &ProducerImpl[T]{
+		broker:  b,
+		codec:   codec,
+		options: o,
+	}
+}
+
+func (p *ProducerImpl[T]) message(message Message[T]) (broker.Message, error) {
+	payload, err := p.codec.Encode(message.Value)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Error creating pulsar client")
-		return nil
+		return broker.Message{}, err
 	}
 
-	return &ProducerImpl[T]{
-		config: cfg,
-		client: client,
+	// copied so the trace context added on send does not leak into the
+	// caller's map
+	headers := make(map[string]string, len(message.Properties))
+	for name, value := range message.Properties {
+		headers[name] = value
+	}
+
+	return broker.Message{
+		Topic:     message.Topic,
+		Key:       message.Key,
+		Payload:   payload,
+		Headers:   headers,
+		EventTime: message.EventTime,
+	}, nil
+}
+
+// startSpan starts the publish span and carries its context in the message
+// headers, so consumers continue the trace
+func (p *ProducerImpl[T]) startSpan(ctx context.Context, brokerMessage *broker.Message) (context.Context, trace.Span) {
+	destination := brokerMessage.Topic
+	if destination == "" {
+		destination = p.broker.DefaultTopic()
+	}
+
+	ctx, span := tracing.StartPublishSpan(ctx, p.broker.System(), destination, len(brokerMessage.Payload))
+	tracing.InjectMessage(ctx, brokerMessage.Headers)
+
+	return ctx, span
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetStatus(codes.Ok, "")
 	}
+	span.End()
+}
+
+// backoff is the wait before the given retry, doubling from the configured base
+func (p *ProducerImpl[T]) backoff(retry int) time.Duration {
+	return p.options.retryBackoff << retry
 }
 
-func (p *ProducerImpl[T]) Send(ctx context.Context, data []byte) error {
+func (p *ProducerImpl[T]) Send(ctx context.Context, message Message[T]) (err error) {
 	log := logger.FromCtx(ctx)
-	producer, err := p.client.CreateProducer(pulsar.ProducerOptions{
-		Topic: p.config.ProducerTopic,
-	})
 
+	brokerMessage, err := p.message(message)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Error creating pulsar producer")
 		return err
 	}
 
-	defer producer.Close()
+	ctx, span := p.startSpan(ctx, &brokerMessage)
+	defer func() {
+		endSpan(span, err)
+	}()
+
+	for retry := 0; ; retry++ {
+		err := p.broker.Publish(ctx, brokerMessage)
+		if err == nil {
+			return nil
+		}
+		if errors.Is(err, broker.ErrClosed) || retry >= p.options.maxRetries {
+			log.Error().Err(err).Str("topic", brokerMessage.Topic).Int("attempts", retry+1).Msg("Error sending message")
+			return err
+		}
 
-	msg := pulsar.ProducerMessage{
-		Payload: data,
+		log.Warn().Err(err).Str("topic", brokerMessage.Topic).Int("attempt", retry+1).Msg("Retrying message")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(p.backoff(retry)):
+		}
 	}
+}
 
-	_, err = producer.Send(ctx, &msg)
+func (p *ProducerImpl[T]) SendAsync(ctx context.Context, message Message[T], callback func(err error)) {
+	brokerMessage, err := p.message(message)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Error sending message")
-		return err
+		callback(err)
+		return
 	}
 
-	return nil
+	ctx, span := p.startSpan(ctx, &brokerMessage)
+	p.sendAsync(ctx, brokerMessage, 0, func(err error) {
+		endSpan(span, err)
+		callback(err)
+	})
+}
+
+func (p *ProducerImpl[T]) sendAsync(ctx context.Context, brokerMessage broker.Message, retry int, callback func(err error)) {
+	p.broker.PublishAsync(ctx, brokerMessage, func(err error) {
+		if err == nil || errors.Is(err, broker.ErrClosed) || retry >= p.options.maxRetries || ctx.Err() != nil {
+			callback(err)
+			return
+		}
+
+		time.AfterFunc(p.backoff(retry), func() {
+			p.sendAsync(ctx, brokerMessage, retry+1, callback)
+		})
+	})
+}
+
+func (p *ProducerImpl[T]) Flush() error {
+	return p.broker.Flush()
+}
+
+func (p *ProducerImpl[T]) Close() error {
+	return p.broker.Close()
 }
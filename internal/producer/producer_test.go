@@ -0,0 +1,69 @@
+package producer_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weeb-vip/list-service/internal/broker"
+	"github.com/weeb-vip/list-service/internal/producer"
+	"github.com/weeb-vip/list-service/tracing"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type value struct {
+	Name string `json:"name"`
+}
+
+func TestTracePropagation(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	codec, err := producer.NewJSONCodec[value]("")
+	assert.NoError(t, err)
+	memoryBroker := broker.NewMemory("events")
+
+	ctx, request := otel.Tracer("test").Start(context.Background(), "request")
+	properties := map[string]string{"event_type": "test"}
+	err = producer.NewProducer[value](memoryBroker, codec).Send(ctx, producer.Message[value]{
+		Key:        "user_1",
+		Value:      value{Name: "a"},
+		Properties: properties,
+	})
+	assert.NoError(t, err)
+	request.End()
+
+	// the caller's properties are left alone
+	assert.Equal(t, map[string]string{"event_type": "test"}, properties)
+	assert.Contains(t, memoryBroker.Messages()[0].Headers, "traceparent")
+
+	consumeCtx, cancel := context.WithCancel(context.Background())
+	var handlerSpan trace.SpanContext
+	err = producer.NewConsumer[value](memoryBroker, codec).Consume(consumeCtx, "", "downstream", func(ctx context.Context, message producer.Message[value]) error {
+		assert.Equal(t, "a", message.Value.Name)
+		assert.Equal(t, "test", message.Properties["event_type"])
+		handlerSpan = trace.SpanContextFromContext(ctx)
+		cancel()
+		return nil
+	})
+	assert.NoError(t, err)
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	publish, process := spans["send events"], spans["process events"]
+	assert.NotNil(t, publish)
+	assert.NotNil(t, process)
+
+	assert.Equal(t, trace.SpanKindProducer, publish.SpanKind())
+	assert.Equal(t, request.SpanContext().SpanID(), publish.Parent().SpanID())
+	assert.Equal(t, trace.SpanKindConsumer, process.SpanKind())
+	assert.Equal(t, publish.SpanContext().SpanID(), process.Parent().SpanID())
+	assert.Equal(t, request.SpanContext().TraceID(), handlerSpan.TraceID())
+	assert.Contains(t, publish.Attributes(), tracing.MessagingSystemKey.String("memory"))
+	assert.Contains(t, process.Attributes(), tracing.MessagingConsumerGroupKey.String("downstream"))
+}
@@ -0,0 +1,206 @@
+package relay
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/weeb-vip/list-service/internal/db/repositories/outbox"
+	"github.com/weeb-vip/list-service/internal/events"
+	"github.com/weeb-vip/list-service/internal/logger"
+	"github.com/weeb-vip/list-service/internal/producer"
+)
+
+const (
+	DefaultBatchSize    = 100
+	DefaultPollInterval = time.Second
+	DefaultMaxBackoff   = time.Minute
+	DefaultLease        = time.Minute
+	DefaultRetention    = 7 * 24 * time.Hour
+	DefaultMaxAttempts  = 20
+
+	// pruneInterval is how often sent messages past the retention are deleted
+	pruneInterval = time.Hour
+)
+
+type RelayImpl interface {
+	// Run publishes outbox messages until ctx is cancelled
+	Run(ctx context.Context) error
+	// RelayOnce publishes one batch and returns how many messages were sent
+	// or given up on
+	RelayOnce(ctx context.Context) (int, error)
+	// Prune deletes messages sent longer than the retention ago and returns
+	// how many
+	Prune(ctx context.Context) (int64, error)
+}
+
+type Relay struct {
+	Repository   outbox.OutboxRepositoryImpl
+	Producer     producer.Producer[events.Event]
+	BatchSize    int
+	PollInterval time.Duration
+	MaxBackoff   time.Duration
+	// Lease is how long a claimed batch is left to this relay, it should
+	// outlast publishing a batch
+	Lease time.Duration
+	// Retention is how long sent messages are kept, 0 keeps them forever
+	Retention time.Duration
+	// MaxAttempts is how often a message is tried before it is failed and
+	// the messages after it go ahead, 0 retries forever
+	MaxAttempts int
+	// Now is the clock retention is measured by
+	Now func() time.Time
+}
+
+type Option func(*Relay)
+
+func WithBatchSize(batchSize int) Option {
+	return func(r *Relay) {
+		r.BatchSize = batchSize
+	}
+}
+
+func WithPollInterval(pollInterval time.Duration) Option {
+	return func(r *Relay) {
+		r.PollInterval = pollInterval
+	}
+}
+
+func WithMaxBackoff(maxBackoff time.Duration) Option {
+	return func(r *Relay) {
+		r.MaxBackoff = maxBackoff
+	}
+}
+
+func WithLease(lease time.Duration) Option {
+	return func(r *Relay) {
+		r.Lease = lease
+	}
+}
+
+func WithRetention(retention time.Duration) Option {
+	return func(r *Relay) {
+		r.Retention = retention
+	}
+}
+
+func WithMaxAttempts(maxAttempts int) Option {
+	return func(r *Relay) {
+		r.MaxAttempts = maxAttempts
+	}
+}
+
+func WithClock(now func() time.Time) Option {
+	return func(r *Relay) {
+		r.Now = now
+	}
+}
+
+func NewRelay(repository outbox.OutboxRepositoryImpl, eventProducer producer.Producer[events.Event], opts ...Option) RelayImpl {
+	relay := &Relay{
+		Repository:   repository,
+		Producer:     eventProducer,
+		BatchSize:    DefaultBatchSize,
+		PollInterval: DefaultPollInterval,
+		MaxBackoff:   DefaultMaxBackoff,
+		Lease:        DefaultLease,
+		Retention:    DefaultRetention,
+		MaxAttempts:  DefaultMaxAttempts,
+		Now:          time.Now,
+	}
+	for _, opt := range opts {
+		opt(relay)
+	}
+
+	return relay
+}
+
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	return r.Repository.ProcessUnsent(ctx, r.BatchSize, r.Lease, func(ctx context.Context, message *outbox.OutboxMessage) error {
+		event := message.Event()
+
+		// publish as part of the trace the change was made in rather than
+		// the relay's poll loop
+		ctx = message.Context(ctx)
+
+		// keyed by user so consumers see each user's changes in order
+		err := r.Producer.Send(ctx, producer.Message[events.Event]{
+			Key:   event.UserID,
+			Value: *event,
+			Properties: map[string]string{
+				"event_id":   event.ID,
+				"event_type": string(event.Type),
+			},
+			EventTime: event.OccurredAt,
+		})
+		if err != nil && r.MaxAttempts > 0 && message.Attempts+1 >= r.MaxAttempts {
+			// a message that cannot be published would hold back every
+			// message after it
+			log := logger.FromCtx(ctx)
+			log.Error().Err(err).
+				Uint64("sequence", message.Sequence).
+				Str("event_id", event.ID).
+				Str("event_type", string(event.Type)).
+				Int("attempts", message.Attempts+1).
+				Msg("Giving up on outbox message")
+			return fmt.Errorf("%w: %w", outbox.ErrFailed, err)
+		}
+
+		return err
+	})
+}
+
+func (r *Relay) Prune(ctx context.Context) (int64, error) {
+	if r.Retention <= 0 {
+		return 0, nil
+	}
+
+	return r.Repository.PruneSent(ctx, r.Now().Add(-r.Retention))
+}
+
+func (r *Relay) Run(ctx context.Context) error {
+	log := logger.FromCtx(ctx)
+	log.Info().Int("batch_size", r.BatchSize).Dur("poll_interval", r.PollInterval).Msg("Starting outbox relay")
+
+	backoff := r.PollInterval
+	var prunedAt time.Time
+	for {
+		if r.Now().Sub(prunedAt) >= pruneInterval {
+			pruned, err := r.Prune(ctx)
+			if err != nil {
+				log.Error().Err(err).Msg("Error pruning sent outbox messages")
+			} else if pruned > 0 {
+				log.Info().Int64("pruned", pruned).Msg("Pruned sent outbox messages")
+			}
+			prunedAt = r.Now()
+		}
+
+		sent, err := r.RelayOnce(ctx)
+
+		wait := r.PollInterval
+		switch {
+		case err != nil:
+			// failed messages stay at the head of the queue until MaxAttempts,
+			// back off before retrying them
+			log.Error().Err(err).Int("sent", sent).Dur("retry_in", backoff).Msg("Error relaying outbox messages")
+			wait = backoff
+			backoff *= 2
+			if backoff > r.MaxBackoff {
+				backoff = r.MaxBackoff
+			}
+		case sent == r.BatchSize:
+			// more messages are likely waiting
+			backoff = r.PollInterval
+			wait = 0
+		default:
+			backoff = r.PollInterval
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("Stopping outbox relay")
+			return nil
+		case <-time.After(wait):
+		}
+	}
+}
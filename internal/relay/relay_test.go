@@ -0,0 +1,258 @@
+package relay_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weeb-vip/list-service/internal/broker"
+	"github.com/weeb-vip/list-service/internal/db/repositories/outbox"
+	"github.com/weeb-vip/list-service/internal/events"
+	"github.com/weeb-vip/list-service/internal/producer"
+	"github.com/weeb-vip/list-service/internal/relay"
+)
+
+// memoryOutbox mirrors the ordering contract of the outbox repository without
+// a database, the mutex standing in for the claim
+type memoryOutbox struct {
+	mutex    sync.Mutex
+	messages []*outbox.OutboxMessage
+}
+
+func (m *memoryOutbox) Add(ctx context.Context, event *events.Event) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.messages = append(m.messages, &outbox.OutboxMessage{
+		Sequence:    uint64(len(m.messages) + 1),
+		EventID:     event.ID,
+		EventType:   string(event.Type),
+		AggregateID: event.AggregateID,
+		UserID:      event.UserID,
+		Payload:     event.Payload,
+		OccurredAt:  event.OccurredAt,
+	})
+	return nil
+}
+
+func (m *memoryOutbox) ProcessUnsent(ctx context.Context, limit int, lease time.Duration, fn func(ctx context.Context, message *outbox.OutboxMessage) error) (int, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	sent := 0
+	for _, message := range m.messages {
+		if sent == limit {
+			break
+		}
+		if message.SentAt != nil || message.FailedAt != nil {
+			continue
+		}
+
+		err := fn(ctx, message)
+		message.Attempts++
+		now := time.Now()
+		if err != nil {
+			lastError := err.Error()
+			message.LastError = &lastError
+			if !errors.Is(err, outbox.ErrFailed) {
+				return sent, err
+			}
+			message.FailedAt = &now
+		} else {
+			message.SentAt = &now
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+func (m *memoryOutbox) PruneSent(ctx context.Context, before time.Time) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var kept []*outbox.OutboxMessage
+	for _, message := range m.messages {
+		if message.SentAt == nil || !message.SentAt.Before(before) {
+			kept = append(kept, message)
+		}
+	}
+	pruned := int64(len(m.messages) - len(kept))
+	m.messages = kept
+
+	return pruned, nil
+}
+
+func addEvents(t *testing.T, store *memoryOutbox, aggregateIDs ...string) {
+	for _, aggregateID := range aggregateIDs {
+		event, err := events.New(events.UserAnimeAdded, aggregateID, "user_1", map[string]string{"id": aggregateID})
+		assert.NoError(t, err)
+		assert.NoError(t, store.Add(context.Background(), event))
+	}
+}
+
+// newProducer publishes to an in memory broker without retries, so failures
+// surface to the relay straight away
+func newProducer(t *testing.T) (*broker.Memory, producer.Producer[events.Event]) {
+	codec, err := events.NewCodec("json")
+	assert.NoError(t, err)
+
+	memoryBroker := broker.NewMemory("events")
+	return memoryBroker, producer.NewProducer[events.Event](memoryBroker, codec, producer.WithMaxRetries(0))
+}
+
+// failingCodec cannot encode any event, like a payload the schema rejects
+type failingCodec struct {
+	producer.Codec[events.Event]
+}
+
+func (failingCodec) Encode(events.Event) ([]byte, error) {
+	return nil, errors.New("payload does not match the schema")
+}
+
+func sentAggregates(t *testing.T, memoryBroker *broker.Memory) []string {
+	codec, err := events.NewCodec("json")
+	assert.NoError(t, err)
+
+	var aggregateIDs []string
+	for _, message := range memoryBroker.Messages() {
+		assert.Equal(t, "user_1", message.Key)
+		assert.Equal(t, string(events.UserAnimeAdded), message.Headers["event_type"])
+
+		event, err := codec.Decode(message.Payload)
+		assert.NoError(t, err)
+		aggregateIDs = append(aggregateIDs, event.AggregateID)
+	}
+	return aggregateIDs
+}
+
+func TestRelayOnce(t *testing.T) {
+	t.Run("publishes in order and marks messages sent", func(t *testing.T) {
+		store := &memoryOutbox{}
+		memoryBroker, memoryProducer := newProducer(t)
+		addEvents(t, store, "a", "b", "c")
+
+		sent, err := relay.NewRelay(store, memoryProducer, relay.WithBatchSize(2)).RelayOnce(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 2, sent)
+
+		sent, err = relay.NewRelay(store, memoryProducer, relay.WithBatchSize(2)).RelayOnce(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 1, sent)
+
+		assert.Equal(t, []string{"a", "b", "c"}, sentAggregates(t, memoryBroker))
+	})
+
+	t.Run("a failed send is retried before later messages", func(t *testing.T) {
+		store := &memoryOutbox{}
+		memoryBroker, memoryProducer := newProducer(t)
+		addEvents(t, store, "a", "b")
+		eventRelay := relay.NewRelay(store, memoryProducer)
+
+		memoryBroker.SetErr(errors.New("broker unavailable"))
+		sent, err := eventRelay.RelayOnce(context.Background())
+		assert.Error(t, err)
+		assert.Equal(t, 0, sent)
+		assert.Equal(t, "broker unavailable", *store.messages[0].LastError)
+		assert.Nil(t, store.messages[1].SentAt)
+
+		memoryBroker.SetErr(nil)
+		sent, err = eventRelay.RelayOnce(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 2, sent)
+		assert.Equal(t, 2, store.messages[0].Attempts)
+		assert.Equal(t, []string{"a", "b"}, sentAggregates(t, memoryBroker))
+	})
+}
+
+func TestRelayGivesUp(t *testing.T) {
+	codec, err := events.NewCodec("json")
+	assert.NoError(t, err)
+	memoryBroker := broker.NewMemory("events")
+	failingProducer := producer.NewProducer[events.Event](memoryBroker, failingCodec{codec}, producer.WithMaxRetries(0))
+
+	t.Run("a message failing every attempt stops holding back later ones", func(t *testing.T) {
+		store := &memoryOutbox{}
+		addEvents(t, store, "a", "b")
+		eventRelay := relay.NewRelay(store, failingProducer, relay.WithMaxAttempts(3))
+
+		for attempt := 1; attempt < 3; attempt++ {
+			sent, err := eventRelay.RelayOnce(context.Background())
+			assert.Error(t, err)
+			assert.Equal(t, 0, sent)
+			assert.Nil(t, store.messages[0].FailedAt)
+		}
+
+		// the last attempt fails the head, the next message is tried in the
+		// same batch
+		sent, err := eventRelay.RelayOnce(context.Background())
+		assert.Error(t, err)
+		assert.Equal(t, 1, sent)
+		assert.Equal(t, 3, store.messages[0].Attempts)
+		assert.NotNil(t, store.messages[0].FailedAt)
+		assert.Contains(t, *store.messages[0].LastError, "payload does not match the schema")
+		assert.Equal(t, 1, store.messages[1].Attempts)
+		assert.Nil(t, store.messages[1].FailedAt)
+		assert.Empty(t, memoryBroker.Messages())
+	})
+
+	t.Run("no limit retries forever", func(t *testing.T) {
+		store := &memoryOutbox{}
+		addEvents(t, store, "a", "b")
+		eventRelay := relay.NewRelay(store, failingProducer, relay.WithMaxAttempts(0))
+
+		for attempt := 0; attempt < relay.DefaultMaxAttempts+1; attempt++ {
+			_, err := eventRelay.RelayOnce(context.Background())
+			assert.Error(t, err)
+		}
+		assert.Nil(t, store.messages[0].FailedAt)
+		assert.Zero(t, store.messages[1].Attempts)
+	})
+}
+
+func TestRelayPrune(t *testing.T) {
+	store := &memoryOutbox{}
+	_, memoryProducer := newProducer(t)
+	addEvents(t, store, "a", "b")
+	_, err := relay.NewRelay(store, memoryProducer).RelayOnce(context.Background())
+	assert.NoError(t, err)
+	addEvents(t, store, "c")
+
+	now := time.Now()
+	pruned, err := relay.NewRelay(store, memoryProducer, relay.WithClock(func() time.Time { return now })).Prune(context.Background())
+	assert.NoError(t, err)
+	assert.Zero(t, pruned)
+
+	later := func() time.Time { return now.Add(relay.DefaultRetention + time.Minute) }
+	pruned, err = relay.NewRelay(store, memoryProducer, relay.WithRetention(0), relay.WithClock(later)).Prune(context.Background())
+	assert.NoError(t, err)
+	assert.Zero(t, pruned)
+
+	pruned, err = relay.NewRelay(store, memoryProducer, relay.WithClock(later)).Prune(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), pruned)
+	// unsent messages are kept however old
+	assert.Len(t, store.messages, 1)
+}
+
+func TestRelayRun(t *testing.T) {
+	store := &memoryOutbox{}
+	memoryBroker, memoryProducer := newProducer(t)
+	addEvents(t, store, "a")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- relay.NewRelay(store, memoryProducer, relay.WithPollInterval(10*time.Millisecond)).Run(ctx)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return len(memoryBroker.Messages()) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	assert.NoError(t, <-done)
+}
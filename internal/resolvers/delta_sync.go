@@ -0,0 +1,264 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/weeb-vip/list-service/graph/model"
+	"github.com/weeb-vip/list-service/http/handlers/requestinfo"
+	"github.com/weeb-vip/list-service/internal/services/delta_sync"
+	"github.com/weeb-vip/list-service/internal/services/user_anime"
+	"github.com/weeb-vip/list-service/internal/services/user_list"
+	"github.com/weeb-vip/list-service/metrics"
+	"github.com/weeb-vip/list-service/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+func ConvertChangesToGraphql(changes *delta_sync.Changes) (*model.SyncChanges, error) {
+	syncChanges := &model.SyncChanges{
+		UserAnimes: make([]*model.UserAnime, 0, len(changes.UserAnimes)),
+		UserLists:  make([]*model.UserList, 0, len(changes.UserLists)),
+		Tombstones: make([]*model.Tombstone, 0, len(changes.Tombstones)),
+		SyncToken:  changes.Token,
+		HasMore:    changes.HasMore,
+	}
+
+	for _, userAnimeEntity := range changes.UserAnimes {
+		userAnimeModel, err := ConvertUserAnimeToGraphql(userAnimeEntity)
+		if err != nil {
+			return nil, err
+		}
+		syncChanges.UserAnimes = append(syncChanges.UserAnimes, userAnimeModel)
+	}
+
+	for _, userListEntity := range changes.UserLists {
+		userListModel, err := ConvertUserListToGraphql(userListEntity)
+		if err != nil {
+			return nil, err
+		}
+		syncChanges.UserLists = append(syncChanges.UserLists, userListModel)
+	}
+
+	for _, tombstone := range changes.Tombstones {
+		syncChanges.Tombstones = append(syncChanges.Tombstones, &model.Tombstone{
+			Type:      model.SyncEntityType(tombstone.Type),
+			ID:        tombstone.ID,
+			AnimeID:   tombstone.AnimeID,
+			DeletedAt: tombstone.DeletedAt,
+		})
+	}
+
+	return syncChanges, nil
+}
+
+func ConvertPushResultToGraphql(pushResult *delta_sync.PushResult) (*model.PushChangesResult, error) {
+	result := &model.PushChangesResult{
+		UserAnimes: make([]*model.UserAnime, 0, len(pushResult.UserAnimes)),
+		UserLists:  make([]*model.UserList, 0, len(pushResult.UserLists)),
+		Conflicts:  make([]*model.SyncConflict, 0, len(pushResult.Conflicts)),
+	}
+
+	for _, userAnimeEntity := range pushResult.UserAnimes {
+		userAnimeModel, err := ConvertUserAnimeToGraphql(userAnimeEntity)
+		if err != nil {
+			return nil, err
+		}
+		result.UserAnimes = append(result.UserAnimes, userAnimeModel)
+	}
+
+	for _, userListEntity := range pushResult.UserLists {
+		userListModel, err := ConvertUserListToGraphql(userListEntity)
+		if err != nil {
+			return nil, err
+		}
+		result.UserLists = append(result.UserLists, userListModel)
+	}
+
+	for _, conflict := range pushResult.Conflicts {
+		syncConflict := &model.SyncConflict{
+			Type:   model.SyncEntityType(conflict.Type),
+			ID:     conflict.ID,
+			Reason: conflict.Reason,
+		}
+		if conflict.ServerAnime != nil {
+			serverAnime, err := ConvertUserAnimeToGraphql(conflict.ServerAnime)
+			if err != nil {
+				return nil, err
+			}
+			syncConflict.ServerAnime = serverAnime
+		}
+		if conflict.ServerList != nil {
+			serverList, err := ConvertUserListToGraphql(conflict.ServerList)
+			if err != nil {
+				return nil, err
+			}
+			syncConflict.ServerList = serverList
+		}
+		result.Conflicts = append(result.Conflicts, syncConflict)
+	}
+
+	return result, nil
+}
+
+func GetChangesSince(ctx context.Context, deltaSyncService delta_sync.DeltaSyncServiceImpl, token *string, limit *int) (*model.SyncChanges, error) {
+	// Start tracing span
+	tracer := tracing.GetTracer(ctx)
+	ctx, span := tracer.Start(ctx, "GetChangesSince")
+	span.SetAttributes(
+		attribute.String("resolver.name", "GetChangesSince"),
+		attribute.Bool("sync.full", token == nil),
+	)
+	defer span.End()
+
+	startTime := time.Now()
+
+	// get userid from requestInfo
+	req := requestinfo.FromContext(ctx)
+	userID := req.UserID
+	if userID == nil {
+		span.RecordError(errors.New("User ID is missing, unauthenticated"))
+		span.SetStatus(codes.Error, "User ID is missing, unauthenticated")
+
+		metrics.GetAppMetrics().ResolverMetric(
+			float64(time.Since(startTime).Milliseconds()),
+			"GetChangesSince",
+			metrics.Error,
+		)
+
+		return nil, errors.New("User ID is missing, unauthenticated")
+	}
+
+	span.SetAttributes(attribute.String("user.id", *userID))
+
+	var pageSize int
+	if limit != nil {
+		pageSize = *limit
+	}
+	changes, err := deltaSyncService.ChangesSince(ctx, *userID, token, pageSize)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		metrics.GetAppMetrics().ResolverMetric(
+			float64(time.Since(startTime).Milliseconds()),
+			"GetChangesSince",
+			metrics.Error,
+		)
+
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "")
+	span.SetAttributes(
+		attribute.Int("sync.user_anime.count", len(changes.UserAnimes)),
+		attribute.Int("sync.user_list.count", len(changes.UserLists)),
+		attribute.Int("sync.tombstone.count", len(changes.Tombstones)),
+	)
+
+	metrics.GetAppMetrics().ResolverMetric(
+		float64(time.Since(startTime).Milliseconds()),
+		"GetChangesSince",
+		metrics.Success,
+	)
+
+	return ConvertChangesToGraphql(changes)
+}
+
+func PushChanges(ctx context.Context, deltaSyncService delta_sync.DeltaSyncServiceImpl, input model.PushChangesInput) (*model.PushChangesResult, error) {
+	// Start tracing span
+	tracer := tracing.GetTracer(ctx)
+	ctx, span := tracer.Start(ctx, "PushChanges")
+	span.SetAttributes(
+		attribute.String("resolver.name", "PushChanges"),
+		attribute.Int("sync.anime_edit.count", len(input.Animes)),
+		attribute.Int("sync.list_edit.count", len(input.Lists)),
+	)
+	defer span.End()
+
+	startTime := time.Now()
+
+	// get userid from requestInfo
+	req := requestinfo.FromContext(ctx)
+	userID := req.UserID
+	if userID == nil {
+		span.RecordError(errors.New("User ID is missing, unauthenticated"))
+		span.SetStatus(codes.Error, "User ID is missing, unauthenticated")
+
+		metrics.GetAppMetrics().ResolverMetric(
+			float64(time.Since(startTime).Milliseconds()),
+			"PushChanges",
+			metrics.Error,
+		)
+
+		return nil, errors.New("User ID is missing, unauthenticated")
+	}
+
+	span.SetAttributes(attribute.String("user.id", *userID))
+
+	animeEdits := make([]*delta_sync.AnimeEdit, 0, len(input.Animes))
+	for _, edit := range input.Animes {
+		var status *user_anime.UserAnimeStatus
+		if edit.Status != nil {
+			statuss := user_anime.UserAnimeStatus(*edit.Status)
+			status = &statuss
+		}
+		animeEdits = append(animeEdits, &delta_sync.AnimeEdit{
+			Entry: user_anime.UserAnime{
+				UserID:             *userID,
+				AnimeID:            edit.AnimeID,
+				Status:             status,
+				Score:              edit.Score,
+				Episodes:           edit.Episodes,
+				Rewatching:         edit.Rewatching,
+				RewatchingEpisodes: edit.RewatchingEpisodes,
+				Tags:               edit.Tags,
+				ListID:             edit.ListID,
+			},
+			Deleted:  edit.Deleted != nil && *edit.Deleted,
+			EditedAt: edit.UpdatedAt,
+		})
+	}
+
+	listEdits := make([]*delta_sync.ListEdit, 0, len(input.Lists))
+	for _, edit := range input.Lists {
+		listEdits = append(listEdits, &delta_sync.ListEdit{
+			List: user_list.UserList{
+				ID:          edit.ID,
+				UserID:      *userID,
+				Name:        edit.Name,
+				IsPublic:    edit.IsPublic,
+				Tags:        edit.Tags,
+				Description: edit.Description,
+			},
+			Deleted:  edit.Deleted != nil && *edit.Deleted,
+			EditedAt: edit.UpdatedAt,
+		})
+	}
+
+	pushResult, err := deltaSyncService.Push(ctx, *userID, animeEdits, listEdits)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		metrics.GetAppMetrics().ResolverMetric(
+			float64(time.Since(startTime).Milliseconds()),
+			"PushChanges",
+			metrics.Error,
+		)
+
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "")
+	span.SetAttributes(attribute.Int("sync.conflict.count", len(pushResult.Conflicts)))
+
+	metrics.GetAppMetrics().ResolverMetric(
+		float64(time.Since(startTime).Milliseconds()),
+		"PushChanges",
+		metrics.Success,
+	)
+
+	return ConvertPushResultToGraphql(pushResult)
+}
@@ -0,0 +1,182 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/weeb-vip/list-service/graph/model"
+	"github.com/weeb-vip/list-service/http/handlers/requestinfo"
+	"github.com/weeb-vip/list-service/internal/buildinfo"
+	"github.com/weeb-vip/list-service/internal/dataloader"
+	user_anime2 "github.com/weeb-vip/list-service/internal/db/repositories/user_anime"
+	"github.com/weeb-vip/list-service/internal/services/user_list"
+	"github.com/weeb-vip/list-service/metrics"
+	"github.com/weeb-vip/list-service/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+var errLoaderMissing = errors.New("DataLoader not available in context")
+
+var errUnauthenticated = errors.New("User ID is missing, unauthenticated")
+
+// FindUserListEntity resolves a list referenced by another subgraph. Lists
+// the caller may not see resolve to nil, as if they did not exist.
+func FindUserListEntity(ctx context.Context, id string) (*model.UserList, error) {
+	// Start tracing span
+	tracer := tracing.GetTracer(ctx)
+	ctx, span := tracer.Start(ctx, "FindUserListEntity")
+	span.SetAttributes(
+		attribute.String("resolver.name", "FindUserListEntity"),
+		attribute.String("user_list.id", id),
+	)
+	defer span.End()
+
+	startTime := time.Now()
+
+	loader, ok := dataloader.GetUserListLoader(ctx)
+	if !ok {
+		span.RecordError(errLoaderMissing)
+		span.SetStatus(codes.Error, errLoaderMissing.Error())
+
+		metrics.GetAppMetrics().ResolverMetric(
+			float64(time.Since(startTime).Milliseconds()),
+			"FindUserListEntity",
+			metrics.Error,
+		)
+
+		return nil, errLoaderMissing
+	}
+
+	userList, err := loader.Load(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		metrics.GetAppMetrics().ResolverMetric(
+			float64(time.Since(startTime).Milliseconds()),
+			"FindUserListEntity",
+			metrics.Error,
+		)
+
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	metrics.GetAppMetrics().ResolverMetric(
+		float64(time.Since(startTime).Milliseconds()),
+		"FindUserListEntity",
+		metrics.Success,
+	)
+
+	if userList == nil || !user_list.VisibleTo(userList, requestinfo.UserID(ctx)) {
+		return nil, nil
+	}
+
+	return ConvertUserListToGraphql(userList)
+}
+
+// FindUserAnimeEntity resolves an entry referenced by another subgraph.
+// Entries are seen by their owner, and by others when the owner shares their
+// anime list and the entry is not on one of the owner's private lists.
+// Entries the caller may not see resolve to nil.
+func FindUserAnimeEntity(ctx context.Context, id string) (*model.UserAnime, error) {
+	// Start tracing span
+	tracer := tracing.GetTracer(ctx)
+	ctx, span := tracer.Start(ctx, "FindUserAnimeEntity")
+	span.SetAttributes(
+		attribute.String("resolver.name", "FindUserAnimeEntity"),
+		attribute.String("user_anime.id", id),
+	)
+	defer span.End()
+
+	startTime := time.Now()
+
+	userAnime, err := findVisibleUserAnime(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		metrics.GetAppMetrics().ResolverMetric(
+			float64(time.Since(startTime).Milliseconds()),
+			"FindUserAnimeEntity",
+			metrics.Error,
+		)
+
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	metrics.GetAppMetrics().ResolverMetric(
+		float64(time.Since(startTime).Milliseconds()),
+		"FindUserAnimeEntity",
+		metrics.Success,
+	)
+
+	if userAnime == nil {
+		return nil, nil
+	}
+
+	return ConvertUserAnimeToGraphql(userAnime)
+}
+
+// findVisibleUserAnime loads the entry with the given id, nil when it does not
+// exist or the caller may not see it
+func findVisibleUserAnime(ctx context.Context, id string) (*user_anime2.UserAnime, error) {
+	userAnimeLoader, ok := dataloader.GetUserAnimeByIDLoader(ctx)
+	if !ok {
+		return nil, errLoaderMissing
+	}
+	userListLoader, ok := dataloader.GetUserListLoader(ctx)
+	if !ok {
+		return nil, errLoaderMissing
+	}
+
+	userAnime, err := userAnimeLoader.Load(ctx, id)
+	if err != nil || userAnime == nil || userAnime.UserID == nil || userAnime.AnimeID == nil {
+		return nil, err
+	}
+
+	owner, settings, err := profileViewer(ctx, *userAnime.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if owner {
+		return userAnime, nil
+	}
+	if !settings.AnimeListPublic {
+		return nil, nil
+	}
+
+	if userAnime.ListID == nil {
+		return userAnime, nil
+	}
+
+	userList, err := userListLoader.Load(ctx, *userAnime.ListID)
+	if err != nil {
+		return nil, err
+	}
+
+	// only the owner's own lists hide their entries
+	if userList != nil && userList.UserID != nil && *userList.UserID == *userAnime.UserID && !user_list.IsPublic(userList) {
+		return nil, nil
+	}
+
+	return userAnime, nil
+}
+
+// GetListServiceAPI returns the build details of the running service,
+// fallbackVersion is reported when no version was set at link time
+func GetListServiceAPI(fallbackVersion string) *model.ListServiceAPI {
+	info := buildinfo.Get(fallbackVersion)
+
+	return &model.ListServiceAPI{
+		Version:   info.Version,
+		Commit:    info.Commit,
+		BuildTime: info.BuildTime,
+		GoVersion: info.GoVersion,
+	}
+}
@@ -0,0 +1,71 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	user_anime2 "github.com/weeb-vip/list-service/internal/db/repositories/user_anime"
+	user_list2 "github.com/weeb-vip/list-service/internal/db/repositories/user_list"
+	webhook2 "github.com/weeb-vip/list-service/internal/db/repositories/webhook"
+	"github.com/weeb-vip/list-service/internal/optional"
+	"github.com/weeb-vip/list-service/internal/services/privacy_settings"
+	"github.com/weeb-vip/list-service/internal/services/webhook"
+)
+
+const (
+	VersionConflictCode = "VERSION_CONFLICT"
+	AlreadyExistsCode   = "ALREADY_EXISTS"
+	NotFoundCode        = "NOT_FOUND"
+	InvalidInputCode    = "INVALID_INPUT"
+)
+
+// convertServiceError attaches a machine readable code to errors clients are
+// expected to handle. A stale write also carries the current server copy, so
+// clients can merge and retry without a refetch. Other errors are returned
+// unchanged.
+func convertServiceError(ctx context.Context, err error) error {
+	extensions := map[string]interface{}{}
+
+	var userAnimeConflict *user_anime2.VersionConflictError
+	var userListConflict *user_list2.VersionConflictError
+	switch {
+	case errors.As(err, &userAnimeConflict):
+		current, convertErr := ConvertUserAnimeToGraphql(userAnimeConflict.Current)
+		if convertErr != nil {
+			return err
+		}
+		extensions["code"] = VersionConflictCode
+		extensions["current"] = current
+	case errors.As(err, &userListConflict):
+		current, convertErr := ConvertUserListToGraphql(userListConflict.Current)
+		if convertErr != nil {
+			return err
+		}
+		extensions["code"] = VersionConflictCode
+		extensions["current"] = current
+	case errors.Is(err, user_anime2.ErrUserAnimeExists):
+		extensions["code"] = AlreadyExistsCode
+	case errors.Is(err, user_anime2.ErrUserAnimeNotFound), errors.Is(err, user_list2.ErrUserListNotFound), errors.Is(err, webhook2.ErrSubscriptionNotFound):
+		extensions["code"] = NotFoundCode
+	case errors.Is(err, webhook.ErrInvalidURL), errors.Is(err, webhook.ErrInvalidEventType), errors.Is(err, webhook.ErrEventTypesRequired), errors.Is(err, privacy_settings.ErrSettingRequired):
+		extensions["code"] = InvalidInputCode
+	default:
+		return err
+	}
+
+	return &gqlerror.Error{
+		Message:    err.Error(),
+		Path:       graphql.GetPath(ctx),
+		Extensions: extensions,
+	}
+}
+
+// optionalFromOmittable carries over whether an input field was provided
+func optionalFromOmittable[T any](value graphql.Omittable[T]) optional.Value[T] {
+	if v, ok := value.ValueOK(); ok {
+		return optional.Of(v)
+	}
+	return optional.Value[T]{}
+}
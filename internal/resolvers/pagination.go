@@ -0,0 +1,15 @@
+package resolvers
+
+import (
+	"github.com/weeb-vip/list-service/graph/model"
+	"github.com/weeb-vip/list-service/internal/pagination"
+)
+
+// ConvertPageInfoToGraphql converts page info to its GraphQL model. Start and end
+// cursors are filled in by the caller once the edges are built.
+func ConvertPageInfoToGraphql(info pagination.Info) *model.PageInfo {
+	return &model.PageInfo{
+		HasNextPage:     info.HasNextPage,
+		HasPreviousPage: info.HasPreviousPage,
+	}
+}
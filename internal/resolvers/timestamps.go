@@ -0,0 +1,25 @@
+package resolvers
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// formatTimestamp renders entity timestamps as RFC3339, leaving unset ones out
+func formatTimestamp(t time.Time) *string {
+	if t.IsZero() {
+		return nil
+	}
+
+	formatted := t.UTC().Format(time.RFC3339)
+	return &formatted
+}
+
+func formatDeletedAt(deletedAt gorm.DeletedAt) *string {
+	if !deletedAt.Valid {
+		return nil
+	}
+
+	return formatTimestamp(deletedAt.Time)
+}
@@ -0,0 +1,336 @@
+package resolvers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/weeb-vip/list-service/graph/model"
+	"github.com/weeb-vip/list-service/http/handlers/requestinfo"
+	"github.com/weeb-vip/list-service/internal/dataloader"
+	privacy_settings2 "github.com/weeb-vip/list-service/internal/db/repositories/privacy_settings"
+	"github.com/weeb-vip/list-service/internal/pagination"
+	"github.com/weeb-vip/list-service/internal/services/privacy_settings"
+	"github.com/weeb-vip/list-service/internal/services/user_anime"
+	"github.com/weeb-vip/list-service/internal/services/user_list"
+	"github.com/weeb-vip/list-service/metrics"
+	"github.com/weeb-vip/list-service/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+const (
+	DefaultFavorites = 10
+	MaxFavorites     = 50
+)
+
+// knownStatuses are reported in this order in stats
+var knownStatuses = []user_anime.UserAnimeStatus{user_anime.Watching, user_anime.Completed, user_anime.OnHold, user_anime.Dropped, user_anime.PlanToWatch}
+
+// profileViewer tells whether the caller is userID and, when not, what userID
+// shares with others
+func profileViewer(ctx context.Context, userID string) (bool, *privacy_settings2.PrivacySettings, error) {
+	if viewerID := requestinfo.UserID(ctx); viewerID != "" && viewerID == userID {
+		return true, nil, nil
+	}
+
+	loader, ok := dataloader.GetPrivacySettingsLoader(ctx)
+	if !ok {
+		return false, nil, errLoaderMissing
+	}
+
+	settings, err := loader.Load(ctx, userID)
+	if err != nil {
+		return false, nil, err
+	}
+
+	return false, settings, nil
+}
+
+// traceUserField runs resolve for a field of the federated User, recording
+// the span and resolver metric under name
+func traceUserField[T any](ctx context.Context, name string, userID string, resolve func(ctx context.Context) (T, error)) (T, error) {
+	// Start tracing span
+	tracer := tracing.GetTracer(ctx)
+	ctx, span := tracer.Start(ctx, name)
+	span.SetAttributes(
+		attribute.String("resolver.name", name),
+		attribute.String("user.id", userID),
+	)
+	defer span.End()
+
+	startTime := time.Now()
+
+	result, err := resolve(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		metrics.GetAppMetrics().ResolverMetric(
+			float64(time.Since(startTime).Milliseconds()),
+			name,
+			metrics.Error,
+		)
+
+		return result, err
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	metrics.GetAppMetrics().ResolverMetric(
+		float64(time.Since(startTime).Milliseconds()),
+		name,
+		metrics.Success,
+	)
+
+	return result, nil
+}
+
+// GetUserLists returns the lists of userID the caller may see
+func GetUserLists(ctx context.Context, userID string) ([]*model.UserList, error) {
+	return traceUserField(ctx, "GetUserLists", userID, func(ctx context.Context) ([]*model.UserList, error) {
+		loader, ok := dataloader.GetUserListsByUserLoader(ctx)
+		if !ok {
+			return nil, errLoaderMissing
+		}
+
+		userLists, err := loader.Load(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		viewerID := requestinfo.UserID(ctx)
+		userListModels := make([]*model.UserList, 0, len(userLists))
+		for _, userListEntity := range userLists {
+			if !user_list.VisibleTo(userListEntity, viewerID) {
+				continue
+			}
+			userListModel, err := ConvertUserListToGraphql(userListEntity)
+			if err != nil {
+				return nil, err
+			}
+			userListModels = append(userListModels, userListModel)
+		}
+
+		return userListModels, nil
+	})
+}
+
+// privateListIds returns the ids of the private lists of userID, whose
+// entries are hidden from other users
+func privateListIds(ctx context.Context, userID string) ([]string, error) {
+	loader, ok := dataloader.GetUserListsByUserLoader(ctx)
+	if !ok {
+		return nil, errLoaderMissing
+	}
+
+	userLists, err := loader.Load(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return user_list.PrivateListIds(userLists, userID), nil
+}
+
+// GetUserAnimeList pages through the entries of userID, nil when userID
+// keeps their anime list private from the caller
+func GetUserAnimeList(ctx context.Context, userAnimeService user_anime.UserAnimeServiceImpl, userID string, filterInput *model.UserAnimeFilter, sortInput *model.UserAnimeSort, first *int, after *string) (*model.UserAnimeConnection, error) {
+	return traceUserField(ctx, "GetUserAnimeList", userID, func(ctx context.Context) (*model.UserAnimeConnection, error) {
+		owner, settings, err := profileViewer(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		filter := ConvertUserAnimeFilterFromGraphql(filterInput)
+		if !owner {
+			if !settings.AnimeListPublic {
+				return nil, nil
+			}
+			filter.ExcludeListIDs, err = privateListIds(ctx, userID)
+			if err != nil {
+				return nil, err
+			}
+		}
+		sort := ConvertUserAnimeSortFromGraphql(sortInput)
+
+		page, err := pagination.Request{First: first, After: after}.Resolve()
+		if err != nil {
+			return nil, err
+		}
+
+		userAnimeEntities, hasMore, total, err := userAnimeService.FindPageByUserId(ctx, userID, filter, sort, page)
+		if err != nil {
+			return nil, err
+		}
+
+		connection := &model.UserAnimeConnection{
+			Edges:      make([]*model.UserAnimeEdge, 0, len(userAnimeEntities)),
+			PageInfo:   ConvertPageInfoToGraphql(pagination.NewInfo(page, hasMore)),
+			TotalCount: int(total),
+		}
+		for _, userAnimeEntity := range userAnimeEntities {
+			userAnimeModel, err := ConvertUserAnimeToGraphql(userAnimeEntity)
+			if err != nil {
+				return nil, err
+			}
+			connection.Edges = append(connection.Edges, &model.UserAnimeEdge{
+				Cursor: sort.Cursor(userAnimeEntity).Encode(),
+				Node:   userAnimeModel,
+			})
+		}
+		if len(connection.Edges) > 0 {
+			connection.PageInfo.StartCursor = &connection.Edges[0].Cursor
+			connection.PageInfo.EndCursor = &connection.Edges[len(connection.Edges)-1].Cursor
+		}
+
+		return connection, nil
+	})
+}
+
+// GetUserStats returns the stats of userID, nil when userID keeps them
+// private from the caller
+func GetUserStats(ctx context.Context, userID string) (*model.UserStats, error) {
+	return traceUserField(ctx, "GetUserStats", userID, func(ctx context.Context) (*model.UserStats, error) {
+		owner, settings, err := profileViewer(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !owner && !settings.StatsPublic {
+			return nil, nil
+		}
+
+		loader, ok := dataloader.GetUserStatsLoader(ctx)
+		if !ok {
+			return nil, errLoaderMissing
+		}
+
+		stats, err := loader.Load(ctx, dataloader.UserStatsKey{UserID: userID, Public: !owner})
+		if err != nil || stats == nil {
+			return nil, err
+		}
+
+		return ConvertUserStatsToGraphql(stats), nil
+	})
+}
+
+func ConvertUserStatsToGraphql(stats *user_anime.UserStats) *model.UserStats {
+	userStats := &model.UserStats{
+		TotalEntries:    int(stats.Entries),
+		ByStatus:        make([]*model.StatusCount, 0, len(knownStatuses)+1),
+		MeanScore:       stats.MeanScore,
+		EpisodesWatched: int(stats.Episodes),
+	}
+
+	unknown := stats.Entries
+	for _, status := range knownStatuses {
+		count := stats.ByStatus[string(status)]
+		unknown -= count
+
+		graphqlStatus := model.Status(strings.ToUpper(string(status)))
+		userStats.ByStatus = append(userStats.ByStatus, &model.StatusCount{
+			Status: &graphqlStatus,
+			Count:  int(count),
+		})
+	}
+	if unknown > 0 {
+		userStats.ByStatus = append(userStats.ByStatus, &model.StatusCount{Count: int(unknown)})
+	}
+
+	return userStats
+}
+
+// GetUserFavorites returns the highest scored entries of userID, nil when
+// userID keeps them private from the caller
+func GetUserFavorites(ctx context.Context, userID string, first *int) ([]*model.UserAnime, error) {
+	return traceUserField(ctx, "GetUserFavorites", userID, func(ctx context.Context) ([]*model.UserAnime, error) {
+		owner, settings, err := profileViewer(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !owner && !settings.FavoritesPublic {
+			return nil, nil
+		}
+
+		limit := DefaultFavorites
+		if first != nil {
+			limit = min(*first, MaxFavorites)
+		}
+		if limit <= 0 {
+			return []*model.UserAnime{}, nil
+		}
+
+		loader, ok := dataloader.GetFavoritesLoader(ctx)
+		if !ok {
+			return nil, errLoaderMissing
+		}
+
+		userAnimes, err := loader.Load(ctx, dataloader.FavoritesKey{UserID: userID, Limit: limit, Public: !owner})
+		if err != nil {
+			return nil, err
+		}
+
+		userAnimeModels := make([]*model.UserAnime, 0, len(userAnimes))
+		for _, userAnimeEntity := range userAnimes {
+			userAnimeModel, err := ConvertUserAnimeToGraphql(userAnimeEntity)
+			if err != nil {
+				return nil, err
+			}
+			userAnimeModels = append(userAnimeModels, userAnimeModel)
+		}
+
+		return userAnimeModels, nil
+	})
+}
+
+func ConvertPrivacySettingsToGraphql(settings *privacy_settings2.PrivacySettings) *model.PrivacySettings {
+	privacySettings := &model.PrivacySettings{
+		AnimeListPublic: settings.AnimeListPublic,
+		StatsPublic:     settings.StatsPublic,
+		FavoritesPublic: settings.FavoritesPublic,
+	}
+	if !settings.UpdatedAt.IsZero() {
+		updatedAt := settings.UpdatedAt
+		privacySettings.UpdatedAt = &updatedAt
+	}
+
+	return privacySettings
+}
+
+// GetPrivacySettings returns the privacy settings of the caller
+func GetPrivacySettings(ctx context.Context, privacySettingsService privacy_settings.PrivacySettingsServiceImpl) (*model.PrivacySettings, error) {
+	userID := requestinfo.UserID(ctx)
+	return traceUserField(ctx, "GetPrivacySettings", userID, func(ctx context.Context) (*model.PrivacySettings, error) {
+		if userID == "" {
+			return nil, errUnauthenticated
+		}
+
+		settings, err := privacySettingsService.Get(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+
+		return ConvertPrivacySettingsToGraphql(settings), nil
+	})
+}
+
+// UpdatePrivacySettings changes the privacy settings of the caller
+func UpdatePrivacySettings(ctx context.Context, privacySettingsService privacy_settings.PrivacySettingsServiceImpl, input model.PrivacySettingsInput) (*model.PrivacySettings, error) {
+	userID := requestinfo.UserID(ctx)
+	return traceUserField(ctx, "UpdatePrivacySettings", userID, func(ctx context.Context) (*model.PrivacySettings, error) {
+		if userID == "" {
+			return nil, errUnauthenticated
+		}
+
+		settings, err := privacySettingsService.Update(ctx, privacy_settings.PrivacySettingsUpdate{
+			UserID:          userID,
+			AnimeListPublic: optionalFromOmittable(input.AnimeListPublic),
+			StatsPublic:     optionalFromOmittable(input.StatsPublic),
+			FavoritesPublic: optionalFromOmittable(input.FavoritesPublic),
+		})
+		if err != nil {
+			return nil, convertServiceError(ctx, err)
+		}
+
+		return ConvertPrivacySettingsToGraphql(settings), nil
+	})
+}
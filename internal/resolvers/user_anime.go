@@ -10,6 +10,8 @@ import (
 	user_anime2 "github.com/weeb-vip/list-service/internal/db/repositories/user_anime"
 	"github.com/weeb-vip/list-service/internal/dataloader"
 	"github.com/weeb-vip/list-service/internal/logger"
+	"github.com/weeb-vip/list-service/internal/optional"
+	"github.com/weeb-vip/list-service/internal/pagination"
 	"github.com/weeb-vip/list-service/internal/services/user_anime"
 	"github.com/weeb-vip/list-service/metrics"
 	"github.com/weeb-vip/list-service/tracing"
@@ -47,15 +49,62 @@ func ConvertUserAnimeToGraphql(userAnimeEntity *user_anime2.UserAnime) (*model.U
 		ListID:             userAnimeEntity.ListID,
 		Rewatching:         userAnimeEntity.Rewatching,
 		RewatchingEpisodes: userAnimeEntity.RewatchingEpisodes,
+		Version:            userAnimeEntity.Version,
+		CreatedAt:          formatTimestamp(userAnimeEntity.CreatedAt),
+		UpdatedAt:          formatTimestamp(userAnimeEntity.UpdatedAt),
+		DeletedAt:          formatDeletedAt(userAnimeEntity.DeletedAt),
 	}, nil
 }
 
-func UpsertUserAnime(ctx context.Context, userAnimeService user_anime.UserAnimeServiceImpl, userAnime model.UserAnimeInput) (*model.UserAnime, error) {
+// ConvertUserAnimeFilterFromGraphql maps the GraphQL filter input onto the repository filter
+func ConvertUserAnimeFilterFromGraphql(input *model.UserAnimeFilter) user_anime2.Filter {
+	if input == nil {
+		return user_anime2.Filter{}
+	}
+
+	return user_anime2.Filter{
+		Tags:         input.Tags,
+		MinScore:     input.MinScore,
+		MaxScore:     input.MaxScore,
+		ListIDs:      input.ListIDs,
+		UpdatedSince: input.UpdatedSince,
+		Rewatching:   input.Rewatching,
+		AnimeIDs:     input.AnimeIDs,
+	}
+}
+
+// ConvertUserAnimeSortFromGraphql maps the GraphQL sort input onto the repository sort,
+// falling back to the default ordering when none is given
+func ConvertUserAnimeSortFromGraphql(input *model.UserAnimeSort) user_anime2.Sort {
+	if input == nil {
+		return user_anime2.DefaultSort
+	}
+
+	sort := user_anime2.Sort{Direction: user_anime2.SortDesc}
+	switch input.Field {
+	case model.UserAnimeSortFieldUpdatedAt:
+		sort.Field = user_anime2.SortByUpdatedAt
+	case model.UserAnimeSortFieldScore:
+		sort.Field = user_anime2.SortByScore
+	case model.UserAnimeSortFieldProgress:
+		sort.Field = user_anime2.SortByProgress
+	default:
+		sort.Field = user_anime2.SortByCreatedAt
+	}
+
+	if input.Direction != nil && *input.Direction == model.SortDirectionAsc {
+		sort.Direction = user_anime2.SortAsc
+	}
+
+	return sort
+}
+
+func AddUserAnime(ctx context.Context, userAnimeService user_anime.UserAnimeServiceImpl, userAnime model.AddAnimeInput) (*model.UserAnime, error) {
 	// Start tracing span
 	tracer := tracing.GetTracer(ctx)
-	ctx, span := tracer.Start(ctx, "UpsertUserAnime")
+	ctx, span := tracer.Start(ctx, "AddUserAnime")
 	span.SetAttributes(
-		attribute.String("resolver.name", "UpsertUserAnime"),
+		attribute.String("resolver.name", "AddUserAnime"),
 		attribute.String("anime.id", userAnime.AnimeID),
 	)
 	defer span.End()
@@ -71,7 +120,7 @@ func UpsertUserAnime(ctx context.Context, userAnimeService user_anime.UserAnimeS
 
 		metrics.GetAppMetrics().ResolverMetric(
 			float64(time.Since(startTime).Milliseconds()),
-			"UpsertUserAnime",
+			"AddUserAnime",
 			metrics.Error,
 		)
 
@@ -83,12 +132,8 @@ func UpsertUserAnime(ctx context.Context, userAnimeService user_anime.UserAnimeS
 	if userAnime.Status != nil {
 		statuss := user_anime.UserAnimeStatus(*userAnime.Status)
 		status = &statuss
-	} else {
-		status = nil
 	}
-	// Convert model.UserListInput to user_list.UserList
 	userAnimeEntity := &user_anime.UserAnime{
-		ID:                 userAnime.ID,
 		UserID:             *userID,
 		AnimeID:            userAnime.AnimeID,
 		Status:             status,
@@ -100,18 +145,18 @@ func UpsertUserAnime(ctx context.Context, userAnimeService user_anime.UserAnimeS
 		ListID:             userAnime.ListID,
 	}
 
-	createdUserAnime, err := userAnimeService.Upsert(ctx, userAnimeEntity)
+	createdUserAnime, err := userAnimeService.Add(ctx, userAnimeEntity)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
 
 		metrics.GetAppMetrics().ResolverMetric(
 			float64(time.Since(startTime).Milliseconds()),
-			"UpsertUserAnime",
+			"AddUserAnime",
 			metrics.Error,
 		)
 
-		return nil, err
+		return nil, convertServiceError(ctx, err)
 	}
 
 	span.SetStatus(codes.Ok, "")
@@ -119,13 +164,95 @@ func UpsertUserAnime(ctx context.Context, userAnimeService user_anime.UserAnimeS
 
 	metrics.GetAppMetrics().ResolverMetric(
 		float64(time.Since(startTime).Milliseconds()),
-		"UpsertUserAnime",
+		"AddUserAnime",
 		metrics.Success,
 	)
 
 	return ConvertUserAnimeToGraphql(createdUserAnime)
 }
 
+// ConvertUpdateAnimeInputFromGraphql maps the GraphQL input onto a partial
+// update, keeping omitted fields unset
+func ConvertUpdateAnimeInputFromGraphql(userID string, input model.UpdateAnimeInput) *user_anime.UserAnimeUpdate {
+	update := &user_anime.UserAnimeUpdate{
+		UserID:             userID,
+		AnimeID:            input.AnimeID,
+		Score:              optionalFromOmittable(input.Score),
+		Episodes:           optionalFromOmittable(input.Episodes),
+		Rewatching:         optionalFromOmittable(input.Rewatching),
+		RewatchingEpisodes: optionalFromOmittable(input.RewatchingEpisodes),
+		Tags:               optionalFromOmittable(input.Tags),
+		ListID:             optionalFromOmittable(input.ListID),
+		ExpectedVersion:    input.ExpectedVersion,
+	}
+	if status, ok := input.Status.ValueOK(); ok {
+		var statuss *user_anime.UserAnimeStatus
+		if status != nil {
+			converted := user_anime.UserAnimeStatus(*status)
+			statuss = &converted
+		}
+		update.Status = optional.Of(statuss)
+	}
+
+	return update
+}
+
+func UpdateUserAnime(ctx context.Context, userAnimeService user_anime.UserAnimeServiceImpl, userAnime model.UpdateAnimeInput) (*model.UserAnime, error) {
+	// Start tracing span
+	tracer := tracing.GetTracer(ctx)
+	ctx, span := tracer.Start(ctx, "UpdateUserAnime")
+	span.SetAttributes(
+		attribute.String("resolver.name", "UpdateUserAnime"),
+		attribute.String("anime.id", userAnime.AnimeID),
+	)
+	defer span.End()
+
+	startTime := time.Now()
+
+	// get userid from requestInfo
+	req := requestinfo.FromContext(ctx)
+	userID := req.UserID
+	if userID == nil {
+		span.RecordError(errors.New("User ID is missing, unauthenticated"))
+		span.SetStatus(codes.Error, "User ID is missing, unauthenticated")
+
+		metrics.GetAppMetrics().ResolverMetric(
+			float64(time.Since(startTime).Milliseconds()),
+			"UpdateUserAnime",
+			metrics.Error,
+		)
+
+		return nil, errors.New("User ID is missing, unauthenticated")
+	}
+
+	span.SetAttributes(attribute.String("user.id", *userID))
+
+	updatedUserAnime, err := userAnimeService.Update(ctx, ConvertUpdateAnimeInputFromGraphql(*userID, userAnime))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		metrics.GetAppMetrics().ResolverMetric(
+			float64(time.Since(startTime).Milliseconds()),
+			"UpdateUserAnime",
+			metrics.Error,
+		)
+
+		return nil, convertServiceError(ctx, err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+	span.SetAttributes(attribute.String("user_anime.id", updatedUserAnime.ID))
+
+	metrics.GetAppMetrics().ResolverMetric(
+		float64(time.Since(startTime).Milliseconds()),
+		"UpdateUserAnime",
+		metrics.Success,
+	)
+
+	return ConvertUserAnimeToGraphql(updatedUserAnime)
+}
+
 func DeleteUserAnime(ctx context.Context, userAnimeService user_anime.UserAnimeServiceImpl, id string) error {
 	// get userid from requestInfo
 	req := requestinfo.FromContext(ctx)
@@ -173,14 +300,14 @@ func GetUserAnimesByID(ctx context.Context, userAnimeService user_anime.UserAnim
 
 	span.SetAttributes(attribute.String("user.id", *userID))
 
-	var status *string
+	filter := ConvertUserAnimeFilterFromGraphql(input.Filter)
 	if input.Status != nil {
-		statuss := string(*input.Status)
-		status = &statuss
-	} else {
-		status = nil
+		status := string(*input.Status)
+		filter.Status = &status
 	}
-	userAnimeEntity, total, err := userAnimeService.FindByUserId(ctx, *userID, status, input.Page, input.Limit)
+	sort := ConvertUserAnimeSortFromGraphql(input.Sort)
+
+	userAnimeEntity, total, err := userAnimeService.FindByUserId(ctx, *userID, filter, sort, input.Page, input.Limit)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
@@ -232,6 +359,107 @@ func GetUserAnimesByID(ctx context.Context, userAnimeService user_anime.UserAnim
 	return userAnimePaginated, nil
 }
 
+func GetUserAnimesConnection(ctx context.Context, userAnimeService user_anime.UserAnimeServiceImpl, input model.UserAnimesConnectionInput) (*model.UserAnimeConnection, error) {
+	// Start tracing span
+	tracer := tracing.GetTracer(ctx)
+	ctx, span := tracer.Start(ctx, "GetUserAnimesConnection")
+	span.SetAttributes(
+		attribute.String("resolver.name", "GetUserAnimesConnection"),
+	)
+	defer span.End()
+
+	startTime := time.Now()
+
+	// get userid from requestInfo
+	req := requestinfo.FromContext(ctx)
+	userID := req.UserID
+	if userID == nil {
+		span.RecordError(errors.New("User ID is missing, unauthenticated"))
+		span.SetStatus(codes.Error, "User ID is missing, unauthenticated")
+
+		metrics.GetAppMetrics().ResolverMetric(
+			float64(time.Since(startTime).Milliseconds()),
+			"GetUserAnimesConnection",
+			metrics.Error,
+		)
+
+		return nil, errors.New("User ID is missing, unauthenticated")
+	}
+
+	span.SetAttributes(attribute.String("user.id", *userID))
+
+	filter := ConvertUserAnimeFilterFromGraphql(input.Filter)
+	if input.Status != nil {
+		status := string(*input.Status)
+		filter.Status = &status
+	}
+	sort := ConvertUserAnimeSortFromGraphql(input.Sort)
+
+	page, err := pagination.Request{
+		First:  input.First,
+		After:  input.After,
+		Last:   input.Last,
+		Before: input.Before,
+	}.Resolve()
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		metrics.GetAppMetrics().ResolverMetric(
+			float64(time.Since(startTime).Milliseconds()),
+			"GetUserAnimesConnection",
+			metrics.Error,
+		)
+
+		return nil, err
+	}
+
+	userAnimeEntities, hasMore, total, err := userAnimeService.FindPageByUserId(ctx, *userID, filter, sort, page)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		metrics.GetAppMetrics().ResolverMetric(
+			float64(time.Since(startTime).Milliseconds()),
+			"GetUserAnimesConnection",
+			metrics.Error,
+		)
+
+		return nil, err
+	}
+
+	connection := &model.UserAnimeConnection{
+		Edges:      make([]*model.UserAnimeEdge, 0, len(userAnimeEntities)),
+		PageInfo:   ConvertPageInfoToGraphql(pagination.NewInfo(page, hasMore)),
+		TotalCount: int(total),
+	}
+	for _, userAnimeEntity := range userAnimeEntities {
+		userAnimeModel, err := ConvertUserAnimeToGraphql(userAnimeEntity)
+		if err != nil {
+			return nil, err
+		}
+		connection.Edges = append(connection.Edges, &model.UserAnimeEdge{
+			Cursor: sort.Cursor(userAnimeEntity).Encode(),
+			Node:   userAnimeModel,
+		})
+	}
+	if len(connection.Edges) > 0 {
+		connection.PageInfo.StartCursor = &connection.Edges[0].Cursor
+		connection.PageInfo.EndCursor = &connection.Edges[len(connection.Edges)-1].Cursor
+	}
+
+	span.SetStatus(codes.Ok, "")
+	span.SetAttributes(attribute.Int("user_anime.count", len(connection.Edges)))
+
+	metrics.GetAppMetrics().ResolverMetric(
+		float64(time.Since(startTime).Milliseconds()),
+		"GetUserAnimesConnection",
+		metrics.Success,
+	)
+
+	return connection, nil
+}
+
 func GetUserAnimeByAnimeID(ctx context.Context, userAnimeService user_anime.UserAnimeServiceImpl, animeID string) (*model.UserAnime, error) {
 	// Start tracing span
 	tracer := tracing.GetTracer(ctx)
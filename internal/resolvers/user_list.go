@@ -8,6 +8,7 @@ import (
 	"github.com/weeb-vip/list-service/graph/model"
 	"github.com/weeb-vip/list-service/http/handlers/requestinfo"
 	user_list2 "github.com/weeb-vip/list-service/internal/db/repositories/user_list"
+	"github.com/weeb-vip/list-service/internal/pagination"
 	"github.com/weeb-vip/list-service/internal/services/user_list"
 	"github.com/weeb-vip/list-service/metrics"
 	"github.com/weeb-vip/list-service/tracing"
@@ -29,15 +30,19 @@ func ConvertUserListToGraphql(userListEntity *user_list2.UserList) (*model.UserL
 		IsPublic:    userListEntity.IsPublic,
 		Tags:        tags,
 		Description: userListEntity.Description,
+		Version:     userListEntity.Version,
+		CreatedAt:   formatTimestamp(userListEntity.CreatedAt),
+		UpdatedAt:   formatTimestamp(userListEntity.UpdatedAt),
+		DeletedAt:   formatDeletedAt(userListEntity.DeletedAt),
 	}, nil
 }
 
-func UpsertUserList(ctx context.Context, userListService user_list.UserListServiceImpl, userList model.UserListInput) (*model.UserList, error) {
+func CreateUserList(ctx context.Context, userListService user_list.UserListServiceImpl, userList model.UserListInput) (*model.UserList, error) {
 	// Start tracing span
 	tracer := tracing.GetTracer(ctx)
-	ctx, span := tracer.Start(ctx, "UpsertUserList")
+	ctx, span := tracer.Start(ctx, "CreateUserList")
 	span.SetAttributes(
-		attribute.String("resolver.name", "UpsertUserList"),
+		attribute.String("resolver.name", "CreateUserList"),
 		attribute.String("user_list.name", userList.Name),
 	)
 	defer span.End()
@@ -53,7 +58,7 @@ func UpsertUserList(ctx context.Context, userListService user_list.UserListServi
 
 		metrics.GetAppMetrics().ResolverMetric(
 			float64(time.Since(startTime).Milliseconds()),
-			"UpsertUserList",
+			"CreateUserList",
 			metrics.Error,
 		)
 
@@ -63,32 +68,25 @@ func UpsertUserList(ctx context.Context, userListService user_list.UserListServi
 	span.SetAttributes(attribute.String("user.id", *userID))
 
 	// Convert model.UserListInput to user_list.UserList
-	var isPublic bool
-	if userList.IsPublic != nil {
-		isPublic = *userList.IsPublic
-	} else {
-		isPublic = false
-	}
 	userListEntity := &user_list.UserList{
-		ID:          userList.ID,
 		UserID:      *userID,
 		Name:        userList.Name,
-		IsPublic:    isPublic,
+		IsPublic:    userList.IsPublic,
 		Tags:        userList.Tags,
 		Description: userList.Description,
 	}
-	createdUserList, err := userListService.Upsert(ctx, userListEntity)
+	createdUserList, err := userListService.Create(ctx, userListEntity)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
 
 		metrics.GetAppMetrics().ResolverMetric(
 			float64(time.Since(startTime).Milliseconds()),
-			"UpsertUserList",
+			"CreateUserList",
 			metrics.Error,
 		)
 
-		return nil, err
+		return nil, convertServiceError(ctx, err)
 	}
 
 	span.SetStatus(codes.Ok, "")
@@ -96,7 +94,7 @@ func UpsertUserList(ctx context.Context, userListService user_list.UserListServi
 
 	metrics.GetAppMetrics().ResolverMetric(
 		float64(time.Since(startTime).Milliseconds()),
-		"UpsertUserList",
+		"CreateUserList",
 		metrics.Success,
 	)
 
@@ -104,6 +102,69 @@ func UpsertUserList(ctx context.Context, userListService user_list.UserListServi
 	return ConvertUserListToGraphql(createdUserList)
 }
 
+func UpdateUserList(ctx context.Context, userListService user_list.UserListServiceImpl, userList model.UpdateListInput) (*model.UserList, error) {
+	// Start tracing span
+	tracer := tracing.GetTracer(ctx)
+	ctx, span := tracer.Start(ctx, "UpdateUserList")
+	span.SetAttributes(
+		attribute.String("resolver.name", "UpdateUserList"),
+		attribute.String("user_list.id", userList.ID),
+	)
+	defer span.End()
+
+	startTime := time.Now()
+
+	// get userid from requestInfo
+	req := requestinfo.FromContext(ctx)
+	userID := req.UserID
+	if userID == nil {
+		span.RecordError(errors.New("User ID is missing, unauthenticated"))
+		span.SetStatus(codes.Error, "User ID is missing, unauthenticated")
+
+		metrics.GetAppMetrics().ResolverMetric(
+			float64(time.Since(startTime).Milliseconds()),
+			"UpdateUserList",
+			metrics.Error,
+		)
+
+		return nil, errors.New("User ID is missing, unauthenticated")
+	}
+
+	span.SetAttributes(attribute.String("user.id", *userID))
+
+	updatedUserList, err := userListService.Update(ctx, &user_list.UserListUpdate{
+		ID:              userList.ID,
+		UserID:          *userID,
+		Name:            optionalFromOmittable(userList.Name),
+		Description:     optionalFromOmittable(userList.Description),
+		Tags:            optionalFromOmittable(userList.Tags),
+		IsPublic:        optionalFromOmittable(userList.IsPublic),
+		ExpectedVersion: userList.ExpectedVersion,
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		metrics.GetAppMetrics().ResolverMetric(
+			float64(time.Since(startTime).Milliseconds()),
+			"UpdateUserList",
+			metrics.Error,
+		)
+
+		return nil, convertServiceError(ctx, err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	metrics.GetAppMetrics().ResolverMetric(
+		float64(time.Since(startTime).Milliseconds()),
+		"UpdateUserList",
+		metrics.Success,
+	)
+
+	return ConvertUserListToGraphql(updatedUserList)
+}
+
 func GetUserListsByID(ctx context.Context, userListService user_list.UserListServiceImpl) ([]*model.UserList, error) {
 	// Start tracing span
 	tracer := tracing.GetTracer(ctx)
@@ -168,6 +229,95 @@ func GetUserListsByID(ctx context.Context, userListService user_list.UserListSer
 	return userListModels, nil
 }
 
+func GetUserListsConnection(ctx context.Context, userListService user_list.UserListServiceImpl, request pagination.Request) (*model.UserListConnection, error) {
+	// Start tracing span
+	tracer := tracing.GetTracer(ctx)
+	ctx, span := tracer.Start(ctx, "GetUserListsConnection")
+	span.SetAttributes(
+		attribute.String("resolver.name", "GetUserListsConnection"),
+	)
+	defer span.End()
+
+	startTime := time.Now()
+
+	// get userid from requestInfo
+	req := requestinfo.FromContext(ctx)
+	userID := req.UserID
+	if userID == nil {
+		span.RecordError(errors.New("User ID is missing, unauthenticated"))
+		span.SetStatus(codes.Error, "User ID is missing, unauthenticated")
+
+		metrics.GetAppMetrics().ResolverMetric(
+			float64(time.Since(startTime).Milliseconds()),
+			"GetUserListsConnection",
+			metrics.Error,
+		)
+
+		return nil, errors.New("User ID is missing, unauthenticated")
+	}
+
+	span.SetAttributes(attribute.String("user.id", *userID))
+
+	page, err := request.Resolve()
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		metrics.GetAppMetrics().ResolverMetric(
+			float64(time.Since(startTime).Milliseconds()),
+			"GetUserListsConnection",
+			metrics.Error,
+		)
+
+		return nil, err
+	}
+
+	userLists, hasMore, total, err := userListService.GetUserListsPage(ctx, *userID, page)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		metrics.GetAppMetrics().ResolverMetric(
+			float64(time.Since(startTime).Milliseconds()),
+			"GetUserListsConnection",
+			metrics.Error,
+		)
+
+		return nil, err
+	}
+
+	connection := &model.UserListConnection{
+		Edges:      make([]*model.UserListEdge, 0, len(userLists)),
+		PageInfo:   ConvertPageInfoToGraphql(pagination.NewInfo(page, hasMore)),
+		TotalCount: int(total),
+	}
+	for _, userListEntity := range userLists {
+		userListModel, err := ConvertUserListToGraphql(userListEntity)
+		if err != nil {
+			return nil, err
+		}
+		connection.Edges = append(connection.Edges, &model.UserListEdge{
+			Cursor: user_list2.Cursor(userListEntity).Encode(),
+			Node:   userListModel,
+		})
+	}
+	if len(connection.Edges) > 0 {
+		connection.PageInfo.StartCursor = &connection.Edges[0].Cursor
+		connection.PageInfo.EndCursor = &connection.Edges[len(connection.Edges)-1].Cursor
+	}
+
+	span.SetStatus(codes.Ok, "")
+	span.SetAttributes(attribute.Int("user_lists.count", len(connection.Edges)))
+
+	metrics.GetAppMetrics().ResolverMetric(
+		float64(time.Since(startTime).Milliseconds()),
+		"GetUserListsConnection",
+		metrics.Success,
+	)
+
+	return connection, nil
+}
+
 func DeleteUserList(ctx context.Context, userListService user_list.UserListServiceImpl, id string) error {
 	// get userid from requestInfo
 	req := requestinfo.FromContext(ctx)
@@ -0,0 +1,343 @@
+package resolvers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/weeb-vip/list-service/graph/model"
+	"github.com/weeb-vip/list-service/http/handlers/requestinfo"
+	webhook2 "github.com/weeb-vip/list-service/internal/db/repositories/webhook"
+	"github.com/weeb-vip/list-service/internal/services/webhook"
+	"github.com/weeb-vip/list-service/metrics"
+	"github.com/weeb-vip/list-service/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+func ConvertWebhookSubscriptionToGraphql(subscription *webhook2.Subscription) *model.WebhookSubscription {
+	return &model.WebhookSubscription{
+		ID:                  subscription.ID,
+		URL:                 subscription.URL,
+		EventTypes:          strings.Split(subscription.EventTypes, ","),
+		Enabled:             subscription.Enabled,
+		ConsecutiveFailures: subscription.ConsecutiveFailures,
+		DisabledAt:          subscription.DisabledAt,
+		CreatedAt:           subscription.CreatedAt,
+	}
+}
+
+func ConvertWebhookDeliveryToGraphql(delivery *webhook2.Delivery) *model.WebhookDelivery {
+	return &model.WebhookDelivery{
+		ID:             delivery.ID,
+		SubscriptionID: delivery.SubscriptionID,
+		EventID:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Status:         model.WebhookDeliveryStatus(strings.ToUpper(string(delivery.Status))),
+		Attempts:       delivery.Attempts,
+		NextAttemptAt:  delivery.NextAttemptAt,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		DeliveredAt:    delivery.DeliveredAt,
+		CreatedAt:      delivery.CreatedAt,
+	}
+}
+
+func CreateWebhookSubscription(ctx context.Context, webhookService webhook.WebhookServiceImpl, input model.WebhookSubscriptionInput) (*model.CreatedWebhookSubscription, error) {
+	// Start tracing span
+	tracer := tracing.GetTracer(ctx)
+	ctx, span := tracer.Start(ctx, "CreateWebhookSubscription")
+	span.SetAttributes(
+		attribute.String("resolver.name", "CreateWebhookSubscription"),
+		attribute.StringSlice("webhook.event_types", input.EventTypes),
+	)
+	defer span.End()
+
+	startTime := time.Now()
+
+	// get userid from requestInfo
+	req := requestinfo.FromContext(ctx)
+	userID := req.UserID
+	if userID == nil {
+		span.RecordError(errors.New("User ID is missing, unauthenticated"))
+		span.SetStatus(codes.Error, "User ID is missing, unauthenticated")
+
+		metrics.GetAppMetrics().ResolverMetric(
+			float64(time.Since(startTime).Milliseconds()),
+			"CreateWebhookSubscription",
+			metrics.Error,
+		)
+
+		return nil, errors.New("User ID is missing, unauthenticated")
+	}
+
+	span.SetAttributes(attribute.String("user.id", *userID))
+
+	subscription, err := webhookService.Create(ctx, &webhook.Subscription{
+		UserID:     *userID,
+		URL:        input.URL,
+		EventTypes: input.EventTypes,
+		Secret:     input.Secret,
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		metrics.GetAppMetrics().ResolverMetric(
+			float64(time.Since(startTime).Milliseconds()),
+			"CreateWebhookSubscription",
+			metrics.Error,
+		)
+
+		return nil, convertServiceError(ctx, err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	metrics.GetAppMetrics().ResolverMetric(
+		float64(time.Since(startTime).Milliseconds()),
+		"CreateWebhookSubscription",
+		metrics.Success,
+	)
+
+	span.SetAttributes(attribute.String("webhook.subscription_id", subscription.ID))
+
+	return &model.CreatedWebhookSubscription{
+		Subscription: ConvertWebhookSubscriptionToGraphql(subscription),
+		Secret:       subscription.Secret,
+	}, nil
+}
+
+func GetWebhookSubscriptions(ctx context.Context, webhookService webhook.WebhookServiceImpl) ([]*model.WebhookSubscription, error) {
+	// Start tracing span
+	tracer := tracing.GetTracer(ctx)
+	ctx, span := tracer.Start(ctx, "GetWebhookSubscriptions")
+	span.SetAttributes(
+		attribute.String("resolver.name", "GetWebhookSubscriptions"),
+	)
+	defer span.End()
+
+	startTime := time.Now()
+
+	// get userid from requestInfo
+	req := requestinfo.FromContext(ctx)
+	userID := req.UserID
+	if userID == nil {
+		span.RecordError(errors.New("User ID is missing, unauthenticated"))
+		span.SetStatus(codes.Error, "User ID is missing, unauthenticated")
+
+		metrics.GetAppMetrics().ResolverMetric(
+			float64(time.Since(startTime).Milliseconds()),
+			"GetWebhookSubscriptions",
+			metrics.Error,
+		)
+
+		return nil, errors.New("User ID is missing, unauthenticated")
+	}
+
+	span.SetAttributes(attribute.String("user.id", *userID))
+
+	subscriptions, err := webhookService.FindByUserId(ctx, *userID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		metrics.GetAppMetrics().ResolverMetric(
+			float64(time.Since(startTime).Milliseconds()),
+			"GetWebhookSubscriptions",
+			metrics.Error,
+		)
+
+		return nil, convertServiceError(ctx, err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	metrics.GetAppMetrics().ResolverMetric(
+		float64(time.Since(startTime).Milliseconds()),
+		"GetWebhookSubscriptions",
+		metrics.Success,
+	)
+
+	span.SetAttributes(attribute.Int("webhook_subscriptions.count", len(subscriptions)))
+
+	subscriptionModels := make([]*model.WebhookSubscription, len(subscriptions))
+	for i, subscription := range subscriptions {
+		subscriptionModels[i] = ConvertWebhookSubscriptionToGraphql(subscription)
+	}
+
+	return subscriptionModels, nil
+}
+
+func DeleteWebhookSubscription(ctx context.Context, webhookService webhook.WebhookServiceImpl, id string) (bool, error) {
+	// Start tracing span
+	tracer := tracing.GetTracer(ctx)
+	ctx, span := tracer.Start(ctx, "DeleteWebhookSubscription")
+	span.SetAttributes(
+		attribute.String("resolver.name", "DeleteWebhookSubscription"),
+		attribute.String("webhook.subscription_id", id),
+	)
+	defer span.End()
+
+	startTime := time.Now()
+
+	// get userid from requestInfo
+	req := requestinfo.FromContext(ctx)
+	userID := req.UserID
+	if userID == nil {
+		span.RecordError(errors.New("User ID is missing, unauthenticated"))
+		span.SetStatus(codes.Error, "User ID is missing, unauthenticated")
+
+		metrics.GetAppMetrics().ResolverMetric(
+			float64(time.Since(startTime).Milliseconds()),
+			"DeleteWebhookSubscription",
+			metrics.Error,
+		)
+
+		return false, errors.New("User ID is missing, unauthenticated")
+	}
+
+	span.SetAttributes(attribute.String("user.id", *userID))
+
+	err := webhookService.Delete(ctx, *userID, id)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		metrics.GetAppMetrics().ResolverMetric(
+			float64(time.Since(startTime).Milliseconds()),
+			"DeleteWebhookSubscription",
+			metrics.Error,
+		)
+
+		return false, convertServiceError(ctx, err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	metrics.GetAppMetrics().ResolverMetric(
+		float64(time.Since(startTime).Milliseconds()),
+		"DeleteWebhookSubscription",
+		metrics.Success,
+	)
+
+	return true, nil
+}
+
+func EnableWebhookSubscription(ctx context.Context, webhookService webhook.WebhookServiceImpl, id string) (*model.WebhookSubscription, error) {
+	// Start tracing span
+	tracer := tracing.GetTracer(ctx)
+	ctx, span := tracer.Start(ctx, "EnableWebhookSubscription")
+	span.SetAttributes(
+		attribute.String("resolver.name", "EnableWebhookSubscription"),
+		attribute.String("webhook.subscription_id", id),
+	)
+	defer span.End()
+
+	startTime := time.Now()
+
+	// get userid from requestInfo
+	req := requestinfo.FromContext(ctx)
+	userID := req.UserID
+	if userID == nil {
+		span.RecordError(errors.New("User ID is missing, unauthenticated"))
+		span.SetStatus(codes.Error, "User ID is missing, unauthenticated")
+
+		metrics.GetAppMetrics().ResolverMetric(
+			float64(time.Since(startTime).Milliseconds()),
+			"EnableWebhookSubscription",
+			metrics.Error,
+		)
+
+		return nil, errors.New("User ID is missing, unauthenticated")
+	}
+
+	span.SetAttributes(attribute.String("user.id", *userID))
+
+	subscription, err := webhookService.Enable(ctx, *userID, id)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		metrics.GetAppMetrics().ResolverMetric(
+			float64(time.Since(startTime).Milliseconds()),
+			"EnableWebhookSubscription",
+			metrics.Error,
+		)
+
+		return nil, convertServiceError(ctx, err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	metrics.GetAppMetrics().ResolverMetric(
+		float64(time.Since(startTime).Milliseconds()),
+		"EnableWebhookSubscription",
+		metrics.Success,
+	)
+
+	return ConvertWebhookSubscriptionToGraphql(subscription), nil
+}
+
+func GetWebhookDeliveries(ctx context.Context, webhookService webhook.WebhookServiceImpl, subscriptionID string, limit *int) ([]*model.WebhookDelivery, error) {
+	// Start tracing span
+	tracer := tracing.GetTracer(ctx)
+	ctx, span := tracer.Start(ctx, "GetWebhookDeliveries")
+	span.SetAttributes(
+		attribute.String("resolver.name", "GetWebhookDeliveries"),
+		attribute.String("webhook.subscription_id", subscriptionID),
+	)
+	defer span.End()
+
+	startTime := time.Now()
+
+	// get userid from requestInfo
+	req := requestinfo.FromContext(ctx)
+	userID := req.UserID
+	if userID == nil {
+		span.RecordError(errors.New("User ID is missing, unauthenticated"))
+		span.SetStatus(codes.Error, "User ID is missing, unauthenticated")
+
+		metrics.GetAppMetrics().ResolverMetric(
+			float64(time.Since(startTime).Milliseconds()),
+			"GetWebhookDeliveries",
+			metrics.Error,
+		)
+
+		return nil, errors.New("User ID is missing, unauthenticated")
+	}
+
+	span.SetAttributes(attribute.String("user.id", *userID))
+
+	deliveries, err := webhookService.FindDeliveries(ctx, *userID, subscriptionID, limit)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		metrics.GetAppMetrics().ResolverMetric(
+			float64(time.Since(startTime).Milliseconds()),
+			"GetWebhookDeliveries",
+			metrics.Error,
+		)
+
+		return nil, convertServiceError(ctx, err)
+	}
+
+	span.SetStatus(codes.Ok, "")
+
+	metrics.GetAppMetrics().ResolverMetric(
+		float64(time.Since(startTime).Milliseconds()),
+		"GetWebhookDeliveries",
+		metrics.Success,
+	)
+
+	span.SetAttributes(attribute.Int("webhook_deliveries.count", len(deliveries)))
+
+	deliveryModels := make([]*model.WebhookDelivery, len(deliveries))
+	for i, delivery := range deliveries {
+		deliveryModels[i] = ConvertWebhookDeliveryToGraphql(delivery)
+	}
+
+	return deliveryModels, nil
+}
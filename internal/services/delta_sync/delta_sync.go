@@ -0,0 +1,421 @@
+package delta_sync
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/weeb-vip/list-service/internal/db"
+	"github.com/weeb-vip/list-service/internal/db/repositories/user_anime"
+	"github.com/weeb-vip/list-service/internal/db/repositories/user_list"
+	"github.com/weeb-vip/list-service/internal/pagination"
+	user_anime_service "github.com/weeb-vip/list-service/internal/services/user_anime"
+	user_list_service "github.com/weeb-vip/list-service/internal/services/user_list"
+	"github.com/weeb-vip/list-service/metrics"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultChangesLimit = 100
+	MaxChangesLimit     = 500
+	MaxPushBatchSize    = 100
+
+	// importSourceOfflineSync labels pushes of offline edits in the imports
+	// metric
+	importSourceOfflineSync = "offline_sync"
+)
+
+var ErrInvalidSyncToken = errors.New("invalid sync token")
+var ErrPushBatchTooLarge = errors.New("too many changes in one push")
+
+type EntityType string
+
+const (
+	EntityUserAnime EntityType = "USER_ANIME"
+	EntityUserList  EntityType = "USER_LIST"
+)
+
+// Watermark is the position of the last row a client has seen in one table
+type Watermark struct {
+	UpdatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+}
+
+// Token is the opaque sync token handed to clients. Entries and lists are
+// tracked separately since they are paged independently.
+type Token struct {
+	Anime Watermark `json:"a"`
+	List  Watermark `json:"l"`
+}
+
+func (t Token) Encode() string {
+	data, _ := json.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeToken(encoded string) (*Token, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidSyncToken
+	}
+
+	var token Token
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, ErrInvalidSyncToken
+	}
+
+	return &token, nil
+}
+
+type Tombstone struct {
+	Type      EntityType
+	ID        string
+	AnimeID   *string
+	DeletedAt time.Time
+}
+
+type Changes struct {
+	UserAnimes []*user_anime.UserAnime
+	UserLists  []*user_list.UserList
+	Tombstones []*Tombstone
+	Token      string
+	HasMore    bool
+}
+
+// AnimeEdit is an entry change made while a client was offline
+type AnimeEdit struct {
+	Entry   user_anime_service.UserAnime
+	Deleted bool
+	// EditedAt is when the change was made on the client
+	EditedAt time.Time
+}
+
+// ListEdit is a list change made while a client was offline. Edits without an
+// id create a new list.
+type ListEdit struct {
+	List     user_list_service.UserList
+	Deleted  bool
+	EditedAt time.Time
+}
+
+// Conflict is an edit that lost to a newer server side change
+type Conflict struct {
+	Type        EntityType
+	ID          string
+	Reason      string
+	ServerAnime *user_anime.UserAnime
+	ServerList  *user_list.UserList
+}
+
+type PushResult struct {
+	UserAnimes []*user_anime.UserAnime
+	UserLists  []*user_list.UserList
+	Conflicts  []*Conflict
+}
+
+type DeltaSyncServiceImpl interface {
+	ChangesSince(ctx context.Context, userId string, token *string, limit int) (*Changes, error)
+	Push(ctx context.Context, userId string, animeEdits []*AnimeEdit, listEdits []*ListEdit) (*PushResult, error)
+}
+
+type DeltaSyncService struct {
+	UserAnimeRepository user_anime.UserAnimeRepositoryImpl
+	UserListRepository  user_list.UserListRepositoryImpl
+	UserAnimeService    user_anime_service.UserAnimeServiceImpl
+	UserListService     user_list_service.UserListServiceImpl
+	Transactor          db.Transactor
+}
+
+func NewDeltaSyncService(
+	userAnimeRepository user_anime.UserAnimeRepositoryImpl,
+	userListRepository user_list.UserListRepositoryImpl,
+	userAnimeService user_anime_service.UserAnimeServiceImpl,
+	userListService user_list_service.UserListServiceImpl,
+	transactor db.Transactor,
+) DeltaSyncServiceImpl {
+	return &DeltaSyncService{
+		UserAnimeRepository: userAnimeRepository,
+		UserListRepository:  userListRepository,
+		UserAnimeService:    userAnimeService,
+		UserListService:     userListService,
+		Transactor:          transactor,
+	}
+}
+
+// ChangesSince returns everything that changed for the user after token. A nil
+// token starts a full sync, which skips rows that were already deleted.
+func (s *DeltaSyncService) ChangesSince(ctx context.Context, userId string, token *string, limit int) (*Changes, error) {
+	if limit <= 0 {
+		limit = DefaultChangesLimit
+	}
+	if limit > MaxChangesLimit {
+		limit = MaxChangesLimit
+	}
+
+	current := &Token{}
+	if token != nil {
+		decoded, err := DecodeToken(*token)
+		if err != nil {
+			return nil, err
+		}
+		current = decoded
+	}
+
+	// timestamps only have second precision, so rows from the current second
+	// are held back until it has passed to avoid skipping late writers
+	cutoff := time.Now().Truncate(time.Second)
+
+	userAnimes, err := s.UserAnimeRepository.FindChangedByUserId(ctx, userId, pagination.ChangeWindow{
+		After:          current.Anime.UpdatedAt,
+		AfterID:        current.Anime.ID,
+		Before:         cutoff,
+		IncludeDeleted: token != nil,
+		Limit:          limit + 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	userLists, err := s.UserListRepository.FindChangedByUserId(ctx, userId, pagination.ChangeWindow{
+		After:          current.List.UpdatedAt,
+		AfterID:        current.List.ID,
+		Before:         cutoff,
+		IncludeDeleted: token != nil,
+		Limit:          limit + 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	changes := &Changes{
+		UserAnimes: []*user_anime.UserAnime{},
+		UserLists:  []*user_list.UserList{},
+		Tombstones: []*Tombstone{},
+	}
+
+	if len(userAnimes) > limit {
+		userAnimes = userAnimes[:limit]
+		changes.HasMore = true
+	}
+	if len(userLists) > limit {
+		userLists = userLists[:limit]
+		changes.HasMore = true
+	}
+
+	next := *current
+	for _, userAnime := range userAnimes {
+		next.Anime = Watermark{UpdatedAt: userAnime.UpdatedAt, ID: userAnime.ID}
+		if userAnime.DeletedAt.Valid {
+			changes.Tombstones = append(changes.Tombstones, &Tombstone{
+				Type:      EntityUserAnime,
+				ID:        userAnime.ID,
+				AnimeID:   userAnime.AnimeID,
+				DeletedAt: userAnime.DeletedAt.Time,
+			})
+			continue
+		}
+		changes.UserAnimes = append(changes.UserAnimes, userAnime)
+	}
+	for _, userList := range userLists {
+		next.List = Watermark{UpdatedAt: userList.UpdatedAt, ID: userList.ID}
+		if userList.DeletedAt.Valid {
+			changes.Tombstones = append(changes.Tombstones, &Tombstone{
+				Type:      EntityUserList,
+				ID:        userList.ID,
+				DeletedAt: userList.DeletedAt.Time,
+			})
+			continue
+		}
+		changes.UserLists = append(changes.UserLists, userList)
+	}
+
+	changes.Token = next.Encode()
+
+	return changes, nil
+}
+
+// Push applies a batch of offline edits in one transaction, so an error leaves
+// none of them applied. Conflicts are resolved on updated_at: an edit made
+// before the server copy was last changed loses, and the current server state
+// is returned so the client can reconcile.
+func (s *DeltaSyncService) Push(ctx context.Context, userId string, animeEdits []*AnimeEdit, listEdits []*ListEdit) (*PushResult, error) {
+	if len(animeEdits)+len(listEdits) > MaxPushBatchSize {
+		metrics.GetAppMetrics().ImportMetric(importSourceOfflineSync, metrics.Failure)
+		return nil, ErrPushBatchTooLarge
+	}
+
+	var result *PushResult
+	err := s.Transactor.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = s.push(ctx, userId, animeEdits, listEdits)
+		return err
+	})
+	if err != nil {
+		metrics.GetAppMetrics().ImportMetric(importSourceOfflineSync, metrics.Error)
+		return nil, err
+	}
+	metrics.GetAppMetrics().ImportMetric(importSourceOfflineSync, metrics.Success)
+
+	return result, nil
+}
+
+func (s *DeltaSyncService) push(ctx context.Context, userId string, animeEdits []*AnimeEdit, listEdits []*ListEdit) (*PushResult, error) {
+	result := &PushResult{
+		UserAnimes: []*user_anime.UserAnime{},
+		UserLists:  []*user_list.UserList{},
+		Conflicts:  []*Conflict{},
+	}
+
+	for _, edit := range listEdits {
+		if err := s.pushList(ctx, userId, edit, result); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, edit := range animeEdits {
+		if err := s.pushAnime(ctx, userId, edit, result); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func (s *DeltaSyncService) pushAnime(ctx context.Context, userId string, edit *AnimeEdit, result *PushResult) error {
+	edit.Entry.UserID = userId
+
+	existing, err := s.UserAnimeRepository.FindByUserIdAndAnimeIdWithDeleted(ctx, userId, edit.Entry.AnimeID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	if existing != nil && serverWins(existing.UpdatedAt, existing.DeletedAt, edit.EditedAt) {
+		conflict := &Conflict{
+			Type:   EntityUserAnime,
+			ID:     existing.ID,
+			Reason: "entry was changed on the server after this edit",
+		}
+		if !existing.DeletedAt.Valid {
+			conflict.ServerAnime = existing
+		}
+		result.Conflicts = append(result.Conflicts, conflict)
+		return nil
+	}
+
+	if edit.Deleted {
+		if existing == nil || existing.DeletedAt.Valid {
+			return nil
+		}
+		return s.UserAnimeService.Delete(ctx, userId, edit.Entry.AnimeID)
+	}
+
+	userAnime, err := s.UserAnimeService.Upsert(ctx, &edit.Entry)
+	var versionConflict *user_anime.VersionConflictError
+	if errors.As(err, &versionConflict) {
+		// another writer got in between the read above and the update
+		result.Conflicts = append(result.Conflicts, &Conflict{
+			Type:        EntityUserAnime,
+			ID:          versionConflict.Current.ID,
+			Reason:      "entry was changed on the server after this edit",
+			ServerAnime: versionConflict.Current,
+		})
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	result.UserAnimes = append(result.UserAnimes, userAnime)
+
+	return nil
+}
+
+func (s *DeltaSyncService) pushList(ctx context.Context, userId string, edit *ListEdit, result *PushResult) error {
+	edit.List.UserID = userId
+
+	if edit.List.ID != nil {
+		existing, err := s.UserListRepository.FindByIdWithDeleted(ctx, *edit.List.ID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if existing == nil {
+			// lists made offline are pushed without an id, an unknown one
+			// cannot be told apart from a list that was purged
+			if !edit.Deleted {
+				result.Conflicts = append(result.Conflicts, &Conflict{
+					Type:   EntityUserList,
+					ID:     *edit.List.ID,
+					Reason: "list does not exist, new lists are pushed without an id",
+				})
+			}
+			return nil
+		}
+
+		if existing.UserID == nil || *existing.UserID != userId {
+			result.Conflicts = append(result.Conflicts, &Conflict{
+				Type:   EntityUserList,
+				ID:     existing.ID,
+				Reason: "list does not belong to this user",
+			})
+			return nil
+		}
+
+		if existing.DeletedAt.Valid {
+			// deleted lists cannot be revived, so only a matching delete succeeds
+			if !edit.Deleted {
+				result.Conflicts = append(result.Conflicts, &Conflict{
+					Type:   EntityUserList,
+					ID:     existing.ID,
+					Reason: "list was deleted on the server",
+				})
+			}
+			return nil
+		}
+
+		if serverWins(existing.UpdatedAt, existing.DeletedAt, edit.EditedAt) {
+			result.Conflicts = append(result.Conflicts, &Conflict{
+				Type:       EntityUserList,
+				ID:         existing.ID,
+				Reason:     "list was changed on the server after this edit",
+				ServerList: existing,
+			})
+			return nil
+		}
+
+		if edit.Deleted {
+			return s.UserListService.DeleteUserList(ctx, userId, existing.ID)
+		}
+	} else if edit.Deleted {
+		return nil
+	}
+
+	userList, err := s.UserListService.Upsert(ctx, &edit.List)
+	var versionConflict *user_list.VersionConflictError
+	if errors.As(err, &versionConflict) {
+		result.Conflicts = append(result.Conflicts, &Conflict{
+			Type:       EntityUserList,
+			ID:         versionConflict.Current.ID,
+			Reason:     "list was changed on the server after this edit",
+			ServerList: versionConflict.Current,
+		})
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	result.UserLists = append(result.UserLists, userList)
+
+	return nil
+}
+
+// serverWins reports whether the server copy changed after the client edit
+func serverWins(updatedAt time.Time, deletedAt gorm.DeletedAt, editedAt time.Time) bool {
+	changedAt := updatedAt
+	if deletedAt.Valid && deletedAt.Time.After(changedAt) {
+		changedAt = deletedAt.Time
+	}
+
+	return changedAt.After(editedAt)
+}
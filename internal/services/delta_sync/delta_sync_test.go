@@ -0,0 +1,64 @@
+package delta_sync_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weeb-vip/list-service/internal/db/dbtest"
+	"github.com/weeb-vip/list-service/internal/db/repositories/outbox"
+	user_anime_repo "github.com/weeb-vip/list-service/internal/db/repositories/user_anime"
+	user_list_repo "github.com/weeb-vip/list-service/internal/db/repositories/user_list"
+	"github.com/weeb-vip/list-service/internal/services/delta_sync"
+	"github.com/weeb-vip/list-service/internal/services/user_anime"
+	"github.com/weeb-vip/list-service/internal/services/user_list"
+)
+
+func TestPushLists(t *testing.T) {
+	ctx := context.Background()
+	database := dbtest.NewSQLite(t)
+	outboxRepository := outbox.NewOutboxRepository(database)
+	userAnimeRepository := user_anime_repo.NewUserAnimeRepository(database)
+	userListRepository := user_list_repo.NewUserListRepository(database)
+	service := delta_sync.NewDeltaSyncService(
+		userAnimeRepository,
+		userListRepository,
+		user_anime.NewUserAnimeService(userAnimeRepository, outboxRepository, database),
+		user_list.NewUserListService(userListRepository, outboxRepository, database),
+		database,
+	)
+
+	t.Run("a list without an id is created", func(t *testing.T) {
+		result, err := service.Push(ctx, "user_1", nil, []*delta_sync.ListEdit{
+			{List: user_list.UserList{Name: "Offline"}, EditedAt: time.Now()},
+		})
+		require.NoError(t, err)
+		assert.Empty(t, result.Conflicts)
+		require.Len(t, result.UserLists, 1)
+		assert.NotEmpty(t, result.UserLists[0].ID)
+		assert.Equal(t, "Offline", *result.UserLists[0].Name)
+	})
+
+	t.Run("an unknown id is a conflict", func(t *testing.T) {
+		unknown := "unknown"
+		result, err := service.Push(ctx, "user_1", nil, []*delta_sync.ListEdit{
+			{List: user_list.UserList{ID: &unknown, Name: "Offline"}, EditedAt: time.Now()},
+		})
+		require.NoError(t, err)
+		assert.Empty(t, result.UserLists)
+		require.Len(t, result.Conflicts, 1)
+		assert.Equal(t, delta_sync.EntityUserList, result.Conflicts[0].Type)
+		assert.Equal(t, unknown, result.Conflicts[0].ID)
+	})
+
+	t.Run("deleting an unknown id is a no-op", func(t *testing.T) {
+		unknown := "unknown"
+		result, err := service.Push(ctx, "user_1", nil, []*delta_sync.ListEdit{
+			{List: user_list.UserList{ID: &unknown}, Deleted: true, EditedAt: time.Now()},
+		})
+		require.NoError(t, err)
+		assert.Empty(t, result.Conflicts)
+	})
+}
@@ -0,0 +1,107 @@
+package privacy_settings
+
+import (
+	"context"
+	"errors"
+
+	"github.com/weeb-vip/list-service/internal/db/repositories/privacy_settings"
+	"github.com/weeb-vip/list-service/internal/optional"
+)
+
+var ErrSettingRequired = errors.New("privacy settings cannot be null")
+
+// PrivacySettingsUpdate is a partial update of a user's settings. Only fields
+// that are set are written.
+type PrivacySettingsUpdate struct {
+	UserID          string
+	AnimeListPublic optional.Value[*bool]
+	StatsPublic     optional.Value[*bool]
+	FavoritesPublic optional.Value[*bool]
+}
+
+// apply writes the set fields of the update onto settings
+func (u *PrivacySettingsUpdate) apply(settings *privacy_settings.PrivacySettings) error {
+	fields := []struct {
+		value  optional.Value[*bool]
+		target *bool
+	}{
+		{u.AnimeListPublic, &settings.AnimeListPublic},
+		{u.StatsPublic, &settings.StatsPublic},
+		{u.FavoritesPublic, &settings.FavoritesPublic},
+	}
+	for _, field := range fields {
+		value, ok := field.value.Get()
+		if !ok {
+			continue
+		}
+		if value == nil {
+			return ErrSettingRequired
+		}
+		*field.target = *value
+	}
+
+	return nil
+}
+
+type PrivacySettingsServiceImpl interface {
+	// Get returns the settings of a user, the defaults when they never saved
+	// any
+	Get(ctx context.Context, userID string) (*privacy_settings.PrivacySettings, error)
+	// FindByUserIds returns the settings of every given user keyed by user id,
+	// defaults included
+	FindByUserIds(ctx context.Context, userIDs []string) (map[string]*privacy_settings.PrivacySettings, error)
+	Update(ctx context.Context, update PrivacySettingsUpdate) (*privacy_settings.PrivacySettings, error)
+}
+
+type PrivacySettingsService struct {
+	Repository privacy_settings.PrivacySettingsRepositoryImpl
+}
+
+func NewPrivacySettingsService(repository privacy_settings.PrivacySettingsRepositoryImpl) PrivacySettingsServiceImpl {
+	return &PrivacySettingsService{
+		Repository: repository,
+	}
+}
+
+func (p *PrivacySettingsService) Get(ctx context.Context, userID string) (*privacy_settings.PrivacySettings, error) {
+	settings, err := p.Repository.FindByUserId(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if settings == nil {
+		return privacy_settings.Default(userID), nil
+	}
+
+	return settings, nil
+}
+
+func (p *PrivacySettingsService) FindByUserIds(ctx context.Context, userIDs []string) (map[string]*privacy_settings.PrivacySettings, error) {
+	saved, err := p.Repository.FindByUserIds(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := make(map[string]*privacy_settings.PrivacySettings, len(userIDs))
+	for _, userID := range userIDs {
+		settings[userID] = privacy_settings.Default(userID)
+	}
+	for _, userSettings := range saved {
+		settings[userSettings.UserID] = userSettings
+	}
+
+	return settings, nil
+}
+
+func (p *PrivacySettingsService) Update(ctx context.Context, update PrivacySettingsUpdate) (*privacy_settings.PrivacySettings, error) {
+	settings, err := p.Get(ctx, update.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := update.apply(settings); err != nil {
+		return nil, err
+	}
+
+	return p.Repository.Save(ctx, settings)
+}
@@ -0,0 +1,58 @@
+package privacy_settings_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weeb-vip/list-service/internal/db/dbtest"
+	privacy_settings_repo "github.com/weeb-vip/list-service/internal/db/repositories/privacy_settings"
+	"github.com/weeb-vip/list-service/internal/optional"
+	"github.com/weeb-vip/list-service/internal/services/privacy_settings"
+)
+
+func TestPrivacySettingsService(t *testing.T) {
+	ctx := context.Background()
+	service := privacy_settings.NewPrivacySettingsService(privacy_settings_repo.NewPrivacySettingsRepository(dbtest.NewSQLite(t)))
+	shown := true
+
+	t.Run("users who never saved settings share nothing", func(t *testing.T) {
+		settings, err := service.Get(ctx, "user_1")
+		require.NoError(t, err)
+		assert.Equal(t, privacy_settings_repo.Default("user_1"), settings)
+	})
+
+	t.Run("updates only write the given settings", func(t *testing.T) {
+		settings, err := service.Update(ctx, privacy_settings.PrivacySettingsUpdate{
+			UserID:      "user_1",
+			StatsPublic: optional.Of(&shown),
+		})
+		require.NoError(t, err)
+		assert.False(t, settings.AnimeListPublic)
+		assert.True(t, settings.StatsPublic)
+
+		settings, err = service.Update(ctx, privacy_settings.PrivacySettingsUpdate{
+			UserID:          "user_1",
+			FavoritesPublic: optional.Of(&shown),
+		})
+		require.NoError(t, err)
+		assert.True(t, settings.StatsPublic)
+		assert.True(t, settings.FavoritesPublic)
+	})
+
+	t.Run("null is rejected", func(t *testing.T) {
+		_, err := service.Update(ctx, privacy_settings.PrivacySettingsUpdate{
+			UserID:          "user_1",
+			AnimeListPublic: optional.Of[*bool](nil),
+		})
+		assert.ErrorIs(t, err, privacy_settings.ErrSettingRequired)
+	})
+
+	t.Run("batches fill in defaults", func(t *testing.T) {
+		settings, err := service.FindByUserIds(ctx, []string{"user_1", "user_2"})
+		require.NoError(t, err)
+		assert.True(t, settings["user_1"].StatsPublic)
+		assert.False(t, settings["user_2"].StatsPublic)
+	})
+}
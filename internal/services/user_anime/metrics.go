@@ -0,0 +1,101 @@
+package user_anime
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/weeb-vip/list-service/internal/db/repositories/user_anime"
+	"github.com/weeb-vip/list-service/internal/logger"
+	"github.com/weeb-vip/list-service/metrics"
+)
+
+const (
+	statusLabelNone  = "none"
+	statusLabelOther = "other"
+)
+
+// StatusLabel returns the metric label of a stored status. Stored statuses
+// are not validated, so anything the service does not know is labelled other
+// to keep the number of series bounded.
+func StatusLabel(status *string) string {
+	if status == nil || *status == "" {
+		return statusLabelNone
+	}
+
+	label := UserAnimeStatus(strings.ToLower(strings.TrimSpace(*status)))
+	switch label {
+	case Watching, Completed, OnHold, Dropped, PlanToWatch:
+		return string(label)
+	}
+
+	return statusLabelOther
+}
+
+// recordChange records a committed entry change. previous is the entry as it
+// was before, nil or soft deleted when the change put it on the list.
+func recordChange(previous *user_anime.UserAnime, changed *user_anime.UserAnime) {
+	appMetrics := metrics.GetAppMetrics()
+	if changed.UserID != nil {
+		appMetrics.ActiveUserMetric(*changed.UserID)
+	}
+
+	to := StatusLabel(changed.Status)
+	if previous == nil || previous.DeletedAt.Valid {
+		appMetrics.EntryAddedMetric(to)
+		return
+	}
+
+	if from := StatusLabel(previous.Status); from != to {
+		appMetrics.StatusTransitionMetric(from, to)
+	}
+}
+
+// ReportTrackedEntries publishes how many entries are stored by status every
+// interval until ctx is done. The count scans the table, so interval should
+// be minutes rather than seconds.
+func ReportTrackedEntries(ctx context.Context, repository user_anime.UserAnimeRepositoryImpl, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		reportTrackedEntries(ctx, repository)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func reportTrackedEntries(ctx context.Context, repository user_anime.UserAnimeRepositoryImpl) {
+	counts, err := repository.CountByStatus(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log := logger.FromCtx(ctx)
+			log.Warn().Err(err).Msg("Failed to count tracked entries")
+		}
+		return
+	}
+
+	// every known status is reported so a status that empties drops to 0
+	entries := map[string]int64{
+		string(Watching):    0,
+		string(Completed):   0,
+		string(OnHold):      0,
+		string(Dropped):     0,
+		string(PlanToWatch): 0,
+		statusLabelNone:     0,
+		statusLabelOther:    0,
+	}
+	for status, count := range counts {
+		status := status
+		entries[StatusLabel(&status)] += count
+	}
+
+	appMetrics := metrics.GetAppMetrics()
+	for status, count := range entries {
+		appMetrics.TrackedEntriesMetric(status, count)
+	}
+}
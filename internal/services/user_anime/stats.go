@@ -0,0 +1,54 @@
+package user_anime
+
+import (
+	"context"
+)
+
+// UserStats sums up the entries of a user
+type UserStats struct {
+	Entries int64
+	// ByStatus counts the entries by StatusLabel
+	ByStatus map[string]int64
+	// MeanScore averages the scored entries, nil when none are scored
+	MeanScore *float64
+	Episodes  int64
+}
+
+// StatsByUserIds returns the stats of every given user keyed by user id,
+// users without entries get empty stats. Entries on the lists given for a user
+// in excludeListIds are left out.
+func (a *UserAnimeService) StatsByUserIds(ctx context.Context, userIds []string, excludeListIds map[string][]string) (map[string]*UserStats, error) {
+	rows, err := a.Repository.StatsByUserIds(ctx, userIds, excludeListIds)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(map[string]*UserStats, len(userIds))
+	for _, userId := range userIds {
+		stats[userId] = &UserStats{ByStatus: map[string]int64{}}
+	}
+
+	scored := make(map[string]int64, len(userIds))
+	scoreSums := make(map[string]float64, len(userIds))
+	for _, row := range rows {
+		userStats, ok := stats[row.UserID]
+		if !ok {
+			continue
+		}
+		userStats.Entries += row.Entries
+		userStats.ByStatus[StatusLabel(row.Status)] += row.Entries
+		userStats.Episodes += row.Episodes
+		scored[row.UserID] += row.Scored
+		scoreSums[row.UserID] += row.ScoreSum
+	}
+
+	for userId, count := range scored {
+		if count == 0 {
+			continue
+		}
+		meanScore := scoreSums[userId] / float64(count)
+		stats[userId].MeanScore = &meanScore
+	}
+
+	return stats, nil
+}
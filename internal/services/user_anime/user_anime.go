@@ -3,7 +3,13 @@ package user_anime
 import (
 	"context"
 	"errors"
+	"github.com/weeb-vip/list-service/internal/db"
+	"github.com/weeb-vip/list-service/internal/db/repositories/outbox"
 	"github.com/weeb-vip/list-service/internal/db/repositories/user_anime"
+	"github.com/weeb-vip/list-service/internal/events"
+	"github.com/weeb-vip/list-service/internal/optional"
+	"github.com/weeb-vip/list-service/internal/pagination"
+	"github.com/weeb-vip/list-service/metrics"
 	"gorm.io/gorm"
 	"strings"
 )
@@ -29,9 +35,62 @@ type UserAnime struct {
 	RewatchingEpisodes *int             `json:"rewatching_episodes"`
 	Tags               []string         `json:"tags"`
 	ListID             *string          `json:"list_id"`
-	CreatedAt          string           `json:"created_at"`
-	UpdatedAt          string           `json:"updated_at"`
-	DeletedAt          string           `json:"deleted_at"`
+	// ExpectedVersion rejects the update if the entry changed since it was read
+	ExpectedVersion *int   `json:"expected_version"`
+	CreatedAt       string `json:"created_at"`
+	UpdatedAt       string `json:"updated_at"`
+	DeletedAt       string `json:"deleted_at"`
+}
+
+// UserAnimeUpdate is a partial update of an entry. Only fields that are set
+// are written, a set field holding nil clears the column.
+type UserAnimeUpdate struct {
+	UserID             string
+	AnimeID            string
+	Status             optional.Value[*UserAnimeStatus]
+	Score              optional.Value[*float64]
+	Episodes           optional.Value[*int]
+	Rewatching         optional.Value[*int]
+	RewatchingEpisodes optional.Value[*int]
+	Tags               optional.Value[[]string]
+	ListID             optional.Value[*string]
+	ExpectedVersion    *int
+}
+
+// Columns returns the columns to write keyed by column name
+func (u *UserAnimeUpdate) Columns() map[string]interface{} {
+	columns := map[string]interface{}{}
+	if status, ok := u.Status.Get(); ok {
+		if status != nil {
+			columns["status"] = string(*status)
+		} else {
+			columns["status"] = nil
+		}
+	}
+	if score, ok := u.Score.Get(); ok {
+		columns["score"] = score
+	}
+	if episodes, ok := u.Episodes.Get(); ok {
+		columns["episodes"] = episodes
+	}
+	if rewatching, ok := u.Rewatching.Get(); ok {
+		columns["rewatching"] = rewatching
+	}
+	if rewatchingEpisodes, ok := u.RewatchingEpisodes.Get(); ok {
+		columns["rewatching_episodes"] = rewatchingEpisodes
+	}
+	if tags, ok := u.Tags.Get(); ok {
+		if tags != nil {
+			columns["tags"] = strings.Join(tags, ",")
+		} else {
+			columns["tags"] = nil
+		}
+	}
+	if listID, ok := u.ListID.Get(); ok {
+		columns["list_id"] = listID
+	}
+
+	return columns
 }
 
 type UserAnimePaginated struct {
@@ -43,20 +102,57 @@ type UserAnimePaginated struct {
 
 type UserAnimeServiceImpl interface {
 	Upsert(ctx context.Context, userAnime *UserAnime) (*user_anime.UserAnime, error)
+	Add(ctx context.Context, userAnime *UserAnime) (*user_anime.UserAnime, error)
+	Update(ctx context.Context, update *UserAnimeUpdate) (*user_anime.UserAnime, error)
 	Delete(ctx context.Context, userid string, id string) error
-	FindByUserId(ctx context.Context, userId string, status *string, page int, limit int) ([]*user_anime.UserAnime, int64, error)
+	FindByUserId(ctx context.Context, userId string, filter user_anime.Filter, sort user_anime.Sort, page int, limit int) ([]*user_anime.UserAnime, int64, error)
+	FindPageByUserId(ctx context.Context, userId string, filter user_anime.Filter, sort user_anime.Sort, page pagination.Page) ([]*user_anime.UserAnime, bool, int64, error)
 	FindByUserIdAndAnimeId(ctx context.Context, userId string, animeId string) (*user_anime.UserAnime, error)
 	FindByUserIdAndAnimeIds(ctx context.Context, userId string, animeIds []string) ([]*user_anime.UserAnime, error)
+	FindByIds(ctx context.Context, ids []string) ([]*user_anime.UserAnime, error)
+	StatsByUserIds(ctx context.Context, userIds []string, excludeListIds map[string][]string) (map[string]*UserStats, error)
 }
 
 type UserAnimeService struct {
-	Repository user_anime.UserAnimeRepositoryImpl
+	Repository       user_anime.UserAnimeRepositoryImpl
+	OutboxRepository outbox.OutboxRepositoryImpl
+	Transactor       db.Transactor
 }
 
-func NewUserAnimeService(userAnimeRepository user_anime.UserAnimeRepositoryImpl) UserAnimeServiceImpl {
+func NewUserAnimeService(userAnimeRepository user_anime.UserAnimeRepositoryImpl, outboxRepository outbox.OutboxRepositoryImpl, transactor db.Transactor) UserAnimeServiceImpl {
 	return &UserAnimeService{
-		Repository: userAnimeRepository,
+		Repository:       userAnimeRepository,
+		OutboxRepository: outboxRepository,
+		Transactor:       transactor,
+	}
+}
+
+// write runs a change together with recording its event, so either both are
+// stored or neither is
+func (a *UserAnimeService) write(ctx context.Context, change func(ctx context.Context) (*user_anime.UserAnime, events.Type, error)) (*user_anime.UserAnime, error) {
+	var userAnime *user_anime.UserAnime
+	err := a.Transactor.Transaction(ctx, func(ctx context.Context) error {
+		changed, eventType, err := change(ctx)
+		if err != nil {
+			return err
+		}
+
+		event, err := events.New(eventType, changed.ID, *changed.UserID, changed)
+		if err != nil {
+			return err
+		}
+		if err := a.OutboxRepository.Add(ctx, event); err != nil {
+			return err
+		}
+
+		userAnime = changed
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+
+	return userAnime, nil
 }
 
 func (a *UserAnimeService) Upsert(ctx context.Context, userAnime *UserAnime) (*user_anime.UserAnime, error) {
@@ -87,8 +183,115 @@ func (a *UserAnimeService) Upsert(ctx context.Context, userAnime *UserAnime) (*u
 		Tags:               &tags,
 		ListID:             userAnime.ListID,
 	}
+	if userAnime.ExpectedVersion != nil {
+		userAnimeEntity.Version = *userAnime.ExpectedVersion
+	}
+
+	var previous *user_anime.UserAnime
+	upserted, err := a.write(ctx, func(ctx context.Context) (*user_anime.UserAnime, events.Type, error) {
+		var err error
+		previous, err = a.previous(ctx, userAnime.UserID, userAnime.AnimeID)
+		if err != nil {
+			return nil, "", err
+		}
+
+		upserted, err := a.Repository.Upsert(ctx, userAnimeEntity)
+		if err != nil {
+			return nil, "", err
+		}
+		// only a freshly inserted row is at the first version
+		if upserted.Version == 1 {
+			return upserted, events.UserAnimeAdded, nil
+		}
+		return upserted, events.UserAnimeUpdated, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	recordChange(previous, upserted)
 
-	return a.Repository.Upsert(ctx, userAnimeEntity)
+	return upserted, nil
+}
+
+// previous returns the stored entry, soft deleted or not, before a change is
+// made to it, or nil when there is none
+func (a *UserAnimeService) previous(ctx context.Context, userId string, animeId string) (*user_anime.UserAnime, error) {
+	previous, err := a.Repository.FindByUserIdAndAnimeIdWithDeleted(ctx, userId, animeId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	return previous, err
+}
+
+// Add puts an anime on the user's list, failing if it is already there
+func (a *UserAnimeService) Add(ctx context.Context, userAnime *UserAnime) (*user_anime.UserAnime, error) {
+	var tags *string
+	if userAnime.Tags != nil {
+		joined := strings.Join(userAnime.Tags, ",")
+		tags = &joined
+	}
+	var status *string
+	if userAnime.Status != nil {
+		statuss := string(*userAnime.Status)
+		status = &statuss
+	}
+	userAnimeEntity := &user_anime.UserAnime{
+		UserID:             &userAnime.UserID,
+		AnimeID:            &userAnime.AnimeID,
+		Status:             status,
+		Score:              userAnime.Score,
+		Episodes:           userAnime.Episodes,
+		Rewatching:         userAnime.Rewatching,
+		RewatchingEpisodes: userAnime.RewatchingEpisodes,
+		Tags:               tags,
+		ListID:             userAnime.ListID,
+	}
+
+	created, err := a.write(ctx, func(ctx context.Context) (*user_anime.UserAnime, events.Type, error) {
+		created, err := a.Repository.Create(ctx, userAnimeEntity)
+		return created, events.UserAnimeAdded, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	recordChange(nil, created)
+
+	return created, nil
+}
+
+// Update applies a partial update to an existing entry
+func (a *UserAnimeService) Update(ctx context.Context, update *UserAnimeUpdate) (*user_anime.UserAnime, error) {
+	var expectedVersion int
+	if update.ExpectedVersion != nil {
+		expectedVersion = *update.ExpectedVersion
+	}
+
+	columns := update.Columns()
+	var previous *user_anime.UserAnime
+	updated, err := a.write(ctx, func(ctx context.Context) (*user_anime.UserAnime, events.Type, error) {
+		// the entry is only read ahead when its status can change
+		if _, ok := columns["status"]; ok {
+			var err error
+			previous, err = a.previous(ctx, update.UserID, update.AnimeID)
+			if err != nil {
+				return nil, "", err
+			}
+		}
+
+		updated, err := a.Repository.Update(ctx, update.UserID, update.AnimeID, columns, expectedVersion)
+		return updated, events.UserAnimeUpdated, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if previous == nil {
+		// the status did not change, record activity only
+		previous = updated
+	}
+	recordChange(previous, updated)
+
+	return updated, nil
 }
 
 func (a *UserAnimeService) Delete(ctx context.Context, userid string, id string) error {
@@ -105,16 +308,19 @@ func (a *UserAnimeService) Delete(ctx context.Context, userid string, id string)
 		return nil
 	}
 
-	err = a.Repository.Delete(ctx, userAnime)
+	_, err = a.write(ctx, func(ctx context.Context) (*user_anime.UserAnime, events.Type, error) {
+		return userAnime, events.UserAnimeDeleted, a.Repository.Delete(ctx, userAnime)
+	})
 	if err != nil {
 		return err
 	}
+	metrics.GetAppMetrics().ActiveUserMetric(userid)
 
 	return nil
 }
 
-func (a *UserAnimeService) FindByUserId(ctx context.Context, userId string, status *string, page int, limit int) ([]*user_anime.UserAnime, int64, error) {
-	userAnimes, total, err := a.Repository.FindByUserId(ctx, userId, status, page, limit)
+func (a *UserAnimeService) FindByUserId(ctx context.Context, userId string, filter user_anime.Filter, sort user_anime.Sort, page int, limit int) ([]*user_anime.UserAnime, int64, error) {
+	userAnimes, total, err := a.Repository.FindByUserId(ctx, userId, filter, sort, page, limit)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -122,6 +328,15 @@ func (a *UserAnimeService) FindByUserId(ctx context.Context, userId string, stat
 	return userAnimes, total, nil
 }
 
+func (a *UserAnimeService) FindPageByUserId(ctx context.Context, userId string, filter user_anime.Filter, sort user_anime.Sort, page pagination.Page) ([]*user_anime.UserAnime, bool, int64, error) {
+	userAnimes, hasMore, total, err := a.Repository.FindByUserIdKeyset(ctx, userId, filter, sort, page)
+	if err != nil {
+		return nil, false, 0, err
+	}
+
+	return userAnimes, hasMore, total, nil
+}
+
 func (a *UserAnimeService) FindByUserIdAndAnimeId(ctx context.Context, userId string, animeId string) (*user_anime.UserAnime, error) {
 	userAnime, err := a.Repository.FindByUserIdAndAnimeId(ctx, userId, animeId)
 	if err != nil {
@@ -147,3 +362,13 @@ func (a *UserAnimeService) FindByUserIdAndAnimeIds(ctx context.Context, userId s
 
 	return userAnimes, nil
 }
+
+// FindByIds returns the entries with the given ids, whoever they belong to
+func (a *UserAnimeService) FindByIds(ctx context.Context, ids []string) ([]*user_anime.UserAnime, error) {
+	userAnimes, err := a.Repository.FindByIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	return userAnimes, nil
+}
@@ -0,0 +1,96 @@
+package user_anime_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weeb-vip/list-service/internal/db/dbtest"
+	"github.com/weeb-vip/list-service/internal/db/repositories/outbox"
+	user_anime_repo "github.com/weeb-vip/list-service/internal/db/repositories/user_anime"
+	"github.com/weeb-vip/list-service/internal/optional"
+	"github.com/weeb-vip/list-service/internal/services/user_anime"
+)
+
+func TestUserAnimeUpdateColumns(t *testing.T) {
+	t.Run("omitted fields are not written", func(t *testing.T) {
+		episodes := 12
+		update := &user_anime.UserAnimeUpdate{
+			Episodes: optional.Of(&episodes),
+		}
+
+		assert.Equal(t, map[string]interface{}{"episodes": &episodes}, update.Columns())
+	})
+
+	t.Run("explicit null clears the column", func(t *testing.T) {
+		update := &user_anime.UserAnimeUpdate{
+			Score: optional.Of[*float64](nil),
+			Tags:  optional.Of[[]string](nil),
+		}
+
+		columns := update.Columns()
+		assert.Len(t, columns, 2)
+		assert.Nil(t, columns["score"])
+		assert.Nil(t, columns["tags"])
+		assert.Contains(t, columns, "score")
+	})
+
+	t.Run("status and tags are stored as strings", func(t *testing.T) {
+		status := user_anime.UserAnimeStatus("WATCHING")
+		update := &user_anime.UserAnimeUpdate{
+			Status: optional.Of(&status),
+			Tags:   optional.Of([]string{"isekai", "rewatch"}),
+		}
+
+		assert.Equal(t, map[string]interface{}{
+			"status": "WATCHING",
+			"tags":   "isekai,rewatch",
+		}, update.Columns())
+	})
+}
+
+func TestStatusLabel(t *testing.T) {
+	status := func(value string) *string {
+		return &value
+	}
+
+	assert.Equal(t, "watching", user_anime.StatusLabel(status("WATCHING")))
+	assert.Equal(t, "plantowatch", user_anime.StatusLabel(status("plantowatch")))
+	assert.Equal(t, "none", user_anime.StatusLabel(nil))
+	assert.Equal(t, "other", user_anime.StatusLabel(status("WATCHLIST")))
+}
+
+func TestStatsByUserIds(t *testing.T) {
+	ctx := context.Background()
+	database := dbtest.NewSQLite(t)
+	repository := user_anime_repo.NewUserAnimeRepository(database)
+	service := user_anime.NewUserAnimeService(repository, outbox.NewOutboxRepository(database), database)
+
+	entry := func(animeId string, status string, score *float64, episodes int) {
+		_, err := repository.Create(ctx, &user_anime_repo.UserAnime{
+			UserID:   &[]string{"user_1"}[0],
+			AnimeID:  &animeId,
+			Status:   &status,
+			Score:    score,
+			Episodes: &episodes,
+		})
+		require.NoError(t, err)
+	}
+	eight, nine := 8.0, 9.0
+	entry("anime_1", "COMPLETED", &eight, 12)
+	entry("anime_2", "COMPLETED", &nine, 24)
+	entry("anime_3", "WATCHING", nil, 3)
+
+	stats, err := service.StatsByUserIds(ctx, []string{"user_1", "user_2"}, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(3), stats["user_1"].Entries)
+	assert.Equal(t, map[string]int64{"completed": 2, "watching": 1}, stats["user_1"].ByStatus)
+	assert.Equal(t, int64(39), stats["user_1"].Episodes)
+	require.NotNil(t, stats["user_1"].MeanScore)
+	assert.InDelta(t, 8.5, *stats["user_1"].MeanScore, 0.001)
+
+	assert.Zero(t, stats["user_2"].Entries)
+	assert.Nil(t, stats["user_2"].MeanScore)
+}
@@ -2,35 +2,123 @@ package user_list
 
 import (
 	"context"
+	"errors"
+	"github.com/weeb-vip/list-service/internal/db"
+	"github.com/weeb-vip/list-service/internal/db/repositories/outbox"
 	"github.com/weeb-vip/list-service/internal/db/repositories/user_list"
+	"github.com/weeb-vip/list-service/internal/events"
+	"github.com/weeb-vip/list-service/internal/optional"
+	"github.com/weeb-vip/list-service/internal/pagination"
+	"github.com/weeb-vip/list-service/metrics"
 	"strings"
 )
 
+var ErrNameRequired = errors.New("list name cannot be empty")
+var ErrIsPublicRequired = errors.New("list visibility cannot be null")
+
 type UserList struct {
-	ID          *string
-	UserID      string
-	Name        string
-	IsPublic    bool
+	ID     *string
+	UserID string
+	Name   string
+	// IsPublic falls back to the column default when nil
+	IsPublic    *bool
 	Tags        []string
 	Description *string
+	// ExpectedVersion rejects the update if the list changed since it was read
+	ExpectedVersion *int
+}
+
+// UserListUpdate is a partial update of a list. Only fields that are set are
+// written.
+type UserListUpdate struct {
+	ID              string
+	UserID          string
+	Name            optional.Value[*string]
+	Description     optional.Value[*string]
+	Tags            optional.Value[[]string]
+	IsPublic        optional.Value[*bool]
+	ExpectedVersion *int
+}
+
+// Columns returns the columns to write keyed by column name
+func (u *UserListUpdate) Columns() (map[string]interface{}, error) {
+	columns := map[string]interface{}{}
+	if name, ok := u.Name.Get(); ok {
+		if name == nil || *name == "" {
+			return nil, ErrNameRequired
+		}
+		columns["name"] = *name
+	}
+	if description, ok := u.Description.Get(); ok {
+		columns["description"] = description
+	}
+	if tags, ok := u.Tags.Get(); ok {
+		// lists always carry a tags value, so null clears to empty
+		columns["tags"] = strings.Join(tags, ",")
+	}
+	if isPublic, ok := u.IsPublic.Get(); ok {
+		if isPublic == nil {
+			return nil, ErrIsPublicRequired
+		}
+		columns["is_public"] = *isPublic
+	}
+
+	return columns, nil
 }
 
 type UserListServiceImpl interface {
 	GetUserListsByID(ctx context.Context, userID string) ([]*user_list.UserList, error)
+	FindByIds(ctx context.Context, ids []string) ([]*user_list.UserList, error)
+	FindByUserIds(ctx context.Context, userIDs []string) ([]*user_list.UserList, error)
+	GetUserListsPage(ctx context.Context, userID string, page pagination.Page) ([]*user_list.UserList, bool, int64, error)
+	Create(ctx context.Context, userList *UserList) (*user_list.UserList, error)
 	Upsert(ctx context.Context, userList *UserList) (*user_list.UserList, error)
+	Update(ctx context.Context, update *UserListUpdate) (*user_list.UserList, error)
 	DeleteUserList(ctx context.Context, userid string, id string) error
 }
 
 type UserListService struct {
-	Repository user_list.UserListRepositoryImpl
+	Repository       user_list.UserListRepositoryImpl
+	OutboxRepository outbox.OutboxRepositoryImpl
+	Transactor       db.Transactor
 }
 
-func NewUserListService(repository user_list.UserListRepositoryImpl) UserListServiceImpl {
+func NewUserListService(repository user_list.UserListRepositoryImpl, outboxRepository outbox.OutboxRepositoryImpl, transactor db.Transactor) UserListServiceImpl {
 	return &UserListService{
-		Repository: repository,
+		Repository:       repository,
+		OutboxRepository: outboxRepository,
+		Transactor:       transactor,
 	}
 }
 
+// write runs a change together with recording its event, so either both are
+// stored or neither is
+func (u *UserListService) write(ctx context.Context, change func(ctx context.Context) (*user_list.UserList, events.Type, error)) (*user_list.UserList, error) {
+	var userList *user_list.UserList
+	err := u.Transactor.Transaction(ctx, func(ctx context.Context) error {
+		changed, eventType, err := change(ctx)
+		if err != nil {
+			return err
+		}
+
+		event, err := events.New(eventType, changed.ID, *changed.UserID, changed)
+		if err != nil {
+			return err
+		}
+		if err := u.OutboxRepository.Add(ctx, event); err != nil {
+			return err
+		}
+
+		userList = changed
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return userList, nil
+}
+
 func (u *UserListService) GetUserListsByID(ctx context.Context, userID string) ([]*user_list.UserList, error) {
 	userLists, err := u.Repository.FindByUserId(ctx, userID)
 	if err != nil {
@@ -40,6 +128,47 @@ func (u *UserListService) GetUserListsByID(ctx context.Context, userID string) (
 	return userLists, nil
 }
 
+// FindByIds returns the lists with the given ids, whoever they belong to
+func (u *UserListService) FindByIds(ctx context.Context, ids []string) ([]*user_list.UserList, error) {
+	userLists, err := u.Repository.FindByIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	return userLists, nil
+}
+
+// FindByUserIds returns the lists of the given users
+func (u *UserListService) FindByUserIds(ctx context.Context, userIDs []string) ([]*user_list.UserList, error) {
+	userLists, err := u.Repository.FindByUserIds(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return userLists, nil
+}
+
+func (u *UserListService) GetUserListsPage(ctx context.Context, userID string, page pagination.Page) ([]*user_list.UserList, bool, int64, error) {
+	userLists, hasMore, total, err := u.Repository.FindByUserIdKeyset(ctx, userID, page)
+	if err != nil {
+		return nil, false, 0, err
+	}
+
+	return userLists, hasMore, total, nil
+}
+
+// Create adds a new list for the user. It always inserts, an id or expected
+// version on userList is ignored.
+func (u *UserListService) Create(ctx context.Context, userList *UserList) (*user_list.UserList, error) {
+	created := *userList
+	created.ID = nil
+	created.ExpectedVersion = nil
+
+	return u.Upsert(ctx, &created)
+}
+
+// Upsert creates userList when it has no id and otherwise replaces the list
+// with that id, which must belong to the user
 func (u *UserListService) Upsert(ctx context.Context, userList *UserList) (*user_list.UserList, error) {
 	// Convert model.UserList to user_list.UserList
 	// convert tags to comma separated string
@@ -55,21 +184,61 @@ func (u *UserListService) Upsert(ctx context.Context, userList *UserList) (*user
 		ID:          id,
 		UserID:      &userList.UserID,
 		Name:        &userList.Name,
-		IsPublic:    &userList.IsPublic,
+		IsPublic:    userList.IsPublic,
 		Tags:        &tags,
 		Description: userList.Description,
 	}
+	if userList.ExpectedVersion != nil {
+		userListEntity.Version = *userList.ExpectedVersion
+	}
+
+	eventType := events.UserListUpdated
+	if userListEntity.ID == "" {
+		eventType = events.UserListCreated
+	}
 
 	// Upsert the user list
-	createdUserList, err := u.Repository.Upsert(ctx, userListEntity)
+	createdUserList, err := u.write(ctx, func(ctx context.Context) (*user_list.UserList, events.Type, error) {
+		upserted, err := u.Repository.Upsert(ctx, userListEntity)
+		return upserted, eventType, err
+	})
 	if err != nil {
 		return nil, err
 	}
+	appMetrics := metrics.GetAppMetrics()
+	appMetrics.ActiveUserMetric(userList.UserID)
+	if eventType == events.UserListCreated {
+		appMetrics.ListCreatedMetric()
+	}
 
 	// Convert user_list.UserList back to model.UserList
 	return createdUserList, nil
 }
 
+// Update applies a partial update to one of the user's lists
+func (u *UserListService) Update(ctx context.Context, update *UserListUpdate) (*user_list.UserList, error) {
+	columns, err := update.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var expectedVersion int
+	if update.ExpectedVersion != nil {
+		expectedVersion = *update.ExpectedVersion
+	}
+
+	updated, err := u.write(ctx, func(ctx context.Context) (*user_list.UserList, events.Type, error) {
+		updated, err := u.Repository.Update(ctx, update.UserID, update.ID, columns, expectedVersion)
+		return updated, events.UserListUpdated, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	metrics.GetAppMetrics().ActiveUserMetric(update.UserID)
+
+	return updated, nil
+}
+
 func (u *UserListService) DeleteUserList(ctx context.Context, userid string, id string) error {
 	userList, err := u.Repository.FindById(ctx, id)
 	if err != nil {
@@ -84,10 +253,13 @@ func (u *UserListService) DeleteUserList(ctx context.Context, userid string, id
 		return nil
 	}
 
-	err = u.Repository.Delete(ctx, userList)
+	_, err = u.write(ctx, func(ctx context.Context) (*user_list.UserList, events.Type, error) {
+		return userList, events.UserListDeleted, u.Repository.Delete(ctx, userList)
+	})
 	if err != nil {
 		return err
 	}
+	metrics.GetAppMetrics().ActiveUserMetric(userid)
 
 	return nil
 }
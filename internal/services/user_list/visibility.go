@@ -0,0 +1,33 @@
+package user_list
+
+import (
+	"github.com/weeb-vip/list-service/internal/db/repositories/user_list"
+)
+
+// IsPublic reports whether userList is shared with other users
+func IsPublic(userList *user_list.UserList) bool {
+	return userList.IsPublic != nil && *userList.IsPublic
+}
+
+// VisibleTo reports whether viewerID may see userList, owners see their lists
+// and everyone sees public ones. viewerID is "" for anonymous callers.
+func VisibleTo(userList *user_list.UserList, viewerID string) bool {
+	if viewerID != "" && userList.UserID != nil && *userList.UserID == viewerID {
+		return true
+	}
+
+	return IsPublic(userList)
+}
+
+// PrivateListIds returns the ids of the lists of ownerID that are not public,
+// entries on them are hidden from other users
+func PrivateListIds(userLists []*user_list.UserList, ownerID string) []string {
+	var ids []string
+	for _, userList := range userLists {
+		if userList.UserID != nil && *userList.UserID == ownerID && !IsPublic(userList) {
+			ids = append(ids, userList.ID)
+		}
+	}
+
+	return ids
+}
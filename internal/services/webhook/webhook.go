@@ -0,0 +1,138 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/netip"
+	"net/url"
+	"strings"
+
+	"github.com/weeb-vip/list-service/internal/db/repositories/webhook"
+	"github.com/weeb-vip/list-service/internal/events"
+	webhook2 "github.com/weeb-vip/list-service/internal/webhook"
+)
+
+var ErrInvalidURL = errors.New("webhook url must be an absolute http or https url of a public host")
+var ErrInvalidEventType = errors.New("unknown webhook event type")
+var ErrEventTypesRequired = errors.New("webhook must subscribe to at least one event type")
+
+// DefaultDeliveryLimit is how many deliveries are listed when no limit is given
+const DefaultDeliveryLimit = 50
+
+// MaxDeliveryLimit caps how many deliveries are listed at once
+const MaxDeliveryLimit = 200
+
+type Subscription struct {
+	UserID     string
+	URL        string
+	EventTypes []string
+	// Secret signs deliveries, one is generated when nil
+	Secret *string
+}
+
+type WebhookServiceImpl interface {
+	Create(ctx context.Context, subscription *Subscription) (*webhook.Subscription, error)
+	FindByUserId(ctx context.Context, userId string) ([]*webhook.Subscription, error)
+	Delete(ctx context.Context, userId string, id string) error
+	Enable(ctx context.Context, userId string, id string) (*webhook.Subscription, error)
+	FindDeliveries(ctx context.Context, userId string, subscriptionId string, limit *int) ([]*webhook.Delivery, error)
+}
+
+type WebhookService struct {
+	Repository webhook.WebhookRepositoryImpl
+}
+
+func NewWebhookService(repository webhook.WebhookRepositoryImpl) WebhookServiceImpl {
+	return &WebhookService{Repository: repository}
+}
+
+func (w *WebhookService) Create(ctx context.Context, subscription *Subscription) (*webhook.Subscription, error) {
+	endpoint, err := url.Parse(subscription.URL)
+	if err != nil || !validEndpoint(endpoint) {
+		return nil, ErrInvalidURL
+	}
+
+	if len(subscription.EventTypes) == 0 {
+		return nil, ErrEventTypesRequired
+	}
+	for _, eventType := range subscription.EventTypes {
+		if !events.Type(eventType).Valid() {
+			return nil, ErrInvalidEventType
+		}
+	}
+
+	var secret string
+	if subscription.Secret != nil && *subscription.Secret != "" {
+		secret = *subscription.Secret
+	} else {
+		secret, err = generateSecret()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return w.Repository.CreateSubscription(ctx, &webhook.Subscription{
+		UserID:     subscription.UserID,
+		URL:        endpoint.String(),
+		EventTypes: strings.Join(subscription.EventTypes, ","),
+		Secret:     secret,
+	})
+}
+
+func (w *WebhookService) FindByUserId(ctx context.Context, userId string) ([]*webhook.Subscription, error) {
+	return w.Repository.FindSubscriptionsByUserId(ctx, userId)
+}
+
+func (w *WebhookService) Delete(ctx context.Context, userId string, id string) error {
+	return w.Repository.DeleteSubscription(ctx, userId, id)
+}
+
+func (w *WebhookService) Enable(ctx context.Context, userId string, id string) (*webhook.Subscription, error) {
+	return w.Repository.EnableSubscription(ctx, userId, id)
+}
+
+func (w *WebhookService) FindDeliveries(ctx context.Context, userId string, subscriptionId string, limit *int) ([]*webhook.Delivery, error) {
+	// 404 rather than an empty log for subscriptions of other users
+	if _, err := w.Repository.FindSubscription(ctx, userId, subscriptionId); err != nil {
+		return nil, err
+	}
+
+	deliveryLimit := DefaultDeliveryLimit
+	if limit != nil && *limit > 0 {
+		deliveryLimit = *limit
+	}
+	if deliveryLimit > MaxDeliveryLimit {
+		deliveryLimit = MaxDeliveryLimit
+	}
+
+	return w.Repository.FindDeliveries(ctx, userId, subscriptionId, deliveryLimit)
+}
+
+// validEndpoint rejects urls that are not absolute http(s) urls, carry
+// credentials or obviously point into the private network. Host names are
+// checked again by the worker once resolved.
+func validEndpoint(endpoint *url.URL) bool {
+	if (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.User != nil {
+		return false
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(endpoint.Hostname(), "."))
+	if host == "" || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && webhook2.BlockedAddress(addr) {
+		return false
+	}
+
+	return true
+}
+
+func generateSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedAddress is returned when an endpoint resolves to an address inside
+// the network the service runs in
+var ErrBlockedAddress = errors.New("webhook endpoint resolves to a blocked address")
+
+// blockedPrefixes are the non-public ranges netip has no predicate for
+var blockedPrefixes = []netip.Prefix{
+	// "this network", of which IsUnspecified only covers 0.0.0.0
+	netip.MustParsePrefix("0.0.0.0/8"),
+	// carrier-grade NAT, used by pod and VPC networks and some cloud metadata
+	// services
+	netip.MustParsePrefix("100.64.0.0/10"),
+	// benchmarking
+	netip.MustParsePrefix("198.18.0.0/15"),
+	// NAT64 and 6to4 embed an IPv4 address, which may be a private one
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("2002::/16"),
+}
+
+// BlockedAddress reports whether addr is a loopback, private, link-local,
+// unspecified or otherwise non-public address, which webhooks are never
+// delivered to
+func BlockedAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsUnspecified() {
+		return true
+	}
+
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewClient returns the client deliveries are posted with. The address is
+// checked after the host name is resolved, so a name pointing into the private
+// network is refused as well. Redirects are not followed, a redirect response
+// counts as a failed delivery. No proxy is used, since one would be dialed in
+// place of the endpoint.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   refuseBlocked,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// refuseBlocked runs before each connection, with the resolved address
+func refuseBlocked(network string, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if BlockedAddress(addrPort.Addr()) {
+		return ErrBlockedAddress
+	}
+
+	return nil
+}
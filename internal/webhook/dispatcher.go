@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/weeb-vip/list-service/internal/db/repositories/webhook"
+	"github.com/weeb-vip/list-service/internal/events"
+)
+
+type DispatcherImpl interface {
+	// Dispatch records a pending delivery of event for every enabled
+	// subscription of its user. Dispatching an event again is a no-op.
+	Dispatch(ctx context.Context, event *events.Event) error
+}
+
+type Dispatcher struct {
+	Repository webhook.WebhookRepositoryImpl
+}
+
+func NewDispatcher(repository webhook.WebhookRepositoryImpl) DispatcherImpl {
+	return &Dispatcher{Repository: repository}
+}
+
+func (d *Dispatcher) Dispatch(ctx context.Context, event *events.Event) error {
+	subscriptions, err := d.Repository.FindEnabledSubscriptions(ctx, event.UserID, string(event.Type))
+	if err != nil {
+		return err
+	}
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	// the delivered body is the event as published
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	deliveries := make([]*webhook.Delivery, len(subscriptions))
+	for i, subscription := range subscriptions {
+		deliveries[i] = &webhook.Delivery{
+			SubscriptionID: subscription.ID,
+			UserID:         event.UserID,
+			EventID:        event.ID,
+			EventType:      string(event.Type),
+			Payload:        payload,
+			Status:         webhook.DeliveryPending,
+			NextAttemptAt:  &now,
+		}
+	}
+
+	return d.Repository.CreateDeliveries(ctx, deliveries)
+}
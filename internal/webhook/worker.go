@@ -0,0 +1,273 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/weeb-vip/list-service/internal/db/repositories/webhook"
+	"github.com/weeb-vip/list-service/internal/logger"
+	"github.com/weeb-vip/list-service/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	DefaultBatchSize    = 50
+	DefaultPollInterval = time.Second
+	DefaultTimeout      = 10 * time.Second
+	DefaultMaxAttempts  = 8
+	DefaultRetryBackoff = 30 * time.Second
+	DefaultMaxBackoff   = 6 * time.Hour
+	DefaultDisableAfter = 50
+)
+
+type WorkerImpl interface {
+	// Run delivers due webhooks until ctx is cancelled
+	Run(ctx context.Context) error
+	// DeliverOnce attempts one batch of due deliveries and returns how many
+	// were attempted
+	DeliverOnce(ctx context.Context) (int, error)
+}
+
+type Worker struct {
+	Repository   webhook.WebhookRepositoryImpl
+	Client       *http.Client
+	BatchSize    int
+	PollInterval time.Duration
+	// MaxAttempts is how often a delivery is tried before it is failed
+	MaxAttempts int
+	// RetryBackoff is the wait after the first failed attempt, it doubles with
+	// every further failure up to MaxBackoff
+	RetryBackoff time.Duration
+	MaxBackoff   time.Duration
+	// DisableAfter consecutive failed attempts disable the subscription
+	DisableAfter int
+	// Now is the clock deliveries are scheduled by
+	Now func() time.Time
+}
+
+type Option func(*Worker)
+
+func WithHTTPClient(client *http.Client) Option {
+	return func(w *Worker) {
+		w.Client = client
+	}
+}
+
+func WithBatchSize(batchSize int) Option {
+	return func(w *Worker) {
+		w.BatchSize = batchSize
+	}
+}
+
+func WithPollInterval(pollInterval time.Duration) Option {
+	return func(w *Worker) {
+		w.PollInterval = pollInterval
+	}
+}
+
+func WithMaxAttempts(maxAttempts int) Option {
+	return func(w *Worker) {
+		w.MaxAttempts = maxAttempts
+	}
+}
+
+func WithRetryBackoff(retryBackoff time.Duration, maxBackoff time.Duration) Option {
+	return func(w *Worker) {
+		w.RetryBackoff = retryBackoff
+		w.MaxBackoff = maxBackoff
+	}
+}
+
+func WithDisableAfter(disableAfter int) Option {
+	return func(w *Worker) {
+		w.DisableAfter = disableAfter
+	}
+}
+
+func WithClock(now func() time.Time) Option {
+	return func(w *Worker) {
+		w.Now = now
+	}
+}
+
+func NewWorker(repository webhook.WebhookRepositoryImpl, opts ...Option) WorkerImpl {
+	worker := &Worker{
+		Repository:   repository,
+		Client:       NewClient(DefaultTimeout),
+		BatchSize:    DefaultBatchSize,
+		PollInterval: DefaultPollInterval,
+		MaxAttempts:  DefaultMaxAttempts,
+		RetryBackoff: DefaultRetryBackoff,
+		MaxBackoff:   DefaultMaxBackoff,
+		DisableAfter: DefaultDisableAfter,
+		Now:          time.Now,
+	}
+	for _, opt := range opts {
+		opt(worker)
+	}
+
+	return worker
+}
+
+// backoff is the wait after the given number of failed attempts
+func (w *Worker) backoff(attempts int) time.Duration {
+	backoff := w.RetryBackoff
+	for i := 1; i < attempts && backoff < w.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > w.MaxBackoff {
+		backoff = w.MaxBackoff
+	}
+	return backoff
+}
+
+func (w *Worker) DeliverOnce(ctx context.Context) (int, error) {
+	// in flight deliveries are leased for longer than a request can take, so
+	// a crashed worker's claims become due again
+	lease := 2 * w.Client.Timeout
+	if lease == 0 {
+		lease = 2 * DefaultTimeout
+	}
+
+	deliveries, err := w.Repository.ClaimDue(ctx, w.Now(), w.BatchSize, lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, delivery := range deliveries {
+		if err := w.deliver(ctx, delivery); err != nil {
+			return i, err
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+func (w *Worker) deliver(ctx context.Context, delivery *webhook.Delivery) error {
+	log := logger.FromCtx(ctx)
+
+	subscription := delivery.Subscription
+	if subscription == nil || !subscription.Enabled {
+		// the endpoint was removed or switched off after the event
+		return w.Repository.UpdateDelivery(ctx, delivery, map[string]interface{}{
+			"status":          webhook.DeliveryFailed,
+			"next_attempt_at": nil,
+			"last_error":      "subscription disabled",
+		})
+	}
+
+	tracer := tracing.GetTracer(ctx)
+	ctx, span := tracer.Start(ctx, "DeliverWebhook",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("webhook.subscription_id", subscription.ID),
+			attribute.String("webhook.delivery_id", delivery.ID),
+			attribute.String("webhook.event_type", delivery.EventType),
+			attribute.Int("webhook.attempt", delivery.Attempts+1),
+		),
+	)
+	defer span.End()
+
+	statusCode, attemptErr := w.post(ctx, subscription, delivery)
+	attempts := delivery.Attempts + 1
+	now := w.Now()
+
+	columns := map[string]interface{}{
+		"attempts": attempts,
+	}
+	if statusCode != 0 {
+		columns["last_status_code"] = statusCode
+		span.SetAttributes(attribute.Int("http.response.status_code", statusCode))
+	}
+
+	if attemptErr == nil {
+		columns["status"] = webhook.DeliverySucceeded
+		columns["delivered_at"] = now
+		columns["next_attempt_at"] = nil
+		columns["last_error"] = nil
+		span.SetStatus(codes.Ok, "")
+	} else {
+		columns["last_error"] = attemptErr.Error()
+		if attempts >= w.MaxAttempts {
+			columns["status"] = webhook.DeliveryFailed
+			columns["next_attempt_at"] = nil
+		} else {
+			columns["next_attempt_at"] = now.Add(w.backoff(attempts))
+		}
+		span.RecordError(attemptErr)
+		span.SetStatus(codes.Error, attemptErr.Error())
+	}
+
+	disabled, err := w.Repository.RecordAttempt(ctx, delivery, columns, attemptErr == nil, w.DisableAfter)
+	if err != nil {
+		return err
+	}
+	if attemptErr != nil {
+		log.Warn().Err(attemptErr).Str("subscription_id", subscription.ID).Str("delivery_id", delivery.ID).Int("attempts", attempts).Msg("Webhook delivery failed")
+	}
+	if disabled {
+		log.Warn().Str("subscription_id", subscription.ID).Int("failures", w.DisableAfter).Msg("Disabled failing webhook subscription")
+	}
+
+	return nil
+}
+
+// post sends the delivery, any response outside 2xx is an error. The response
+// body is not kept, the endpoint could be anything the service can reach.
+func (w *Worker) post(ctx context.Context, subscription *webhook.Subscription, delivery *webhook.Delivery) (int, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "list-service-webhooks")
+	request.Header.Set(EventHeader, delivery.EventType)
+	request.Header.Set(DeliveryHeader, delivery.ID)
+	request.Header.Set(SignatureHeader, Sign(subscription.Secret, w.Now(), delivery.Payload))
+	propagation.TraceContext{}.Inject(ctx, propagation.HeaderCarrier(request.Header))
+
+	response, err := w.Client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("endpoint responded %d", response.StatusCode)
+	}
+	_, _ = io.Copy(io.Discard, response.Body)
+
+	return response.StatusCode, nil
+}
+
+func (w *Worker) Run(ctx context.Context) error {
+	log := logger.FromCtx(ctx)
+	log.Info().Int("batch_size", w.BatchSize).Dur("poll_interval", w.PollInterval).Msg("Starting webhook worker")
+
+	for {
+		attempted, err := w.DeliverOnce(ctx)
+
+		wait := w.PollInterval
+		switch {
+		case err != nil:
+			log.Error().Err(err).Msg("Error delivering webhooks")
+		case attempted == w.BatchSize:
+			// more deliveries are likely due
+			wait = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("Stopping webhook worker")
+			return nil
+		case <-time.After(wait):
+		}
+	}
+}
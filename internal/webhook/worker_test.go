@@ -0,0 +1,350 @@
+package webhook_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	webhook2 "github.com/weeb-vip/list-service/internal/db/repositories/webhook"
+	"github.com/weeb-vip/list-service/internal/events"
+	"github.com/weeb-vip/list-service/internal/webhook"
+)
+
+// memoryRepository keeps subscriptions and deliveries in memory, applying
+// columns the way the database repository does
+type memoryRepository struct {
+	mutex         sync.Mutex
+	subscriptions []*webhook2.Subscription
+	deliveries    []*webhook2.Delivery
+}
+
+func (m *memoryRepository) CreateSubscription(ctx context.Context, subscription *webhook2.Subscription) (*webhook2.Subscription, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	subscription.Enabled = true
+	m.subscriptions = append(m.subscriptions, subscription)
+	return subscription, nil
+}
+
+func (m *memoryRepository) FindSubscriptionsByUserId(ctx context.Context, userId string) ([]*webhook2.Subscription, error) {
+	return nil, nil
+}
+
+func (m *memoryRepository) FindSubscription(ctx context.Context, userId string, id string) (*webhook2.Subscription, error) {
+	return nil, webhook2.ErrSubscriptionNotFound
+}
+
+func (m *memoryRepository) DeleteSubscription(ctx context.Context, userId string, id string) error {
+	return nil
+}
+
+func (m *memoryRepository) EnableSubscription(ctx context.Context, userId string, id string) (*webhook2.Subscription, error) {
+	return nil, nil
+}
+
+func (m *memoryRepository) FindEnabledSubscriptions(ctx context.Context, userId string, eventType string) ([]*webhook2.Subscription, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var subscriptions []*webhook2.Subscription
+	for _, subscription := range m.subscriptions {
+		if subscription.UserID == userId && subscription.Enabled && subscription.EventTypes == eventType {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	return subscriptions, nil
+}
+
+func (m *memoryRepository) CreateDeliveries(ctx context.Context, deliveries []*webhook2.Delivery) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, delivery := range deliveries {
+		delivery.ID = delivery.SubscriptionID + "/" + delivery.EventID
+		m.deliveries = append(m.deliveries, delivery)
+	}
+	return nil
+}
+
+func (m *memoryRepository) ClaimDue(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]*webhook2.Delivery, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var deliveries []*webhook2.Delivery
+	for _, delivery := range m.deliveries {
+		if len(deliveries) == limit {
+			break
+		}
+		if delivery.Status != webhook2.DeliveryPending || delivery.NextAttemptAt.After(now) {
+			continue
+		}
+		for _, subscription := range m.subscriptions {
+			if subscription.ID == delivery.SubscriptionID {
+				copied := *subscription
+				delivery.Subscription = &copied
+			}
+		}
+		leased := now.Add(lease)
+		delivery.NextAttemptAt = &leased
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
+
+func (m *memoryRepository) RecordAttempt(ctx context.Context, delivery *webhook2.Delivery, columns map[string]interface{}, succeeded bool, disableAfter int) (bool, error) {
+	m.apply(delivery, columns)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, subscription := range m.subscriptions {
+		if subscription.ID != delivery.SubscriptionID {
+			continue
+		}
+		if succeeded {
+			subscription.ConsecutiveFailures = 0
+			return false, nil
+		}
+		subscription.ConsecutiveFailures++
+		if disableAfter > 0 && subscription.Enabled && subscription.ConsecutiveFailures >= disableAfter {
+			subscription.Enabled = false
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memoryRepository) UpdateDelivery(ctx context.Context, delivery *webhook2.Delivery, columns map[string]interface{}) error {
+	m.apply(delivery, columns)
+	return nil
+}
+
+func (m *memoryRepository) apply(delivery *webhook2.Delivery, columns map[string]interface{}) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for column, value := range columns {
+		switch column {
+		case "status":
+			delivery.Status = value.(webhook2.DeliveryStatus)
+		case "attempts":
+			delivery.Attempts = value.(int)
+		case "next_attempt_at":
+			if value == nil {
+				delivery.NextAttemptAt = nil
+			} else {
+				next := value.(time.Time)
+				delivery.NextAttemptAt = &next
+			}
+		case "last_status_code":
+			code := value.(int)
+			delivery.LastStatusCode = &code
+		case "last_error":
+			if value == nil {
+				delivery.LastError = nil
+			} else {
+				lastError := value.(string)
+				delivery.LastError = &lastError
+			}
+		case "delivered_at":
+			deliveredAt := value.(time.Time)
+			delivery.DeliveredAt = &deliveredAt
+		}
+	}
+}
+
+func (m *memoryRepository) FindDeliveries(ctx context.Context, userId string, subscriptionId string, limit int) ([]*webhook2.Delivery, error) {
+	return nil, nil
+}
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+// setup serves handler on loopback, the worker reaches it through the test
+// server's client since the default one refuses private addresses
+func setup(t *testing.T, handler http.HandlerFunc) (*memoryRepository, *webhook2.Subscription, *clock, *http.Client) {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	repository := &memoryRepository{}
+	subscription, err := repository.CreateSubscription(context.Background(), &webhook2.Subscription{
+		ID:         "subscription_1",
+		UserID:     "user_1",
+		URL:        server.URL,
+		EventTypes: string(events.UserAnimeAdded),
+		Secret:     "secret",
+	})
+	assert.NoError(t, err)
+
+	event, err := events.New(events.UserAnimeAdded, "anime_1", "user_1", map[string]string{"id": "anime_1"})
+	assert.NoError(t, err)
+	assert.NoError(t, webhook.NewDispatcher(repository).Dispatch(context.Background(), event))
+
+	// deliveries are queued at the wall clock time, start the worker's clock
+	// past anything dispatched during the test
+	return repository, subscription, &clock{now: time.Now().Add(time.Minute)}, server.Client()
+}
+
+func TestWorkerDelivers(t *testing.T) {
+	var received []*http.Request
+	var bodies [][]byte
+	repository, _, clock, client := setup(t, func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, r)
+		bodies = append(bodies, body)
+	})
+
+	attempted, err := webhook.NewWorker(repository, webhook.WithHTTPClient(client), webhook.WithClock(clock.Now)).DeliverOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, attempted)
+
+	assert.Len(t, received, 1)
+	assert.Equal(t, string(events.UserAnimeAdded), received[0].Header.Get(webhook.EventHeader))
+	assert.NoError(t, webhook.Verify("secret", received[0].Header.Get(webhook.SignatureHeader), bodies[0], time.Minute, clock.now))
+	assert.JSONEq(t, string(repository.deliveries[0].Payload), string(bodies[0]))
+
+	delivery := repository.deliveries[0]
+	assert.Equal(t, webhook2.DeliverySucceeded, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, 200, *delivery.LastStatusCode)
+	assert.Nil(t, delivery.NextAttemptAt)
+}
+
+func TestWorkerRetriesWithBackoff(t *testing.T) {
+	failing := true
+	repository, _, clock, client := setup(t, func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}
+	})
+	worker := webhook.NewWorker(repository, webhook.WithHTTPClient(client), webhook.WithClock(clock.Now), webhook.WithRetryBackoff(time.Minute, time.Hour))
+	delivery := repository.deliveries[0]
+
+	_, err := worker.DeliverOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, webhook2.DeliveryPending, delivery.Status)
+	assert.Equal(t, 503, *delivery.LastStatusCode)
+	assert.Equal(t, "endpoint responded 503", *delivery.LastError)
+	assert.Equal(t, clock.now.Add(time.Minute), *delivery.NextAttemptAt)
+
+	// not due yet
+	attempted, err := worker.DeliverOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, attempted)
+
+	clock.now = clock.now.Add(time.Minute)
+	_, err = worker.DeliverOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, clock.now.Add(2*time.Minute), *delivery.NextAttemptAt)
+
+	failing = false
+	clock.now = clock.now.Add(2 * time.Minute)
+	_, err = worker.DeliverOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, webhook2.DeliverySucceeded, delivery.Status)
+	assert.Equal(t, 3, delivery.Attempts)
+	assert.Nil(t, delivery.LastError)
+}
+
+func TestWorkerGivesUpAndDisables(t *testing.T) {
+	repository, subscription, clock, client := setup(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	worker := webhook.NewWorker(repository, webhook.WithHTTPClient(client), webhook.WithClock(clock.Now), webhook.WithMaxAttempts(2), webhook.WithDisableAfter(3), webhook.WithRetryBackoff(time.Second, time.Second))
+
+	// a second event for the same endpoint
+	event, err := events.New(events.UserAnimeAdded, "anime_2", "user_1", map[string]string{"id": "anime_2"})
+	assert.NoError(t, err)
+	assert.NoError(t, webhook.NewDispatcher(repository).Dispatch(context.Background(), event))
+
+	for i := 0; i < 2; i++ {
+		_, err := worker.DeliverOnce(context.Background())
+		assert.NoError(t, err)
+		clock.now = clock.now.Add(time.Second)
+	}
+
+	// both deliveries ran out of attempts and the third failed attempt
+	// disabled the subscription
+	first, second := repository.deliveries[0], repository.deliveries[1]
+	assert.Equal(t, webhook2.DeliveryFailed, first.Status)
+	assert.Equal(t, 2, first.Attempts)
+	assert.Equal(t, webhook2.DeliveryFailed, second.Status)
+	assert.False(t, subscription.Enabled)
+	assert.Equal(t, 4, subscription.ConsecutiveFailures)
+
+	// disabled subscriptions get no new deliveries
+	event, err = events.New(events.UserAnimeAdded, "anime_3", "user_1", map[string]string{"id": "anime_3"})
+	assert.NoError(t, err)
+	assert.NoError(t, webhook.NewDispatcher(repository).Dispatch(context.Background(), event))
+	assert.Len(t, repository.deliveries, 2)
+}
+
+func TestWorkerRefusesPrivateEndpoints(t *testing.T) {
+	called := false
+	repository, _, clock, _ := setup(t, func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+
+	_, err := webhook.NewWorker(repository, webhook.WithClock(clock.Now)).DeliverOnce(context.Background())
+	assert.NoError(t, err)
+
+	delivery := repository.deliveries[0]
+	assert.False(t, called)
+	assert.Equal(t, webhook2.DeliveryPending, delivery.Status)
+	assert.Contains(t, *delivery.LastError, webhook.ErrBlockedAddress.Error())
+}
+
+func TestClientDoesNotFollowRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://127.0.0.1:1/", http.StatusFound)
+	}))
+	t.Cleanup(server.Close)
+
+	client := webhook.NewClient(time.Second)
+	// the test server listens on loopback, only the redirect handling is
+	// exercised here
+	client.Transport = server.Client().Transport
+
+	response, err := client.Get(server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusFound, response.StatusCode)
+	assert.NoError(t, response.Body.Close())
+}
+
+func TestBlockedAddress(t *testing.T) {
+	blocked := []string{
+		"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254",
+		"0.0.0.0", "0.1.2.3", "100.64.0.1", "100.100.100.200", "198.18.0.1", "198.19.255.255",
+		"::1", "fe80::1", "fd00::1", "::ffff:127.0.0.1",
+		"64:ff9b::a00:1", "64:ff9b:1::1", "2002:a00:1::1",
+	}
+	for _, address := range blocked {
+		assert.True(t, webhook.BlockedAddress(netip.MustParseAddr(address)), address)
+	}
+	for _, address := range []string{"93.184.216.34", "100.128.0.1", "198.20.0.1", "2606:2800:220:1::"} {
+		assert.False(t, webhook.BlockedAddress(netip.MustParseAddr(address)), address)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"id":"a"}`)
+	header := webhook.Sign("secret", now, body)
+
+	assert.NoError(t, webhook.Verify("secret", header, body, time.Minute, now))
+	assert.ErrorIs(t, webhook.Verify("other", header, body, time.Minute, now), webhook.ErrInvalidSignature)
+	assert.ErrorIs(t, webhook.Verify("secret", header, []byte(`{"id":"b"}`), time.Minute, now), webhook.ErrInvalidSignature)
+	assert.ErrorIs(t, webhook.Verify("secret", header, body, time.Minute, now.Add(time.Hour)), webhook.ErrInvalidSignature)
+}
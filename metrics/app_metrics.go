@@ -1,7 +1,8 @@
 package metrics
 
 import (
-	"github.com/weeb-vip/list-service/config"
+	"database/sql"
+
 	metricsLib "github.com/weeb-vip/go-metrics-lib"
 )
 
@@ -17,7 +18,7 @@ var appMetrics *AppMetrics
 // GetAppMetrics returns the singleton metrics instance
 func GetAppMetrics() *AppMetrics {
 	if appMetrics == nil {
-		cfg := config.LoadConfigOrPanic()
+		cfg := current
 
 		// Initialize with Prometheus metrics
 		impl := NewMetricsInstance()
@@ -58,6 +59,87 @@ func (m *AppMetrics) DatabaseMetric(duration float64, table string, method strin
 	m.metricsImpl.DatabaseMetric(duration, labels)
 }
 
+// DatabaseRowsMetric records how many rows a database operation returned or
+// changed
+func (m *AppMetrics) DatabaseRowsMetric(table string, method string, rows int64) {
+	_ = m.metricsImpl.HistogramMetric("database_rows_affected", float64(rows), map[string]string{
+		"service": m.defaultTags["service"],
+		"table":   table,
+		"method":  method,
+		"env":     m.defaultTags["env"],
+	})
+}
+
+// DatabasePoolMetric records the connection pool stats of one database,
+// counters in sql.DBStats are published as their running totals
+func (m *AppMetrics) DatabasePoolMetric(pool string, stats sql.DBStats) {
+	labels := func(extra string, value string) map[string]string {
+		tags := map[string]string{
+			"service": m.defaultTags["service"],
+			"pool":    pool,
+			"env":     m.defaultTags["env"],
+		}
+		if extra != "" {
+			tags[extra] = value
+		}
+		return tags
+	}
+
+	for state, conns := range map[string]int{
+		"open":     stats.OpenConnections,
+		"in_use":   stats.InUse,
+		"idle":     stats.Idle,
+		"max_open": stats.MaxOpenConnections,
+	} {
+		_ = m.metricsImpl.GaugeMetric("database_connections", float64(conns), labels("state", state))
+	}
+
+	for reason, closed := range map[string]int64{
+		"max_idle":      stats.MaxIdleClosed,
+		"max_idle_time": stats.MaxIdleTimeClosed,
+		"max_lifetime":  stats.MaxLifetimeClosed,
+	} {
+		_ = m.metricsImpl.GaugeMetric("database_connections_closed", float64(closed), labels("reason", reason))
+	}
+
+	_ = m.metricsImpl.GaugeMetric("database_connection_waits", float64(stats.WaitCount), labels("", ""))
+	_ = m.metricsImpl.GaugeMetric("database_connection_wait_milliseconds", float64(stats.WaitDuration.Milliseconds()), labels("", ""))
+}
+
+// CacheMetric counts cache lookups by result
+func (m *AppMetrics) CacheMetric(cache string, operation string, result string) {
+	_ = m.metricsImpl.CountMetric("cache_requests_total", map[string]string{
+		"service":   m.defaultTags["service"],
+		"cache":     cache,
+		"operation": operation,
+		"result":    result,
+		"env":       m.defaultTags["env"],
+	})
+}
+
+// BackfillBatchMetric counts backfill batches by result
+func (m *AppMetrics) BackfillBatchMetric(job string, result string) {
+	_ = m.metricsImpl.CountMetric("backfill_batches_total", map[string]string{
+		"service": m.defaultTags["service"],
+		"job":     job,
+		"result":  result,
+		"env":     m.defaultTags["env"],
+	})
+}
+
+// BackfillProgressMetric records how many rows a backfill has scanned and
+// changed so far
+func (m *AppMetrics) BackfillProgressMetric(job string, scanned int64, changed int64) {
+	for kind, rows := range map[string]int64{"scanned": scanned, "changed": changed} {
+		_ = m.metricsImpl.GaugeMetric("backfill_rows", float64(rows), map[string]string{
+			"service": m.defaultTags["service"],
+			"job":     job,
+			"kind":    kind,
+			"env":     m.defaultTags["env"],
+		})
+	}
+}
+
 // RepositoryMetric records repository operation metrics
 func (m *AppMetrics) RepositoryMetric(duration float64, repository string, method string, result string) {
 	// Use database metric with repository name as table for now
@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// Business metrics of list activity. Every label holds one of a fixed set of
+// values, user and anime ids are never used as labels.
+//
+//	list_entries_added_total{status}             counter, entries put on a list, by status
+//	list_status_transitions_total{from,to}       counter, entries moved from one status to another
+//	lists_created_total                          counter, custom lists created
+//	list_imports_total{source,result}            counter, bulk imports run, such as offline sync pushes
+//	list_active_users_total                      counter, users seen for the first time in the UTC day
+//	list_active_users_today                      gauge, users seen so far in the UTC day
+//	list_tracked_entries{status}                 gauge, entries stored, by status
+//
+// Status labels are one of the statuses of the service, none or other. The
+// active user metrics are kept per instance, sum them across instances only
+// when users stick to one instance. Tracked entries are counted from the
+// database, so every instance reports the same value.
+const (
+	metricEntriesAdded      = "list_entries_added_total"
+	metricStatusTransitions = "list_status_transitions_total"
+	metricListsCreated      = "lists_created_total"
+	metricImports           = "list_imports_total"
+	metricActiveUsers       = "list_active_users_total"
+	metricActiveUsersToday  = "list_active_users_today"
+	metricTrackedEntries    = "list_tracked_entries"
+)
+
+// activeUsers remembers the users seen in the current UTC day, by hash
+var activeUsers = &dailyUsers{seen: map[[sha256.Size]byte]struct{}{}}
+
+type dailyUsers struct {
+	mu   sync.Mutex
+	day  string
+	seen map[[sha256.Size]byte]struct{}
+}
+
+// add records userID as seen now, returning whether it is the first time
+// today and how many users have been seen today
+func (d *dailyUsers) add(userID string, now time.Time) (bool, int) {
+	key := sha256.Sum256([]byte(userID))
+	day := now.UTC().Format(time.DateOnly)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if day != d.day {
+		d.day = day
+		d.seen = map[[sha256.Size]byte]struct{}{}
+	}
+	if _, ok := d.seen[key]; ok {
+		return false, len(d.seen)
+	}
+	d.seen[key] = struct{}{}
+
+	return true, len(d.seen)
+}
+
+func (m *AppMetrics) businessTags(extra map[string]string) map[string]string {
+	tags := map[string]string{
+		"service": m.defaultTags["service"],
+		"env":     m.defaultTags["env"],
+	}
+	for key, value := range extra {
+		tags[key] = value
+	}
+	return tags
+}
+
+// EntryAddedMetric counts an entry put on a user's list with status
+func (m *AppMetrics) EntryAddedMetric(status string) {
+	_ = m.metricsImpl.CountMetric(metricEntriesAdded, m.businessTags(map[string]string{"status": status}))
+}
+
+// StatusTransitionMetric counts an entry moving from one status to another
+func (m *AppMetrics) StatusTransitionMetric(from string, to string) {
+	_ = m.metricsImpl.CountMetric(metricStatusTransitions, m.businessTags(map[string]string{"from": from, "to": to}))
+}
+
+// ListCreatedMetric counts a custom list being created
+func (m *AppMetrics) ListCreatedMetric() {
+	_ = m.metricsImpl.CountMetric(metricListsCreated, m.businessTags(nil))
+}
+
+// ImportMetric counts a bulk import from source by result
+func (m *AppMetrics) ImportMetric(source string, result string) {
+	_ = m.metricsImpl.CountMetric(metricImports, m.businessTags(map[string]string{"source": source, "result": result}))
+}
+
+// ActiveUserMetric records activity by userID, counting each user once per
+// UTC day
+func (m *AppMetrics) ActiveUserMetric(userID string) {
+	first, today := activeUsers.add(userID, time.Now())
+	if !first {
+		return
+	}
+
+	_ = m.metricsImpl.CountMetric(metricActiveUsers, m.businessTags(nil))
+	_ = m.metricsImpl.GaugeMetric(metricActiveUsersToday, float64(today), m.businessTags(nil))
+}
+
+// TrackedEntriesMetric records how many entries are stored with status
+func (m *AppMetrics) TrackedEntriesMetric(status string, entries int64) {
+	_ = m.metricsImpl.GaugeMetric(metricTrackedEntries, float64(entries), m.businessTags(map[string]string{"status": status}))
+}
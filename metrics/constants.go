@@ -5,4 +5,10 @@ const (
 	Success = "success"
 	Error   = "error"
 	Failure = "failure"
+)
+
+// Cache result constants
+const (
+	CacheHit  = "hit"
+	CacheMiss = "miss"
 )
\ No newline at end of file
@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"errors"
+
+	metricsLib "github.com/weeb-vip/go-metrics-lib"
+)
+
+// fanout sends every metric to each of its clients
+type fanout []metricsLib.Client
+
+func (f fanout) Histogram(metric string, value float64, labels map[string]string, rate float64) error {
+	var errs []error
+	for _, client := range f {
+		errs = append(errs, client.Histogram(metric, value, labels, rate))
+	}
+	return errors.Join(errs...)
+}
+
+func (f fanout) Count(metric string, labels map[string]string, rate float64) error {
+	var errs []error
+	for _, client := range f {
+		errs = append(errs, client.Count(metric, labels, rate))
+	}
+	return errors.Join(errs...)
+}
+
+func (f fanout) Gauge(metric string, value float64, labels map[string]string, rate float64) error {
+	var errs []error
+	for _, client := range f {
+		errs = append(errs, client.Gauge(metric, value, labels, rate))
+	}
+	return errors.Join(errs...)
+}
+
+func (f fanout) Summary(metric string, value float64, labels map[string]string, rate float64) error {
+	var errs []error
+	for _, client := range f {
+		errs = append(errs, client.Summary(metric, value, labels, rate))
+	}
+	return errors.Join(errs...)
+}
@@ -1,20 +1,64 @@
 package metrics
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
 	metricsLib "github.com/weeb-vip/go-metrics-lib"
 	"github.com/weeb-vip/go-metrics-lib/clients/prometheus"
 	"github.com/weeb-vip/list-service/config"
 )
 
+const (
+	BackendPrometheus = "prometheus"
+	BackendDatadog    = "datadog"
+	BackendOTLP       = "otlp"
+)
+
 var metricsInstance metricsLib.MetricsImpl
 
+// current is the configuration metrics are labelled and exported with
+var current config.Config
+
 var prometheusInstance *prometheus.PrometheusClient
 
+// pushers are the backends that buffer metrics and are flushed on Shutdown
+var pushers []pusher
+
+// pusher is a backend that sends metrics from the service instead of having
+// them scraped
+type pusher interface {
+	Shutdown(ctx context.Context) error
+}
+
+// defaultDurationBuckets are used until Configure is called, in tests
+var defaultDurationBuckets = []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000}
+
+// histogram is a histogram every backend is set up with
+type histogram struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+}
+
+// histograms returns the histograms of the service, durations are the
+// buckets of the duration histograms in milliseconds
+func histograms(durations []float64) []histogram {
+	return []histogram{
+		{"resolver_request_duration_histogram_milliseconds", "graphql resolver millisecond", []string{"service", "protocol", "resolver", "result", "env"}, durations},
+		{"database_query_duration_histogram_milliseconds", "database calls millisecond", []string{"service", "table", "method", "result", "env"}, durations},
+		{"database_rows_affected", "rows returned or changed by database calls", []string{"service", "table", "method", "env"}, []float64{0, 1, 5, 10, 50, 100, 500, 1000, 5000}},
+	}
+}
+
+// NewMetricsInstance returns the metrics of the configured backends, or of
+// Prometheus alone when Configure has not been called
 func NewMetricsInstance() metricsLib.MetricsImpl {
 	if metricsInstance == nil {
-		prometheusInstance = NewPrometheusInstance()
-		initMetrics(prometheusInstance)
-		metricsInstance = metricsLib.NewMetrics(prometheusInstance, 1)
+		metricsInstance = metricsLib.NewMetrics(NewPrometheusInstance(), 1)
 	}
 	return metricsInstance
 }
@@ -22,47 +66,113 @@ func NewMetricsInstance() metricsLib.MetricsImpl {
 func NewPrometheusInstance() *prometheus.PrometheusClient {
 	if prometheusInstance == nil {
 		prometheusInstance = prometheus.NewPrometheusClient()
-		initMetrics(prometheusInstance)
+		initMetrics(prometheusInstance, durationBuckets())
 	}
 	return prometheusInstance
 }
 
-func initMetrics(prometheusInstance *prometheus.PrometheusClient) {
-	prometheusInstance.CreateHistogramVec("resolver_request_duration_histogram_milliseconds", "graphql resolver millisecond", []string{"service", "protocol", "resolver", "result", "env"}, []float64{
-		// create buckets 10000 split into 10 buckets
-		100,
-		200,
-		300,
-		400,
-		500,
-		600,
-		700,
-		800,
-		900,
-		1000,
-	})
-
-	prometheusInstance.CreateHistogramVec("database_query_duration_histogram_milliseconds", "database calls millisecond", []string{"service", "table", "method", "result", "env"}, []float64{
-		// create buckets 10000 split into 10 buckets
-		100,
-		200,
-		300,
-		400,
-		500,
-		600,
-		700,
-		800,
-		900,
-		1000,
-	})
+// Handler serves the Prometheus metrics, it is nil when Prometheus is not one
+// of the configured backends
+func Handler() http.Handler {
+	if prometheusInstance == nil && metricsInstance != nil {
+		return nil
+	}
+
+	return NewPrometheusInstance().Handler()
+}
+
+func initMetrics(prometheusInstance *prometheus.PrometheusClient, durations []float64) {
+	for _, h := range histograms(durations) {
+		prometheusInstance.CreateHistogramVec(h.name, h.help, h.labels, h.buckets)
+	}
+
+	prometheusInstance.CreateGaugeVec("database_connections", "database connections by state", []string{"service", "pool", "state", "env"})
+	prometheusInstance.CreateGaugeVec("database_connections_closed", "database connections closed by the pool, by reason", []string{"service", "pool", "reason", "env"})
+	prometheusInstance.CreateGaugeVec("database_connection_waits", "times a connection was waited for", []string{"service", "pool", "env"})
+	prometheusInstance.CreateGaugeVec("database_connection_wait_milliseconds", "time spent waiting for connections", []string{"service", "pool", "env"})
+
+	prometheusInstance.CreateCounterVec("cache_requests_total", "cache lookups", []string{"service", "cache", "operation", "result", "env"})
+	prometheusInstance.CreateCounterVec("backfill_batches_total", "backfill batches", []string{"service", "job", "result", "env"})
+	prometheusInstance.CreateGaugeVec("backfill_rows", "rows scanned and changed by a backfill", []string{"service", "job", "kind", "env"})
+
+	prometheusInstance.CreateCounterVec(metricEntriesAdded, "entries put on a list, by status", []string{"service", "status", "env"})
+	prometheusInstance.CreateCounterVec(metricStatusTransitions, "entries moved from one status to another", []string{"service", "from", "to", "env"})
+	prometheusInstance.CreateCounterVec(metricListsCreated, "custom lists created", []string{"service", "env"})
+	prometheusInstance.CreateCounterVec(metricImports, "bulk imports run, by source and result", []string{"service", "source", "result", "env"})
+	prometheusInstance.CreateCounterVec(metricActiveUsers, "users seen for the first time in the UTC day", []string{"service", "env"})
+	prometheusInstance.CreateGaugeVec(metricActiveUsersToday, "users seen so far in the UTC day", []string{"service", "env"})
+	prometheusInstance.CreateGaugeVec(metricTrackedEntries, "entries stored, by status", []string{"service", "status", "env"})
+}
+
+// durationBuckets returns the configured duration buckets, config validation
+// has already rejected malformed ones
+func durationBuckets() []float64 {
+	buckets, err := current.MetricsConfig.DurationBuckets()
+	if err != nil || len(buckets) == 0 {
+		return defaultDurationBuckets
+	}
+
+	return buckets
+}
+
+// Configure sets the configuration metrics are labelled with and sets up the
+// configured backends. It is called once at startup, before anything is
+// recorded. With several backends every metric is sent to each of them.
+func Configure(cfg config.Config) error {
+	current = cfg
+
+	var clients []metricsLib.Client
+	for _, backend := range cfg.MetricsConfig.BackendList() {
+		switch backend {
+		case BackendPrometheus:
+			clients = append(clients, NewPrometheusInstance())
+		case BackendDatadog:
+			client, err := NewDogStatsDClient(cfg.DataDogConfig)
+			if err != nil {
+				return fmt.Errorf("failed to create datadog metrics client: %w", err)
+			}
+			clients = append(clients, client)
+			pushers = append(pushers, client)
+		case BackendOTLP:
+			client, err := NewOTLPClient(cfg, durationBuckets())
+			if err != nil {
+				return fmt.Errorf("failed to create otlp metrics client: %w", err)
+			}
+			clients = append(clients, client)
+			pushers = append(pushers, client)
+		default:
+			return fmt.Errorf("unknown metrics backend %q", backend)
+		}
+	}
+
+	switch len(clients) {
+	case 0:
+		metricsInstance = nil
+	case 1:
+		metricsInstance = metricsLib.NewMetrics(clients[0], 1)
+	default:
+		metricsInstance = metricsLib.NewMetrics(fanout(clients), 1)
+	}
+	appMetrics = nil
+
+	return nil
+}
+
+// Shutdown flushes the backends that push metrics and closes them
+func Shutdown(ctx context.Context) error {
+	var errs []error
+	for _, p := range pushers {
+		errs = append(errs, p.Shutdown(ctx))
+	}
+	pushers = nil
+
+	return errors.Join(errs...)
 }
 
 func GetCurrentEnv() string {
-	cfg := config.LoadConfigOrPanic()
-	return cfg.AppConfig.Env
+	return current.AppConfig.Env
 }
 
 func GetServiceName() string {
-	cfg := config.LoadConfigOrPanic()
-	return cfg.AppConfig.APPName
+	return current.AppConfig.APPName
 }
@@ -1,79 +1,59 @@
 package metrics
 
 import (
-	metricsLib "github.com/weeb-vip/go-metrics-lib"
-	"github.com/weeb-vip/go-metrics-lib/clients/datadog"
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
 	"github.com/weeb-vip/list-service/config"
 )
 
-var datadogInstance *datadog.DataDogClient
+// DogStatsDClient sends metrics to a Datadog agent. Histograms and summaries
+// are sent as distributions, the agent computes their percentiles so the
+// configured buckets do not apply.
+type DogStatsDClient struct {
+	client *statsd.Client
+}
 
-func NewMetricsInstanceD() metricsLib.MetricsImpl {
-	cfg := config.LoadConfigOrPanic()
-	if metricsInstance == nil {
-		datadogInstance = datadog.NewDatadogClient(datadog.DataDogConfig{
-			DD_AGENT_HOST: cfg.DataDogConfig.DD_AGENT_HOST,
-			DD_AGENT_PORT: cfg.DataDogConfig.DD_AGENT_PORT,
-		})
-		initMetricsD(datadogInstance)
-		metricsInstance = metricsLib.NewMetrics(datadogInstance, 1)
+func NewDogStatsDClient(cfg config.DataDogConfig) (*DogStatsDClient, error) {
+	client, err := statsd.New(fmt.Sprintf("%s:%d", cfg.DD_AGENT_HOST, cfg.DD_AGENT_PORT))
+	if err != nil {
+		return nil, err
 	}
-	return metricsInstance
+
+	return &DogStatsDClient{client: client}, nil
 }
 
-func NewDatadogInstance() *datadog.DataDogClient {
-	cfg := config.LoadConfigOrPanic()
-	if datadogInstance == nil {
-		datadogInstance = datadog.NewDatadogClient(datadog.DataDogConfig{
-			DD_AGENT_HOST: cfg.DataDogConfig.DD_AGENT_HOST,
-			DD_AGENT_PORT: cfg.DataDogConfig.DD_AGENT_PORT,
-		})
-		initMetricsD(datadogInstance)
-	}
-	return datadogInstance
+func (d *DogStatsDClient) Histogram(metric string, value float64, labels map[string]string, rate float64) error {
+	return d.client.Distribution(metric, value, tags(labels), rate)
 }
 
-func initMetricsD(datadogInstance *datadog.DataDogClient) {
-	cfg := config.LoadConfigOrPanic()
-	datadogInstance.CreateHistogram("resolver_request_duration_histogram_milliseconds", []float64{
-		// create buckets 10000 split into 10 buckets
-		100,
-		200,
-		300,
-		400,
-		500,
-		600,
-		700,
-		800,
-		900,
-		1000,
-	},
-		map[string]string{
-			"service":  cfg.AppConfig.APPName,
-			"protocol": "",
-			"resolver": "",
-			"result":   metricsLib.Success,
-		},
-		1)
+func (d *DogStatsDClient) Count(metric string, labels map[string]string, rate float64) error {
+	return d.client.Count(metric, 1, tags(labels), rate)
+}
+
+func (d *DogStatsDClient) Gauge(metric string, value float64, labels map[string]string, rate float64) error {
+	return d.client.Gauge(metric, value, tags(labels), rate)
+}
+
+func (d *DogStatsDClient) Summary(metric string, value float64, labels map[string]string, rate float64) error {
+	return d.client.Distribution(metric, value, tags(labels), rate)
+}
+
+// Shutdown flushes buffered metrics to the agent and closes the client
+func (d *DogStatsDClient) Shutdown(ctx context.Context) error {
+	return d.client.Close()
+}
+
+// tags formats labels as DogStatsD key:value tags, sorted so each series
+// always has the same tag string
+func tags(labels map[string]string) []string {
+	tags := make([]string, 0, len(labels))
+	for key, value := range labels {
+		tags = append(tags, key+":"+value)
+	}
+	sort.Strings(tags)
 
-	datadogInstance.CreateHistogram("database_query_duration_histogram_milliseconds", []float64{
-		// create buckets 10000 split into 10 buckets
-		100,
-		200,
-		300,
-		400,
-		500,
-		600,
-		700,
-		800,
-		900,
-		1000,
-	},
-		map[string]string{
-			"service": cfg.AppConfig.APPName,
-			"table":   "",
-			"method":  "",
-			"result":  "",
-		},
-		1)
+	return tags
 }
@@ -0,0 +1,158 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/weeb-vip/list-service/config"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.20.0"
+)
+
+// OTLPClient pushes metrics to an OpenTelemetry collector over gRPC.
+// Instruments are created on first use, histograms with the buckets they
+// were set up with.
+type OTLPClient struct {
+	provider *sdkmetric.MeterProvider
+	meter    metric.Meter
+	buckets  map[string][]float64
+
+	mu         sync.Mutex
+	histograms map[string]metric.Float64Histogram
+	counters   map[string]metric.Int64Counter
+	gauges     map[string]metric.Float64Gauge
+}
+
+// NewOTLPClient connects to the configured endpoint, durations are the
+// buckets of the duration histograms
+func NewOTLPClient(cfg config.Config, durations []float64) (*OTLPClient, error) {
+	ctx := context.Background()
+
+	options := []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpoint(cfg.MetricsConfig.OTLPEndpoint)}
+	if cfg.MetricsConfig.OTLPInsecure {
+		options = append(options, otlpmetricgrpc.WithInsecure())
+	}
+
+	exporter, err := otlpmetricgrpc.New(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metrics exporter: %w", err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceNameKey.String(cfg.AppConfig.APPName),
+			semconv.ServiceVersionKey.String(cfg.AppConfig.Version),
+			semconv.DeploymentEnvironmentKey.String(cfg.AppConfig.Env),
+		),
+		resource.WithSchemaURL(semconv.SchemaURL),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource: %w", err)
+	}
+
+	provider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithResource(res),
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter,
+			sdkmetric.WithInterval(time.Duration(cfg.MetricsConfig.OTLPIntervalMs)*time.Millisecond),
+		)),
+	)
+
+	buckets := map[string][]float64{}
+	for _, h := range histograms(durations) {
+		buckets[h.name] = h.buckets
+	}
+
+	return &OTLPClient{
+		provider:   provider,
+		meter:      provider.Meter("github.com/weeb-vip/list-service"),
+		buckets:    buckets,
+		histograms: map[string]metric.Float64Histogram{},
+		counters:   map[string]metric.Int64Counter{},
+		gauges:     map[string]metric.Float64Gauge{},
+	}, nil
+}
+
+func (o *OTLPClient) Histogram(name string, value float64, labels map[string]string, rate float64) error {
+	o.mu.Lock()
+	histogram, ok := o.histograms[name]
+	if !ok {
+		var options []metric.Float64HistogramOption
+		if buckets, ok := o.buckets[name]; ok {
+			options = append(options, metric.WithExplicitBucketBoundaries(buckets...))
+		}
+
+		var err error
+		histogram, err = o.meter.Float64Histogram(name, options...)
+		if err != nil {
+			o.mu.Unlock()
+			return err
+		}
+		o.histograms[name] = histogram
+	}
+	o.mu.Unlock()
+
+	histogram.Record(context.Background(), value, metric.WithAttributes(attributes(labels)...))
+	return nil
+}
+
+func (o *OTLPClient) Count(name string, labels map[string]string, rate float64) error {
+	o.mu.Lock()
+	counter, ok := o.counters[name]
+	if !ok {
+		var err error
+		counter, err = o.meter.Int64Counter(name)
+		if err != nil {
+			o.mu.Unlock()
+			return err
+		}
+		o.counters[name] = counter
+	}
+	o.mu.Unlock()
+
+	counter.Add(context.Background(), 1, metric.WithAttributes(attributes(labels)...))
+	return nil
+}
+
+func (o *OTLPClient) Gauge(name string, value float64, labels map[string]string, rate float64) error {
+	o.mu.Lock()
+	gauge, ok := o.gauges[name]
+	if !ok {
+		var err error
+		gauge, err = o.meter.Float64Gauge(name)
+		if err != nil {
+			o.mu.Unlock()
+			return err
+		}
+		o.gauges[name] = gauge
+	}
+	o.mu.Unlock()
+
+	gauge.Record(context.Background(), value, metric.WithAttributes(attributes(labels)...))
+	return nil
+}
+
+// Summary records to a histogram, OTLP has no client side summaries
+func (o *OTLPClient) Summary(name string, value float64, labels map[string]string, rate float64) error {
+	return o.Histogram(name, value, labels, rate)
+}
+
+// Shutdown pushes the metrics recorded since the last export and stops the
+// exporter
+func (o *OTLPClient) Shutdown(ctx context.Context) error {
+	return o.provider.Shutdown(ctx)
+}
+
+func attributes(labels map[string]string) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(labels))
+	for key, value := range labels {
+		attrs = append(attrs, attribute.String(key, value))
+	}
+
+	return attrs
+}
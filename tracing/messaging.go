@@ -0,0 +1,71 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// messagePropagator writes W3C trace context, the same format the HTTP
+// middleware reads
+var messagePropagator = propagation.TraceContext{}
+
+// Messaging semantic convention attributes
+const (
+	MessagingSystemKey          = attribute.Key("messaging.system")
+	MessagingDestinationNameKey = attribute.Key("messaging.destination.name")
+	MessagingOperationTypeKey   = attribute.Key("messaging.operation.type")
+	MessagingOperationNameKey   = attribute.Key("messaging.operation.name")
+	MessagingMessageIDKey       = attribute.Key("messaging.message.id")
+	MessagingBodySizeKey        = attribute.Key("messaging.message.body.size")
+	MessagingConsumerGroupKey   = attribute.Key("messaging.consumer.group.name")
+)
+
+// InjectMessage adds the trace context of ctx to message headers, creating
+// the map when needed
+func InjectMessage(ctx context.Context, headers map[string]string) map[string]string {
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	messagePropagator.Inject(ctx, propagation.MapCarrier(headers))
+	return headers
+}
+
+// ExtractMessage returns ctx with the trace context carried by message
+// headers as the remote parent
+func ExtractMessage(ctx context.Context, headers map[string]string) context.Context {
+	return messagePropagator.Extract(ctx, propagation.MapCarrier(headers))
+}
+
+// StartPublishSpan starts a producer span following the messaging conventions
+func StartPublishSpan(ctx context.Context, system string, destination string, bodySize int) (context.Context, trace.Span) {
+	return GetTracer(ctx).Start(ctx, "send "+destination,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			MessagingSystemKey.String(system),
+			MessagingDestinationNameKey.String(destination),
+			MessagingOperationTypeKey.String("send"),
+			MessagingOperationNameKey.String("send"),
+			MessagingBodySizeKey.Int(bodySize),
+		),
+	)
+}
+
+// StartProcessSpan starts a consumer span for handling a message. The span is
+// a child of the trace context in the headers, so handlers show up under the
+// request that published the message.
+func StartProcessSpan(ctx context.Context, system string, destination string, group string, headers map[string]string) (context.Context, trace.Span) {
+	ctx = ExtractMessage(ctx, headers)
+	return GetTracer(ctx).Start(ctx, "process "+destination,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			MessagingSystemKey.String(system),
+			MessagingDestinationNameKey.String(destination),
+			MessagingOperationTypeKey.String("process"),
+			MessagingOperationNameKey.String("process"),
+			MessagingConsumerGroupKey.String(group),
+		),
+	)
+}
@@ -14,14 +14,15 @@ import (
 var (
 	shutdownFunc func(context.Context) error
 	appCtx       context.Context
+	environment  string
 )
 
 // InitTracing initializes the tracing system with Tempo/Grafana provider
-func InitTracing(ctx context.Context) (context.Context, error) {
-	cfg := config.LoadConfigOrPanic()
+func InitTracing(ctx context.Context, cfg config.Config) (context.Context, error) {
+	environment = cfg.AppConfig.Env
 
 	// Get tracing configuration
-	tracingCfg := GetTracingConfig()
+	tracingCfg := cfg.TracingConfig
 
 	// Setup Tempo provider configuration
 	providerConfig := providers.ProviderConfig{
@@ -78,12 +79,10 @@ func GetServiceName(ctx context.Context) string {
 
 // GetEnvironmentAttribute returns the environment as an OpenTelemetry attribute
 func GetEnvironmentAttribute() trace.SpanStartOption {
-	cfg := config.LoadConfigOrPanic()
-	return trace.WithAttributes(attribute.String("environment", cfg.AppConfig.Env))
+	return trace.WithAttributes(attribute.String("environment", environment))
 }
 
 // GetEnvironmentTag returns the environment for DataDog/Jaeger tags
 func GetEnvironmentTag() string {
-	cfg := config.LoadConfigOrPanic()
-	return cfg.AppConfig.Env
+	return environment
 }
\ No newline at end of file